@@ -1,20 +1,178 @@
 create table user
+(
+    id           int                                     not null auto_increment,
+    user_name    varchar(100)                            not null,
+    email        varchar(100)                            not null,
+    currency     char(3)     default 'USD'               not null,
+    timezone     varchar(64) default 'UTC'               not null,
+    date_created datetime    default current_timestamp() not null,
+    constraint user_pk primary key (id)
+);
+
+create table import_batch
 (
     id           int                                  not null auto_increment,
-    user_name    varchar(100)                         not null,
-    email        varchar(100)                         not null,
+    user_id      int                                  not null,
+    filename     varchar(255)                         null,
+    checksum     char(64)                             null,
+    row_count    int      default 0                   not null,
+    status       varchar(20)                          not null,
     date_created datetime default current_timestamp() not null,
-    constraint user_pk primary key (id)
+    constraint import_batch_pk primary key (id),
+    constraint import_batch_user_id_fk foreign key (user_id) references user (id)
 );
 
 create table transaction
 (
-    id           int        not null auto_increment,
-    user_id      int        not null,
-    amount       float      not null,
-    date_created datetime   not null,
+    id           int            not null auto_increment,
+    user_id      int            not null,
+    batch_id     int            null,
+    amount       decimal(19, 4) not null,
+    currency     char(3)        not null,
+    description  varchar(255)   null,
+    reference    varchar(100)   null,
+    category     varchar(50)    null,
+    fingerprint  char(64)       not null,
+    balance      decimal(19, 4) null,
+    date_created datetime       not null,
     constraint transaction_pk primary key (id),
-    constraint user_id_fk foreign key (user_id) references user (id)
+    constraint user_id_fk foreign key (user_id) references user (id),
+    constraint batch_id_fk foreign key (batch_id) references import_batch (id)
+);
+
+create index transaction_fingerprint_idx on transaction (user_id, fingerprint);
+create index transaction_user_date_idx on transaction (user_id, date_created);
+
+create table job
+(
+    id                    int                                  not null auto_increment,
+    user_id               int                                  not null,
+    status                varchar(20)                          not null,
+    payload               longblob                             not null,
+    options               json                                 null,
+    error                 text                                 null,
+    batch_id              int                                  null,
+    inserted_transactions int                                  null,
+    skipped_transactions  int                                  null,
+    date_created          datetime default current_timestamp() not null,
+    date_updated          datetime default current_timestamp() not null,
+    date_started          datetime                             null,
+    date_finished         datetime                             null,
+    constraint job_pk primary key (id),
+    constraint job_user_id_fk foreign key (user_id) references user (id)
+);
+
+create table outbox
+(
+    id                int                                  not null auto_increment,
+    user_id           int                                  not null,
+    email             varchar(100)                         not null,
+    message           mediumtext                           not null,
+    text              mediumtext                           null,
+    attachments       longblob                             null,
+    status            varchar(20)                          not null,
+    attempts          int      default 0                   not null,
+    error             text                                 null,
+    date_created      datetime default current_timestamp() not null,
+    date_next_attempt datetime default current_timestamp() not null,
+    date_sent         datetime                             null,
+    constraint outbox_pk primary key (id),
+    constraint outbox_user_id_fk foreign key (user_id) references user (id)
+);
+
+create index outbox_status_idx on outbox (status, date_next_attempt);
+
+create table dead_letter
+(
+    id            int                                  not null auto_increment,
+    user_id       int                                  not null,
+    channel       varchar(20)                          not null,
+    target        varchar(500)                         not null,
+    email         varchar(255)                         not null,
+    message       mediumtext                           not null,
+    notification  longblob                             not null,
+    error         text                                 not null,
+    attempts      int                                  not null,
+    status        varchar(20)                          not null,
+    date_created  datetime default current_timestamp() not null,
+    date_redriven datetime                             null,
+    constraint dead_letter_pk primary key (id),
+    constraint dead_letter_user_id_fk foreign key (user_id) references user (id)
+);
+
+create table notification_delivery
+(
+    id               int                                  not null auto_increment,
+    notification_key varchar(100)                         not null,
+    channel          varchar(20)                          not null,
+    target           varchar(500)                         not null,
+    status           varchar(20)                          not null,
+    date_created     datetime default current_timestamp() not null,
+    date_updated     datetime default current_timestamp() not null,
+    constraint notification_delivery_pk primary key (id),
+    constraint notification_delivery_uk unique (notification_key, channel, target)
+);
+
+create table notification_preference
+(
+    id      int          not null auto_increment,
+    user_id int          not null,
+    channel varchar(20)  not null,
+    target  varchar(500) not null,
+    secret  varchar(255) null,
+    constraint notification_preference_pk primary key (id),
+    constraint notification_preference_user_id_fk foreign key (user_id) references user (id)
+);
+
+create table fx_rate
+(
+    id             int            not null auto_increment,
+    base_currency  char(3)        not null,
+    quote_currency char(3)        not null,
+    rate           decimal(19, 8) not null,
+    effective_date date           not null,
+    constraint fx_rate_pk primary key (id),
+    constraint fx_rate_uk unique (base_currency, quote_currency, effective_date)
+);
+
+create table category_rule
+(
+    id           int                                  not null auto_increment,
+    user_id      int                                  null,
+    category     varchar(50)                          not null,
+    kind         varchar(20)                          not null,
+    pattern      varchar(255)                         null,
+    min_amount   decimal(19, 4)                       null,
+    max_amount   decimal(19, 4)                       null,
+    priority     int      default 0                   not null,
+    date_created datetime default current_timestamp() not null,
+    constraint category_rule_pk primary key (id),
+    constraint category_rule_user_id_fk foreign key (user_id) references user (id)
+);
+
+create table import_profile
+(
+    name               varchar(100) not null,
+    amount_column      varchar(100) not null,
+    date_column        varchar(100) not null,
+    currency_column    varchar(100) null,
+    description_column varchar(100) null,
+    reference_column   varchar(100) null,
+    constraint import_profile_pk primary key (name)
+);
+
+create table idempotency_key
+(
+    idempotency_key varchar(255)                         not null,
+    fingerprint     char(64)                             not null,
+    status          varchar(20)                          not null,
+    status_code     int                                  null,
+    content_type    varchar(100)                         null,
+    response        mediumblob                           null,
+    reservation     char(32)                             not null,
+    date_created    datetime default current_timestamp() not null,
+    date_finished   datetime                             null,
+    constraint idempotency_key_pk primary key (idempotency_key)
 );
 
 insert into user (id,user_name,email) values (100, "juan perez", "xxxxxxx@gmail.com");
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+
 	"transaction-tool-api/src/internal/database"
+	"transaction-tool-api/src/internal/idempotency"
 	"transaction-tool-api/src/internal/notifier"
 	"transaction-tool-api/src/internal/summarizer"
 
@@ -11,18 +14,52 @@ import (
 func main() {
 	router := gin.Default()
 
-	controller := summarizer.NewController(
-		summarizer.NewService(
-			summarizer.NewRepository(
-				database.NewSQLClient(
-					database.GetLocalMySQLClientConfig(),
-				),
-			),
-			notifier.NewClient(notifier.GetOptions()),
-		),
+	sqlClient := database.NewSQLClient(
+		database.GetLocalMySQLClientConfig(),
+	)
+
+	repository := summarizer.NewRepository(sqlClient)
+
+	service := summarizer.NewService(repository)
+
+	deadLetters := notifier.NewDeadLetterStore(sqlClient)
+	preferences := notifier.NewPreferenceStore(sqlClient)
+	deliveries := notifier.NewDeliveryStore(sqlClient)
+	channels := notifier.NewChannels(notifier.NewClient(notifier.GetOptions()), notifier.GetChannelOptions())
+
+	dispatcher := summarizer.NewDispatcher(
+		repository,
+		notifier.NewUserNotifier(preferences, deliveries, deadLetters, channels, notifier.GetRetryOptions()),
+		summarizer.GetDispatcherOptions(),
 	)
+	dispatcher.Start(context.Background())
+
+	worker := summarizer.NewWorker(repository, service, summarizer.GetWorkerOptions())
+	worker.Start(context.Background())
+
+	controller := summarizer.NewController(service, worker)
+	notifierController := notifier.NewController(channels, deadLetters, preferences, deliveries)
+	idempotent := idempotency.NewMiddleware(idempotency.NewStore(sqlClient))
 
-	router.POST("/transaction-tool/resume/:user_id", controller.ResumeTransactions)
+	router.POST("/transaction-tool/resume/:user_id", idempotent, controller.ResumeTransactions)
+	router.POST("/transaction-tool/resume/:user_id/preview", controller.PreviewResume)
+	router.GET("/transaction-tool/jobs/:id", controller.GetJob)
+	router.POST("/transaction-tool/fx-rates", controller.LoadFXRates)
+	router.PUT("/transaction-tool/import-profiles/:name", controller.SaveImportProfile)
+	router.GET("/transaction-tool/import-profiles/:name", controller.GetImportProfile)
+	router.GET("/transaction-tool/users/:user_id/import-batches", controller.GetImportBatches)
+	router.GET("/transaction-tool/import-batches/:id/transactions", controller.GetImportBatchTransactions)
+	router.DELETE("/transaction-tool/import-batches/:id", controller.RollbackImportBatch)
+	router.POST("/transaction-tool/category-rules", controller.CreateCategoryRule)
+	router.GET("/transaction-tool/category-rules", controller.GetCategoryRules)
+	router.DELETE("/transaction-tool/category-rules/:id", controller.DeleteCategoryRule)
+	router.POST("/transaction-tool/users/:user_id/recategorize", controller.RecategorizeTransactions)
+	router.GET("/transaction-tool/users/:user_id/forecast", controller.GetForecast)
+	router.GET("/transaction-tool/resume/:user_id/:year", controller.GetResumePDF)
+	router.GET("/transaction-tool/dead-letters", notifierController.GetDeadLetters)
+	router.POST("/transaction-tool/dead-letters/:id/redrive", notifierController.RedriveDeadLetter)
+	router.GET("/transaction-tool/users/:user_id/notification-preferences", notifierController.GetPreferences)
+	router.PUT("/transaction-tool/users/:user_id/notification-preferences", notifierController.SavePreferences)
 
 	if err := router.Run(":8080"); err != nil {
 		panic(err)
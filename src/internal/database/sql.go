@@ -29,6 +29,7 @@ func (cfg SQLConfig) toMySQLConfig() *mysql.Config {
 	config.DBName = cfg.DBName
 	config.Net = cfg.Net
 	config.Addr = cfg.Host + ":" + cfg.Port
+	config.ParseTime = true
 
 	return config
 }
@@ -0,0 +1,199 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func badRequestError(message string) Error {
+	return Error{
+		Status:  http.StatusBadRequest,
+		Code:    "bad request",
+		Message: message,
+	}
+}
+
+func conflictError(message string) Error {
+	return Error{
+		Status:  http.StatusConflict,
+		Code:    "conflict",
+		Message: message,
+	}
+}
+
+func internalError(message string) Error {
+	return Error{
+		Status:  http.StatusInternalServerError,
+		Code:    "internal error",
+		Message: message,
+	}
+}
+
+// NewMiddleware makes the requests with an Idempotency-Key header safe to retry: the response of
+// the first request is stored, and replayed for the next ones with the same key and payload.
+func NewMiddleware(store Store) gin.HandlerFunc {
+	return middleware{store: store}.handle
+}
+
+type middleware struct {
+	store Store
+}
+
+func (m middleware) handle(c *gin.Context) {
+	key := c.GetHeader(HeaderKey)
+	if key == "" {
+		c.Next()
+		return
+	}
+
+	if len(key) > maxKeyLength {
+		c.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			badRequestError(fmt.Sprintf("%s header is longer than %d characters", HeaderKey, maxKeyLength)))
+		return
+	}
+
+	body, fingerprint, err := spoolBody(c.Request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, badRequestError(err.Error()))
+		return
+	}
+	defer func() {
+		_ = body.Close()
+		_ = os.Remove(body.Name())
+	}()
+
+	record, reserved, err := m.store.reserve(c.Request.Context(), key, fingerprint)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	if !reserved {
+		m.replay(c, record, fingerprint)
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	c.Request.Body = body
+
+	// The result is stored even when the client went away, which is when it is most likely to retry.
+	// Server errors are not stored, and neither are panics, which the recovery middleware turns into
+	// server errors, so that the request can be retried with the same key.
+	ctx := context.Background()
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		if err := m.store.release(ctx, record); err != nil {
+			log.Printf("error releasing idempotency key '%s' due to: %s", key, err.Error())
+		}
+	}()
+
+	c.Next()
+
+	if recorder.Status() >= http.StatusInternalServerError {
+		return
+	}
+
+	// from here on the request is not processed again, even if its response cannot be stored
+	completed = true
+
+	record.Status = StatusCompleted
+	record.StatusCode = recorder.Status()
+	record.ContentType = recorder.Header().Get("Content-Type")
+	record.Response = recorder.body.Bytes()
+
+	if err = m.store.complete(ctx, record); err != nil {
+		log.Printf("error completing idempotency key '%s' due to: %s", key, err.Error())
+	}
+}
+
+func (m middleware) replay(c *gin.Context, record Record, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		c.AbortWithStatusJSON(
+			http.StatusConflict,
+			conflictError(fmt.Sprintf("idempotency key '%s' was already used with a different request", record.Key)))
+		return
+	}
+
+	if record.Status != StatusCompleted {
+		c.AbortWithStatusJSON(
+			http.StatusConflict,
+			conflictError(fmt.Sprintf("request with idempotency key '%s' is still being processed", record.Key)))
+		return
+	}
+
+	c.Header(HeaderReplayed, "true")
+	if record.ContentType == "" {
+		c.Status(record.StatusCode)
+		c.Abort()
+		return
+	}
+	c.Data(record.StatusCode, record.ContentType, record.Response)
+	c.Abort()
+}
+
+// spoolBody copies the request body to a temporary file while hashing it together with the
+// method, path and query, so that large uploads are fingerprinted without holding them in memory.
+func spoolBody(r *http.Request) (*os.File, string, error) {
+	file, err := os.CreateTemp("", "idempotent-body-*")
+	if err != nil {
+		return nil, "", fmt.Errorf("error buffering request body due to: %w", err)
+	}
+
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%s %s?%s\n", r.Method, r.URL.Path, r.URL.RawQuery)
+
+	if r.Body != nil {
+		if _, err = io.Copy(io.MultiWriter(file, hash), r.Body); err == nil {
+			_, err = file.Seek(0, io.SeekStart)
+		}
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, "", fmt.Errorf("error buffering request body due to: %w", err)
+	}
+
+	return file, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,198 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fingerprint(method, target, body string) string {
+	hash := sha256.Sum256([]byte(method + " " + target + "\n" + body))
+	return hex.EncodeToString(hash[:])
+}
+
+func TestMiddlewareHandle(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		target    = "/resume/5?async=true"
+		print     = fingerprint(http.MethodPost, target, "10,2021-10-01T00:00:00Z")
+		reserved  = Record{Key: "key", Fingerprint: print, Status: StatusProcessing, Reservation: "token"}
+		completed = Record{
+			Key:         "key",
+			Fingerprint: print,
+			Status:      StatusCompleted,
+			StatusCode:  http.StatusAccepted,
+			ContentType: "application/json; charset=utf-8",
+			Response:    []byte(`{"ID":1}`),
+			Reservation: "token",
+		}
+	)
+
+	tests := []struct {
+		name             string
+		key              string
+		handlerStatus    int
+		mockApplier      func(m *storeMock)
+		expectedCalls    int
+		expectedCode     int
+		expectedBody     any
+		expectedReplayed bool
+	}{
+		{
+			name:          "request without key",
+			handlerStatus: http.StatusAccepted,
+			expectedCalls: 1,
+			expectedCode:  http.StatusAccepted,
+			expectedBody:  gin.H{"ID": 1},
+		},
+		{
+			name:         "key too long",
+			key:          strings.Repeat("k", 256),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("Idempotency-Key header is longer than 255 characters"),
+		},
+		{
+			name: "error reserving key",
+			key:  "key",
+			mockApplier: func(m *storeMock) {
+				m.On("reserve", "key", print).Return(nil, false, customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name:          "first request stores its response",
+			key:           "key",
+			handlerStatus: http.StatusAccepted,
+			mockApplier: func(m *storeMock) {
+				m.On("reserve", "key", print).Return(reserved, true, nil).Once()
+				m.On("complete", completed).Return(customErr).Once()
+			},
+			expectedCalls: 1,
+			expectedCode:  http.StatusAccepted,
+			expectedBody:  gin.H{"ID": 1},
+		},
+		{
+			name:          "first request with server error releases the key",
+			key:           "key",
+			handlerStatus: http.StatusInternalServerError,
+			mockApplier: func(m *storeMock) {
+				m.On("reserve", "key", print).Return(reserved, true, nil).Once()
+				m.On("release", reserved).Return(nil).Once()
+			},
+			expectedCalls: 1,
+			expectedCode:  http.StatusInternalServerError,
+			expectedBody:  gin.H{"ID": 1},
+		},
+		{
+			name: "key used with a different request",
+			key:  "key",
+			mockApplier: func(m *storeMock) {
+				m.On("reserve", "key", print).Return(Record{Key: "key", Fingerprint: "other"}, false, nil).Once()
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: conflictError("idempotency key 'key' was already used with a different request"),
+		},
+		{
+			name: "key still being processed",
+			key:  "key",
+			mockApplier: func(m *storeMock) {
+				m.On("reserve", "key", print).Return(reserved, false, nil).Once()
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: conflictError("request with idempotency key 'key' is still being processed"),
+		},
+		{
+			name: "completed request replayed",
+			key:  "key",
+			mockApplier: func(m *storeMock) {
+				m.On("reserve", "key", print).Return(completed, false, nil).Once()
+			},
+			expectedCode:     http.StatusAccepted,
+			expectedBody:     gin.H{"ID": 1},
+			expectedReplayed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+
+			var (
+				calls     int
+				storeMock = &storeMock{}
+				router    = gin.New()
+				r         = httptest.NewRecorder()
+				req       = httptest.NewRequest(
+					http.MethodPost, target, bytes.NewBufferString("10,2021-10-01T00:00:00Z"))
+			)
+
+			if test.mockApplier != nil {
+				test.mockApplier(storeMock)
+				defer storeMock.AssertExpectations(t)
+			}
+
+			router.POST("/resume/:user_id", NewMiddleware(storeMock), func(c *gin.Context) {
+				calls++
+				body, err := io.ReadAll(c.Request.Body)
+				require.Nil(t, err)
+				require.Equal(t, "10,2021-10-01T00:00:00Z", string(body))
+				c.JSON(test.handlerStatus, gin.H{"ID": 1})
+			})
+
+			if test.key != "" {
+				req.Header.Set(HeaderKey, test.key)
+			}
+
+			router.ServeHTTP(r, req)
+
+			b, err := json.Marshal(test.expectedBody)
+			require.Nil(t, err)
+
+			assert.Equal(t, test.expectedCalls, calls)
+			assert.Equal(t, test.expectedCode, r.Code)
+			assert.Equal(t, string(b), r.Body.String())
+			assert.Equal(t, test.expectedReplayed, r.Header().Get(HeaderReplayed) == "true")
+		})
+	}
+}
+
+func TestMiddlewareHandleReleasesKeyOnPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var (
+		print     = fingerprint(http.MethodPost, "/resume/5?", "10,2021-10-01T00:00:00Z")
+		storeMock = &storeMock{}
+		router    = gin.New()
+		r         = httptest.NewRecorder()
+		req       = httptest.NewRequest(http.MethodPost, "/resume/5", bytes.NewBufferString("10,2021-10-01T00:00:00Z"))
+		reserved  = Record{Key: "key", Fingerprint: print, Status: StatusProcessing, Reservation: "token"}
+	)
+
+	storeMock.On("reserve", "key", print).Return(reserved, true, nil).Once()
+	storeMock.On("release", reserved).Return(nil).Once()
+	defer storeMock.AssertExpectations(t)
+
+	// the recovery middleware is outside the idempotency one, as in the application
+	router.Use(gin.RecoveryWithWriter(io.Discard))
+	router.POST("/resume/:user_id", NewMiddleware(storeMock), func(c *gin.Context) {
+		panic("handler panicked")
+	})
+
+	req.Header.Set(HeaderKey, "key")
+
+	router.ServeHTTP(r, req)
+
+	assert.Equal(t, http.StatusInternalServerError, r.Code)
+}
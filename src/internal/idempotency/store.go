@@ -0,0 +1,160 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// processingExpiry is the time after which a key still being processed is taken over by a retry of its
+// request, as the instance processing it stopped before completing or releasing it.
+const processingExpiry = 15 * time.Minute
+
+type Status string
+
+const (
+	StatusProcessing Status = "processing"
+	StatusCompleted  Status = "completed"
+)
+
+type Record struct {
+	Key         string
+	Fingerprint string
+	Status      Status
+	StatusCode  int
+	ContentType string
+	Response    []byte
+	// Reservation identifies the request which reserved the key, so that it only releases its own reservation
+	// and not the one of the request which took it over.
+	Reservation string
+}
+
+func NewStore(client *sql.DB) Store {
+	return store{client: client, reservation: newReservation}
+}
+
+type Store interface {
+	reserve(context.Context, string, string) (Record, bool, error)
+	complete(context.Context, Record) error
+	release(context.Context, Record) error
+}
+
+type store struct {
+	client      *sql.DB
+	reservation func() (string, error)
+}
+
+// newReservation returns a random token identifying a reservation.
+func newReservation() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// reserve saves the key as being processed, or takes it over when the same request has been processed for
+// longer than the processing expiry. Otherwise, the stored record is returned instead and the returned bool
+// is false.
+func (s store) reserve(ctx context.Context, key, fingerprint string) (Record, bool, error) {
+	var (
+		insertQuery = `INSERT IGNORE INTO idempotency_key (idempotency_key, fingerprint, status, reservation) ` +
+			`VALUES (?,?,?,?)`
+		updateQuery = `UPDATE idempotency_key SET reservation = ?, date_created = now() WHERE idempotency_key = ? ` +
+			`AND fingerprint = ? AND status = ? AND date_created < now() - INTERVAL ? SECOND`
+	)
+
+	reservation, err := s.reservation()
+	if err != nil {
+		return Record{}, false, fmt.Errorf("error generating reservation of idempotency key '%s' due to: %w", key, err)
+	}
+	reserved := Record{Key: key, Fingerprint: fingerprint, Status: StatusProcessing, Reservation: reservation}
+
+	result, err := s.client.ExecContext(ctx, insertQuery, key, fingerprint, StatusProcessing, reservation)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("error reserving idempotency key '%s' due to: %w", key, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return Record{}, false, fmt.Errorf("error getting reserved idempotency keys due to: %w", err)
+	}
+
+	if rowsAffected == 1 {
+		return reserved, true, nil
+	}
+
+	result, err = s.client.ExecContext(
+		ctx, updateQuery, reservation, key, fingerprint, StatusProcessing, int64(processingExpiry.Seconds()))
+	if err != nil {
+		return Record{}, false, fmt.Errorf("error taking over idempotency key '%s' due to: %w", key, err)
+	}
+
+	if rowsAffected, err = result.RowsAffected(); err != nil {
+		return Record{}, false, fmt.Errorf("error getting taken over idempotency keys due to: %w", err)
+	}
+
+	if rowsAffected == 1 {
+		return reserved, true, nil
+	}
+
+	record, err := s.getRecord(ctx, key)
+	if err != nil {
+		return Record{}, false, err
+	}
+
+	return record, false, nil
+}
+
+func (s store) getRecord(ctx context.Context, key string) (Record, error) {
+	var (
+		record      Record
+		statusCode  sql.NullInt64
+		contentType sql.NullString
+		query       = `SELECT idempotency_key, fingerprint, status, status_code, content_type, response ` +
+			`FROM idempotency_key WHERE idempotency_key = ?`
+	)
+
+	err := s.client.QueryRowContext(ctx, query, key).Scan(
+		&record.Key,
+		&record.Fingerprint,
+		&record.Status,
+		&statusCode,
+		&contentType,
+		&record.Response,
+	)
+	if err != nil {
+		return Record{}, fmt.Errorf("error scanning idempotency key '%s' due to: %w", key, err)
+	}
+
+	record.StatusCode = int(statusCode.Int64)
+	record.ContentType = contentType.String
+
+	return record, nil
+}
+
+func (s store) complete(ctx context.Context, record Record) error {
+	query := `UPDATE idempotency_key SET status = ?, status_code = ?, content_type = ?, response = ?, ` +
+		`date_finished = now() WHERE idempotency_key = ?`
+
+	_, err := s.client.ExecContext(
+		ctx, query, StatusCompleted, record.StatusCode, record.ContentType, record.Response, record.Key)
+	if err != nil {
+		return fmt.Errorf("error completing idempotency key '%s' due to: %w", record.Key, err)
+	}
+	return nil
+}
+
+// release deletes the reservation of the record, unless it was taken over by a retry of its request meanwhile,
+// which is still being processed.
+func (s store) release(ctx context.Context, record Record) error {
+	query := `DELETE FROM idempotency_key WHERE idempotency_key = ? AND status = ? AND reservation = ?`
+
+	if _, err := s.client.ExecContext(ctx, query, record.Key, StatusProcessing, record.Reservation); err != nil {
+		return fmt.Errorf("error releasing idempotency key '%s' due to: %w", record.Key, err)
+	}
+	return nil
+}
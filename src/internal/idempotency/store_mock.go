@@ -0,0 +1,33 @@
+package idempotency
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type storeMock struct {
+	mock.Mock
+}
+
+func (m *storeMock) reserve(_ context.Context, key, fingerprint string) (Record, bool, error) {
+	var (
+		record Record
+		args   = m.Called(key, fingerprint)
+	)
+
+	if value, ok := args.Get(0).(Record); ok {
+		record = value
+	}
+	return record, args.Bool(1), args.Error(2)
+}
+
+func (m *storeMock) complete(_ context.Context, record Record) error {
+	args := m.Called(record)
+	return args.Error(0)
+}
+
+func (m *storeMock) release(_ context.Context, record Record) error {
+	args := m.Called(record)
+	return args.Error(0)
+}
@@ -0,0 +1,243 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDBMock() (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	return db, mock
+}
+
+func TestStoreReserve(t *testing.T) {
+	var (
+		customErr   = errors.New("custom error")
+		insertQuery = regexp.QuoteMeta(
+			`INSERT IGNORE INTO idempotency_key (idempotency_key, fingerprint, status, reservation) VALUES (?,?,?,?)`)
+		selectQuery = regexp.QuoteMeta(
+			`SELECT idempotency_key, fingerprint, status, status_code, content_type, response ` +
+				`FROM idempotency_key WHERE idempotency_key = ?`)
+		updateQuery = regexp.QuoteMeta(
+			`UPDATE idempotency_key SET reservation = ?, date_created = now() WHERE idempotency_key = ? ` +
+				`AND fingerprint = ? AND status = ? AND date_created < now() - INTERVAL ? SECOND`)
+		columns = []string{"idempotency_key", "fingerprint", "status", "status_code", "content_type", "response"}
+	)
+
+	tests := []struct {
+		name             string
+		mockApplier      func(sqlmock.Sqlmock)
+		reservationErr   error
+		expectedRecord   Record
+		expectedReserved bool
+		expectedErr      error
+	}{
+		{
+			name:           "error generating reservation",
+			mockApplier:    func(m sqlmock.Sqlmock) {},
+			reservationErr: customErr,
+			expectedErr: fmt.Errorf(
+				"error generating reservation of idempotency key '%s' due to: %w", "key", customErr),
+		},
+		{
+			name: "error inserting key",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(insertQuery).WithArgs("key", "fingerprint", "processing", "token").
+					WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error reserving idempotency key '%s' due to: %w", "key", customErr),
+		},
+		{
+			name: "key reserved",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(insertQuery).WithArgs("key", "fingerprint", "processing", "token").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedRecord: Record{
+				Key:         "key",
+				Fingerprint: "fingerprint",
+				Status:      StatusProcessing,
+				Reservation: "token",
+			},
+			expectedReserved: true,
+		},
+		{
+			name: "error taking over existing key",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(insertQuery).WithArgs("key", "fingerprint", "processing", "token").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectExec(updateQuery).WithArgs("token", "key", "fingerprint", "processing", int64(900)).
+					WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error taking over idempotency key '%s' due to: %w", "key", customErr),
+		},
+		{
+			name: "expired key taken over",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(insertQuery).WithArgs("key", "fingerprint", "processing", "token").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectExec(updateQuery).WithArgs("token", "key", "fingerprint", "processing", int64(900)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedRecord: Record{
+				Key:         "key",
+				Fingerprint: "fingerprint",
+				Status:      StatusProcessing,
+				Reservation: "token",
+			},
+			expectedReserved: true,
+		},
+		{
+			name: "error scanning existing key",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(insertQuery).WithArgs("key", "fingerprint", "processing", "token").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectExec(updateQuery).WithArgs("token", "key", "fingerprint", "processing", int64(900)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectQuery(selectQuery).WithArgs("key").WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error scanning idempotency key '%s' due to: %w", "key", customErr),
+		},
+		{
+			name: "existing key",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(insertQuery).WithArgs("key", "fingerprint", "processing", "token").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectExec(updateQuery).WithArgs("token", "key", "fingerprint", "processing", int64(900)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectQuery(selectQuery).WithArgs("key").WillReturnRows(sqlmock.NewRows(columns).
+					AddRow("key", "other", "completed", 200, "application/json", []byte(`{}`)))
+			},
+			expectedRecord: Record{
+				Key:         "key",
+				Fingerprint: "other",
+				Status:      StatusCompleted,
+				StatusCode:  200,
+				ContentType: "application/json",
+				Response:    []byte(`{}`),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			reservation := func() (string, error) {
+				return "token", test.reservationErr
+			}
+
+			record, reserved, err := store{client: db, reservation: reservation}.reserve(
+				context.TODO(), "key", "fingerprint")
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expectedReserved, reserved)
+			assert.Equal(t, test.expectedRecord, record)
+		})
+	}
+}
+
+func TestStoreComplete(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(
+			`UPDATE idempotency_key SET status = ?, status_code = ?, content_type = ?, response = ?, ` +
+				`date_finished = now() WHERE idempotency_key = ?`)
+		record = Record{Key: "key", StatusCode: 200, Response: []byte(`{}`)}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    error
+	}{
+		{
+			name: "error executing query",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("completed", 200, "", []byte(`{}`), "key").WillReturnError(customErr)
+			},
+			expected: fmt.Errorf("error completing idempotency key '%s' due to: %w", "key", customErr),
+		},
+		{
+			name: "key completed",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("completed", 200, "", []byte(`{}`), "key").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			assert.Equal(t, test.expected, store{client: db}.complete(context.TODO(), record))
+		})
+	}
+}
+
+func TestStoreRelease(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(
+			`DELETE FROM idempotency_key WHERE idempotency_key = ? AND status = ? AND reservation = ?`)
+		record = Record{Key: "key", Status: StatusProcessing, Reservation: "token"}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    error
+	}{
+		{
+			name: "error executing query",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("key", "processing", "token").WillReturnError(customErr)
+			},
+			expected: fmt.Errorf("error releasing idempotency key '%s' due to: %w", "key", customErr),
+		},
+		{
+			name: "key released",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("key", "processing", "token").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "reservation taken over kept",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("key", "processing", "token").WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			assert.Equal(t, test.expected, store{client: db}.release(context.TODO(), record))
+		})
+	}
+}
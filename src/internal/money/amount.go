@@ -0,0 +1,190 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of decimal places kept by an Amount, matching the DECIMAL(19,4) columns.
+const Scale = 4
+
+const unit = 10000
+
+// Amount is a monetary value expressed in ten-thousandths, so sums are exact.
+type Amount int64
+
+func FromInt(value int64) Amount {
+	return Amount(value * unit)
+}
+
+func Parse(value string) (Amount, error) {
+	units, err := parseDecimal("amount", value, Scale)
+	if err != nil {
+		return 0, err
+	}
+	return Amount(units), nil
+}
+
+func parseDecimal(kind string, value string, scale int) (int64, error) {
+	str := strings.TrimSpace(value)
+	if str == "" {
+		return 0, fmt.Errorf("%s is empty", kind)
+	}
+
+	negative := false
+	switch str[0] {
+	case '-':
+		negative = true
+		str = str[1:]
+	case '+':
+		str = str[1:]
+	}
+
+	integerPart, fractionalPart, hasPoint := strings.Cut(str, ".")
+	if integerPart == "" && fractionalPart == "" || hasPoint && fractionalPart == "" {
+		return 0, fmt.Errorf("%s '%s' is not a decimal number", kind, value)
+	}
+
+	if len(fractionalPart) > scale {
+		return 0, fmt.Errorf("%s '%s' has more than %d decimal places", kind, value, scale)
+	}
+
+	digits := integerPart + fractionalPart + strings.Repeat("0", scale-len(fractionalPart))
+	for _, digit := range digits {
+		if digit < '0' || digit > '9' {
+			return 0, fmt.Errorf("%s '%s' is not a decimal number", kind, value)
+		}
+	}
+
+	units, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s '%s' is out of range", kind, value)
+	}
+
+	if negative {
+		units = -units
+	}
+
+	return units, nil
+}
+
+func MustParse(value string) Amount {
+	amount, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+	return amount
+}
+
+// Round rounds the amount to the given decimal places, half away from zero.
+func (a Amount) Round(places int) Amount {
+	if places >= Scale {
+		return a
+	}
+	step := int64(math.Pow10(Scale - places))
+	return Amount(divRound(int64(a), step) * step)
+}
+
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// DivRound divides the amount by n and rounds the quotient to the given decimal places, half away from zero.
+func (a Amount) DivRound(n int64, places int) Amount {
+	if n == 0 {
+		return 0
+	}
+	if places > Scale {
+		places = Scale
+	}
+	step := int64(math.Pow10(Scale - places))
+	return Amount(divRound(int64(a), n*step) * step)
+}
+
+func divRound(numerator, denominator int64) int64 {
+	if denominator < 0 {
+		numerator, denominator = -numerator, -denominator
+	}
+
+	quotient, remainder := numerator/denominator, numerator%denominator
+	if remainder < 0 {
+		remainder = -remainder
+	}
+
+	if 2*remainder >= denominator {
+		if numerator < 0 {
+			quotient--
+		} else {
+			quotient++
+		}
+	}
+	return quotient
+}
+
+func (a Amount) StringFixed(places int) string {
+	if places > Scale {
+		places = Scale
+	}
+	if places < 0 {
+		places = 0
+	}
+
+	rounded := int64(a.Round(places))
+
+	sign := ""
+	if rounded < 0 {
+		sign = "-"
+	}
+
+	abs := uint64(rounded)
+	if rounded < 0 {
+		abs = uint64(-rounded)
+	}
+
+	integerPart := strconv.FormatUint(abs/unit, 10)
+	if places == 0 {
+		return sign + integerPart
+	}
+
+	fractionalPart := fmt.Sprintf("%04d", abs%unit)[:places]
+	return sign + integerPart + "." + fractionalPart
+}
+
+func (a Amount) String() string {
+	return a.StringFixed(Scale)
+}
+
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+func (a *Amount) Scan(src any) error {
+	var (
+		amount Amount
+		err    error
+	)
+
+	switch value := src.(type) {
+	case []byte:
+		amount, err = Parse(string(value))
+	case string:
+		amount, err = Parse(value)
+	case int64:
+		amount = FromInt(value)
+	default:
+		return fmt.Errorf("cannot scan %T into amount", src)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	*a = amount
+	return nil
+}
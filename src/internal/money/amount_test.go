@@ -0,0 +1,157 @@
+package money
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    Amount
+		expectedErr error
+	}{
+		{
+			name:        "empty",
+			value:       "",
+			expectedErr: errors.New("amount is empty"),
+		},
+		{
+			name:        "not a number",
+			value:       "bad format",
+			expectedErr: errors.New("amount 'bad format' is not a decimal number"),
+		},
+		{
+			name:        "only sign",
+			value:       "-",
+			expectedErr: errors.New("amount '-' is not a decimal number"),
+		},
+		{
+			name:        "trailing point",
+			value:       "10.",
+			expectedErr: errors.New("amount '10.' is not a decimal number"),
+		},
+		{
+			name:        "exponent",
+			value:       "1e3",
+			expectedErr: errors.New("amount '1e3' is not a decimal number"),
+		},
+		{
+			name:        "too many decimal places",
+			value:       "10.12345",
+			expectedErr: errors.New("amount '10.12345' has more than 4 decimal places"),
+		},
+		{
+			name:        "out of range",
+			value:       "99999999999999999999",
+			expectedErr: errors.New("amount '99999999999999999999' is out of range"),
+		},
+		{
+			name:     "integer",
+			value:    "15",
+			expected: 150000,
+		},
+		{
+			name:     "negative decimal",
+			value:    "-10.5",
+			expected: -105000,
+		},
+		{
+			name:     "positive sign and spaces",
+			value:    " +0.0001 ",
+			expected: 1,
+		},
+		{
+			name:     "no integer part",
+			value:    ".25",
+			expected: 2500,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			amount, err := Parse(test.value)
+			assert.Equal(t, test.expected, amount)
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
+
+func TestAmountStringFixed(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Amount
+		places   int
+		expected string
+	}{
+		{name: "zero", amount: 0, places: 2, expected: "0.00"},
+		{name: "exact", amount: MustParse("10.5"), places: 2, expected: "10.50"},
+		{name: "round half up", amount: MustParse("0.005"), places: 2, expected: "0.01"},
+		{name: "round half away from zero", amount: MustParse("-0.005"), places: 2, expected: "-0.01"},
+		{name: "round down", amount: MustParse("-20.5549"), places: 2, expected: "-20.55"},
+		{name: "no decimal places", amount: MustParse("2.5"), places: 0, expected: "3"},
+		{name: "all decimal places", amount: MustParse("-0.0001"), places: 4, expected: "-0.0001"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.amount.StringFixed(test.places))
+		})
+	}
+}
+
+func TestAmountAbs(t *testing.T) {
+	assert.Equal(t, MustParse("10.5"), MustParse("-10.5").Abs())
+	assert.Equal(t, MustParse("10.5"), MustParse("10.5").Abs())
+	assert.Equal(t, Amount(0), Amount(0).Abs())
+}
+
+func TestAmountDivRound(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Amount
+		n        int64
+		places   int
+		expected Amount
+	}{
+		{name: "division by zero", amount: FromInt(10), n: 0, places: 2, expected: 0},
+		{name: "exact division", amount: FromInt(-90), n: 3, places: 2, expected: FromInt(-30)},
+		{name: "repeating decimal", amount: FromInt(10), n: 3, places: 2, expected: MustParse("3.33")},
+		{name: "half away from zero", amount: MustParse("0.05"), n: 2, places: 2, expected: MustParse("0.03")},
+		{name: "negative half", amount: MustParse("-0.05"), n: 2, places: 2, expected: MustParse("-0.03")},
+		{name: "full scale", amount: FromInt(2), n: 3, places: 4, expected: MustParse("0.6667")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.amount.DivRound(test.n, test.places))
+		})
+	}
+}
+
+func TestAmountScan(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         any
+		expected    Amount
+		expectedErr bool
+	}{
+		{name: "decimal bytes", src: []byte("-10.5000"), expected: MustParse("-10.5")},
+		{name: "decimal string", src: "3.25", expected: MustParse("3.25")},
+		{name: "integer", src: int64(7), expected: FromInt(7)},
+		{name: "unsupported type", src: 1.5, expectedErr: true},
+		{name: "bad decimal", src: "bad", expectedErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var amount Amount
+			err := amount.Scan(test.src)
+			assert.Equal(t, test.expected, amount)
+			assert.Equal(t, test.expectedErr, err != nil)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RateScale is the number of decimal places kept by a Rate, matching the DECIMAL(19,8) columns.
+const RateScale = 8
+
+const rateUnit = 100000000
+
+// Rate is an exchange rate expressed in hundred-millionths: one unit of the base currency is worth Rate quote units.
+type Rate int64
+
+func ParseRate(value string) (Rate, error) {
+	units, err := parseDecimal("rate", value, RateScale)
+	if err != nil {
+		return 0, err
+	}
+	if units <= 0 {
+		return 0, fmt.Errorf("rate '%s' must be positive", value)
+	}
+	return Rate(units), nil
+}
+
+func MustParseRate(value string) Rate {
+	rate, err := ParseRate(value)
+	if err != nil {
+		panic(err)
+	}
+	return rate
+}
+
+func (r Rate) String() string {
+	str := strconv.FormatInt(int64(r), 10)
+	if len(str) <= RateScale {
+		str = strings.Repeat("0", RateScale-len(str)+1) + str
+	}
+	return str[:len(str)-RateScale] + "." + str[len(str)-RateScale:]
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+func (r *Rate) Scan(src any) error {
+	var (
+		rate Rate
+		err  error
+	)
+
+	switch value := src.(type) {
+	case []byte:
+		rate, err = ParseRate(string(value))
+	case string:
+		rate, err = ParseRate(value)
+	default:
+		return fmt.Errorf("cannot scan %T into rate", src)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	*r = rate
+	return nil
+}
+
+// Mul converts an amount from the base to the quote currency, rounding half away from zero.
+func (a Amount) Mul(rate Rate) Amount {
+	return Amount(bigDivRound(
+		new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(rate))),
+		big.NewInt(rateUnit)))
+}
+
+// Div converts an amount from the quote to the base currency, rounding half away from zero.
+func (a Amount) Div(rate Rate) (Amount, error) {
+	if rate == 0 {
+		return 0, errors.New("rate is zero")
+	}
+	return Amount(bigDivRound(
+		new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(rateUnit)),
+		big.NewInt(int64(rate)))), nil
+}
+
+func bigDivRound(numerator, denominator *big.Int) int64 {
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(new(big.Int).Abs(denominator)) >= 0 {
+		if numerator.Sign()*denominator.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return quotient.Int64()
+}
@@ -0,0 +1,94 @@
+package money
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    Rate
+		expectedErr error
+	}{
+		{
+			name:        "not a number",
+			value:       "bad",
+			expectedErr: errors.New("rate 'bad' is not a decimal number"),
+		},
+		{
+			name:        "not positive",
+			value:       "0",
+			expectedErr: errors.New("rate '0' must be positive"),
+		},
+		{
+			name:        "too many decimal places",
+			value:       "0.123456789",
+			expectedErr: errors.New("rate '0.123456789' has more than 8 decimal places"),
+		},
+		{
+			name:     "rate",
+			value:    "17.05",
+			expected: 1705000000,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rate, err := ParseRate(test.value)
+			assert.Equal(t, test.expected, rate)
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
+
+func TestRateString(t *testing.T) {
+	assert.Equal(t, "17.05000000", MustParseRate("17.05").String())
+	assert.Equal(t, "0.00000001", MustParseRate("0.00000001").String())
+	assert.Equal(t, "0.05865103", MustParseRate("0.05865103").String())
+}
+
+func TestAmountMul(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Amount
+		rate     Rate
+		expected Amount
+	}{
+		{name: "exact", amount: FromInt(10), rate: MustParseRate("17.05"), expected: MustParse("170.5")},
+		{name: "rounded", amount: MustParse("0.0001"), rate: MustParseRate("0.5"), expected: MustParse("0.0001")},
+		{name: "negative rounded", amount: MustParse("-0.0001"), rate: MustParseRate("0.5"), expected: MustParse("-0.0001")},
+		{name: "truncated", amount: MustParse("0.0001"), rate: MustParseRate("0.49"), expected: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.amount.Mul(test.rate))
+		})
+	}
+}
+
+func TestAmountDiv(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      Amount
+		rate        Rate
+		expected    Amount
+		expectedErr error
+	}{
+		{name: "zero rate", amount: FromInt(10), rate: 0, expectedErr: errors.New("rate is zero")},
+		{name: "exact", amount: MustParse("170.5"), rate: MustParseRate("17.05"), expected: FromInt(10)},
+		{name: "rounded", amount: FromInt(-100), rate: MustParseRate("3"), expected: MustParse("-33.3333")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			amount, err := test.amount.Div(test.rate)
+			assert.Equal(t, test.expected, amount)
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
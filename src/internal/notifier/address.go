@@ -0,0 +1,68 @@
+package notifier
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// sharedAddressSpace is the range carriers use behind their NATs, which is not reachable on the internet either.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP tells whether the address is reachable on the internet, so that the targets users choose cannot
+// point at the host itself or at the internal network.
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsUnspecified() && !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// isPublicHost tells whether the host of a target can be public. Host names are only checked once resolved,
+// when connecting to them, but localhost and the addresses which are not public are refused right away.
+func isPublicHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return isPublicIP(ip)
+	}
+	return true
+}
+
+// notPublicError is a connection refused because its address is not public.
+type notPublicError struct {
+	address string
+}
+
+func (e notPublicError) Error() string {
+	return fmt.Sprintf("address %s is not public", e.address)
+}
+
+// dialPublic refuses to connect to the addresses which are not public. It is checked on every connection, as a
+// host name can resolve to another address after its target was validated.
+func dialPublic(_ string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return notPublicError{address: host}
+	}
+	return nil
+}
+
+// newPublicHTTPClient returns a client which only connects to public addresses, for the targets users choose.
+// It connects to them directly, as a proxy would connect to any address on its behalf.
+func newPublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: dialPublic}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,60 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip       string
+		expected bool
+	}{
+		{ip: "8.8.8.8", expected: true},
+		{ip: "2001:4860:4860::8888", expected: true},
+		{ip: "0.0.0.0"},
+		{ip: "127.0.0.1"},
+		{ip: "::1"},
+		{ip: "::ffff:127.0.0.1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "fd00::1"},
+		{ip: "169.254.169.254"},
+		{ip: "fe80::1"},
+		{ip: "100.64.0.1"},
+		{ip: "224.0.0.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ip, func(t *testing.T) {
+			assert.Equal(t, test.expected, isPublicIP(net.ParseIP(test.ip)))
+		})
+	}
+}
+
+func TestDialPublic(t *testing.T) {
+	assert.Nil(t, dialPublic("tcp", "8.8.8.8:443", nil))
+	assert.Equal(t, notPublicError{address: "127.0.0.1"}, dialPublic("tcp", "127.0.0.1:443", nil))
+	assert.Equal(t, notPublicError{address: "::1"}, dialPublic("tcp6", "[::1]:443", nil))
+	assert.NotNil(t, dialPublic("tcp", "no port", nil))
+}
+
+func TestPublicHTTPClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	err := postJSON(
+		context.TODO(), newPublicHTTPClient(time.Second), ChannelWebhook, server.URL, []byte(`{}`), nil)
+
+	var notPublic notPublicError
+	assert.True(t, errors.As(err, &notPublic))
+	assert.True(t, isPermanentError(err))
+}
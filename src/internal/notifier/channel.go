@@ -0,0 +1,262 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+type ChannelType string
+
+const (
+	ChannelEmail   ChannelType = "email"
+	ChannelWebhook ChannelType = "webhook"
+	ChannelSMS     ChannelType = "sms"
+	ChannelSlack   ChannelType = "slack"
+)
+
+// Channel delivers a message to the target of a preference of its own type.
+type Channel interface {
+	Send(ctx context.Context, preference Preference, message Message) error
+}
+
+// UserNotifier notifies a user through the channels of their preferences, or by email when they have none.
+type UserNotifier interface {
+	NotifyUser(ctx context.Context, userID int64, message Message) error
+}
+
+type ChannelOptions struct {
+	SMSURL   string
+	SMSToken string
+	Timeout  time.Duration
+}
+
+// GetChannelOptions reads the settings of the SMS gateway, which is optional. Users cannot be notified by SMS
+// without it.
+func GetChannelOptions() ChannelOptions {
+	return ChannelOptions{
+		SMSURL:   os.Getenv("NOTIFIER_SMS_URL"),
+		SMSToken: os.Getenv("NOTIFIER_SMS_TOKEN"),
+		Timeout:  10 * time.Second,
+	}
+}
+
+// NewChannels returns every channel available, sending emails with the given client.
+func NewChannels(client Client, options ChannelOptions) map[ChannelType]Channel {
+	// the sms gateway is ours, while webhook and slack urls are chosen by users
+	var (
+		httpClient   = &http.Client{Timeout: options.Timeout}
+		publicClient = newPublicHTTPClient(options.Timeout)
+	)
+
+	channels := map[ChannelType]Channel{
+		ChannelEmail:   emailChannel{client: client},
+		ChannelWebhook: webhookChannel{httpClient: publicClient, now: time.Now},
+		ChannelSlack:   slackChannel{httpClient: publicClient},
+	}
+	if options.SMSURL != "" {
+		channels[ChannelSMS] = smsChannel{httpClient: httpClient, url: options.SMSURL, token: options.SMSToken}
+	}
+
+	return channels
+}
+
+func NewUserNotifier(
+	preferences PreferenceStore, deliveries DeliveryStore, deadLetters DeadLetterStore,
+	channels map[ChannelType]Channel, options RetryOptions) UserNotifier {
+	return userNotifier{
+		preferences: preferences,
+		deliveries:  deliveries,
+		deadLetters: deadLetters,
+		channels:    channels,
+		retry:       newRetrier(options),
+	}
+}
+
+type userNotifier struct {
+	preferences PreferenceStore
+	deliveries  DeliveryStore
+	deadLetters DeadLetterStore
+	channels    map[ChannelType]Channel
+	retry       retrier
+}
+
+// NotifyUser sends the message through every channel the user chose, even when some of them fail. Each channel
+// retries its temporary errors and dead letters the message once it gives up on it, and a message sent again
+// with the same key skips the channels it already went through.
+func (n userNotifier) NotifyUser(ctx context.Context, userID int64, message Message) error {
+	preferences, err := n.preferences.getPreferences(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error getting notification preferences of user id %d due to: %w", userID, err)
+	}
+
+	if len(preferences) == 0 {
+		preferences = []Preference{{UserID: userID, Channel: ChannelEmail}}
+	}
+
+	var deliveries []Delivery
+	if message.Key != "" {
+		if deliveries, err = n.deliveries.getDeliveries(ctx, message.Key); err != nil {
+			return fmt.Errorf("error getting deliveries of notification %s due to: %w", message.Key, err)
+		}
+	}
+	statuses := deliveryStatuses(deliveries)
+
+	var failures []string
+	for _, preference := range preferences {
+		switch statuses[preference.deliveryTarget()] {
+		case DeliveryStatusSent:
+			continue
+		case DeliveryStatusDeadLettered:
+			failures = append(failures, fmt.Sprintf("%s notification was dead lettered", preference.Channel))
+			continue
+		}
+
+		channel, ok := n.channels[preference.Channel]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("%s channel is not available", preference.Channel))
+			continue
+		}
+
+		if err = n.send(ctx, channel, preference, message); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("error notifying user id %d due to: %s", userID, strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// send sends the message through the channel of the preference, retrying its temporary errors, and records
+// how it went. The message is dead lettered when the channel gives up on it.
+func (n userNotifier) send(ctx context.Context, channel Channel, preference Preference, message Message) error {
+	attempts, err := n.retry.do(ctx, func() error {
+		return channel.Send(ctx, preference, message)
+	})
+	if err == nil {
+		n.saveDelivery(ctx, message.Key, preference, DeliveryStatusSent)
+		return nil
+	}
+
+	var interrupted retriesInterruptedError
+	if errors.As(err, &interrupted) {
+		return err
+	}
+
+	deadLetter := newDeadLetter(preference, message, err.Error(), attempts)
+	err = fmt.Errorf("%s notification failed after %d attempts due to: %w", preference.Channel, attempts, err)
+
+	// without its dead letter, the message goes through the channel again on its next attempt
+	if saveErr := n.deadLetters.saveDeadLetter(ctx, deadLetter); saveErr != nil {
+		log.Printf(
+			"error saving %s dead letter of user id %d due to: %s", preference.Channel, preference.UserID,
+			saveErr.Error())
+		return err
+	}
+
+	n.saveDelivery(ctx, message.Key, preference, DeliveryStatusDeadLettered)
+	return err
+}
+
+// saveDelivery records how the message went through the channel of the preference, when it has a key. Errors
+// are only logged, since the message was already sent or dead lettered.
+func (n userNotifier) saveDelivery(ctx context.Context, key string, preference Preference, status DeliveryStatus) {
+	if key == "" {
+		return
+	}
+
+	err := n.deliveries.saveDelivery(ctx, Delivery{
+		NotificationKey: key,
+		Channel:         preference.Channel,
+		Target:          preference.Target,
+		Status:          status,
+	})
+	if err != nil {
+		log.Printf(
+			"error saving %s delivery of notification %s due to: %s", preference.Channel, key, err.Error())
+	}
+}
+
+// emailChannel sends the message to the address of the preference, or to its own recipients when the
+// preference has none.
+type emailChannel struct {
+	client Client
+}
+
+func (c emailChannel) Send(ctx context.Context, preference Preference, message Message) error {
+	if preference.Target != "" {
+		message.To, message.Cc, message.Bcc = []string{preference.Target}, nil, nil
+	}
+	return c.client.Send(ctx, message)
+}
+
+// summary is the message in plain text, for the channels which can only show a short text.
+func (m Message) summary() string {
+	if m.Text != "" {
+		return m.Text
+	}
+	if m.Subject != "" {
+		return m.Subject
+	}
+	return defaultSubject
+}
+
+// postJSON posts the payload to the url, failing on any response but a successful one. The url is left out
+// of the errors since it may be a secret itself, as the ones of Slack webhooks are.
+func postJSON(
+	ctx context.Context, httpClient *http.Client, channel ChannelType, target string, payload []byte,
+	headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("error creating %s request due to: %w", channel, withoutURL(err))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending %s request due to: %w", channel, withoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	// the body is drained so that the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError{channel: channel, code: resp.StatusCode}
+	}
+	return nil
+}
+
+// withoutURL returns the error of a request without its url, as the one of a Slack target is a secret which
+// would otherwise be kept in the dead letters and in the outbox.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// statusError is an unsuccessful response to a request of a channel.
+type statusError struct {
+	channel ChannelType
+	code    int
+}
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d from %s", e.code, e.channel)
+}
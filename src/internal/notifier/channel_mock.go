@@ -0,0 +1,16 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type channelMock struct {
+	mock.Mock
+}
+
+func (m *channelMock) Send(_ context.Context, preference Preference, message Message) error {
+	args := m.Called(preference, message)
+	return args.Error(0)
+}
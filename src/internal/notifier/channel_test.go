@@ -0,0 +1,285 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUserNotifierNotifyUser(t *testing.T) {
+	var (
+		customErr    = errors.New("custom error")
+		permanentErr = statusError{channel: ChannelSlack, code: 404}
+		message      = Message{Key: "outbox-1", To: []string{"email"}, HTML: "message"}
+		email        = Preference{UserID: 1, Channel: ChannelEmail}
+		slack        = Preference{UserID: 1, Channel: ChannelSlack, Target: "https://hooks.slack.com/services/x"}
+		sms          = Preference{UserID: 1, Channel: ChannelSMS, Target: "+5491100000000"}
+		sent         = func(preference Preference) Delivery {
+			return Delivery{
+				NotificationKey: "outbox-1",
+				Channel:         preference.Channel,
+				Target:          preference.Target,
+				Status:          DeliveryStatusSent,
+			}
+		}
+		deadLettered = func(preference Preference) Delivery {
+			delivery := sent(preference)
+			delivery.Status = DeliveryStatusDeadLettered
+			return delivery
+		}
+	)
+
+	type mocks struct {
+		preferences *preferenceStoreMock
+		deliveries  *deliveryStoreMock
+		deadLetters *deadLetterStoreMock
+		email       *channelMock
+		slack       *channelMock
+	}
+
+	tests := []struct {
+		name        string
+		message     Message
+		sleepErr    error
+		mockApplier func(m mocks)
+		expected    error
+	}{
+		{
+			name:    "error getting preferences",
+			message: message,
+			mockApplier: func(m mocks) {
+				m.preferences.On("getPreferences", int64(1)).Return(nil, customErr).Once()
+			},
+			expected: fmt.Errorf("error getting notification preferences of user id 1 due to: %w", customErr),
+		},
+		{
+			name:    "error getting deliveries",
+			message: message,
+			mockApplier: func(m mocks) {
+				m.preferences.On("getPreferences", int64(1)).Return([]Preference{email}, nil).Once()
+				m.deliveries.On("getDeliveries", "outbox-1").Return(nil, customErr).Once()
+			},
+			expected: fmt.Errorf("error getting deliveries of notification outbox-1 due to: %w", customErr),
+		},
+		{
+			name:    "no preferences and no key to track the message",
+			message: Message{To: []string{"email"}, HTML: "message"},
+			mockApplier: func(m mocks) {
+				m.preferences.On("getPreferences", int64(1)).Return([]Preference{}, nil).Once()
+				m.email.On("Send", email, Message{To: []string{"email"}, HTML: "message"}).Return(nil).Once()
+			},
+		},
+		{
+			name:    "channel not available",
+			message: message,
+			mockApplier: func(m mocks) {
+				m.preferences.On("getPreferences", int64(1)).Return([]Preference{sms, email}, nil).Once()
+				m.deliveries.On("getDeliveries", "outbox-1").Return([]Delivery{}, nil).Once()
+				m.email.On("Send", email, message).Return(nil).Once()
+				m.deliveries.On("saveDelivery", sent(email)).Return(nil).Once()
+			},
+			expected: errors.New("error notifying user id 1 due to: sms channel is not available"),
+		},
+		{
+			name:    "every channel retried and notified even when one is dead lettered",
+			message: message,
+			mockApplier: func(m mocks) {
+				m.preferences.On("getPreferences", int64(1)).Return([]Preference{slack, email}, nil).Once()
+				m.deliveries.On("getDeliveries", "outbox-1").Return([]Delivery{}, nil).Once()
+				m.slack.On("Send", slack, message).Return(customErr).Twice()
+				m.deadLetters.On("saveDeadLetter", newDeadLetter(slack, message, customErr.Error(), 2)).
+					Return(nil).Once()
+				m.deliveries.On("saveDelivery", deadLettered(slack)).Return(nil).Once()
+				m.email.On("Send", email, message).Return(customErr).Once()
+				m.email.On("Send", email, message).Return(nil).Once()
+				m.deliveries.On("saveDelivery", sent(email)).Return(nil).Once()
+			},
+			expected: errors.New(
+				"error notifying user id 1 due to: slack notification failed after 2 attempts due to: custom error"),
+		},
+		{
+			name:    "channels the message went through are skipped",
+			message: message,
+			mockApplier: func(m mocks) {
+				m.preferences.On("getPreferences", int64(1)).Return([]Preference{slack, email}, nil).Once()
+				m.deliveries.On("getDeliveries", "outbox-1").
+					Return([]Delivery{sent(slack), deadLettered(email)}, nil).Once()
+			},
+			expected: errors.New("error notifying user id 1 due to: email notification was dead lettered"),
+		},
+		{
+			name:    "message not tracked when its dead letter cannot be saved",
+			message: message,
+			mockApplier: func(m mocks) {
+				m.preferences.On("getPreferences", int64(1)).Return([]Preference{slack}, nil).Once()
+				m.deliveries.On("getDeliveries", "outbox-1").Return([]Delivery{}, nil).Once()
+				m.slack.On("Send", slack, message).Return(permanentErr).Once()
+				m.deadLetters.On("saveDeadLetter", newDeadLetter(slack, message, permanentErr.Error(), 1)).
+					Return(customErr).Once()
+			},
+			expected: errors.New("error notifying user id 1 due to: slack notification failed after 1 attempts " +
+				"due to: unexpected status 404 from slack"),
+		},
+		{
+			name:    "error saving a delivery is only logged",
+			message: message,
+			mockApplier: func(m mocks) {
+				m.preferences.On("getPreferences", int64(1)).Return([]Preference{email}, nil).Once()
+				m.deliveries.On("getDeliveries", "outbox-1").Return([]Delivery{}, nil).Once()
+				m.email.On("Send", email, message).Return(nil).Once()
+				m.deliveries.On("saveDelivery", sent(email)).Return(customErr).Once()
+			},
+		},
+		{
+			name:     "retries interrupted are not dead lettered",
+			message:  message,
+			sleepErr: context.Canceled,
+			mockApplier: func(m mocks) {
+				m.preferences.On("getPreferences", int64(1)).Return([]Preference{email}, nil).Once()
+				m.deliveries.On("getDeliveries", "outbox-1").Return([]Delivery{}, nil).Once()
+				m.email.On("Send", email, message).Return(customErr).Once()
+			},
+			expected: errors.New("error notifying user id 1 due to: notification retries interrupted due to: " +
+				"context canceled"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := mocks{
+				preferences: &preferenceStoreMock{},
+				deliveries:  &deliveryStoreMock{},
+				deadLetters: &deadLetterStoreMock{},
+				email:       &channelMock{},
+				slack:       &channelMock{},
+			}
+
+			test.mockApplier(m)
+			defer m.preferences.AssertExpectations(t)
+			defer m.deliveries.AssertExpectations(t)
+			defer m.deadLetters.AssertExpectations(t)
+			defer m.email.AssertExpectations(t)
+			defer m.slack.AssertExpectations(t)
+
+			n := userNotifier{
+				preferences: m.preferences,
+				deliveries:  m.deliveries,
+				deadLetters: m.deadLetters,
+				channels:    map[ChannelType]Channel{ChannelEmail: m.email, ChannelSlack: m.slack},
+				retry: retrier{
+					maxAttempts:    2,
+					initialBackoff: time.Second,
+					maxBackoff:     time.Minute,
+					random:         func(n int64) int64 { return 0 },
+					sleep: func(_ context.Context, _ time.Duration) error {
+						return test.sleepErr
+					},
+				},
+			}
+
+			assert.Equal(t, test.expected, n.NotifyUser(context.TODO(), 1, test.message))
+		})
+	}
+}
+
+func TestUserNotifierNotifyUserDeadLettersWithoutURL(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	var (
+		target      = server.URL + "/services/secret"
+		deadLetters = &deadLetterStoreMock{}
+		deliveries  = &deliveryStoreMock{}
+		preferences = &preferenceStoreMock{}
+	)
+
+	preferences.On("getPreferences", int64(1)).
+		Return([]Preference{{UserID: 1, Channel: ChannelSlack, Target: target}}, nil).Once()
+	deadLetters.On("saveDeadLetter", mock.MatchedBy(func(deadLetter DeadLetter) bool {
+		return strings.HasPrefix(deadLetter.Error, "error sending slack request due to: ") &&
+			!strings.Contains(deadLetter.Error, server.URL)
+	})).Return(nil).Once()
+	defer preferences.AssertExpectations(t)
+	defer deadLetters.AssertExpectations(t)
+
+	n := userNotifier{
+		preferences: preferences,
+		deliveries:  deliveries,
+		deadLetters: deadLetters,
+		channels:    map[ChannelType]Channel{ChannelSlack: slackChannel{httpClient: server.Client()}},
+		retry:       retrier{maxAttempts: 1},
+	}
+
+	err := n.NotifyUser(context.TODO(), 1, Message{To: []string{"email"}, HTML: "message"})
+
+	assert.ErrorContains(t, err, "slack notification failed after 1 attempts")
+	assert.NotContains(t, err.Error(), server.URL)
+}
+
+func TestEmailChannelSend(t *testing.T) {
+	message := Message{To: []string{"email"}, Cc: []string{"copy"}, HTML: "message"}
+
+	tests := []struct {
+		name       string
+		preference Preference
+		expected   Message
+	}{
+		{
+			name:       "recipients of the message",
+			preference: Preference{Channel: ChannelEmail},
+			expected:   message,
+		},
+		{
+			name:       "address of the preference",
+			preference: Preference{Channel: ChannelEmail, Target: "other@mail.com"},
+			expected:   Message{To: []string{"other@mail.com"}, HTML: "message"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientMock := &Mock{}
+			clientMock.On("Send", test.expected).Return(nil).Once()
+			defer clientMock.AssertExpectations(t)
+
+			assert.Nil(t, emailChannel{client: clientMock}.Send(context.TODO(), test.preference, message))
+		})
+	}
+}
+
+func TestNewChannels(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  ChannelOptions
+		expected []ChannelType
+	}{
+		{
+			name:     "sms gateway not configured",
+			expected: []ChannelType{ChannelEmail, ChannelWebhook, ChannelSlack},
+		},
+		{
+			name:     "sms gateway configured",
+			options:  ChannelOptions{SMSURL: "http://sms.local"},
+			expected: []ChannelType{ChannelEmail, ChannelWebhook, ChannelSlack, ChannelSMS},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			channels := NewChannels(&Mock{}, test.options)
+
+			assert.Len(t, channels, len(test.expected))
+			for _, channel := range test.expected {
+				assert.Contains(t, channels, channel)
+			}
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package notifier
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"net/textproto"
 	"os"
 	"strconv"
 
@@ -11,6 +14,58 @@ import (
 
 type Client interface {
 	NotifyToUser(ctx context.Context, message string, email string) error
+	Send(ctx context.Context, message Message) error
+}
+
+const defaultSubject = "Transaction resume"
+
+// reservedHeaders are the headers set from the message itself and its body, which custom headers cannot set,
+// in their canonical form.
+var reservedHeaders = map[string]bool{
+	"From":                      true,
+	"To":                        true,
+	"Cc":                        true,
+	"Bcc":                       true,
+	"Reply-To":                  true,
+	"Subject":                   true,
+	"Content-Type":              true,
+	"Mime-Version":              true,
+	"Content-Transfer-Encoding": true,
+}
+
+// Message is a notification with everything an email can carry. HTML and Text are alternatives of the same
+// content, so mail readers show the one they support best. The subject is the one of the resume when it is
+// not given. The key identifies the notification across its attempts, so that it goes through each channel
+// once; notifications without one are not tracked.
+type Message struct {
+	Key         string `json:",omitempty"`
+	To          []string
+	Cc          []string            `json:",omitempty"`
+	Bcc         []string            `json:",omitempty"`
+	ReplyTo     string              `json:",omitempty"`
+	Subject     string              `json:",omitempty"`
+	HTML        string              `json:",omitempty"`
+	Text        string              `json:",omitempty"`
+	Headers     map[string][]string `json:",omitempty"`
+	Attachments []Attachment        `json:",omitempty"`
+}
+
+// recipients returns every address the message is sent to, blind copies included.
+func (m Message) recipients() []string {
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	recipients = append(recipients, m.To...)
+	recipients = append(recipients, m.Cc...)
+	return append(recipients, m.Bcc...)
+}
+
+// Attachment is a file sent along with a notification. Inline attachments are shown within the html, which
+// references them by content id as cid:<ContentID>, instead of being listed as files.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Content     []byte
+	ContentID   string `json:",omitempty"`
+	Inline      bool   `json:",omitempty"`
 }
 
 type dialer interface {
@@ -71,12 +126,73 @@ func NewClient(options Options) Client {
 	}
 }
 
-func (c client) NotifyToUser(_ context.Context, message string, email string) error {
+func (c client) NotifyToUser(ctx context.Context, message string, email string) error {
+	return c.Send(ctx, Message{To: []string{email}, HTML: message})
+}
+
+func (c client) Send(_ context.Context, message Message) error {
+	if len(message.recipients()) == 0 {
+		return errors.New("message has no recipients")
+	}
+	if message.HTML == "" && message.Text == "" {
+		return errors.New("message has no body")
+	}
+
+	subject := message.Subject
+	if subject == "" {
+		subject = defaultSubject
+	}
+
 	m := mail.NewMessage()
+
+	// custom headers cannot set the ones of the message itself, whatever their case
+	for field, values := range message.Headers {
+		if !reservedHeaders[textproto.CanonicalMIMEHeaderKey(field)] {
+			m.SetHeader(field, values...)
+		}
+	}
+
 	m.SetHeader("From", c.sender)
-	m.SetHeader("To", email)
-	m.SetHeader("Subject", "Transaction resume")
-	m.SetBody("text/html", message)
+	m.SetHeader("Subject", subject)
+	if len(message.To) > 0 {
+		m.SetHeader("To", message.To...)
+	}
+	if len(message.Cc) > 0 {
+		m.SetHeader("Cc", message.Cc...)
+	}
+	if len(message.Bcc) > 0 {
+		m.SetHeader("Bcc", message.Bcc...)
+	}
+	if message.ReplyTo != "" {
+		m.SetHeader("Reply-To", message.ReplyTo)
+	}
+
+	switch {
+	case message.Text != "" && message.HTML != "":
+		m.SetBody("text/plain", message.Text)
+		m.AddAlternative("text/html", message.HTML)
+	case message.HTML != "":
+		m.SetBody("text/html", message.HTML)
+	default:
+		m.SetBody("text/plain", message.Text)
+	}
+
+	for _, attachment := range message.Attachments {
+		if attachment.Inline {
+			m.EmbedReader(
+				attachment.Name,
+				bytes.NewReader(attachment.Content),
+				mail.SetHeader(map[string][]string{
+					"Content-Type": {attachment.ContentType},
+					"Content-ID":   {"<" + attachment.ContentID + ">"},
+				}))
+			continue
+		}
+		m.AttachReader(
+			attachment.Name,
+			bytes.NewReader(attachment.Content),
+			mail.SetHeader(map[string][]string{"Content-Type": {attachment.ContentType}}))
+	}
 
 	if err := c.dialer.DialAndSend(m); err != nil {
 		return fmt.Errorf("unexpected error sending mail to user due to: %w", err)
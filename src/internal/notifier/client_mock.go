@@ -16,6 +16,16 @@ func (m *Mock) NotifyToUser(_ context.Context, message string, email string) err
 	return args.Error(0)
 }
 
+func (m *Mock) Send(_ context.Context, message Message) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
+
+func (m *Mock) NotifyUser(_ context.Context, userID int64, message Message) error {
+	args := m.Called(userID, message)
+	return args.Error(0)
+}
+
 type dialerMock struct {
 	mock.Mock
 }
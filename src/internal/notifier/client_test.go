@@ -1,13 +1,17 @@
 package notifier
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gopkg.in/mail.v2"
 )
 
 func TestClientNotifyToUser(t *testing.T) {
@@ -47,3 +51,148 @@ func TestClientNotifyToUser(t *testing.T) {
 		})
 	}
 }
+
+func TestClientSend(t *testing.T) {
+	customErr := errors.New("custom error")
+
+	// written returns the message as sent, along with its recipients
+	written := func(messages []*mail.Message) string {
+		var b bytes.Buffer
+		if _, err := messages[0].WriteTo(&b); err != nil {
+			return ""
+		}
+		return strings.Join(messages[0].GetHeader("Bcc"), ",") + "\n" + b.String()
+	}
+
+	tests := []struct {
+		name          string
+		message       Message
+		mockApplier   func(m *dialerMock)
+		expectedLines []string
+		expected      error
+	}{
+		{
+			name:     "no recipients",
+			message:  Message{HTML: "message"},
+			expected: errors.New("message has no recipients"),
+		},
+		{
+			name:     "no body",
+			message:  Message{To: []string{"email"}},
+			expected: errors.New("message has no body"),
+		},
+		{
+			name:    "return error",
+			message: Message{To: []string{"email"}, Text: "message"},
+			mockApplier: func(m *dialerMock) {
+				m.On("DialAndSend", mock.Anything).Return(customErr).Once()
+			},
+			expected: fmt.Errorf("unexpected error sending mail to user due to: %w", customErr),
+		},
+		{
+			name:    "html message with the default subject",
+			message: Message{To: []string{"email"}, HTML: "<p>message</p>"},
+			expectedLines: []string{
+				"From: sender",
+				"To: email",
+				"Subject: Transaction resume",
+				"Content-Type: text/html; charset=UTF-8",
+				"<p>message</p>",
+			},
+		},
+		{
+			name:    "plain text message",
+			message: Message{To: []string{"email"}, Subject: "Statement", Text: "message"},
+			expectedLines: []string{
+				"Subject: Statement",
+				"Content-Type: text/plain; charset=UTF-8",
+			},
+		},
+		{
+			name: "html message with an inline image",
+			message: Message{
+				To:   []string{"email"},
+				HTML: `<p><img src="cid:chart-1@transaction-tool" alt="chart"></p>`,
+				Attachments: []Attachment{
+					{
+						Name:        "chart-1.png",
+						ContentType: "image/png",
+						Content:     []byte("png"),
+						ContentID:   "chart-1@transaction-tool",
+						Inline:      true,
+					},
+				},
+			},
+			expectedLines: []string{
+				"Content-Type: multipart/related",
+				"Content-Type: text/html; charset=UTF-8",
+				"Content-Type: image/png",
+				"Content-ID: <chart-1@transaction-tool>",
+				`Content-Disposition: inline; filename="chart-1.png"`,
+				base64.StdEncoding.EncodeToString([]byte("png")),
+			},
+		},
+		{
+			name: "message with every field",
+			message: Message{
+				To:      []string{"first", "second"},
+				Cc:      []string{"copy"},
+				Bcc:     []string{"hidden"},
+				ReplyTo: "support",
+				Subject: "Statement",
+				HTML:    "<p>message</p>",
+				Text:    "message",
+				Headers: map[string][]string{
+					"X-Campaign":   {"resume"},
+					"Subject":      {"replaced"},
+					"to":           {"intruder"},
+					"BCC":          {"intruder"},
+					"content-type": {"text/plain"},
+				},
+				Attachments: []Attachment{
+					{Name: "statement.pdf", ContentType: "application/pdf", Content: []byte("pdf")},
+				},
+			},
+			expectedLines: []string{
+				"hidden\n",
+				"To: first, second",
+				"Cc: copy",
+				"Reply-To: support",
+				"Subject: Statement",
+				"X-Campaign: resume",
+				"Content-Type: multipart/alternative",
+				"Content-Type: text/plain; charset=UTF-8",
+				"Content-Type: text/html; charset=UTF-8",
+				"Content-Type: application/pdf",
+				`filename="statement.pdf"`,
+				base64.StdEncoding.EncodeToString([]byte("pdf")),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dMock := &dialerMock{}
+			if test.mockApplier != nil {
+				test.mockApplier(dMock)
+			}
+			if test.expectedLines != nil {
+				dMock.On("DialAndSend", mock.MatchedBy(func(messages []*mail.Message) bool {
+					// attachments are read once, so the message is only written once
+					sent := written(messages)
+					for _, line := range test.expectedLines {
+						if !strings.Contains(sent, line) {
+							return false
+						}
+					}
+					return !strings.Contains(sent, "replaced") && !strings.Contains(sent, "intruder") &&
+						!strings.Contains(sent, "Bcc:") && !strings.Contains(sent, "Content-Type: text/plain\r\n")
+				})).Return(nil).Once()
+			}
+			defer dMock.AssertExpectations(t)
+
+			c := client{sender: "sender", dialer: dMock}
+			assert.Equal(t, test.expected, c.Send(context.TODO(), test.message))
+		})
+	}
+}
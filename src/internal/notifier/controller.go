@@ -0,0 +1,306 @@
+package notifier
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func NewController(
+	channels map[ChannelType]Channel, deadLetters DeadLetterStore, preferences PreferenceStore,
+	deliveries DeliveryStore) Controller {
+	return controller{channels: channels, deadLetters: deadLetters, preferences: preferences, deliveries: deliveries}
+}
+
+type Controller interface {
+	GetDeadLetters(c *gin.Context)
+	RedriveDeadLetter(c *gin.Context)
+	GetPreferences(c *gin.Context)
+	SavePreferences(c *gin.Context)
+}
+
+type controller struct {
+	channels    map[ChannelType]Channel
+	deadLetters DeadLetterStore
+	preferences PreferenceStore
+	deliveries  DeliveryStore
+}
+
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func badRequestError(message string) Error {
+	return Error{
+		Status:  http.StatusBadRequest,
+		Code:    "bad request",
+		Message: message,
+	}
+}
+
+func notFoundError(message string) Error {
+	return Error{
+		Status:  http.StatusNotFound,
+		Code:    "not found",
+		Message: message,
+	}
+}
+
+func conflictError(message string) Error {
+	return Error{
+		Status:  http.StatusConflict,
+		Code:    "conflict",
+		Message: message,
+	}
+}
+
+func internalError(message string) Error {
+	return Error{
+		Status:  http.StatusInternalServerError,
+		Code:    "internal error",
+		Message: message,
+	}
+}
+
+func badGatewayError(message string) Error {
+	return Error{
+		Status:  http.StatusBadGateway,
+		Code:    "bad gateway",
+		Message: message,
+	}
+}
+
+func (ctl controller) GetDeadLetters(c *gin.Context) {
+	status := DeadLetterStatus(c.DefaultQuery("status", string(DeadLetterStatusPending)))
+	if status != DeadLetterStatusPending && status != DeadLetterStatusRedriven {
+		c.JSON(http.StatusBadRequest, badRequestError(fmt.Sprintf("dead letter status '%s' is not valid", status)))
+		return
+	}
+
+	deadLetters, err := ctl.deadLetters.getDeadLetters(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, withoutSecretTargets(deadLetters))
+}
+
+// RedriveDeadLetter sends the notification of the dead letter again through its channel, with the preference
+// the user has now, unless it already went through it.
+func (ctl controller) RedriveDeadLetter(c *gin.Context) {
+	deadLetterIDStr := c.Param("id")
+	if deadLetterIDStr == "" {
+		c.JSON(http.StatusBadRequest, badRequestError("missing dead letter id param"))
+		return
+	}
+
+	deadLetterID, err := strconv.ParseInt(deadLetterIDStr, 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			badRequestError(fmt.Sprintf("dead letter id '%s' is not an integer", deadLetterIDStr)))
+		return
+	}
+
+	deadLetter, err := ctl.deadLetters.getDeadLetterByID(c.Request.Context(), deadLetterID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, notFoundError(fmt.Sprintf("dead letter id %d not found", deadLetterID)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	if deadLetter.Status == DeadLetterStatusRedriven {
+		c.JSON(
+			http.StatusConflict,
+			conflictError(fmt.Sprintf("dead letter id %d was already redriven", deadLetterID)))
+		return
+	}
+
+	channel, ok := ctl.channels[deadLetter.Channel]
+	if !ok {
+		c.JSON(
+			http.StatusConflict,
+			conflictError(fmt.Sprintf("%s channel is not available", deadLetter.Channel)))
+		return
+	}
+
+	preference, ok, err := ctl.deadLetterPreference(c.Request.Context(), deadLetter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+	if !ok {
+		c.JSON(
+			http.StatusConflict,
+			conflictError(fmt.Sprintf(
+				"user id %d no longer has the %s preference of dead letter id %d",
+				deadLetter.UserID, deadLetter.Channel, deadLetterID)))
+		return
+	}
+
+	sent, err := ctl.deadLetterSent(c.Request.Context(), deadLetter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	if !sent {
+		if err = channel.Send(c.Request.Context(), preference, deadLetter.Notification); err != nil {
+			c.JSON(http.StatusBadGateway, badGatewayError(err.Error()))
+			return
+		}
+
+		// the delivery is saved before marking the dead letter, so that redriving it again does not send it twice
+		if deadLetter.Notification.Key != "" {
+			err = ctl.deliveries.saveDelivery(c.Request.Context(), Delivery{
+				NotificationKey: deadLetter.Notification.Key,
+				Channel:         deadLetter.Channel,
+				Target:          deadLetter.Target,
+				Status:          DeliveryStatusSent,
+			})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+				return
+			}
+		}
+	}
+
+	if err = ctl.deadLetters.markDeadLetterRedriven(c.Request.Context(), deadLetterID); err != nil {
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// deadLetterPreference returns the preference of the user the dead letter was sent through, with its current
+// secret, and false when the user no longer has it. Users without preferences are notified by email.
+func (ctl controller) deadLetterPreference(ctx context.Context, deadLetter DeadLetter) (Preference, bool, error) {
+	preferences, err := ctl.preferences.getPreferences(ctx, deadLetter.UserID)
+	if err != nil {
+		return Preference{}, false, err
+	}
+
+	if len(preferences) == 0 {
+		preferences = []Preference{{UserID: deadLetter.UserID, Channel: ChannelEmail}}
+	}
+
+	for _, preference := range preferences {
+		if preference.Channel == deadLetter.Channel && preference.Target == deadLetter.Target {
+			return preference, true, nil
+		}
+	}
+	return Preference{}, false, nil
+}
+
+// deadLetterSent tells whether the notification of the dead letter already went through its channel, as it
+// does when a redrive failed after sending it.
+func (ctl controller) deadLetterSent(ctx context.Context, deadLetter DeadLetter) (bool, error) {
+	if deadLetter.Notification.Key == "" {
+		return false, nil
+	}
+
+	deliveries, err := ctl.deliveries.getDeliveries(ctx, deadLetter.Notification.Key)
+	if err != nil {
+		return false, err
+	}
+
+	target := deliveryTarget{channel: deadLetter.Channel, target: deadLetter.Target}
+	return deliveryStatuses(deliveries)[target] == DeliveryStatusSent, nil
+}
+
+func (ctl controller) getUserID(c *gin.Context) (int64, bool) {
+	userIDStr := c.Param("user_id")
+	if userIDStr == "" {
+		c.JSON(http.StatusBadRequest, badRequestError("missing user id param"))
+		return 0, false
+	}
+
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, badRequestError(fmt.Sprintf("user id '%s' is not an integer", userIDStr)))
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// GetPreferences lists the channels the user is notified through, without their secrets. Users without any
+// are notified by email.
+func (ctl controller) GetPreferences(c *gin.Context) {
+	userID, ok := ctl.getUserID(c)
+	if !ok {
+		return
+	}
+
+	preferences, err := ctl.preferences.getPreferences(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, withoutSecrets(preferences))
+}
+
+// SavePreferences replaces the channels the user is notified through with the ones of the body.
+func (ctl controller) SavePreferences(c *gin.Context) {
+	userID, ok := ctl.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var preferences []Preference
+	if err := c.ShouldBindJSON(&preferences); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			badRequestError(fmt.Sprintf("error reading notification preferences body due to: %s", err.Error())))
+		return
+	}
+
+	for i := range preferences {
+		if err := preferences[i].validate(); err != nil {
+			c.JSON(http.StatusBadRequest, badRequestError(err.Error()))
+			return
+		}
+		preferences[i].UserID = userID
+	}
+
+	if err := ctl.preferences.savePreferences(c.Request.Context(), userID, preferences); err != nil {
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, withoutSecrets(preferences))
+}
+
+// withoutSecretTargets hides the target of the Slack dead letters, as their url is a secret.
+func withoutSecretTargets(deadLetters []DeadLetter) []DeadLetter {
+	listed := make([]DeadLetter, 0, len(deadLetters))
+	for _, deadLetter := range deadLetters {
+		if deadLetter.Channel == ChannelSlack {
+			deadLetter.Target = ""
+		}
+		listed = append(listed, deadLetter)
+	}
+	return listed
+}
+
+func withoutSecrets(preferences []Preference) []Preference {
+	listed := make([]Preference, 0, len(preferences))
+	for _, preference := range preferences {
+		preference.Secret = ""
+		listed = append(listed, preference)
+	}
+	return listed
+}
@@ -0,0 +1,522 @@
+package notifier
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestContext(params map[string]string, query map[string]string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+
+	r := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(r)
+
+	values := url.Values{}
+	for key, value := range query {
+		values.Set(key, value)
+	}
+
+	ctx.Request = &http.Request{
+		URL:    &url.URL{RawQuery: values.Encode()},
+		Header: http.Header{},
+		Body:   io.NopCloser(&bytes.Buffer{}),
+	}
+
+	for key, value := range params {
+		ctx.AddParam(key, value)
+	}
+
+	return ctx, r
+}
+
+func TestControllerGetDeadLetters(t *testing.T) {
+	var (
+		customErr   = errors.New("custom error")
+		deadLetters = []DeadLetter{
+			{ID: 1, Channel: ChannelEmail, Target: "email", Email: "email", Status: DeadLetterStatusRedriven},
+			{
+				ID:      2,
+				Channel: ChannelSlack,
+				Target:  "https://hooks.slack.com/services/x",
+				Email:   "email",
+				Status:  DeadLetterStatusRedriven,
+			},
+		}
+		listed = []DeadLetter{
+			deadLetters[0],
+			{ID: 2, Channel: ChannelSlack, Email: "email", Status: DeadLetterStatusRedriven},
+		}
+	)
+
+	tests := []struct {
+		name         string
+		query        map[string]string
+		mockApplier  func(dm *deadLetterStoreMock)
+		expectedCode int
+		expectedBody any
+	}{
+		{
+			name:         "invalid status",
+			query:        map[string]string{"status": "bad"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("dead letter status 'bad' is not valid"),
+		},
+		{
+			name: "store error",
+			mockApplier: func(dm *deadLetterStoreMock) {
+				dm.On("getDeadLetters", DeadLetterStatusPending).Return(nil, customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name:  "dead letters by status without the slack urls",
+			query: map[string]string{"status": "redriven"},
+			mockApplier: func(dm *deadLetterStoreMock) {
+				dm.On("getDeadLetters", DeadLetterStatusRedriven).Return(deadLetters, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: listed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx, r          = getTestContext(nil, test.query)
+				deadLettersMock = &deadLetterStoreMock{}
+				ctl             = controller{deadLetters: deadLettersMock}
+			)
+
+			if test.mockApplier != nil {
+				test.mockApplier(deadLettersMock)
+				defer deadLettersMock.AssertExpectations(t)
+			}
+
+			b, err := json.Marshal(test.expectedBody)
+			require.Nil(t, err)
+
+			ctl.GetDeadLetters(ctx)
+
+			assert.Equal(t, test.expectedCode, r.Code)
+			assert.Equal(t, string(b), r.Body.String())
+		})
+	}
+}
+
+func TestControllerRedriveDeadLetter(t *testing.T) {
+	var (
+		customErr  = errors.New("custom error")
+		message    = Message{Key: "outbox-1", To: []string{"email"}, Cc: []string{"copy"}, HTML: "message"}
+		webhook    = Preference{UserID: 5, Channel: ChannelWebhook, Target: "https://hooks.local", Secret: "secret"}
+		deadLetter = DeadLetter{
+			ID:           1,
+			UserID:       5,
+			Channel:      ChannelWebhook,
+			Target:       "https://hooks.local",
+			Email:        "email, copy",
+			Message:      "message",
+			Notification: message,
+			Status:       DeadLetterStatusPending,
+		}
+		emailDeadLetter = DeadLetter{
+			ID:           1,
+			UserID:       5,
+			Channel:      ChannelEmail,
+			Notification: Message{To: []string{"email"}, HTML: "message"},
+			Status:       DeadLetterStatusPending,
+		}
+		delivery = Delivery{
+			NotificationKey: "outbox-1",
+			Channel:         ChannelWebhook,
+			Target:          "https://hooks.local",
+			Status:          DeliveryStatusSent,
+		}
+	)
+
+	type mocks struct {
+		deadLetters *deadLetterStoreMock
+		preferences *preferenceStoreMock
+		deliveries  *deliveryStoreMock
+		email       *channelMock
+		webhook     *channelMock
+	}
+
+	tests := []struct {
+		name         string
+		params       map[string]string
+		mockApplier  func(m mocks)
+		expectedCode int
+		expectedBody any
+	}{
+		{
+			name:         "missing dead letter id param",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("missing dead letter id param"),
+		},
+		{
+			name:         "dead letter id is not an integer",
+			params:       map[string]string{"id": "bad format"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError(fmt.Sprintf("dead letter id '%s' is not an integer", "bad format")),
+		},
+		{
+			name:   "dead letter not found",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m mocks) {
+				m.deadLetters.On("getDeadLetterByID", int64(1)).
+					Return(nil, fmt.Errorf("wrapped: %w", sql.ErrNoRows)).Once()
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: notFoundError("dead letter id 1 not found"),
+		},
+		{
+			name:   "dead letter already redriven",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m mocks) {
+				m.deadLetters.On("getDeadLetterByID", int64(1)).
+					Return(DeadLetter{ID: 1, Status: DeadLetterStatusRedriven}, nil).Once()
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: conflictError("dead letter id 1 was already redriven"),
+		},
+		{
+			name:   "channel not available",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m mocks) {
+				m.deadLetters.On("getDeadLetterByID", int64(1)).
+					Return(DeadLetter{ID: 1, Channel: ChannelSMS, Status: DeadLetterStatusPending}, nil).Once()
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: conflictError("sms channel is not available"),
+		},
+		{
+			name:   "error getting preferences",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m mocks) {
+				m.deadLetters.On("getDeadLetterByID", int64(1)).Return(deadLetter, nil).Once()
+				m.preferences.On("getPreferences", int64(5)).Return(nil, customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name:   "preference removed by the user",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m mocks) {
+				m.deadLetters.On("getDeadLetterByID", int64(1)).Return(deadLetter, nil).Once()
+				m.preferences.On("getPreferences", int64(5)).Return([]Preference{}, nil).Once()
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: conflictError("user id 5 no longer has the webhook preference of dead letter id 1"),
+		},
+		{
+			name:   "error getting deliveries",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m mocks) {
+				m.deadLetters.On("getDeadLetterByID", int64(1)).Return(deadLetter, nil).Once()
+				m.preferences.On("getPreferences", int64(5)).Return([]Preference{webhook}, nil).Once()
+				m.deliveries.On("getDeliveries", "outbox-1").Return(nil, customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name:   "notification fails again",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m mocks) {
+				m.deadLetters.On("getDeadLetterByID", int64(1)).Return(deadLetter, nil).Once()
+				m.preferences.On("getPreferences", int64(5)).Return([]Preference{webhook}, nil).Once()
+				m.deliveries.On("getDeliveries", "outbox-1").Return([]Delivery{}, nil).Once()
+				m.webhook.On("Send", webhook, message).Return(customErr).Once()
+			},
+			expectedCode: http.StatusBadGateway,
+			expectedBody: badGatewayError(customErr.Error()),
+		},
+		{
+			name:   "error saving the delivery",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m mocks) {
+				m.deadLetters.On("getDeadLetterByID", int64(1)).Return(deadLetter, nil).Once()
+				m.preferences.On("getPreferences", int64(5)).Return([]Preference{webhook}, nil).Once()
+				m.deliveries.On("getDeliveries", "outbox-1").Return([]Delivery{}, nil).Once()
+				m.webhook.On("Send", webhook, message).Return(nil).Once()
+				m.deliveries.On("saveDelivery", delivery).Return(customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name:   "dead letter redriven through its channel",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m mocks) {
+				m.deadLetters.On("getDeadLetterByID", int64(1)).Return(deadLetter, nil).Once()
+				m.preferences.On("getPreferences", int64(5)).Return([]Preference{webhook}, nil).Once()
+				m.deliveries.On("getDeliveries", "outbox-1").Return([]Delivery{}, nil).Once()
+				m.webhook.On("Send", webhook, message).Return(nil).Once()
+				m.deliveries.On("saveDelivery", delivery).Return(nil).Once()
+				m.deadLetters.On("markDeadLetterRedriven", int64(1)).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "dead letter already sent is only marked",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m mocks) {
+				m.deadLetters.On("getDeadLetterByID", int64(1)).Return(deadLetter, nil).Once()
+				m.preferences.On("getPreferences", int64(5)).Return([]Preference{webhook}, nil).Once()
+				m.deliveries.On("getDeliveries", "outbox-1").Return([]Delivery{delivery}, nil).Once()
+				m.deadLetters.On("markDeadLetterRedriven", int64(1)).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "untracked email dead letter of a user without preferences",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m mocks) {
+				m.deadLetters.On("getDeadLetterByID", int64(1)).Return(emailDeadLetter, nil).Once()
+				m.preferences.On("getPreferences", int64(5)).Return([]Preference{}, nil).Once()
+				m.email.On("Send", Preference{UserID: 5, Channel: ChannelEmail}, emailDeadLetter.Notification).
+					Return(nil).Once()
+				m.deadLetters.On("markDeadLetterRedriven", int64(1)).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "error marking the dead letter",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m mocks) {
+				m.deadLetters.On("getDeadLetterByID", int64(1)).Return(deadLetter, nil).Once()
+				m.preferences.On("getPreferences", int64(5)).Return([]Preference{webhook}, nil).Once()
+				m.deliveries.On("getDeliveries", "outbox-1").Return([]Delivery{delivery}, nil).Once()
+				m.deadLetters.On("markDeadLetterRedriven", int64(1)).Return(customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx, r = getTestContext(test.params, nil)
+				m      = mocks{
+					deadLetters: &deadLetterStoreMock{},
+					preferences: &preferenceStoreMock{},
+					deliveries:  &deliveryStoreMock{},
+					email:       &channelMock{},
+					webhook:     &channelMock{},
+				}
+				ctl = controller{
+					channels:    map[ChannelType]Channel{ChannelEmail: m.email, ChannelWebhook: m.webhook},
+					deadLetters: m.deadLetters,
+					preferences: m.preferences,
+					deliveries:  m.deliveries,
+				}
+			)
+
+			if test.mockApplier != nil {
+				test.mockApplier(m)
+				defer m.deadLetters.AssertExpectations(t)
+				defer m.preferences.AssertExpectations(t)
+				defer m.deliveries.AssertExpectations(t)
+				defer m.email.AssertExpectations(t)
+				defer m.webhook.AssertExpectations(t)
+			}
+
+			expectedBody := ""
+			if test.expectedBody != nil {
+				b, err := json.Marshal(test.expectedBody)
+				require.Nil(t, err)
+				expectedBody = string(b)
+			}
+
+			ctl.RedriveDeadLetter(ctx)
+
+			assert.Equal(t, test.expectedCode, r.Code)
+			assert.Equal(t, expectedBody, r.Body.String())
+		})
+	}
+}
+
+func TestControllerGetPreferences(t *testing.T) {
+	var (
+		customErr   = errors.New("custom error")
+		preferences = []Preference{
+			{UserID: 5, Channel: ChannelEmail},
+			{UserID: 5, Channel: ChannelWebhook, Target: "https://hooks.local", Secret: "secret"},
+		}
+	)
+
+	tests := []struct {
+		name         string
+		params       map[string]string
+		mockApplier  func(pm *preferenceStoreMock)
+		expectedCode int
+		expectedBody any
+	}{
+		{
+			name:         "user id is not an integer",
+			params:       map[string]string{"user_id": "x"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("user id 'x' is not an integer"),
+		},
+		{
+			name:   "store error",
+			params: map[string]string{"user_id": "5"},
+			mockApplier: func(pm *preferenceStoreMock) {
+				pm.On("getPreferences", int64(5)).Return(nil, customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name:   "preferences without secrets",
+			params: map[string]string{"user_id": "5"},
+			mockApplier: func(pm *preferenceStoreMock) {
+				pm.On("getPreferences", int64(5)).Return(preferences, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []Preference{
+				{UserID: 5, Channel: ChannelEmail},
+				{UserID: 5, Channel: ChannelWebhook, Target: "https://hooks.local"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx, r          = getTestContext(test.params, nil)
+				preferencesMock = &preferenceStoreMock{}
+				ctl             = controller{preferences: preferencesMock}
+			)
+
+			if test.mockApplier != nil {
+				test.mockApplier(preferencesMock)
+				defer preferencesMock.AssertExpectations(t)
+			}
+
+			b, err := json.Marshal(test.expectedBody)
+			require.Nil(t, err)
+
+			ctl.GetPreferences(ctx)
+
+			assert.Equal(t, test.expectedCode, r.Code)
+			assert.Equal(t, string(b), r.Body.String())
+		})
+	}
+}
+
+func TestControllerSavePreferences(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		body      = `[{"Channel":"slack","Target":"https://hooks.slack.com/services/x"},` +
+			`{"Channel":"webhook","Target":"https://hooks.local","Secret":"secret"}]`
+		preferences = []Preference{
+			{UserID: 5, Channel: ChannelSlack, Target: "https://hooks.slack.com/services/x"},
+			{UserID: 5, Channel: ChannelWebhook, Target: "https://hooks.local", Secret: "secret"},
+		}
+	)
+
+	tests := []struct {
+		name         string
+		params       map[string]string
+		body         string
+		mockApplier  func(pm *preferenceStoreMock)
+		expectedCode int
+		expectedBody any
+	}{
+		{
+			name:         "missing user id param",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("missing user id param"),
+		},
+		{
+			name:         "body is not a list",
+			params:       map[string]string{"user_id": "5"},
+			body:         `{"Channel":"email"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError(
+				"error reading notification preferences body due to: " +
+					"json: cannot unmarshal object into Go value of type []notifier.Preference"),
+		},
+		{
+			name:         "preference not valid",
+			params:       map[string]string{"user_id": "5"},
+			body:         `[{"Channel":"webhook","Target":"https://hooks.local"}]`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("webhook preference needs a secret to sign its requests"),
+		},
+		{
+			name:   "store error",
+			params: map[string]string{"user_id": "5"},
+			body:   body,
+			mockApplier: func(pm *preferenceStoreMock) {
+				pm.On("savePreferences", int64(5), preferences).Return(customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name:   "preferences cleared",
+			params: map[string]string{"user_id": "5"},
+			body:   `[]`,
+			mockApplier: func(pm *preferenceStoreMock) {
+				pm.On("savePreferences", int64(5), []Preference{}).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []Preference{},
+		},
+		{
+			name:   "preferences saved",
+			params: map[string]string{"user_id": "5"},
+			body:   body,
+			mockApplier: func(pm *preferenceStoreMock) {
+				pm.On("savePreferences", int64(5), preferences).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: []Preference{
+				{UserID: 5, Channel: ChannelSlack, Target: "https://hooks.slack.com/services/x"},
+				{UserID: 5, Channel: ChannelWebhook, Target: "https://hooks.local"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx, r          = getTestContext(test.params, nil)
+				preferencesMock = &preferenceStoreMock{}
+				ctl             = controller{preferences: preferencesMock}
+			)
+
+			ctx.Request.Body = io.NopCloser(bytes.NewBufferString(test.body))
+
+			if test.mockApplier != nil {
+				test.mockApplier(preferencesMock)
+				defer preferencesMock.AssertExpectations(t)
+			}
+
+			b, err := json.Marshal(test.expectedBody)
+			require.Nil(t, err)
+
+			ctl.SavePreferences(ctx)
+
+			assert.Equal(t, test.expectedCode, r.Code)
+			assert.Equal(t, string(b), r.Body.String())
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package notifier
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type DeadLetterStatus string
+
+const (
+	DeadLetterStatusPending  DeadLetterStatus = "pending"
+	DeadLetterStatusRedriven DeadLetterStatus = "redriven"
+)
+
+// DeadLetter is a notification which could not be sent through one of the channels of the user, which is the
+// only one it is sent through again when redriven. It is listed by its recipients and body, while the whole
+// notification, with its attachments, is only needed to send it again.
+type DeadLetter struct {
+	ID           int64
+	UserID       int64
+	Channel      ChannelType
+	Target       string
+	Email        string
+	Message      string
+	Notification Message `json:"-"`
+	Error        string
+	Attempts     int
+	Status       DeadLetterStatus
+	DateCreated  time.Time
+	DateRedriven *time.Time
+}
+
+func newDeadLetter(preference Preference, message Message, err string, attempts int) DeadLetter {
+	body := message.HTML
+	if body == "" {
+		body = message.Text
+	}
+
+	return DeadLetter{
+		UserID:       preference.UserID,
+		Channel:      preference.Channel,
+		Target:       preference.Target,
+		Email:        strings.Join(message.recipients(), ", "),
+		Message:      body,
+		Notification: message,
+		Error:        err,
+		Attempts:     attempts,
+		Status:       DeadLetterStatusPending,
+	}
+}
+
+func NewDeadLetterStore(client *sql.DB) DeadLetterStore {
+	return deadLetterStore{client: client}
+}
+
+type DeadLetterStore interface {
+	saveDeadLetter(context.Context, DeadLetter) error
+	getDeadLetters(context.Context, DeadLetterStatus) ([]DeadLetter, error)
+	getDeadLetterByID(context.Context, int64) (DeadLetter, error)
+	markDeadLetterRedriven(context.Context, int64) error
+}
+
+type deadLetterStore struct {
+	client *sql.DB
+}
+
+const deadLetterColumns = `id, user_id, channel, target, email, message, notification, error, attempts, status, ` +
+	`date_created, date_redriven`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDeadLetter(row scanner) (DeadLetter, error) {
+	var (
+		deadLetter   DeadLetter
+		notification []byte
+		dateRedriven sql.NullTime
+	)
+
+	err := row.Scan(
+		&deadLetter.ID,
+		&deadLetter.UserID,
+		&deadLetter.Channel,
+		&deadLetter.Target,
+		&deadLetter.Email,
+		&deadLetter.Message,
+		&notification,
+		&deadLetter.Error,
+		&deadLetter.Attempts,
+		&deadLetter.Status,
+		&deadLetter.DateCreated,
+		&dateRedriven,
+	)
+	if err != nil {
+		return DeadLetter{}, err
+	}
+
+	if err = json.Unmarshal(notification, &deadLetter.Notification); err != nil {
+		return DeadLetter{}, err
+	}
+
+	if dateRedriven.Valid {
+		deadLetter.DateRedriven = &dateRedriven.Time
+	}
+
+	return deadLetter, nil
+}
+
+func (s deadLetterStore) saveDeadLetter(ctx context.Context, deadLetter DeadLetter) error {
+	query := `INSERT INTO dead_letter (user_id, channel, target, email, message, notification, error, attempts, ` +
+		`status) VALUES (?,?,?,?,?,?,?,?,?)`
+
+	notification, err := json.Marshal(deadLetter.Notification)
+	if err != nil {
+		return fmt.Errorf("error encoding dead letter notification due to: %w", err)
+	}
+
+	_, err = s.client.ExecContext(
+		ctx, query, deadLetter.UserID, deadLetter.Channel, deadLetter.Target, deadLetter.Email, deadLetter.Message,
+		notification, deadLetter.Error, deadLetter.Attempts, deadLetter.Status)
+	if err != nil {
+		return fmt.Errorf("error inserting dead letter due to: %w", err)
+	}
+	return nil
+}
+
+func (s deadLetterStore) getDeadLetters(ctx context.Context, status DeadLetterStatus) ([]DeadLetter, error) {
+	var (
+		deadLetters = make([]DeadLetter, 0)
+		query       = `SELECT ` + deadLetterColumns + ` FROM dead_letter WHERE status = ? ORDER BY id`
+	)
+
+	rows, err := s.client.QueryContext(ctx, query, status)
+	if err != nil {
+		return nil, fmt.Errorf("error querying dead letters due to: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		deadLetter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning dead letter due to: %w", err)
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dead letters due to: %w", err)
+	}
+
+	return deadLetters, nil
+}
+
+func (s deadLetterStore) getDeadLetterByID(ctx context.Context, deadLetterID int64) (DeadLetter, error) {
+	query := `SELECT ` + deadLetterColumns + ` FROM dead_letter WHERE id = ?`
+
+	deadLetter, err := scanDeadLetter(s.client.QueryRowContext(ctx, query, deadLetterID))
+	if err != nil {
+		return DeadLetter{}, fmt.Errorf("error scanning dead letter by id %d due to: %w", deadLetterID, err)
+	}
+
+	return deadLetter, nil
+}
+
+func (s deadLetterStore) markDeadLetterRedriven(ctx context.Context, deadLetterID int64) error {
+	query := `UPDATE dead_letter SET status = ?, date_redriven = current_timestamp() WHERE id = ?`
+
+	if _, err := s.client.ExecContext(ctx, query, DeadLetterStatusRedriven, deadLetterID); err != nil {
+		return fmt.Errorf("error marking dead letter id %d as redriven due to: %w", deadLetterID, err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type deadLetterStoreMock struct {
+	mock.Mock
+}
+
+func (m *deadLetterStoreMock) saveDeadLetter(_ context.Context, deadLetter DeadLetter) error {
+	args := m.Called(deadLetter)
+	return args.Error(0)
+}
+
+func (m *deadLetterStoreMock) getDeadLetters(_ context.Context, status DeadLetterStatus) ([]DeadLetter, error) {
+	var (
+		deadLetters []DeadLetter
+		args        = m.Called(status)
+	)
+
+	if value, ok := args.Get(0).([]DeadLetter); ok {
+		deadLetters = value
+	}
+	return deadLetters, args.Error(1)
+}
+
+func (m *deadLetterStoreMock) getDeadLetterByID(_ context.Context, deadLetterID int64) (DeadLetter, error) {
+	var (
+		deadLetter DeadLetter
+		args       = m.Called(deadLetterID)
+	)
+
+	if value, ok := args.Get(0).(DeadLetter); ok {
+		deadLetter = value
+	}
+	return deadLetter, args.Error(1)
+}
+
+func (m *deadLetterStoreMock) markDeadLetterRedriven(_ context.Context, deadLetterID int64) error {
+	args := m.Called(deadLetterID)
+	return args.Error(0)
+}
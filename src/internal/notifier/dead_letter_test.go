@@ -0,0 +1,237 @@
+package notifier
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDBMock() (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		panic(err)
+	}
+	return db, mock
+}
+
+func TestDeadLetterStoreSaveDeadLetter(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(`INSERT INTO dead_letter (user_id, channel, target, email, message, ` +
+			`notification, error, attempts, status) VALUES (?,?,?,?,?,?,?,?,?)`)
+		email        = Preference{UserID: 1, Channel: ChannelEmail}
+		webhook      = Preference{UserID: 1, Channel: ChannelWebhook, Target: "https://hooks.local", Secret: "secret"}
+		deadLetter   = newDeadLetter(email, Message{To: []string{"email"}, HTML: "message"}, "error", 3)
+		notification = []byte(`{"To":["email"],"HTML":"message"}`)
+	)
+
+	tests := []struct {
+		name        string
+		deadLetter  DeadLetter
+		mockApplier func(sqlmock.Sqlmock)
+		expected    error
+	}{
+		{
+			name:       "error executing query",
+			deadLetter: deadLetter,
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).
+					WithArgs(1, "email", "", "email", "message", notification, "error", 3, "pending").
+					WillReturnError(customErr)
+			},
+			expected: fmt.Errorf("error inserting dead letter due to: %w", customErr),
+		},
+		{
+			name:       "dead letter inserted successfully",
+			deadLetter: deadLetter,
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).
+					WithArgs(1, "email", "", "email", "message", notification, "error", 3, "pending").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{
+			name: "webhook dead letter with copies and attachments inserted successfully",
+			deadLetter: newDeadLetter(webhook, Message{
+				Key:         "outbox-1",
+				To:          []string{"email"},
+				Cc:          []string{"copy"},
+				Text:        "text",
+				Attachments: []Attachment{{Name: "statement.pdf", Content: []byte("pdf")}},
+			}, "error", 3),
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).
+					WithArgs(
+						1, "webhook", "https://hooks.local", "email, copy", "text",
+						[]byte(`{"Key":"outbox-1","To":["email"],"Cc":["copy"],"Text":"text",`+
+							`"Attachments":[{"Name":"statement.pdf","ContentType":"","Content":"cGRm"}]}`),
+						"error", 3, "pending").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			assert.Equal(t, test.expected, deadLetterStore{client: db}.saveDeadLetter(context.TODO(), test.deadLetter))
+		})
+	}
+}
+
+func TestDeadLetterStoreGetDeadLetters(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		date      = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		query     = regexp.QuoteMeta(`SELECT id, user_id, channel, target, email, message, notification, error, ` +
+			`attempts, status, date_created, date_redriven FROM dead_letter WHERE status = ? ORDER BY id`)
+		columns = []string{
+			"id", "user_id", "channel", "target", "email", "message", "notification", "error", "attempts", "status",
+			"date_created", "date_redriven",
+		}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    []DeadLetter
+		expectedErr error
+	}{
+		{
+			name: "error executing query",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs("pending").WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error querying dead letters due to: %w", customErr),
+		},
+		{
+			name: "no dead letters",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs("pending").WillReturnRows(sqlmock.NewRows(columns))
+			},
+			expected: []DeadLetter{},
+		},
+		{
+			name: "dead letters",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs("pending").WillReturnRows(
+					sqlmock.NewRows(columns).AddRow(
+						1, 1, "email", "", "email", "message", []byte(`{"To":["email"],"HTML":"message"}`),
+						"error", 3, "pending", date, nil))
+			},
+			expected: []DeadLetter{
+				{
+					ID:           1,
+					UserID:       1,
+					Channel:      ChannelEmail,
+					Email:        "email",
+					Message:      "message",
+					Notification: Message{To: []string{"email"}, HTML: "message"},
+					Error:        "error",
+					Attempts:     3,
+					Status:       DeadLetterStatusPending,
+					DateCreated:  date,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			deadLetters, err := deadLetterStore{client: db}.getDeadLetters(context.TODO(), DeadLetterStatusPending)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, deadLetters)
+		})
+	}
+}
+
+func TestDeadLetterStoreGetDeadLetterByID(t *testing.T) {
+	var (
+		date  = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		query = regexp.QuoteMeta(`SELECT id, user_id, channel, target, email, message, notification, error, ` +
+			`attempts, status, date_created, date_redriven FROM dead_letter WHERE id = ?`)
+		columns = []string{
+			"id", "user_id", "channel", "target", "email", "message", "notification", "error", "attempts", "status",
+			"date_created", "date_redriven",
+		}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    DeadLetter
+		expectedErr error
+	}{
+		{
+			name: "dead letter not found",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(columns))
+			},
+			expectedErr: fmt.Errorf("error scanning dead letter by id %d due to: %w", 1, sql.ErrNoRows),
+		},
+		{
+			name: "redriven dead letter",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(1)).WillReturnRows(
+					sqlmock.NewRows(columns).AddRow(
+						1, 1, "webhook", "https://hooks.local", "email, copy", "message",
+						[]byte(`{"To":["email"],"Cc":["copy"],"HTML":"message","Attachments":[{"Content":"cGRm"}]}`),
+						"error", 3, "redriven", date, date))
+			},
+			expected: DeadLetter{
+				ID:      1,
+				UserID:  1,
+				Channel: ChannelWebhook,
+				Target:  "https://hooks.local",
+				Email:   "email, copy",
+				Message: "message",
+				Notification: Message{
+					To:          []string{"email"},
+					Cc:          []string{"copy"},
+					HTML:        "message",
+					Attachments: []Attachment{{Content: []byte("pdf")}},
+				},
+				Error:        "error",
+				Attempts:     3,
+				Status:       DeadLetterStatusRedriven,
+				DateCreated:  date,
+				DateRedriven: &date,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			deadLetter, err := deadLetterStore{client: db}.getDeadLetterByID(context.TODO(), 1)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, deadLetter)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package notifier
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusSent         DeliveryStatus = "sent"
+	DeliveryStatusDeadLettered DeliveryStatus = "dead_lettered"
+)
+
+// Delivery is how a notification went through one of the channels of the user, so that sending it again, as
+// it is when another channel failed, skips the channels it already went through.
+type Delivery struct {
+	NotificationKey string
+	Channel         ChannelType
+	Target          string
+	Status          DeliveryStatus
+}
+
+// deliveryTarget is a channel and the target reached through it.
+type deliveryTarget struct {
+	channel ChannelType
+	target  string
+}
+
+func (p Preference) deliveryTarget() deliveryTarget {
+	return deliveryTarget{channel: p.Channel, target: p.Target}
+}
+
+// deliveryStatuses returns the status of the deliveries by their channel and target.
+func deliveryStatuses(deliveries []Delivery) map[deliveryTarget]DeliveryStatus {
+	statuses := make(map[deliveryTarget]DeliveryStatus, len(deliveries))
+	for _, delivery := range deliveries {
+		statuses[deliveryTarget{channel: delivery.Channel, target: delivery.Target}] = delivery.Status
+	}
+	return statuses
+}
+
+func NewDeliveryStore(client *sql.DB) DeliveryStore {
+	return deliveryStore{client: client}
+}
+
+type DeliveryStore interface {
+	getDeliveries(context.Context, string) ([]Delivery, error)
+	saveDelivery(context.Context, Delivery) error
+}
+
+type deliveryStore struct {
+	client *sql.DB
+}
+
+func (s deliveryStore) getDeliveries(ctx context.Context, notificationKey string) ([]Delivery, error) {
+	var (
+		deliveries = make([]Delivery, 0)
+		query      = `SELECT notification_key, channel, target, status FROM notification_delivery ` +
+			`WHERE notification_key = ?`
+	)
+
+	rows, err := s.client.QueryContext(ctx, query, notificationKey)
+	if err != nil {
+		return nil, fmt.Errorf("error querying notification deliveries due to: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var delivery Delivery
+		err = rows.Scan(&delivery.NotificationKey, &delivery.Channel, &delivery.Target, &delivery.Status)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning notification delivery due to: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification deliveries due to: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// saveDelivery records the delivery, replacing the status of the one through the same channel and target, as
+// a dead lettered notification is sent once redriven.
+func (s deliveryStore) saveDelivery(ctx context.Context, delivery Delivery) error {
+	query := `INSERT INTO notification_delivery (notification_key, channel, target, status) VALUES (?,?,?,?) ` +
+		`ON DUPLICATE KEY UPDATE status = VALUES(status), date_updated = current_timestamp()`
+
+	_, err := s.client.ExecContext(
+		ctx, query, delivery.NotificationKey, delivery.Channel, delivery.Target, delivery.Status)
+	if err != nil {
+		return fmt.Errorf("error saving notification delivery due to: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type deliveryStoreMock struct {
+	mock.Mock
+}
+
+func (m *deliveryStoreMock) getDeliveries(_ context.Context, notificationKey string) ([]Delivery, error) {
+	var (
+		deliveries []Delivery
+		args       = m.Called(notificationKey)
+	)
+
+	if value, ok := args.Get(0).([]Delivery); ok {
+		deliveries = value
+	}
+	return deliveries, args.Error(1)
+}
+
+func (m *deliveryStoreMock) saveDelivery(_ context.Context, delivery Delivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
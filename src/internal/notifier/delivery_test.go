@@ -0,0 +1,126 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryStoreGetDeliveries(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(`SELECT notification_key, channel, target, status FROM notification_delivery ` +
+			`WHERE notification_key = ?`)
+		columns = []string{"notification_key", "channel", "target", "status"}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    []Delivery
+		expectedErr error
+	}{
+		{
+			name: "error executing query",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs("outbox-1").WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error querying notification deliveries due to: %w", customErr),
+		},
+		{
+			name: "no deliveries",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs("outbox-1").WillReturnRows(sqlmock.NewRows(columns))
+			},
+			expected: []Delivery{},
+		},
+		{
+			name: "deliveries",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs("outbox-1").WillReturnRows(
+					sqlmock.NewRows(columns).
+						AddRow("outbox-1", "email", "", "sent").
+						AddRow("outbox-1", "webhook", "https://hooks.local", "dead_lettered"))
+			},
+			expected: []Delivery{
+				{NotificationKey: "outbox-1", Channel: ChannelEmail, Status: DeliveryStatusSent},
+				{
+					NotificationKey: "outbox-1",
+					Channel:         ChannelWebhook,
+					Target:          "https://hooks.local",
+					Status:          DeliveryStatusDeadLettered,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			deliveries, err := deliveryStore{client: db}.getDeliveries(context.TODO(), "outbox-1")
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, deliveries)
+		})
+	}
+}
+
+func TestDeliveryStoreSaveDelivery(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(`INSERT INTO notification_delivery (notification_key, channel, target, ` +
+			`status) VALUES (?,?,?,?) ON DUPLICATE KEY UPDATE status = VALUES(status), ` +
+			`date_updated = current_timestamp()`)
+		delivery = Delivery{
+			NotificationKey: "outbox-1",
+			Channel:         ChannelSlack,
+			Target:          "https://hooks.slack.com/services/x",
+			Status:          DeliveryStatusSent,
+		}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    error
+	}{
+		{
+			name: "error executing query",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("outbox-1", "slack", "https://hooks.slack.com/services/x", "sent").
+					WillReturnError(customErr)
+			},
+			expected: fmt.Errorf("error saving notification delivery due to: %w", customErr),
+		},
+		{
+			name: "delivery saved",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("outbox-1", "slack", "https://hooks.slack.com/services/x", "sent").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			assert.Equal(t, test.expected, deliveryStore{client: db}.saveDelivery(context.TODO(), delivery))
+		})
+	}
+}
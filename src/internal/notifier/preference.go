@@ -0,0 +1,138 @@
+package notifier
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Preference is a channel a user chose to be notified through, with the target to reach them on it: an email
+// address, a webhook or Slack url or a phone number. Webhook requests are signed with its secret, which is
+// never listed back.
+type Preference struct {
+	UserID  int64
+	Channel ChannelType
+	Target  string
+	Secret  string `json:",omitempty"`
+}
+
+var phoneNumberRegex = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+
+// validate checks the target of the preference can be reached through its channel. The target of an email
+// preference is optional, the address of the user is used without it. Urls must be https ones on public
+// hosts, so that users cannot make us send requests to the internal network.
+func (p Preference) validate() error {
+	switch p.Channel {
+	case ChannelEmail:
+		if p.Target != "" && !strings.Contains(p.Target, "@") {
+			return fmt.Errorf("email target '%s' is not an email address", p.Target)
+		}
+	case ChannelWebhook, ChannelSlack:
+		target, err := url.Parse(p.Target)
+		if err != nil || target.Scheme != "https" || target.Host == "" {
+			return fmt.Errorf("%s target must be an https url", p.Channel)
+		}
+		if !isPublicHost(target.Hostname()) {
+			return fmt.Errorf("%s target must be a public address", p.Channel)
+		}
+		if p.Channel == ChannelWebhook && p.Secret == "" {
+			return errors.New("webhook preference needs a secret to sign its requests")
+		}
+	case ChannelSMS:
+		if !phoneNumberRegex.MatchString(p.Target) {
+			return fmt.Errorf("sms target '%s' is not a phone number", p.Target)
+		}
+	default:
+		return fmt.Errorf("channel '%s' is not valid", p.Channel)
+	}
+	return nil
+}
+
+func NewPreferenceStore(client *sql.DB) PreferenceStore {
+	return preferenceStore{client: client}
+}
+
+type PreferenceStore interface {
+	getPreferences(context.Context, int64) ([]Preference, error)
+	savePreferences(context.Context, int64, []Preference) error
+}
+
+type preferenceStore struct {
+	client *sql.DB
+}
+
+func (s preferenceStore) getPreferences(ctx context.Context, userID int64) ([]Preference, error) {
+	var (
+		preferences = make([]Preference, 0)
+		query       = `SELECT user_id, channel, target, secret FROM notification_preference ` +
+			`WHERE user_id = ? ORDER BY id`
+	)
+
+	rows, err := s.client.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying notification preferences due to: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			preference Preference
+			secret     sql.NullString
+		)
+		if err = rows.Scan(&preference.UserID, &preference.Channel, &preference.Target, &secret); err != nil {
+			return nil, fmt.Errorf("error scanning notification preference due to: %w", err)
+		}
+		preference.Secret = secret.String
+		preferences = append(preferences, preference)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification preferences due to: %w", err)
+	}
+
+	return preferences, nil
+}
+
+// savePreferences replaces the preferences of the user, all of them or none.
+func (s preferenceStore) savePreferences(ctx context.Context, userID int64, preferences []Preference) (err error) {
+	var (
+		deleteQuery = `DELETE FROM notification_preference WHERE user_id = ?`
+		insertQuery = `INSERT INTO notification_preference (user_id, channel, target, secret) VALUES (?,?,?,?)`
+	)
+
+	tnx, err := s.client.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error beginning notification preferences transaction due to: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tnx.Rollback()
+			return
+		}
+		if err = tnx.Commit(); err != nil {
+			err = fmt.Errorf("error committing notification preferences due to: %w", err)
+		}
+	}()
+
+	if _, err = tnx.ExecContext(ctx, deleteQuery, userID); err != nil {
+		return fmt.Errorf("error deleting notification preferences due to: %w", err)
+	}
+
+	for _, preference := range preferences {
+		var secret sql.NullString
+		if preference.Secret != "" {
+			secret = sql.NullString{String: preference.Secret, Valid: true}
+		}
+
+		_, err = tnx.ExecContext(ctx, insertQuery, userID, preference.Channel, preference.Target, secret)
+		if err != nil {
+			return fmt.Errorf("error inserting notification preference due to: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type preferenceStoreMock struct {
+	mock.Mock
+}
+
+func (m *preferenceStoreMock) getPreferences(_ context.Context, userID int64) ([]Preference, error) {
+	var (
+		preferences []Preference
+		args        = m.Called(userID)
+	)
+
+	if value, ok := args.Get(0).([]Preference); ok {
+		preferences = value
+	}
+	return preferences, args.Error(1)
+}
+
+func (m *preferenceStoreMock) savePreferences(_ context.Context, userID int64, preferences []Preference) error {
+	args := m.Called(userID, preferences)
+	return args.Error(0)
+}
@@ -0,0 +1,251 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreferenceValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		preference Preference
+		expected   error
+	}{
+		{
+			name:       "unknown channel",
+			preference: Preference{Channel: "fax", Target: "123"},
+			expected:   errors.New("channel 'fax' is not valid"),
+		},
+		{
+			name:       "email of the user",
+			preference: Preference{Channel: ChannelEmail},
+		},
+		{
+			name:       "email target not valid",
+			preference: Preference{Channel: ChannelEmail, Target: "someone"},
+			expected:   errors.New("email target 'someone' is not an email address"),
+		},
+		{
+			name:       "email target",
+			preference: Preference{Channel: ChannelEmail, Target: "someone@mail.com"},
+		},
+		{
+			name:       "webhook target not an url",
+			preference: Preference{Channel: ChannelWebhook, Target: "ftp://hooks.local", Secret: "secret"},
+			expected:   errors.New("webhook target must be an https url"),
+		},
+		{
+			name:       "webhook target over http",
+			preference: Preference{Channel: ChannelWebhook, Target: "http://hooks.local/resume", Secret: "secret"},
+			expected:   errors.New("webhook target must be an https url"),
+		},
+		{
+			name:       "webhook target on loopback",
+			preference: Preference{Channel: ChannelWebhook, Target: "https://127.0.0.1:8080/resume", Secret: "secret"},
+			expected:   errors.New("webhook target must be a public address"),
+		},
+		{
+			name:       "webhook target on localhost",
+			preference: Preference{Channel: ChannelWebhook, Target: "https://LocalHost./resume", Secret: "secret"},
+			expected:   errors.New("webhook target must be a public address"),
+		},
+		{
+			name:       "webhook target on a private network",
+			preference: Preference{Channel: ChannelWebhook, Target: "https://10.0.0.8/resume", Secret: "secret"},
+			expected:   errors.New("webhook target must be a public address"),
+		},
+		{
+			name:       "slack target on the metadata address",
+			preference: Preference{Channel: ChannelSlack, Target: "https://169.254.169.254/latest"},
+			expected:   errors.New("slack target must be a public address"),
+		},
+		{
+			name:       "slack target on ipv6 loopback",
+			preference: Preference{Channel: ChannelSlack, Target: "https://[::1]/hook"},
+			expected:   errors.New("slack target must be a public address"),
+		},
+		{
+			name:       "webhook without secret",
+			preference: Preference{Channel: ChannelWebhook, Target: "https://hooks.local/resume"},
+			expected:   errors.New("webhook preference needs a secret to sign its requests"),
+		},
+		{
+			name:       "webhook",
+			preference: Preference{Channel: ChannelWebhook, Target: "https://hooks.local/resume", Secret: "secret"},
+		},
+		{
+			name:       "slack target not an url",
+			preference: Preference{Channel: ChannelSlack, Target: "hooks.slack.com"},
+			expected:   errors.New("slack target must be an https url"),
+		},
+		{
+			name:       "slack",
+			preference: Preference{Channel: ChannelSlack, Target: "https://hooks.slack.com/services/x"},
+		},
+		{
+			name:       "sms target not a phone number",
+			preference: Preference{Channel: ChannelSMS, Target: "555-1234"},
+			expected:   errors.New("sms target '555-1234' is not a phone number"),
+		},
+		{
+			name:       "sms",
+			preference: Preference{Channel: ChannelSMS, Target: "+5491100000000"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.preference.validate())
+		})
+	}
+}
+
+func TestPreferenceStoreGetPreferences(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(
+			`SELECT user_id, channel, target, secret FROM notification_preference WHERE user_id = ? ORDER BY id`)
+		columns = []string{"user_id", "channel", "target", "secret"}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    []Preference
+		expectedErr error
+	}{
+		{
+			name: "error executing query",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(1).WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error querying notification preferences due to: %w", customErr),
+		},
+		{
+			name: "no preferences",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows(columns))
+			},
+			expected: []Preference{},
+		},
+		{
+			name: "preferences",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(1).WillReturnRows(
+					sqlmock.NewRows(columns).
+						AddRow(1, "email", "", nil).
+						AddRow(1, "webhook", "https://hooks.local", "secret"))
+			},
+			expected: []Preference{
+				{UserID: 1, Channel: ChannelEmail},
+				{UserID: 1, Channel: ChannelWebhook, Target: "https://hooks.local", Secret: "secret"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			preferences, err := preferenceStore{client: db}.getPreferences(context.TODO(), 1)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, preferences)
+		})
+	}
+}
+
+func TestPreferenceStoreSavePreferences(t *testing.T) {
+	var (
+		customErr   = errors.New("custom error")
+		deleteQuery = regexp.QuoteMeta(`DELETE FROM notification_preference WHERE user_id = ?`)
+		insertQuery = regexp.QuoteMeta(
+			`INSERT INTO notification_preference (user_id, channel, target, secret) VALUES (?,?,?,?)`)
+		preferences = []Preference{
+			{UserID: 1, Channel: ChannelEmail},
+			{UserID: 1, Channel: ChannelWebhook, Target: "https://hooks.local", Secret: "secret"},
+		}
+	)
+
+	tests := []struct {
+		name        string
+		preferences []Preference
+		mockApplier func(sqlmock.Sqlmock)
+		expected    error
+	}{
+		{
+			name: "error beginning transaction",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin().WillReturnError(customErr)
+			},
+			expected: fmt.Errorf("error beginning notification preferences transaction due to: %w", customErr),
+		},
+		{
+			name:        "error inserting preference",
+			preferences: preferences,
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectExec(deleteQuery).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec(insertQuery).WithArgs(1, "email", "", nil).WillReturnError(customErr)
+				m.ExpectRollback()
+			},
+			expected: fmt.Errorf("error inserting notification preference due to: %w", customErr),
+		},
+		{
+			name: "preferences cleared",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectExec(deleteQuery).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:        "error committing",
+			preferences: preferences[:1],
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectExec(deleteQuery).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectExec(insertQuery).WithArgs(1, "email", "", nil).WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectCommit().WillReturnError(customErr)
+			},
+			expected: fmt.Errorf("error committing notification preferences due to: %w", customErr),
+		},
+		{
+			name:        "preferences replaced",
+			preferences: preferences,
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectExec(deleteQuery).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec(insertQuery).WithArgs(1, "email", "", nil).WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectExec(insertQuery).WithArgs(1, "webhook", "https://hooks.local", "secret").
+					WillReturnResult(sqlmock.NewResult(2, 1))
+				m.ExpectCommit()
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			err := preferenceStore{client: db}.savePreferences(context.TODO(), 1, test.preferences)
+
+			assert.Equal(t, test.expected, err)
+		})
+	}
+}
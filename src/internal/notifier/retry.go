@@ -0,0 +1,142 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/mail.v2"
+)
+
+type RetryOptions struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func GetRetryOptions() RetryOptions {
+	options := RetryOptions{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+	}
+
+	if maxAttemptsStr := os.Getenv("NOTIFIER_MAX_ATTEMPTS"); maxAttemptsStr != "" {
+		maxAttempts, err := strconv.Atoi(maxAttemptsStr)
+		if err != nil || maxAttempts < 1 {
+			panic("notifier max attempts is not a positive number")
+		}
+		options.MaxAttempts = maxAttempts
+	}
+
+	return options
+}
+
+// retrier sends a notification again after its temporary errors, waiting a backoff which doubles after each
+// attempt up to the max one, with some jitter.
+type retrier struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	random         func(n int64) int64
+	sleep          func(ctx context.Context, d time.Duration) error
+}
+
+func newRetrier(options RetryOptions) retrier {
+	return retrier{
+		maxAttempts:    options.MaxAttempts,
+		initialBackoff: options.InitialBackoff,
+		maxBackoff:     options.MaxBackoff,
+		random:         rand.Int63n,
+		sleep:          sleep,
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retriesInterruptedError is the context ending while waiting for the next attempt. The notification is not
+// dead lettered then, as it can still be sent.
+type retriesInterruptedError struct {
+	err error
+}
+
+func (e retriesInterruptedError) Error() string {
+	return fmt.Sprintf("notification retries interrupted due to: %s", e.err.Error())
+}
+
+func (e retriesInterruptedError) Unwrap() error {
+	return e.err
+}
+
+// do calls send until it succeeds, fails with a permanent error or runs out of attempts, returning the
+// attempts it made.
+func (r retrier) do(ctx context.Context, send func() error) (int, error) {
+	for attempts := 1; ; attempts++ {
+		err := send()
+		if err == nil {
+			return attempts, nil
+		}
+
+		if isPermanentError(err) || attempts >= r.maxAttempts {
+			return attempts, err
+		}
+
+		if sleepErr := r.sleep(ctx, r.backoff(attempts)); sleepErr != nil {
+			return attempts, retriesInterruptedError{err: sleepErr}
+		}
+	}
+}
+
+func (r retrier) backoff(attempt int) time.Duration {
+	backoff := r.initialBackoff << (attempt - 1)
+	if backoff <= 0 || backoff > r.maxBackoff {
+		backoff = r.maxBackoff
+	}
+
+	half := int64(backoff / 2)
+	return time.Duration(half + r.random(half+1))
+}
+
+// isPermanentError tells whether sending the notification again gets the same error: the permanent replies of
+// the mail server, the client errors of the other channels but for timeouts and rate limits, and the targets
+// which are not public.
+func isPermanentError(err error) bool {
+	var notPublic notPublicError
+	if errors.As(err, &notPublic) {
+		return true
+	}
+
+	var status statusError
+	if errors.As(err, &status) {
+		return status.code >= 400 && status.code < 500 &&
+			status.code != http.StatusRequestTimeout && status.code != http.StatusTooManyRequests
+	}
+
+	var sendErr *mail.SendError
+	if errors.As(err, &sendErr) {
+		err = sendErr.Cause
+	}
+
+	var protocolErr *textproto.Error
+	if errors.As(err, &protocolErr) {
+		return protocolErr.Code >= 500
+	}
+
+	return false
+}
@@ -0,0 +1,168 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mail.v2"
+)
+
+func TestRetrierDo(t *testing.T) {
+	var (
+		customErr    = errors.New("custom error")
+		temporaryErr = fmt.Errorf("wrapped: %w", &mail.SendError{
+			Cause: &textproto.Error{Code: 421, Msg: "service not available"}})
+		permanentErr = fmt.Errorf("wrapped: %w", &mail.SendError{
+			Cause: &textproto.Error{Code: 550, Msg: "mailbox unavailable"}})
+	)
+
+	tests := []struct {
+		name             string
+		errs             []error
+		sleepErr         error
+		expectedAttempts int
+		expected         error
+		expectedSleeps   []time.Duration
+	}{
+		{
+			name:             "first attempt succeeds",
+			errs:             []error{nil},
+			expectedAttempts: 1,
+		},
+		{
+			name:             "temporary error then success",
+			errs:             []error{temporaryErr, nil},
+			expectedAttempts: 2,
+			expectedSleeps:   []time.Duration{time.Second},
+		},
+		{
+			name:             "permanent error is not retried",
+			errs:             []error{permanentErr},
+			expectedAttempts: 1,
+			expected:         permanentErr,
+		},
+		{
+			name:             "retries exhausted",
+			errs:             []error{customErr, customErr, customErr},
+			expectedAttempts: 3,
+			expected:         customErr,
+			expectedSleeps:   []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:             "retries interrupted",
+			errs:             []error{customErr},
+			sleepErr:         context.Canceled,
+			expectedAttempts: 1,
+			expected:         retriesInterruptedError{err: context.Canceled},
+			expectedSleeps:   []time.Duration{time.Second},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				sends  int
+				sleeps []time.Duration
+				r      = retrier{
+					maxAttempts:    3,
+					initialBackoff: time.Second,
+					maxBackoff:     time.Minute,
+					random: func(n int64) int64 {
+						return n - 1
+					},
+					sleep: func(_ context.Context, d time.Duration) error {
+						sleeps = append(sleeps, d)
+						return test.sleepErr
+					},
+				}
+			)
+
+			attempts, err := r.do(context.TODO(), func() error {
+				sends++
+				return test.errs[sends-1]
+			})
+
+			assert.Equal(t, test.expectedAttempts, attempts)
+			assert.Equal(t, test.expectedAttempts, sends)
+			assert.Equal(t, test.expected, err)
+			assert.Equal(t, test.expectedSleeps, sleeps)
+		})
+	}
+}
+
+func TestRetrierBackoff(t *testing.T) {
+	r := retrier{
+		initialBackoff: time.Second,
+		maxBackoff:     5 * time.Second,
+		random: func(n int64) int64 {
+			return 0
+		},
+	}
+
+	assert.Equal(t, 500*time.Millisecond, r.backoff(1))
+	assert.Equal(t, time.Second, r.backoff(2))
+	assert.Equal(t, 2*time.Second, r.backoff(3))
+	assert.Equal(t, 2500*time.Millisecond, r.backoff(4))
+	assert.Equal(t, 2500*time.Millisecond, r.backoff(70))
+}
+
+func TestIsPermanentError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "network error",
+			err:      errors.New("connection refused"),
+			expected: false,
+		},
+		{
+			name:     "temporary reply",
+			err:      &textproto.Error{Code: 451, Msg: "try again later"},
+			expected: false,
+		},
+		{
+			name:     "permanent reply",
+			err:      &textproto.Error{Code: 554, Msg: "transaction failed"},
+			expected: true,
+		},
+		{
+			name: "permanent reply inside send error",
+			err: fmt.Errorf("unexpected error sending mail to user due to: %w",
+				&mail.SendError{Cause: &textproto.Error{Code: 553, Msg: "mailbox name not allowed"}}),
+			expected: true,
+		},
+		{
+			name:     "server error of a channel",
+			err:      fmt.Errorf("wrapped: %w", statusError{channel: ChannelWebhook, code: 503}),
+			expected: false,
+		},
+		{
+			name:     "rate limited by a channel",
+			err:      statusError{channel: ChannelSlack, code: 429},
+			expected: false,
+		},
+		{
+			name:     "client error of a channel",
+			err:      statusError{channel: ChannelSlack, code: 404},
+			expected: true,
+		},
+		{
+			name:     "target not public",
+			err:      fmt.Errorf("wrapped: %w", notPublicError{address: "127.0.0.1"}),
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, isPermanentError(test.err))
+		})
+	}
+}
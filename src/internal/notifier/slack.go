@@ -0,0 +1,37 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// slackChannel posts the subject and the plain text of the message to the incoming webhook url of the
+// preference.
+type slackChannel struct {
+	httpClient *http.Client
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+func (c slackChannel) Send(ctx context.Context, preference Preference, message Message) error {
+	subject := message.Subject
+	if subject == "" {
+		subject = defaultSubject
+	}
+
+	text := "*" + subject + "*"
+	if message.Text != "" {
+		text += "\n" + message.Text
+	}
+
+	payload, err := json.Marshal(slackPayload{Text: text})
+	if err != nil {
+		return fmt.Errorf("error encoding slack payload due to: %w", err)
+	}
+
+	return postJSON(ctx, c.httpClient, ChannelSlack, preference.Target, payload, nil)
+}
@@ -0,0 +1,64 @@
+package notifier
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlackChannelSend(t *testing.T) {
+	tests := []struct {
+		name         string
+		message      Message
+		status       int
+		expectedBody string
+		expected     error
+	}{
+		{
+			name:     "webhook fails",
+			message:  Message{Text: "message"},
+			status:   http.StatusNotFound,
+			expected: statusError{channel: ChannelSlack, code: 404},
+		},
+		{
+			name:         "subject and plain text posted",
+			message:      Message{Subject: "Statement", HTML: "<p>message</p>", Text: "message"},
+			status:       http.StatusOK,
+			expectedBody: `{"text":"*Statement*\nmessage"}`,
+		},
+		{
+			name:         "default subject posted without plain text",
+			message:      Message{HTML: "<p>message</p>"},
+			status:       http.StatusOK,
+			expectedBody: `{"text":"*Transaction resume*"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				if test.expectedBody != "" {
+					assert.Equal(t, test.expectedBody, string(body))
+				}
+
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			var (
+				c          = slackChannel{httpClient: server.Client()}
+				preference = Preference{UserID: 1, Channel: ChannelSlack, Target: server.URL}
+			)
+
+			assert.Equal(t, test.expected, c.Send(context.TODO(), preference, test.message))
+		})
+	}
+}
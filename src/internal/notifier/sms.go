@@ -0,0 +1,35 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// smsChannel sends the plain text of the message to the phone number of the preference through an SMS
+// gateway, authenticated with a bearer token when one is configured.
+type smsChannel struct {
+	httpClient *http.Client
+	url        string
+	token      string
+}
+
+type smsPayload struct {
+	To   string
+	Text string
+}
+
+func (c smsChannel) Send(ctx context.Context, preference Preference, message Message) error {
+	payload, err := json.Marshal(smsPayload{To: preference.Target, Text: message.summary()})
+	if err != nil {
+		return fmt.Errorf("error encoding sms payload due to: %w", err)
+	}
+
+	headers := map[string]string{}
+	if c.token != "" {
+		headers["Authorization"] = "Bearer " + c.token
+	}
+
+	return postJSON(ctx, c.httpClient, ChannelSMS, c.url, payload, headers)
+}
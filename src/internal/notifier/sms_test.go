@@ -0,0 +1,68 @@
+package notifier
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSMSChannelSend(t *testing.T) {
+	tests := []struct {
+		name                  string
+		token                 string
+		message               Message
+		status                int
+		expectedAuthorization string
+		expectedBody          string
+		expected              error
+	}{
+		{
+			name:     "gateway fails",
+			message:  Message{Text: "message"},
+			status:   http.StatusBadGateway,
+			expected: statusError{channel: ChannelSMS, code: 502},
+		},
+		{
+			name:         "plain text sent",
+			message:      Message{HTML: "<p>message</p>", Text: "message"},
+			status:       http.StatusAccepted,
+			expectedBody: `{"To":"+5491100000000","Text":"message"}`,
+		},
+		{
+			name:                  "subject sent without plain text",
+			token:                 "token",
+			message:               Message{HTML: "<p>message</p>", Subject: "Statement"},
+			status:                http.StatusOK,
+			expectedAuthorization: "Bearer token",
+			expectedBody:          `{"To":"+5491100000000","Text":"Statement"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, test.expectedAuthorization, r.Header.Get("Authorization"))
+				if test.expectedBody != "" {
+					assert.Equal(t, test.expectedBody, string(body))
+				}
+
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			var (
+				c          = smsChannel{httpClient: server.Client(), url: server.URL, token: test.token}
+				preference = Preference{UserID: 1, Channel: ChannelSMS, Target: "+5491100000000"}
+			)
+
+			assert.Equal(t, test.expected, c.Send(context.TODO(), preference, test.message))
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Webhook requests are signed with the secret of the preference, so receivers can check they come from us
+// and were not replayed: the signature is the HMAC-SHA256 of the timestamp, a dot and the body.
+const (
+	webhookTimestampHeader = "X-Transaction-Tool-Timestamp"
+	webhookSignatureHeader = "X-Transaction-Tool-Signature"
+)
+
+type webhookChannel struct {
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+type webhookPayload struct {
+	UserID      int64
+	Subject     string
+	Text        string       `json:",omitempty"`
+	HTML        string       `json:",omitempty"`
+	Attachments []Attachment `json:",omitempty"`
+}
+
+func (c webhookChannel) Send(ctx context.Context, preference Preference, message Message) error {
+	subject := message.Subject
+	if subject == "" {
+		subject = defaultSubject
+	}
+
+	payload, err := json.Marshal(webhookPayload{
+		UserID:      preference.UserID,
+		Subject:     subject,
+		Text:        message.Text,
+		HTML:        message.HTML,
+		Attachments: message.Attachments,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding webhook payload due to: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(c.now().Unix(), 10)
+
+	return postJSON(ctx, c.httpClient, ChannelWebhook, preference.Target, payload, map[string]string{
+		webhookTimestampHeader: timestamp,
+		webhookSignatureHeader: "sha256=" + signWebhook(preference.Secret, timestamp, payload),
+	})
+}
+
+func signWebhook(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
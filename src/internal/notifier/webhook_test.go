@@ -0,0 +1,93 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookChannelSend(t *testing.T) {
+	var (
+		now     = time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+		message = Message{
+			To:          []string{"email"},
+			HTML:        "<p>message</p>",
+			Text:        "message",
+			Attachments: []Attachment{{Name: "statement.pdf", ContentType: "application/pdf", Content: []byte("pdf")}},
+		}
+	)
+
+	tests := []struct {
+		name         string
+		status       int
+		expectedBody string
+		expected     error
+	}{
+		{
+			name:     "receiver fails",
+			status:   http.StatusInternalServerError,
+			expected: statusError{channel: ChannelWebhook, code: 500},
+		},
+		{
+			name:   "signed payload received",
+			status: http.StatusNoContent,
+			expectedBody: `{"UserID":1,"Subject":"Transaction resume","Text":"message",` +
+				`"HTML":"\u003cp\u003emessage\u003c/p\u003e","Attachments":[{"Name":"statement.pdf",` +
+				`"ContentType":"application/pdf","Content":"cGRm"}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+
+				// the receiver checks the signature the way the README tells them to
+				mac := hmac.New(sha256.New, []byte("secret"))
+				mac.Write([]byte(r.Header.Get("X-Transaction-Tool-Timestamp") + "."))
+				mac.Write(body)
+
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				assert.Equal(t, "1640995200", r.Header.Get("X-Transaction-Tool-Timestamp"))
+				assert.Equal(
+					t, "sha256="+hex.EncodeToString(mac.Sum(nil)), r.Header.Get("X-Transaction-Tool-Signature"))
+				if test.expectedBody != "" {
+					assert.Equal(t, test.expectedBody, string(body))
+				}
+
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			var (
+				c          = webhookChannel{httpClient: server.Client(), now: func() time.Time { return now }}
+				preference = Preference{UserID: 1, Channel: ChannelWebhook, Target: server.URL, Secret: "secret"}
+			)
+
+			assert.Equal(t, test.expected, c.Send(context.TODO(), preference, message))
+		})
+	}
+}
+
+func TestWebhookChannelSendUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	var (
+		c          = webhookChannel{httpClient: server.Client(), now: time.Now}
+		preference = Preference{UserID: 1, Channel: ChannelWebhook, Target: server.URL, Secret: "secret"}
+	)
+
+	err := c.Send(context.TODO(), preference, Message{HTML: "message"})
+
+	assert.ErrorContains(t, err, "error sending webhook request due to: ")
+}
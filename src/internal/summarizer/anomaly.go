@@ -0,0 +1,265 @@
+package summarizer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"transaction-tool-api/src/internal/money"
+)
+
+type UnusualActivityKind string
+
+const (
+	UnusualTransaction UnusualActivityKind = "transaction"
+	UnusualMonth       UnusualActivityKind = "month"
+	UnusualBalanceDrop UnusualActivityKind = "balance_drop"
+)
+
+const (
+	// anomalyScoreThreshold is the robust z-score above which an amount is unusual, the one proposed by
+	// Iglewicz and Hoaglin for the modified z-score.
+	anomalyScoreThreshold = 3.5
+	// minBaselineTransactions and minBaselineMonths are the amounts the history needs to tell what is usual.
+	minBaselineTransactions = 10
+	minBaselineMonths       = 3
+	// balanceDropPercentage is how much of the balance it started with a day has to lose to be a sudden drop.
+	balanceDropPercentage = 50
+
+	monthLayout = "2006-01"
+)
+
+// UnusualActivity is a transaction or a month of the summary which is unusually large compared to the
+// history of the user, or a day the balance suddenly dropped. It is a first signal of fraud, not a proof.
+// Dates are days, except for months.
+type UnusualActivity struct {
+	Kind        UnusualActivityKind
+	Date        string
+	Description string `json:",omitempty"`
+	Amount      string
+	Currency    string
+	Detail      string
+}
+
+type anomaly struct {
+	UnusualActivity
+	date time.Time
+}
+
+// baseline describes the usual size of some amounts with their median and their median absolute deviation,
+// which, unlike the mean and the standard deviation, are not skewed by the outliers themselves.
+type baseline struct {
+	median    money.Amount
+	deviation float64
+}
+
+// amountStats summarizes the absolute amounts of a currency and direction with what their baseline needs:
+// their count, their median, and the median and the sum of their absolute deviations from it.
+type amountStats struct {
+	currency        string
+	debit           bool
+	count           int
+	median          money.Amount
+	medianDeviation money.Amount
+	totalDeviation  money.Amount
+}
+
+func newAmountStats(amounts []money.Amount) amountStats {
+	stats := amountStats{count: len(amounts)}
+	if len(amounts) == 0 {
+		return stats
+	}
+
+	stats.median = medianAmount(amounts)
+
+	deviations := make([]money.Amount, 0, len(amounts))
+	for _, amount := range amounts {
+		deviations = append(deviations, (amount - stats.median).Abs())
+		stats.totalDeviation += (amount - stats.median).Abs()
+	}
+	stats.medianDeviation = medianAmount(deviations)
+
+	return stats
+}
+
+func newBaseline(amounts []money.Amount, minSize int) (baseline, bool) {
+	return newAmountStats(amounts).baseline(minSize)
+}
+
+// baseline returns the baseline of the amounts, when there are at least the given number of them.
+func (s amountStats) baseline(minSize int) (baseline, bool) {
+	if s.count < minSize || s.count == 0 {
+		return baseline{}, false
+	}
+
+	// the median absolute deviation is scaled to be comparable to a standard deviation
+	deviation := float64(s.medianDeviation) / 0.6745
+	if deviation == 0 {
+		// more than half of the amounts are the same, so the mean absolute deviation is used instead
+		deviation = float64(s.totalDeviation) / float64(s.count) * 1.2533
+	}
+	if deviation == 0 {
+		return baseline{}, false
+	}
+
+	return baseline{median: s.median, deviation: deviation}, true
+}
+
+// score returns the robust z-score of the amount: how many deviations it is above the median.
+func (b baseline) score(amount money.Amount) float64 {
+	return float64(amount-b.median) / b.deviation
+}
+
+func medianAmount(amounts []money.Amount) money.Amount {
+	sorted := append([]money.Amount(nil), amounts...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[middle]
+	}
+	return (sorted[middle-1] + sorted[middle]).DivRound(2, 4)
+}
+
+// anomalyKey groups the amounts of the same currency and direction, so that a large salary does not make
+// a large debit look usual.
+func anomalyKey(currency string, debit bool) string {
+	if debit {
+		return currency + "|debit"
+	}
+	return currency + "|credit"
+}
+
+// anomalyDetector flags the unusual activity of a summary as its transactions are added: the transactions
+// and the months of debits unusually large compared to the history of the user, and the days the balance
+// lost at least half of what it started with. Without enough history, only balance drops are flagged.
+type anomalyDetector struct {
+	currency     string
+	transactions map[string]baseline
+	months       baseline
+	monthsKnown  bool
+	monthDebits  map[time.Time]money.Amount
+	day          time.Time
+	dayStart     money.Amount
+	dayEnd       money.Amount
+	anomalies    []anomaly
+}
+
+func newAnomalyDetector(currency string) *anomalyDetector {
+	return &anomalyDetector{
+		currency:     currency,
+		transactions: make(map[string]baseline),
+		monthDebits:  make(map[time.Time]money.Amount),
+	}
+}
+
+// learn sets the baselines from the stats of the absolute amounts of the history by currency and direction,
+// and from its debits by month in the reporting currency.
+func (d *anomalyDetector) learn(stats []amountStats, monthDebits map[time.Time]money.Amount) {
+	for _, keyStats := range stats {
+		if usual, ok := keyStats.baseline(minBaselineTransactions); ok {
+			d.transactions[anomalyKey(keyStats.currency, keyStats.debit)] = usual
+		}
+	}
+
+	debits := make([]money.Amount, 0, len(monthDebits))
+	for _, amount := range monthDebits {
+		debits = append(debits, amount)
+	}
+	d.months, d.monthsKnown = newBaseline(debits, minBaselineMonths)
+}
+
+// add checks the transaction, whose date is expected in the summary location, given its amount in the
+// reporting currency and the running balance after it.
+func (d *anomalyDetector) add(txn transaction, txnCurrency string, amount money.Amount, balance money.Amount) {
+	day := civilDate(txn.date)
+
+	if usual, ok := d.transactions[anomalyKey(txnCurrency, txn.amount < 0)]; ok && txn.amount != 0 {
+		if score := usual.score(txn.amount.Abs()); score > anomalyScoreThreshold {
+			median := usual.median
+			if txn.amount < 0 {
+				median = -median
+			}
+			d.anomalies = append(d.anomalies, anomaly{
+				UnusualActivity: UnusualActivity{
+					Kind:        UnusualTransaction,
+					Date:        day.Format(balanceDateLayout),
+					Description: txn.description,
+					Amount:      txn.amount.StringFixed(2),
+					Currency:    txnCurrency,
+					Detail: fmt.Sprintf(
+						"unusually large transaction, usually %s (robust z-score %.1f)", median.StringFixed(2), score),
+				},
+				date: day,
+			})
+		}
+	}
+
+	if amount < 0 {
+		d.monthDebits[time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)] -= amount
+	}
+
+	if !day.Equal(d.day) {
+		if drop, ok := d.balanceDrop(); ok {
+			d.anomalies = append(d.anomalies, drop)
+		}
+		d.day = day
+		d.dayStart = balance - amount
+	}
+	d.dayEnd = balance
+}
+
+// balanceDrop returns the drop of the balance in the current day, if it is a sudden one.
+func (d *anomalyDetector) balanceDrop() (anomaly, bool) {
+	if d.day.IsZero() || d.dayStart <= 0 || (d.dayStart-d.dayEnd)*100 < d.dayStart*balanceDropPercentage {
+		return anomaly{}, false
+	}
+
+	return anomaly{
+		UnusualActivity: UnusualActivity{
+			Kind:     UnusualBalanceDrop,
+			Date:     d.day.Format(balanceDateLayout),
+			Amount:   (d.dayEnd - d.dayStart).StringFixed(2),
+			Currency: d.currency,
+			Detail: fmt.Sprintf(
+				"balance fell from %s to %s in a day", d.dayStart.StringFixed(2), d.dayEnd.StringFixed(2)),
+		},
+		date: d.day,
+	}, true
+}
+
+// getAnomalies returns the unusual activity found so far, including the one of the current day and of the
+// months, sorted by date.
+func (d *anomalyDetector) getAnomalies() []anomaly {
+	anomalies := append([]anomaly(nil), d.anomalies...)
+
+	if drop, ok := d.balanceDrop(); ok {
+		anomalies = append(anomalies, drop)
+	}
+
+	if d.monthsKnown {
+		for month, debits := range d.monthDebits {
+			if score := d.months.score(debits); score > anomalyScoreThreshold {
+				anomalies = append(anomalies, anomaly{
+					UnusualActivity: UnusualActivity{
+						Kind:     UnusualMonth,
+						Date:     month.Format(monthLayout),
+						Amount:   (-debits).StringFixed(2),
+						Currency: d.currency,
+						Detail: fmt.Sprintf(
+							"unusually large debits in the month, usually %s (robust z-score %.1f)",
+							(-d.months.median).StringFixed(2), score),
+					},
+					date: month,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		return anomalies[i].date.Before(anomalies[j].date)
+	})
+
+	return anomalies
+}
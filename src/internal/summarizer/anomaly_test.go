@@ -0,0 +1,189 @@
+package summarizer
+
+import (
+	"testing"
+	"time"
+	"transaction-tool-api/src/internal/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizerUnusualActivity(t *testing.T) {
+	var (
+		date    = time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+		history []transaction
+	)
+
+	// a year of salaries and of two debits a month around 50
+	for i, amount := range []int64{-45, -50, -55, -48, -52, -50, -47, -53, -50, -49, -51, -50} {
+		month := date.AddDate(0, i-12, 0)
+		history = append(history,
+			transaction{amount: money.FromInt(1000), currency: "USD", date: month},
+			transaction{amount: money.FromInt(amount), currency: "USD", date: month},
+			transaction{amount: money.FromInt(amount), currency: "USD", date: month.AddDate(0, 0, 5)},
+		)
+	}
+
+	tests := []struct {
+		name     string
+		history  []transaction
+		txns     []transaction
+		expected []UnusualActivity
+	}{
+		{
+			name:    "usual activity",
+			history: history,
+			txns: []transaction{
+				{amount: money.FromInt(1000), date: date},
+				{amount: money.FromInt(-52), date: date},
+				{amount: money.FromInt(-46), date: date.AddDate(0, 0, 3)},
+			},
+		},
+		{
+			name:    "unusual transaction and month",
+			history: history,
+			txns: []transaction{
+				{amount: money.FromInt(1000), date: date},
+				{amount: money.FromInt(-50), date: date},
+				{amount: money.FromInt(-400), date: date.AddDate(0, 0, 3), description: "laptop"},
+			},
+			expected: []UnusualActivity{
+				{
+					Kind:     UnusualMonth,
+					Date:     "2022-01",
+					Amount:   "-450.00",
+					Currency: "USD",
+					Detail:   "unusually large debits in the month, usually -100.00 (robust z-score 78.7)",
+				},
+				{
+					Kind:        UnusualTransaction,
+					Date:        "2022-01-13",
+					Description: "laptop",
+					Amount:      "-400.00",
+					Currency:    "USD",
+					Detail:      "unusually large transaction, usually -50.00 (robust z-score 157.4)",
+				},
+			},
+		},
+		{
+			name: "sudden balance drop",
+			txns: []transaction{
+				{amount: money.FromInt(1000), date: date},
+				{amount: money.FromInt(-50), date: date.AddDate(0, 0, 1)},
+				{amount: money.FromInt(-1000), currency: "EUR", date: date.AddDate(0, 0, 1)},
+			},
+			expected: []UnusualActivity{
+				{
+					Kind:     UnusualBalanceDrop,
+					Date:     "2022-01-11",
+					Amount:   "-1050.00",
+					Currency: "USD",
+					Detail:   "balance fell from 1000.00 to -50.00 in a day",
+				},
+			},
+		},
+		{
+			name: "not enough history",
+			history: []transaction{
+				{amount: money.FromInt(-10), currency: "USD", date: date.AddDate(0, -1, 0)},
+			},
+			txns: []transaction{
+				{amount: money.FromInt(-5000), date: date},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			summ := newSummarizer("USD", newFXRates([]fxRate{
+				{
+					baseCurrency:  "EUR",
+					quoteCurrency: "USD",
+					rate:          money.MustParseRate("1"),
+					effectiveDate: date.AddDate(-2, 0, 0),
+				},
+			}))
+			stats, dailyDebits := aggregateHistory(test.history)
+			require.Nil(t, summ.learnHistory(stats, dailyDebits))
+
+			for _, txn := range test.txns {
+				require.Nil(t, summ.add(txn))
+			}
+
+			assert.Equal(t, test.expected, summ.resume(User{}).UnusualActivity)
+		})
+	}
+}
+
+// aggregateHistory aggregates the history in UTC like the database does for the summary to learn it.
+func aggregateHistory(history []transaction) ([]amountStats, []dailyBalance) {
+	var (
+		amounts = make(map[anomalyStatsKey][]money.Amount)
+		keys    []anomalyStatsKey
+		debits  = make(map[dailyBalanceKey]money.Amount)
+		days    []dailyBalanceKey
+		stats   []amountStats
+		daily   []dailyBalance
+	)
+
+	for _, txn := range history {
+		key := anomalyStatsKey{currency: txn.currency, debit: txn.amount < 0}
+		if _, ok := amounts[key]; !ok {
+			keys = append(keys, key)
+		}
+		amounts[key] = append(amounts[key], txn.amount.Abs())
+
+		if txn.amount < 0 {
+			day := dailyBalanceKey{currency: txn.currency, date: civilDate(txn.date.UTC())}
+			if _, ok := debits[day]; !ok {
+				days = append(days, day)
+			}
+			debits[day] += txn.amount
+		}
+	}
+
+	for _, key := range keys {
+		keyStats := newAmountStats(amounts[key])
+		keyStats.currency, keyStats.debit = key.currency, key.debit
+		stats = append(stats, keyStats)
+	}
+	for _, day := range days {
+		daily = append(daily, dailyBalance{currency: day.currency, date: day.date, amount: debits[day]})
+	}
+
+	return stats, daily
+}
+
+type anomalyStatsKey struct {
+	currency string
+	debit    bool
+}
+
+type dailyBalanceKey struct {
+	currency string
+	date     time.Time
+}
+
+func TestSummarizerYearResumesUnusualActivity(t *testing.T) {
+	var (
+		date = time.Date(2021, time.December, 31, 0, 0, 0, 0, time.UTC)
+		summ = newSummarizer("USD", fxRates{})
+	)
+
+	require.Nil(t, summ.add(transaction{amount: money.FromInt(100), date: date}))
+	require.Nil(t, summ.add(transaction{amount: money.FromInt(-80), date: date.AddDate(0, 0, 1)}))
+
+	resumes := summ.yearResumes(User{})
+	require.Len(t, resumes, 2)
+	assert.Nil(t, resumes[0].UnusualActivity)
+	assert.Equal(t, []UnusualActivity{
+		{
+			Kind:     UnusualBalanceDrop,
+			Date:     "2022-01-01",
+			Amount:   "-80.00",
+			Currency: "USD",
+			Detail:   "balance fell from 100.00 to 20.00 in a day",
+		},
+	}, resumes[1].UnusualActivity)
+}
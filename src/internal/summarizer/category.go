@@ -0,0 +1,180 @@
+package summarizer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"transaction-tool-api/src/internal/money"
+)
+
+type categoryRuleKind string
+
+const (
+	ruleContains    categoryRuleKind = "contains"
+	ruleRegex       categoryRuleKind = "regex"
+	ruleAmountRange categoryRuleKind = "amount_range"
+	ruleMerchant    categoryRuleKind = "merchant"
+
+	maxCategoryLength = 50
+	maxPatternLength  = 255
+
+	// uncategorized names the total of the transactions no rule matched.
+	uncategorized = "Uncategorized"
+)
+
+// CategoryRule assigns its category to the transactions it matches: the ones whose description contains
+// the pattern, matches it as a regular expression or starts with it as a merchant name (ignoring case), or
+// whose amount is within the range. Rules without a user id apply to every user.
+type CategoryRule struct {
+	ID          int64
+	UserID      int64 `json:",omitempty"`
+	Category    string
+	Kind        categoryRuleKind
+	Pattern     string `json:",omitempty"`
+	MinAmount   string `json:",omitempty"`
+	MaxAmount   string `json:",omitempty"`
+	Priority    int
+	DateCreated time.Time
+}
+
+func (r CategoryRule) validate() error {
+	if r.Category == "" || len(r.Category) > maxCategoryLength {
+		return fmt.Errorf("category must have between 1 and %d characters", maxCategoryLength)
+	}
+
+	switch r.Kind {
+	case ruleContains, ruleRegex, ruleMerchant:
+		// patterns are matched without their surrounding spaces, and an empty one would match everything
+		if strings.TrimSpace(r.Pattern) == "" || len(r.Pattern) > maxPatternLength {
+			return fmt.Errorf("pattern of a %s rule must have between 1 and %d characters", r.Kind, maxPatternLength)
+		}
+		if r.Kind == ruleRegex {
+			if _, err := regexp.Compile(r.Pattern); err != nil {
+				return fmt.Errorf("pattern '%s' is not a valid regular expression", r.Pattern)
+			}
+		}
+	case ruleAmountRange:
+		if r.MinAmount == "" && r.MaxAmount == "" {
+			return fmt.Errorf("an %s rule needs a min or a max amount", r.Kind)
+		}
+		minAmount, maxAmount, err := r.amountRange()
+		if err != nil {
+			return err
+		}
+		if minAmount != nil && maxAmount != nil && *minAmount > *maxAmount {
+			return fmt.Errorf("min amount %s is greater than max amount %s", r.MinAmount, r.MaxAmount)
+		}
+	default:
+		return fmt.Errorf(
+			"kind '%s' is not valid, expected %s, %s, %s or %s",
+			r.Kind, ruleContains, ruleRegex, ruleAmountRange, ruleMerchant)
+	}
+
+	return nil
+}
+
+// amountRange returns the bounds of the rule, nil when they are not set.
+func (r CategoryRule) amountRange() (*money.Amount, *money.Amount, error) {
+	parse := func(value string) (*money.Amount, error) {
+		if value == "" {
+			return nil, nil
+		}
+		amount, err := money.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("amount '%s' of category rule is not valid", value)
+		}
+		return &amount, nil
+	}
+
+	minAmount, err := parse(r.MinAmount)
+	if err != nil {
+		return nil, nil, err
+	}
+	maxAmount, err := parse(r.MaxAmount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return minAmount, maxAmount, nil
+}
+
+type compiledRule struct {
+	category  string
+	kind      categoryRuleKind
+	pattern   string
+	regex     *regexp.Regexp
+	minAmount *money.Amount
+	maxAmount *money.Amount
+}
+
+func (r compiledRule) matches(txn transaction) bool {
+	description := strings.ToLower(strings.TrimSpace(txn.description))
+
+	switch r.kind {
+	case ruleContains:
+		return strings.Contains(description, r.pattern)
+	case ruleRegex:
+		return r.regex.MatchString(txn.description)
+	case ruleMerchant:
+		return strings.HasPrefix(description, r.pattern)
+	case ruleAmountRange:
+		return (r.minAmount == nil || txn.amount >= *r.minAmount) &&
+			(r.maxAmount == nil || txn.amount <= *r.maxAmount)
+	}
+
+	return false
+}
+
+// categorizer assigns to each transaction the category of the first rule it matches, so rules are
+// expected in order of precedence.
+type categorizer struct {
+	rules []compiledRule
+}
+
+func newCategorizer(rules []CategoryRule) (categorizer, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+
+	for _, rule := range rules {
+		matcher := compiledRule{
+			category: rule.Category,
+			kind:     rule.Kind,
+			pattern:  strings.ToLower(strings.TrimSpace(rule.Pattern)),
+		}
+
+		var err error
+		switch rule.Kind {
+		case ruleRegex:
+			matcher.regex, err = regexp.Compile(rule.Pattern)
+		case ruleAmountRange:
+			matcher.minAmount, matcher.maxAmount, err = rule.amountRange()
+		}
+		if err != nil {
+			return categorizer{}, fmt.Errorf("error compiling category rule id %d due to: %w", rule.ID, err)
+		}
+
+		compiled = append(compiled, matcher)
+	}
+
+	return categorizer{rules: compiled}, nil
+}
+
+// categorize returns the category of the transaction, empty when no rule matches it.
+func (c categorizer) categorize(txn transaction) string {
+	for _, rule := range c.rules {
+		if rule.matches(txn) {
+			return rule.category
+		}
+	}
+	return ""
+}
+
+// storedTransaction is a transaction already saved, with its current category.
+type storedTransaction struct {
+	id  int64
+	txn transaction
+}
+
+type RecategorizeResult struct {
+	UpdatedTransactions int
+}
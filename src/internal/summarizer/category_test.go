@@ -0,0 +1,122 @@
+package summarizer
+
+import (
+	"errors"
+	"testing"
+	"transaction-tool-api/src/internal/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategoryRuleValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     CategoryRule
+		expected error
+	}{
+		{
+			name:     "missing category",
+			rule:     CategoryRule{Kind: ruleContains, Pattern: "coffee"},
+			expected: errors.New("category must have between 1 and 50 characters"),
+		},
+		{
+			name:     "kind not valid",
+			rule:     CategoryRule{Category: "Food", Kind: "exact"},
+			expected: errors.New("kind 'exact' is not valid, expected contains, regex, amount_range or merchant"),
+		},
+		{
+			name:     "missing pattern",
+			rule:     CategoryRule{Category: "Food", Kind: ruleMerchant},
+			expected: errors.New("pattern of a merchant rule must have between 1 and 255 characters"),
+		},
+		{
+			name:     "pattern of spaces",
+			rule:     CategoryRule{Category: "Food", Kind: ruleContains, Pattern: "  \t "},
+			expected: errors.New("pattern of a contains rule must have between 1 and 255 characters"),
+		},
+		{
+			name:     "regular expression not valid",
+			rule:     CategoryRule{Category: "Food", Kind: ruleRegex, Pattern: "coffee("},
+			expected: errors.New("pattern 'coffee(' is not a valid regular expression"),
+		},
+		{
+			name:     "missing amount range",
+			rule:     CategoryRule{Category: "Rent", Kind: ruleAmountRange},
+			expected: errors.New("an amount_range rule needs a min or a max amount"),
+		},
+		{
+			name:     "amount not valid",
+			rule:     CategoryRule{Category: "Rent", Kind: ruleAmountRange, MinAmount: "ten"},
+			expected: errors.New("amount 'ten' of category rule is not valid"),
+		},
+		{
+			name:     "amount range inverted",
+			rule:     CategoryRule{Category: "Rent", Kind: ruleAmountRange, MinAmount: "-10", MaxAmount: "-20"},
+			expected: errors.New("min amount -10 is greater than max amount -20"),
+		},
+		{
+			name: "valid rule",
+			rule: CategoryRule{Category: "Rent", Kind: ruleAmountRange, MaxAmount: "-1000"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.rule.validate())
+		})
+	}
+}
+
+func TestCategorizerCategorize(t *testing.T) {
+	categories, err := newCategorizer([]CategoryRule{
+		{Category: "Coffee", Kind: ruleRegex, Pattern: `(?i)^starbucks #\d+`},
+		{Category: "Groceries", Kind: ruleMerchant, Pattern: "Walmart"},
+		{Category: "Food", Kind: ruleContains, Pattern: "  COFFEE "},
+		{Category: "Rent", Kind: ruleAmountRange, MinAmount: "-2000", MaxAmount: "-1000"},
+	})
+	require.Nil(t, err)
+
+	tests := []struct {
+		name     string
+		txn      transaction
+		expected string
+	}{
+		{
+			name:     "regular expression",
+			txn:      transaction{amount: money.FromInt(-5), description: "STARBUCKS #123 coffee"},
+			expected: "Coffee",
+		},
+		{
+			name:     "merchant",
+			txn:      transaction{amount: money.FromInt(-50), description: " walmart supercenter"},
+			expected: "Groceries",
+		},
+		{
+			name:     "merchant not at the start",
+			txn:      transaction{amount: money.FromInt(-50), description: "refund walmart"},
+			expected: "",
+		},
+		{
+			name:     "contains",
+			txn:      transaction{amount: money.FromInt(-3), description: "Corner Coffee Shop"},
+			expected: "Food",
+		},
+		{
+			name:     "amount range",
+			txn:      transaction{amount: money.FromInt(-1000)},
+			expected: "Rent",
+		},
+		{
+			name:     "no rule matches",
+			txn:      transaction{amount: money.FromInt(-999), description: "gym"},
+			expected: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, categories.categorize(test.txn))
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package summarizer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strings"
+	"transaction-tool-api/src/internal/money"
+	"transaction-tool-api/src/internal/notifier"
+)
+
+// Charts are drawn as png images, since mail readers strip inline svg, in a plot area surrounded by a margin.
+// The images carry no text: their title is written under them and their figures go in the alt text.
+const (
+	chartWidth       = 600
+	chartHeight      = 240
+	chartMargin      = 10
+	chartContentType = "image/png"
+)
+
+var (
+	creditColor  = color.RGBA{R: 0x2e, G: 0x7d, B: 0x32, A: 0xff}
+	debitColor   = color.RGBA{R: 0xc6, G: 0x28, B: 0x28, A: 0xff}
+	balanceColor = color.RGBA{R: 0x15, G: 0x65, B: 0xc0, A: 0xff}
+	axisColor    = color.RGBA{R: 0x9e, G: 0x9e, B: 0x9e, A: 0xff}
+)
+
+// chart is a drawn chart, with the title written under it and the alt text read by the mail readers which
+// do not show it.
+type chart struct {
+	title   string
+	alt     string
+	content []byte
+}
+
+// chartImages writes the charts of the resume templates. Previews, opened in a browser, carry them as data
+// urls, while emails reference them by content id and carry them as inline attachments, since mail readers
+// block data urls too.
+type chartImages struct {
+	attach      bool
+	attachments []notifier.Attachment
+}
+
+// funcs returns the functions the resume templates draw their charts with.
+func (c *chartImages) funcs() template.FuncMap {
+	return template.FuncMap{
+		"monthsChart":  c.image(monthsChart),
+		"balanceChart": c.image(balanceChart),
+	}
+}
+
+// image returns a template function writing the chart drawn by draw as an image, empty when there is nothing
+// to draw.
+func (c *chartImages) image(
+	draw func([]MonthTransaction) (*chart, error)) func([]MonthTransaction) (template.HTML, error) {
+	return func(months []MonthTransaction) (template.HTML, error) {
+		drawn, err := draw(months)
+		if err != nil || drawn == nil {
+			return "", err
+		}
+
+		src := "data:" + chartContentType + ";base64," + base64.StdEncoding.EncodeToString(drawn.content)
+		if c.attach {
+			number := len(c.attachments) + 1
+			contentID := fmt.Sprintf("chart-%d@transaction-tool", number)
+			c.attachments = append(c.attachments, notifier.Attachment{
+				Name:        fmt.Sprintf("chart-%d.png", number),
+				ContentType: chartContentType,
+				Content:     drawn.content,
+				ContentID:   contentID,
+				Inline:      true,
+			})
+			src = "cid:" + contentID
+		}
+
+		return template.HTML(fmt.Sprintf(
+			`<img src="%s" width="%d" height="%d" alt="%s"><br>%s`,
+			src, chartWidth, chartHeight, template.HTMLEscapeString(drawn.alt),
+			template.HTMLEscapeString(drawn.title))), nil
+	}
+}
+
+// monthsChart draws the credits and debits of each month as a pair of bars, nil when there is nothing to draw.
+func monthsChart(months []MonthTransaction) (*chart, error) {
+	var (
+		credits = make([]float64, 0, len(months))
+		debits  = make([]float64, 0, len(months))
+		figures = make([]string, 0, len(months))
+		largest money.Amount
+	)
+
+	for _, month := range months {
+		credit, err := money.Parse(month.Credits)
+		if err != nil {
+			return nil, err
+		}
+		debit, err := money.Parse(month.Debits)
+		if err != nil {
+			return nil, err
+		}
+
+		if credit.Abs() > largest {
+			largest = credit.Abs()
+		}
+		if debit.Abs() > largest {
+			largest = debit.Abs()
+		}
+		credits = append(credits, float64(credit.Abs()))
+		debits = append(debits, float64(debit.Abs()))
+		figures = append(figures, fmt.Sprintf("%s credits %s, debits %s", month.Month, month.Credits, month.Debits))
+	}
+
+	if largest == 0 {
+		return nil, nil
+	}
+
+	var (
+		img        = newChartImage()
+		plotWidth  = float64(chartWidth - 2*chartMargin)
+		plotHeight = float64(chartHeight - 2*chartMargin)
+		baseline   = chartHeight - chartMargin
+		slot       = plotWidth / float64(len(months))
+	)
+
+	for i := range months {
+		x := float64(chartMargin) + float64(i)*slot
+		for j, value := range []float64{credits[i], debits[i]} {
+			height := round(value / float64(largest) * plotHeight)
+			left := round(x + slot*(0.1+0.45*float64(j)))
+			fill(img, image.Rect(left, baseline-height, left+round(slot*0.35), baseline),
+				[]color.RGBA{creditColor, debitColor}[j])
+		}
+	}
+	fill(img, image.Rect(chartMargin, baseline, chartWidth-chartMargin, baseline+1), axisColor)
+
+	return encodeChart(
+		img, "Credits (green) and debits (red) by month",
+		"Credits and debits by month: "+strings.Join(figures, "; ")+".")
+}
+
+// balanceChart draws the balance at the end of each month as a line, starting from the one before the first
+// month, nil when there are no months.
+func balanceChart(months []MonthTransaction) (*chart, error) {
+	if len(months) == 0 {
+		return nil, nil
+	}
+
+	end, err := money.Parse(months[0].EndBalance)
+	if err != nil {
+		return nil, err
+	}
+	netChange, err := money.Parse(months[0].NetChange)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		balances = []money.Amount{end - netChange}
+		lowest   = end - netChange
+		highest  = end - netChange
+		figures  = []string{"starting at " + (end - netChange).StringFixed(2)}
+	)
+
+	for _, month := range months {
+		balance, err := money.Parse(month.EndBalance)
+		if err != nil {
+			return nil, err
+		}
+
+		if balance < lowest {
+			lowest = balance
+		}
+		if balance > highest {
+			highest = balance
+		}
+		balances = append(balances, balance)
+		figures = append(figures, month.Month+" "+month.EndBalance)
+	}
+
+	var (
+		img        = newChartImage()
+		plotWidth  = float64(chartWidth - 2*chartMargin)
+		plotHeight = float64(chartHeight - 2*chartMargin)
+		step       = plotWidth / float64(len(months))
+	)
+
+	// a flat balance is drawn in the middle of the plot area
+	span := float64(highest - lowest)
+	top := float64(highest)
+	if span == 0 {
+		span, top = 2, top+1
+	}
+
+	y := func(balance money.Amount) int {
+		return chartMargin + round((top-float64(balance))/span*plotHeight)
+	}
+
+	if lowest < 0 && highest > 0 {
+		for x := chartMargin; x < chartWidth-chartMargin; x += 8 {
+			fill(img, image.Rect(x, y(0), x+4, y(0)+1), axisColor)
+		}
+	}
+
+	for i := 1; i < len(balances); i++ {
+		drawLine(
+			img, chartMargin+round(float64(i-1)*step), y(balances[i-1]), chartMargin+round(float64(i)*step),
+			y(balances[i]), balanceColor)
+	}
+
+	return encodeChart(
+		img, "Balance (blue) at the end of each month",
+		"Balance at the end of each month: "+strings.Join(figures, "; ")+".")
+}
+
+func newChartImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	return img
+}
+
+func fill(img *image.RGBA, rect image.Rectangle, c color.RGBA) {
+	draw.Draw(img, rect, &image.Uniform{C: c}, image.Point{}, draw.Src)
+}
+
+// drawLine draws a line two pixels wide from (x0, y0) to (x1, y1).
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	steps := math.Max(math.Abs(float64(x1-x0)), math.Abs(float64(y1-y0)))
+	if steps == 0 {
+		steps = 1
+	}
+
+	for i := 0.0; i <= steps; i++ {
+		x := x0 + round(float64(x1-x0)*i/steps)
+		y := y0 + round(float64(y1-y0)*i/steps)
+		fill(img, image.Rect(x, y, x+2, y+2), c)
+	}
+}
+
+func encodeChart(img image.Image, title string, alt string) (*chart, error) {
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, img); err != nil {
+		return nil, fmt.Errorf("error encoding chart due to: %w", err)
+	}
+	return &chart{title: title, alt: alt, content: buffer.Bytes()}, nil
+}
+
+func round(value float64) int {
+	return int(math.Round(value))
+}
@@ -0,0 +1,194 @@
+package summarizer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html/template"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"transaction-tool-api/src/internal/notifier"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonthsChart(t *testing.T) {
+	tests := []struct {
+		name          string
+		months        []MonthTransaction
+		expectedTitle string
+		expectedAlt   string
+		expectedColor map[image.Point]color.RGBA
+		expectedErr   bool
+	}{
+		{
+			name: "no months",
+		},
+		{
+			name:   "no credits nor debits",
+			months: []MonthTransaction{{Month: "January", Credits: "0.00", Debits: "0.00"}},
+		},
+		{
+			name:        "invalid amount",
+			months:      []MonthTransaction{{Month: "January", Credits: "a lot", Debits: "0.00"}},
+			expectedErr: true,
+		},
+		{
+			name: "credits and debits by month",
+			months: []MonthTransaction{
+				{Month: "January", Credits: "100.00", Debits: "-50.00"},
+				{Month: "February", Credits: "0.00", Debits: "-100.00"},
+			},
+			expectedTitle: "Credits (green) and debits (red) by month",
+			expectedAlt: "Credits and debits by month: January credits 100.00, debits -50.00; " +
+				"February credits 0.00, debits -100.00.",
+			expectedColor: map[image.Point]color.RGBA{
+				{X: 80, Y: 20}:   creditColor,
+				{X: 200, Y: 20}:  {R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+				{X: 200, Y: 150}: debitColor,
+				{X: 340, Y: 200}: {R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+				{X: 470, Y: 20}:  debitColor,
+				{X: 300, Y: 230}: axisColor,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			drawn, err := monthsChart(test.months)
+			assert.Equal(t, test.expectedErr, err != nil)
+			assertChart(t, drawn, test.expectedTitle, test.expectedAlt, test.expectedColor)
+		})
+	}
+}
+
+func TestBalanceChart(t *testing.T) {
+	tests := []struct {
+		name          string
+		months        []MonthTransaction
+		expectedTitle string
+		expectedAlt   string
+		expectedColor map[image.Point]color.RGBA
+		expectedErr   bool
+	}{
+		{
+			name: "no months",
+		},
+		{
+			name:        "invalid amount",
+			months:      []MonthTransaction{{Month: "January", NetChange: "1.00", EndBalance: "a lot"}},
+			expectedErr: true,
+		},
+		{
+			name:          "flat balance",
+			months:        []MonthTransaction{{Month: "January", NetChange: "0.00", EndBalance: "10.00"}},
+			expectedTitle: "Balance (blue) at the end of each month",
+			expectedAlt:   "Balance at the end of each month: starting at 10.00; January 10.00.",
+			expectedColor: map[image.Point]color.RGBA{
+				{X: 10, Y: 120}:  balanceColor,
+				{X: 300, Y: 120}: balanceColor,
+				{X: 300, Y: 100}: {R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+			},
+		},
+		{
+			name: "balance crossing zero",
+			months: []MonthTransaction{
+				{Month: "January", NetChange: "50.00", EndBalance: "50.00"},
+				{Month: "February", NetChange: "-100.00", EndBalance: "-50.00"},
+			},
+			expectedTitle: "Balance (blue) at the end of each month",
+			expectedAlt:   "Balance at the end of each month: starting at 0.00; January 50.00; February -50.00.",
+			expectedColor: map[image.Point]color.RGBA{
+				{X: 10, Y: 120}:  balanceColor,
+				{X: 300, Y: 10}:  balanceColor,
+				{X: 590, Y: 230}: balanceColor,
+				{X: 445, Y: 120}: balanceColor,
+				{X: 402, Y: 120}: axisColor,
+				{X: 406, Y: 120}: {R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			drawn, err := balanceChart(test.months)
+			assert.Equal(t, test.expectedErr, err != nil)
+			assertChart(t, drawn, test.expectedTitle, test.expectedAlt, test.expectedColor)
+		})
+	}
+}
+
+func TestChartImages(t *testing.T) {
+	months := []MonthTransaction{{Month: "January", Credits: "1.00", Debits: "0.00"}}
+	drawn, err := monthsChart(months)
+	require.Nil(t, err)
+
+	tests := []struct {
+		name                string
+		attach              bool
+		expected            template.HTML
+		expectedAttachments []notifier.Attachment
+	}{
+		{
+			name: "preview with data urls",
+			expected: template.HTML(`<img src="data:image/png;base64,` +
+				base64.StdEncoding.EncodeToString(drawn.content) + `" width="600" height="240" ` +
+				`alt="Credits and debits by month: January credits 1.00, debits 0.00."><br>` +
+				`Credits (green) and debits (red) by month`),
+		},
+		{
+			name:   "email with inline attachments",
+			attach: true,
+			expected: `<img src="cid:chart-1@transaction-tool" width="600" height="240" ` +
+				`alt="Credits and debits by month: January credits 1.00, debits 0.00."><br>` +
+				`Credits (green) and debits (red) by month`,
+			expectedAttachments: []notifier.Attachment{
+				{
+					Name:        "chart-1.png",
+					ContentType: "image/png",
+					Content:     drawn.content,
+					ContentID:   "chart-1@transaction-tool",
+					Inline:      true,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			images := chartImages{attach: test.attach}
+
+			html, err := images.image(monthsChart)(months)
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, html)
+			assert.Equal(t, test.expectedAttachments, images.attachments)
+
+			// nothing to draw writes no image
+			html, err = images.image(balanceChart)(nil)
+			assert.Nil(t, err)
+			assert.Empty(t, html)
+			assert.Equal(t, test.expectedAttachments, images.attachments)
+		})
+	}
+}
+
+func assertChart(t *testing.T, drawn *chart, title string, alt string, colors map[image.Point]color.RGBA) {
+	t.Helper()
+
+	if colors == nil {
+		assert.Nil(t, drawn)
+		return
+	}
+	require.NotNil(t, drawn)
+	assert.Equal(t, title, drawn.title)
+	assert.Equal(t, alt, drawn.alt)
+
+	img, err := png.Decode(bytes.NewReader(drawn.content))
+	require.Nil(t, err)
+	assert.Equal(t, image.Rect(0, 0, chartWidth, chartHeight), img.Bounds())
+	for point, expected := range colors {
+		assert.Equal(t, expected, color.RGBAModel.Convert(img.At(point.X, point.Y)), "pixel %v", point)
+	}
+}
@@ -0,0 +1,161 @@
+package summarizer
+
+import (
+	"math/big"
+	"sort"
+	"time"
+	"transaction-tool-api/src/internal/money"
+)
+
+// Comparison compares a resume with the same period of the previous year, as saved for the user. Deltas are
+// the changes from the previous period, and their percentages are relative to the size of the previous
+// values, being empty when those are zero. The net change of a period does not include any opening balance.
+type Comparison struct {
+	PreviousFrom      string
+	PreviousTo        string
+	NetChange         ComparedAmount
+	CreditAvg         ComparedAmount
+	DebitAvg          ComparedAmount
+	MonthTransactions []ComparedMonth
+}
+
+type ComparedAmount struct {
+	Current         string
+	Previous        string
+	Delta           string
+	DeltaPercentage string `json:",omitempty"`
+}
+
+// ComparedMonth compares the number of transactions of a month.
+type ComparedMonth struct {
+	Month           string
+	Current         int
+	Previous        int
+	Delta           int
+	DeltaPercentage string `json:",omitempty"`
+}
+
+// periodTotals aggregates the transactions saved for a period, in the reporting currency.
+type periodTotals struct {
+	from        time.Time
+	to          time.Time
+	count       int
+	credits     money.Amount
+	creditCount int64
+	debits      money.Amount
+	debitCount  int64
+	months      map[time.Month]int
+}
+
+// previousPeriod returns the same period of the summary in the previous year, from the midnight of its first
+// day until the midnight after its last one in the summary location.
+func (s *summarizer) previousPeriod() (time.Time, time.Time) {
+	first, last := s.firstDate.AddDate(-1, 0, 0), s.lastDate.AddDate(-1, 0, 0)
+	return time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, s.location),
+		time.Date(last.Year(), last.Month(), last.Day()+1, 0, 0, 0, 0, s.location)
+}
+
+// setPreviousPeriod sets the totals of the period the summary is compared with, converting them to the
+// reporting currency with the rates effective on their day.
+func (s *summarizer) setPreviousPeriod(from, to time.Time, totals []dailyTotal, rates fxRates) error {
+	previous := &periodTotals{from: from, to: to, months: make(map[time.Month]int)}
+
+	for _, daily := range totals {
+		credits, err := rates.convert(daily.credits, daily.currency, s.currency, daily.date)
+		if err != nil {
+			return err
+		}
+		debits, err := rates.convert(daily.debits, daily.currency, s.currency, daily.date)
+		if err != nil {
+			return err
+		}
+
+		previous.count += daily.count
+		previous.credits += credits
+		previous.creditCount += daily.creditCount
+		previous.debits += debits
+		previous.debitCount += daily.debitCount
+		previous.months[daily.date.Month()] += daily.count
+	}
+
+	s.previous = previous
+
+	return nil
+}
+
+// getComparison compares the summary with the previous period, nil when nothing was saved in it.
+func (s *summarizer) getComparison() *Comparison {
+	if s.previous == nil || s.previous.count == 0 {
+		return nil
+	}
+
+	var (
+		previous          = s.previous
+		previousCreditAvg = previous.credits.DivRound(previous.creditCount, averagePlaces)
+		previousDebitAvg  = previous.debits.DivRound(previous.debitCount, averagePlaces)
+		months            []time.Month
+	)
+
+	for month := range s.months {
+		months = append(months, month)
+	}
+	for month := range previous.months {
+		if _, ok := s.months[month]; !ok {
+			months = append(months, month)
+		}
+	}
+
+	// months are listed from the start of the fiscal year
+	sort.Slice(months, func(i, j int) bool {
+		return (months[i]+12-s.fiscalYearStart)%12 < (months[j]+12-s.fiscalYearStart)%12
+	})
+
+	comparison := &Comparison{
+		PreviousFrom: previous.from.Format(balanceDateLayout),
+		PreviousTo:   previous.to.AddDate(0, 0, -1).Format(balanceDateLayout),
+		NetChange:    compareAmounts(s.balance, previous.credits+previous.debits, 2),
+		CreditAvg:    compareAmounts(s.getCreditAvg(), previousCreditAvg, averagePlaces),
+		DebitAvg:     compareAmounts(s.getDebitAvg(), previousDebitAvg, averagePlaces),
+	}
+
+	for _, month := range months {
+		var current int
+		if summary, ok := s.months[month]; ok {
+			current = summary.count
+		}
+
+		comparedMonth := ComparedMonth{
+			Month:    month.String(),
+			Current:  current,
+			Previous: previous.months[month],
+			Delta:    current - previous.months[month],
+		}
+		if comparedMonth.Previous != 0 {
+			comparedMonth.DeltaPercentage = percentage(int64(comparedMonth.Delta), int64(comparedMonth.Previous))
+		}
+		comparison.MonthTransactions = append(comparison.MonthTransactions, comparedMonth)
+	}
+
+	return comparison
+}
+
+func compareAmounts(current, previous money.Amount, places int) ComparedAmount {
+	compared := ComparedAmount{
+		Current:  current.StringFixed(places),
+		Previous: previous.StringFixed(places),
+		Delta:    (current - previous).StringFixed(places),
+	}
+	if previous != 0 {
+		compared.DeltaPercentage = percentage(int64(current-previous), int64(previous))
+	}
+	return compared
+}
+
+// percentage returns the delta as a percentage of the size of the previous value, rounded to two decimal
+// places half away from zero.
+func percentage(delta, previous int64) string {
+	if previous < 0 {
+		previous = -previous
+	}
+	return new(big.Rat).SetFrac(big.NewInt(delta*100), big.NewInt(previous)).FloatString(2)
+}
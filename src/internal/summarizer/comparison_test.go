@@ -0,0 +1,121 @@
+package summarizer
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"transaction-tool-api/src/internal/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizerPreviousPeriod(t *testing.T) {
+	var (
+		location = time.FixedZone("UTC-3", -3*60*60)
+		summ     = newSummarizer("USD", fxRates{})
+	)
+
+	summ.location = location
+	require.Nil(t, summ.add(transaction{amount: money.FromInt(10), date: time.Date(2022, 3, 31, 2, 0, 0, 0, time.UTC)}))
+	require.Nil(t, summ.add(transaction{amount: money.FromInt(-5), date: time.Date(2022, 2, 1, 12, 0, 0, 0, time.UTC)}))
+
+	from, to := summ.previousPeriod()
+
+	assert.Equal(t, time.Date(2021, 2, 1, 0, 0, 0, 0, location), from)
+	assert.Equal(t, time.Date(2021, 3, 31, 0, 0, 0, 0, location), to)
+}
+
+func TestSummarizerComparison(t *testing.T) {
+	var (
+		date  = time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC)
+		from  = date.AddDate(-1, 0, 0)
+		to    = date.AddDate(-1, 1, 0)
+		rates = newFXRates([]fxRate{
+			{
+				baseCurrency:  "USD",
+				quoteCurrency: "MXN",
+				rate:          money.MustParseRate("20"),
+				effectiveDate: from,
+			},
+		})
+		txns = transactions{
+			items: []transaction{
+				{amount: money.FromInt(100), date: date},
+				{amount: money.FromInt(-30), date: date},
+				{amount: money.FromInt(-20), date: date.AddDate(0, 0, 27)},
+			},
+		}
+	)
+
+	tests := []struct {
+		name        string
+		totals      []dailyTotal
+		expected    *Comparison
+		expectedErr error
+	}{
+		{
+			name: "nothing saved in the previous period",
+		},
+		{
+			name: "missing fx rate",
+			totals: []dailyTotal{
+				{currency: "EUR", date: from, count: 1, credits: money.FromInt(10), creditCount: 1},
+			},
+			expectedErr: errors.New("there is no fx rate from EUR to USD effective on 2021-03-01"),
+		},
+		{
+			name: "compared with the previous period",
+			totals: []dailyTotal{
+				{
+					currency:    "USD",
+					date:        from,
+					count:       3,
+					credits:     money.FromInt(80),
+					creditCount: 1,
+					debits:      money.FromInt(-60),
+					debitCount:  2,
+				},
+				{currency: "MXN", date: from.AddDate(0, 0, 10), count: 1, debits: money.FromInt(-200), debitCount: 1},
+				{currency: "USD", date: from.AddDate(0, 1, 0), count: 1, credits: money.FromInt(5), creditCount: 1},
+			},
+			expected: &Comparison{
+				PreviousFrom: "2021-03-01",
+				PreviousTo:   "2021-03-31",
+				NetChange: ComparedAmount{
+					Current:         "50.00",
+					Previous:        "15.00",
+					Delta:           "35.00",
+					DeltaPercentage: "233.33",
+				},
+				CreditAvg: ComparedAmount{
+					Current:         "100.00",
+					Previous:        "42.50",
+					Delta:           "57.50",
+					DeltaPercentage: "135.29",
+				},
+				DebitAvg: ComparedAmount{
+					Current:         "-25.00",
+					Previous:        "-23.33",
+					Delta:           "-1.67",
+					DeltaPercentage: "-7.16",
+				},
+				MonthTransactions: []ComparedMonth{
+					{Month: "March", Current: 3, Previous: 4, Delta: -1, DeltaPercentage: "-25.00"},
+					{Month: "April", Current: 0, Previous: 1, Delta: -1, DeltaPercentage: "-100.00"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			summ := summarize(t, txns)
+
+			err := summ.setPreviousPeriod(from, to, test.totals, rates)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, summ.getComparison())
+		})
+	}
+}
@@ -1,31 +1,54 @@
 package summarizer
 
 import (
+	"database/sql"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"transaction-tool-api/src/internal/money"
 
 	"github.com/gin-gonic/gin"
 )
 
-func NewController(service Service) Controller {
-	return controller{service: service}
+func NewController(service Service, worker Worker) Controller {
+	return controller{service: service, worker: worker}
 }
 
 type Controller interface {
 	ResumeTransactions(c *gin.Context)
+	PreviewResume(c *gin.Context)
+	GetJob(c *gin.Context)
+	LoadFXRates(c *gin.Context)
+	SaveImportProfile(c *gin.Context)
+	GetImportProfile(c *gin.Context)
+	GetImportBatches(c *gin.Context)
+	GetImportBatchTransactions(c *gin.Context)
+	RollbackImportBatch(c *gin.Context)
+	CreateCategoryRule(c *gin.Context)
+	GetCategoryRules(c *gin.Context)
+	DeleteCategoryRule(c *gin.Context)
+	RecategorizeTransactions(c *gin.Context)
+	GetForecast(c *gin.Context)
+	GetResumePDF(c *gin.Context)
 }
 
 type controller struct {
+	parser  parser
 	service Service
+	worker  Worker
 }
 
 type Error struct {
 	Status  int
 	Code    string
 	Message string
+	Errors  []RowError `json:",omitempty"`
 }
 
 func badRequestError(message string) Error {
@@ -36,21 +59,318 @@ func badRequestError(message string) Error {
 	}
 }
 
+func rowValidationError(rowErrs rowErrors) Error {
+	return Error{
+		Status:  http.StatusBadRequest,
+		Code:    "bad request",
+		Message: rowErrs.Error(),
+		Errors:  rowErrs.items,
+	}
+}
+
+func notFoundError(message string) Error {
+	return Error{
+		Status:  http.StatusNotFound,
+		Code:    "not found",
+		Message: message,
+	}
+}
+
+func conflictError(message string) Error {
+	return Error{
+		Status:  http.StatusConflict,
+		Code:    "conflict",
+		Message: message,
+	}
+}
+
+func internalError(message string) Error {
+	return Error{
+		Status:  http.StatusInternalServerError,
+		Code:    "internal error",
+		Message: message,
+	}
+}
+
 func (ctl controller) ResumeTransactions(c *gin.Context) {
+	userID, ok := ctl.getUserID(c)
+	if !ok {
+		return
+	}
+
+	options, ok := ctl.getResumeOptions(c)
+	if !ok {
+		return
+	}
+
+	if c.Query("async") == "true" {
+		ctl.enqueueResume(c, userID, options)
+		return
+	}
+
+	reader := ctl.parser.newUploadReader(c.Request.Body, userID, options)
+
+	result, err := ctl.service.notifyResume(c.Request.Context(), reader, options)
+	if err != nil {
+		ctl.resumeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+type ResumePreview struct {
+	Resume Resume
+	HTML   string `json:",omitempty"`
+}
+
+func (ctl controller) PreviewResume(c *gin.Context) {
+	userID, ok := ctl.getUserID(c)
+	if !ok {
+		return
+	}
+
+	options, ok := ctl.getResumeOptions(c)
+	if !ok {
+		return
+	}
+
+	reader := ctl.parser.newUploadReader(c.Request.Body, userID, options)
+
+	resume, err := ctl.service.previewResume(c.Request.Context(), reader, options)
+	if err != nil {
+		ctl.resumeError(c, err)
+		return
+	}
+
+	preview := ResumePreview{Resume: resume}
+
+	if c.Query("html") == "true" {
+		if preview.HTML, err = resume.ToHTML(resumeHTMLTemplate); err != nil {
+			c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+func (ctl controller) getUserID(c *gin.Context) (int64, bool) {
 	userIDStr := c.Param("user_id")
 	if userIDStr == "" {
 		c.JSON(http.StatusBadRequest, badRequestError("missing user id param"))
-		return
+		return 0, false
 	}
 
 	userID, err := strconv.ParseInt(userIDStr, 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, badRequestError(fmt.Sprintf("user id '%s' is not an integer", userIDStr)))
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// resumeError responds with a client error when the error was caused by the uploaded file.
+func (ctl controller) resumeError(c *gin.Context, err error) {
+	var (
+		rowErrs      rowErrors
+		invalidErr   invalidCSVError
+		duplicateErr duplicateTransactionError
+		savedErr     transactionsSavedError
+	)
+
+	switch {
+	case errors.As(err, &duplicateErr), errors.As(err, &savedErr):
+		c.JSON(http.StatusConflict, conflictError(err.Error()))
+	case errors.As(err, &rowErrs):
+		c.JSON(http.StatusBadRequest, rowValidationError(rowErrs))
+	case errors.As(err, &invalidErr):
+		c.JSON(http.StatusBadRequest, badRequestError(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+	}
+}
+
+func (ctl controller) getResumeOptions(c *gin.Context) (resumeOptions, bool) {
+	var options resumeOptions
+
+	if profileName := c.Query("profile"); profileName != "" {
+		profile, err := ctl.service.getImportProfile(c.Request.Context(), profileName)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(
+					http.StatusBadRequest,
+					badRequestError(fmt.Sprintf("import profile '%s' not found", profileName)))
+				return resumeOptions{}, false
+			}
+			c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+			return resumeOptions{}, false
+		}
+		options.Mapping = profile.mapping()
+	}
+
+	options.Mapping = options.Mapping.override(columnMapping{
+		Amount:      c.Query("amount_column"),
+		Date:        c.Query("date_column"),
+		Currency:    c.Query("currency_column"),
+		Description: c.Query("description_column"),
+		Reference:   c.Query("reference_column"),
+	})
+
+	if !options.Mapping.isEmpty() && (options.Mapping.Amount == "" || options.Mapping.Date == "") {
+		c.JSON(http.StatusBadRequest, badRequestError("amount and date columns are required in a column mapping"))
+		return resumeOptions{}, false
+	}
+
+	switch validation := validationMode(c.Query("validation")); validation {
+	case "":
+	case validationFailFast, validationFull:
+		options.Validation = validation
+	default:
+		c.JSON(
+			http.StatusBadRequest,
+			badRequestError(fmt.Sprintf(
+				"validation '%s' is not valid, expected %s or %s", validation, validationFailFast, validationFull)))
+		return resumeOptions{}, false
+	}
+
+	switch duplicates := duplicatePolicy(c.Query("duplicates")); duplicates {
+	case "":
+	case duplicatesReject, duplicatesSkip, duplicatesAllow:
+		options.Duplicates = duplicates
+	default:
+		c.JSON(
+			http.StatusBadRequest,
+			badRequestError(fmt.Sprintf(
+				"duplicates '%s' is not valid, expected %s, %s or %s",
+				duplicates, duplicatesReject, duplicatesSkip, duplicatesAllow)))
+		return resumeOptions{}, false
+	}
+
+	if maxErrorsStr := c.Query("max_errors"); maxErrorsStr != "" {
+		maxErrors, err := strconv.Atoi(maxErrorsStr)
+		if err != nil || maxErrors < 1 || maxErrors > maxRowErrorsLimit {
+			c.JSON(
+				http.StatusBadRequest,
+				badRequestError(fmt.Sprintf(
+					"max errors '%s' must be an integer between 1 and %d", maxErrorsStr, maxRowErrorsLimit)))
+			return resumeOptions{}, false
+		}
+		options.MaxErrors = maxErrors
+	}
+
+	if fiscalYearStartStr := c.Query("fiscal_year_start"); fiscalYearStartStr != "" {
+		fiscalYearStart, err := strconv.Atoi(fiscalYearStartStr)
+		if err != nil || fiscalYearStart < int(time.January) || fiscalYearStart > int(time.December) {
+			c.JSON(
+				http.StatusBadRequest,
+				badRequestError(fmt.Sprintf(
+					"fiscal year start '%s' must be a month number between 1 and 12", fiscalYearStartStr)))
+			return resumeOptions{}, false
+		}
+		options.FiscalYearStart = time.Month(fiscalYearStart)
+	}
+
+	switch years := yearGrouping(c.Query("years")); years {
+	case "":
+	case yearsCombined, yearsSeparate:
+		options.Years = years
+	default:
+		c.JSON(
+			http.StatusBadRequest,
+			badRequestError(fmt.Sprintf(
+				"years '%s' is not valid, expected %s or %s", years, yearsCombined, yearsSeparate)))
+		return resumeOptions{}, false
+	}
+
+	switch openingBalanceStr := c.Query("opening_balance"); openingBalanceStr {
+	case "":
+	case previousBalance:
+		options.PreviousBalance = true
+	default:
+		openingBalance, err := money.Parse(openingBalanceStr)
+		if err != nil {
+			c.JSON(
+				http.StatusBadRequest,
+				badRequestError(fmt.Sprintf(
+					"opening balance '%s' must be a decimal amount or %s", openingBalanceStr, previousBalance)))
+			return resumeOptions{}, false
+		}
+		options.OpeningBalance = &openingBalance
+	}
+
+	if forecastMonthsStr := c.Query("forecast_months"); forecastMonthsStr != "" {
+		forecastMonths, err := strconv.Atoi(forecastMonthsStr)
+		if err != nil || forecastMonths < 1 || forecastMonths > maxForecastMonths {
+			c.JSON(
+				http.StatusBadRequest,
+				badRequestError(fmt.Sprintf(
+					"forecast months '%s' must be an integer between 1 and %d", forecastMonthsStr, maxForecastMonths)))
+			return resumeOptions{}, false
+		}
+		options.ForecastMonths = forecastMonths
+	}
+
+	options.Filename = c.Query("filename")
+
+	return options, true
+}
+
+func (ctl controller) enqueueResume(c *gin.Context, userID int64, options resumeOptions) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			badRequestError(fmt.Sprintf("error reading csv body due to: %s", err.Error())))
+		return
+	}
+
+	if len(payload) == 0 {
+		c.JSON(http.StatusBadRequest, badRequestError("csv body is empty"))
+		return
+	}
+
+	job, err := ctl.service.createJob(c.Request.Context(), userID, payload, options)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	ctl.worker.wakeUp()
+
+	c.Header("Location", fmt.Sprintf("/transaction-tool/jobs/%d", job.ID))
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (ctl controller) GetJob(c *gin.Context) {
+	jobIDStr := c.Param("id")
+	if jobIDStr == "" {
+		c.JSON(http.StatusBadRequest, badRequestError("missing job id param"))
+		return
+	}
+
+	jobID, err := strconv.ParseInt(jobIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, badRequestError(fmt.Sprintf("job id '%s' is not an integer", jobIDStr)))
 		return
 	}
 
-	reader := csv.NewReader(c.Request.Body)
-	file, err := reader.ReadAll()
+	job, err := ctl.service.getJob(c.Request.Context(), jobID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, notFoundError(fmt.Sprintf("job id %d not found", jobID)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func (ctl controller) LoadFXRates(c *gin.Context) {
+	file, err := csv.NewReader(c.Request.Body).ReadAll()
 	if err != nil {
 		c.JSON(
 			http.StatusBadRequest,
@@ -58,72 +378,321 @@ func (ctl controller) ResumeTransactions(c *gin.Context) {
 		return
 	}
 
-	bankTransactions, err := ctl.parseFileToTransactions(file, userID)
+	rates, err := ctl.parser.parseFileToFXRates(file)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, badRequestError(err.Error()))
 		return
 	}
 
-	if len(bankTransactions.items) == 0 {
+	if len(rates) == 0 {
 		c.JSON(http.StatusBadRequest, badRequestError("csv body is empty"))
 		return
 	}
 
-	if err = ctl.service.notifyResume(c.Request.Context(), bankTransactions); err != nil {
-		c.JSON(http.StatusInternalServerError, Error{
-			Status:  http.StatusInternalServerError,
-			Code:    "internal error",
-			Message: err.Error(),
-		})
+	if err = ctl.service.saveFXRates(c.Request.Context(), rates); err != nil {
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
 		return
 	}
 
 	c.Status(http.StatusOK)
 }
 
-func (ctl controller) parseFileToTransactions(file [][]string, userID int64) (transactions, error) {
+func (ctl controller) SaveImportProfile(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, badRequestError("missing import profile name param"))
+		return
+	}
+
+	var profile ImportProfile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			badRequestError(fmt.Sprintf("error reading import profile body due to: %s", err.Error())))
+		return
+	}
+
+	profile.Name = name
+
+	if profile.AmountColumn == "" || profile.DateColumn == "" {
+		c.JSON(http.StatusBadRequest, badRequestError("amount and date columns are required in an import profile"))
+		return
+	}
+
+	if err := ctl.service.saveImportProfile(c.Request.Context(), profile); err != nil {
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (ctl controller) GetImportProfile(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, badRequestError("missing import profile name param"))
+		return
+	}
+
+	profile, err := ctl.service.getImportProfile(c.Request.Context(), name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, notFoundError(fmt.Sprintf("import profile '%s' not found", name)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (ctl controller) GetImportBatches(c *gin.Context) {
+	userID, ok := ctl.getUserID(c)
+	if !ok {
+		return
+	}
+
+	batches, err := ctl.service.getImportBatches(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	if batches == nil {
+		batches = []ImportBatch{}
+	}
+
+	c.JSON(http.StatusOK, batches)
+}
+
+func (ctl controller) GetImportBatchTransactions(c *gin.Context) {
+	batchID, ok := ctl.getBatchID(c)
+	if !ok {
+		return
+	}
+
+	txns, err := ctl.service.getImportBatchTransactions(c.Request.Context(), batchID)
+	if err != nil {
+		ctl.importBatchError(c, batchID, err)
+		return
+	}
+
+	if txns == nil {
+		txns = []SavedTransaction{}
+	}
+
+	c.JSON(http.StatusOK, txns)
+}
+
+func (ctl controller) RollbackImportBatch(c *gin.Context) {
+	batchID, ok := ctl.getBatchID(c)
+	if !ok {
+		return
+	}
+
+	batch, err := ctl.service.rollbackImportBatch(c.Request.Context(), batchID)
+	if err != nil {
+		ctl.importBatchError(c, batchID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+func (ctl controller) getBatchID(c *gin.Context) (int64, bool) {
+	batchIDStr := c.Param("id")
+	if batchIDStr == "" {
+		c.JSON(http.StatusBadRequest, badRequestError("missing import batch id param"))
+		return 0, false
+	}
+
+	batchID, err := strconv.ParseInt(batchIDStr, 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			badRequestError(fmt.Sprintf("import batch id '%s' is not an integer", batchIDStr)))
+		return 0, false
+	}
+
+	return batchID, true
+}
+
+func (ctl controller) importBatchError(c *gin.Context, batchID int64, err error) {
 	var (
-		totalElementsByRow = 2
-		bankTransactions   = transactions{userID: userID, items: make([]transaction, 0, len(file))}
-		year               int
+		rolledBackErr batchRolledBackError
+		laterBatchErr laterBatchError
 	)
 
-	for i, row := range file {
-		if len(row) != totalElementsByRow {
-			return transactions{}, fmt.Errorf(
-				"for row number %d is expected %d elements, however got %d", i+1, totalElementsByRow, len(row))
-		}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, notFoundError(fmt.Sprintf("import batch id %d not found", batchID)))
+	case errors.As(err, &rolledBackErr), errors.As(err, &laterBatchErr):
+		c.JSON(http.StatusConflict, conflictError(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+	}
+}
 
-		amount, err := strconv.ParseFloat(row[0], 64)
-		if err != nil {
-			return transactions{}, fmt.Errorf(
-				"error parsing float amount (%s) from row number %d", row[0], i+1)
+func (ctl controller) CreateCategoryRule(c *gin.Context) {
+	var rule CategoryRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			badRequestError(fmt.Sprintf("error reading category rule body due to: %s", err.Error())))
+		return
+	}
+
+	if err := rule.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, badRequestError(err.Error()))
+		return
+	}
+
+	rule, err := ctl.service.createCategoryRule(c.Request.Context(), rule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// GetCategoryRules lists the global rules, along with the ones of the user of the user_id query param if any.
+func (ctl controller) GetCategoryRules(c *gin.Context) {
+	var userID int64
+
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		var err error
+		if userID, err = strconv.ParseInt(userIDStr, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, badRequestError(fmt.Sprintf("user id '%s' is not an integer", userIDStr)))
+			return
 		}
+	}
+
+	rules, err := ctl.service.getCategoryRules(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	if rules == nil {
+		rules = []CategoryRule{}
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
 
-		if amount == 0 {
-			return transactions{}, fmt.Errorf(
-				"for row number %d, transaction amount is zero", i+1)
+func (ctl controller) DeleteCategoryRule(c *gin.Context) {
+	ruleIDStr := c.Param("id")
+	if ruleIDStr == "" {
+		c.JSON(http.StatusBadRequest, badRequestError("missing category rule id param"))
+		return
+	}
+
+	ruleID, err := strconv.ParseInt(ruleIDStr, 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			badRequestError(fmt.Sprintf("category rule id '%s' is not an integer", ruleIDStr)))
+		return
+	}
+
+	if err = ctl.service.deleteCategoryRule(c.Request.Context(), ruleID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, notFoundError(fmt.Sprintf("category rule id %d not found", ruleID)))
+			return
 		}
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
 
-		date, err := time.Parse(time.RFC3339, row[1])
-		if err != nil {
-			return transactions{}, fmt.Errorf(
-				"error parsing date '%s' because of no compliance with RFC3339 layout for row number %d",
-				row[1], i+1)
+	c.Status(http.StatusNoContent)
+}
+
+func (ctl controller) RecategorizeTransactions(c *gin.Context) {
+	userID, ok := ctl.getUserID(c)
+	if !ok {
+		return
+	}
+
+	result, err := ctl.service.recategorizeTransactions(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetForecast projects the balance of the user for the months of the months query param, or the default ones.
+func (ctl controller) GetForecast(c *gin.Context) {
+	userID, ok := ctl.getUserID(c)
+	if !ok {
+		return
+	}
+
+	months := defaultForecastMonths
+	if monthsStr := c.Query("months"); monthsStr != "" {
+		var err error
+		if months, err = strconv.Atoi(monthsStr); err != nil || months < 1 || months > maxForecastMonths {
+			c.JSON(
+				http.StatusBadRequest,
+				badRequestError(fmt.Sprintf(
+					"months '%s' must be an integer between 1 and %d", monthsStr, maxForecastMonths)))
+			return
 		}
+	}
 
-		if year == 0 {
-			year = date.Year()
-		} else if year != date.Year() {
-			return transactions{}, fmt.Errorf(
-				"transaction set must belong to same year, 1-number-transaction year"+
-					" is %d and %d-number-transaction year is %d", year, i+1, date.Year())
+	forecast, err := ctl.service.getForecast(c.Request.Context(), userID, months)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, notFoundError(fmt.Sprintf("user id %d not found", userID)))
+			return
 		}
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
+		return
+	}
+
+	if forecast.Months == nil {
+		forecast.Months = []ForecastMonth{}
+	}
+
+	c.JSON(http.StatusOK, forecast)
+}
 
-		bankTransactions.items = append(bankTransactions.items, transaction{
-			amount: amount,
-			date:   date,
-		})
+// GetResumePDF responds with the PDF statement of the user for the year of the year param, which comes with
+// the .pdf extension. The statement is streamed from the transactions of the user as it is rendered.
+func (ctl controller) GetResumePDF(c *gin.Context) {
+	userID, ok := ctl.getUserID(c)
+	if !ok {
+		return
+	}
+
+	yearStr := c.Param("year")
+	year, err := strconv.Atoi(strings.TrimSuffix(yearStr, ".pdf"))
+	if err != nil || !strings.HasSuffix(yearStr, ".pdf") {
+		c.JSON(
+			http.StatusBadRequest,
+			badRequestError(fmt.Sprintf("year '%s' must be a year followed by .pdf", yearStr)))
+		return
+	}
+
+	// the statement is sent as it is rendered, so an error after it started can only cut it short
+	c.Header("Content-Type", statementContentType)
+	err = ctl.service.getStatement(c.Request.Context(), userID, year, c.Writer)
+	if err != nil && c.Writer.Written() {
+		log.Printf("error sending statement of user id %d for %d due to: %s", userID, year, err.Error())
+		c.Abort()
+		return
+	}
+	if err != nil {
+		c.Header("Content-Type", "")
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(
+				http.StatusNotFound,
+				notFoundError(fmt.Sprintf("statement of user id %d for %d not found", userID, year)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, internalError(err.Error()))
 	}
-	return bankTransactions, nil
 }
@@ -2,6 +2,7 @@ package summarizer
 
 import (
 	"bytes"
+	"database/sql"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
@@ -9,8 +10,10 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
+	"transaction-tool-api/src/internal/money"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -18,13 +21,18 @@ import (
 )
 
 func getTestContext(
-	params map[string]string, body [][]string) (*gin.Context, *httptest.ResponseRecorder) {
+	params map[string]string, query map[string]string, body [][]string) (*gin.Context, *httptest.ResponseRecorder) {
 	gin.SetMode(gin.TestMode)
 
 	r := httptest.NewRecorder()
 	ctx, _ := gin.CreateTestContext(r)
 
-	ctx.Request = &http.Request{}
+	values := url.Values{}
+	for key, value := range query {
+		values.Set(key, value)
+	}
+
+	ctx.Request = &http.Request{URL: &url.URL{RawQuery: values.Encode()}, Header: http.Header{}}
 
 	for key, value := range params {
 		ctx.AddParam(key, value)
@@ -51,24 +59,27 @@ func TestControllerResumeTransactions(t *testing.T) {
 		bankTransactions = transactions{
 			items: []transaction{
 				{
-					amount: -10,
+					amount: money.FromInt(-10),
 					date:   date,
 				},
 				{
-					amount: 15,
+					amount: money.FromInt(15),
 					date:   date.Add(time.Hour),
 				},
 			},
 			userID: 5,
 		}
 		customErr = errors.New("custom error")
+		payload   = []byte("-10," + date.Format(time.RFC3339) + "\n")
+		job       = Job{ID: 1, UserID: 5, Status: JobStatusQueued, DateCreated: date, DateUpdated: date}
 	)
 
 	tests := []struct {
 		name         string
 		params       map[string]string
+		query        map[string]string
 		body         [][]string
-		mockApplier  func(m *serviceMock)
+		mockApplier  func(m *serviceMock, w *workerMock)
 		expectedCode int
 		expectedBody any
 	}{
@@ -93,11 +104,47 @@ func TestControllerResumeTransactions(t *testing.T) {
 			name:   "csv row bad format",
 			params: map[string]string{"user_id": "5"},
 			body: [][]string{
-				{"", "", ""},
+				{"", "", "", ""},
 			},
 			expectedCode: http.StatusBadRequest,
 			expectedBody: badRequestError(fmt.Errorf(
-				"for row number %d is expected %d elements, however got %d", 1, 2, 3).Error()),
+				"for row number %d is expected %d or %d elements, however got %d", 1, 2, 3, 4).Error()),
+		},
+		{
+			name:         "validation mode not valid",
+			params:       map[string]string{"user_id": "5"},
+			query:        map[string]string{"validation": "lazy"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("validation 'lazy' is not valid, expected fail_fast or full"),
+		},
+		{
+			name:         "max errors out of range",
+			params:       map[string]string{"user_id": "5"},
+			query:        map[string]string{"validation": "full", "max_errors": "1001"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("max errors '1001' must be an integer between 1 and 1000"),
+		},
+		{
+			name:   "csv rows with errors on full validation",
+			params: map[string]string{"user_id": "5"},
+			query:  map[string]string{"validation": "full", "max_errors": "1"},
+			body: [][]string{
+				{"", "", "", ""},
+				{"0", date.Format(time.RFC3339)},
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: Error{
+				Status:  http.StatusBadRequest,
+				Code:    "bad request",
+				Message: "csv body has 2 errors, only the first 1 are reported",
+				Errors: []RowError{
+					{
+						Row:     1,
+						Code:    "invalid_length",
+						Message: "for row number 1 is expected 2 or 3 elements, however got 4",
+					},
+				},
+			},
 		},
 		{
 			name:   "service internal error",
@@ -106,41 +153,198 @@ func TestControllerResumeTransactions(t *testing.T) {
 				{"-10", date.Format(time.RFC3339)},
 				{"15", date.Add(time.Hour).Format(time.RFC3339)},
 			},
-			mockApplier: func(m *serviceMock) {
-				m.On("notifyResume", bankTransactions).Return(customErr).Once()
+			mockApplier: func(m *serviceMock, w *workerMock) {
+				m.On("notifyResume", bankTransactions, resumeOptions{}).Return(nil, customErr).Once()
 			},
 			expectedCode: http.StatusInternalServerError,
-			expectedBody: Error{
-				Status:  http.StatusInternalServerError,
-				Code:    "internal error",
-				Message: customErr.Error(),
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name:   "duplicate transaction rejected",
+			params: map[string]string{"user_id": "5"},
+			body: [][]string{
+				{"-10", date.Format(time.RFC3339)},
+				{"15", date.Add(time.Hour).Format(time.RFC3339)},
 			},
+			mockApplier: func(m *serviceMock, w *workerMock) {
+				m.On("notifyResume", bankTransactions, resumeOptions{}).
+					Return(nil, duplicateTransactionError{txn: bankTransactions.items[0]}).Once()
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: conflictError(duplicateTransactionError{txn: bankTransactions.items[0]}.Error()),
 		},
 		{
-			name:   "service internal error",
+			name:   "every transaction already saved",
 			params: map[string]string{"user_id": "5"},
 			body: [][]string{
 				{"-10", date.Format(time.RFC3339)},
 				{"15", date.Add(time.Hour).Format(time.RFC3339)},
 			},
-			mockApplier: func(m *serviceMock) {
-				m.On("notifyResume", bankTransactions).Return(nil).Once()
+			mockApplier: func(m *serviceMock, w *workerMock) {
+				m.On("notifyResume", bankTransactions, resumeOptions{}).
+					Return(nil, transactionsSavedError{userID: 5, skipped: 2}).Once()
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: conflictError(transactionsSavedError{userID: 5, skipped: 2}.Error()),
+		},
+		{
+			name:         "duplicate policy not valid",
+			params:       map[string]string{"user_id": "5"},
+			query:        map[string]string{"duplicates": "ignore"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("duplicates 'ignore' is not valid, expected reject, skip or allow"),
+		},
+		{
+			name:         "fiscal year start not valid",
+			params:       map[string]string{"user_id": "5"},
+			query:        map[string]string{"fiscal_year_start": "13"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("fiscal year start '13' must be a month number between 1 and 12"),
+		},
+		{
+			name:         "opening balance not valid",
+			params:       map[string]string{"user_id": "5"},
+			query:        map[string]string{"opening_balance": "last"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("opening balance 'last' must be a decimal amount or previous"),
+		},
+		{
+			name:         "forecast months not valid",
+			params:       map[string]string{"user_id": "5"},
+			query:        map[string]string{"forecast_months": "13"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("forecast months '13' must be an integer between 1 and 12"),
+		},
+		{
+			name:         "years grouping not valid",
+			params:       map[string]string{"user_id": "5"},
+			query:        map[string]string{"years": "yearly"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("years 'yearly' is not valid, expected combined or separate"),
+		},
+		{
+			name:   "user notified by fiscal year",
+			params: map[string]string{"user_id": "5"},
+			query:  map[string]string{"fiscal_year_start": "4", "years": "separate"},
+			body: [][]string{
+				{"-10", date.Format(time.RFC3339)},
+				{"15", date.Add(time.Hour).Format(time.RFC3339)},
+			},
+			mockApplier: func(m *serviceMock, w *workerMock) {
+				m.On("notifyResume", bankTransactions, resumeOptions{FiscalYearStart: time.April, Years: yearsSeparate}).
+					Return(ImportResult{InsertedTransactions: 2}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: ImportResult{InsertedTransactions: 2},
+		},
+		{
+			name:   "user notified from the previous balance",
+			params: map[string]string{"user_id": "5"},
+			query:  map[string]string{"opening_balance": "previous"},
+			body: [][]string{
+				{"-10", date.Format(time.RFC3339)},
+				{"15", date.Add(time.Hour).Format(time.RFC3339)},
+			},
+			mockApplier: func(m *serviceMock, w *workerMock) {
+				m.On("notifyResume", bankTransactions, resumeOptions{PreviousBalance: true}).
+					Return(ImportResult{InsertedTransactions: 2}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: ImportResult{InsertedTransactions: 2},
+		},
+		{
+			name:   "user notified successfully",
+			params: map[string]string{"user_id": "5"},
+			query:  map[string]string{"duplicates": "skip"},
+			body: [][]string{
+				{"-10", date.Format(time.RFC3339)},
+				{"15", date.Add(time.Hour).Format(time.RFC3339)},
+			},
+			mockApplier: func(m *serviceMock, w *workerMock) {
+				m.On("notifyResume", bankTransactions, resumeOptions{Duplicates: duplicatesSkip}).
+					Return(ImportResult{InsertedTransactions: 1, SkippedTransactions: 1}, nil).Once()
 			},
 			expectedCode: http.StatusOK,
+			expectedBody: ImportResult{InsertedTransactions: 1, SkippedTransactions: 1},
+		},
+		{
+			name:         "async csv file is empty",
+			params:       map[string]string{"user_id": "5"},
+			query:        map[string]string{"async": "true"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("csv body is empty"),
+		},
+		{
+			name:   "async job creation fails",
+			params: map[string]string{"user_id": "5"},
+			query:  map[string]string{"async": "true"},
+			body:   [][]string{{"-10", date.Format(time.RFC3339)}},
+			mockApplier: func(m *serviceMock, w *workerMock) {
+				m.On("createJob", int64(5), payload, resumeOptions{}).Return(nil, customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name:   "import profile not found",
+			params: map[string]string{"user_id": "5"},
+			query:  map[string]string{"profile": "bank"},
+			mockApplier: func(m *serviceMock, w *workerMock) {
+				m.On("getImportProfile", "bank").Return(nil, fmt.Errorf("wrapped: %w", sql.ErrNoRows)).Once()
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("import profile 'bank' not found"),
+		},
+		{
+			name:         "incomplete column mapping",
+			params:       map[string]string{"user_id": "5"},
+			query:        map[string]string{"amount_column": "Monto"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("amount and date columns are required in a column mapping"),
+		},
+		{
+			name:   "async job created with import profile",
+			params: map[string]string{"user_id": "5"},
+			query:  map[string]string{"async": "true", "profile": "bank", "date_column": "Fecha"},
+			body:   [][]string{{"-10", date.Format(time.RFC3339)}},
+			mockApplier: func(m *serviceMock, w *workerMock) {
+				m.On("getImportProfile", "bank").
+					Return(ImportProfile{Name: "bank", AmountColumn: "Monto", DateColumn: "Date"}, nil).Once()
+				m.On("createJob", int64(5), payload, resumeOptions{
+					Mapping: columnMapping{Amount: "Monto", Date: "Fecha"},
+				}).Return(job, nil).Once()
+				w.On("wakeUp").Once()
+			},
+			expectedCode: http.StatusAccepted,
+			expectedBody: job,
+		},
+		{
+			name:   "async job created",
+			params: map[string]string{"user_id": "5"},
+			query:  map[string]string{"async": "true"},
+			body:   [][]string{{"-10", date.Format(time.RFC3339)}},
+			mockApplier: func(m *serviceMock, w *workerMock) {
+				m.On("createJob", int64(5), payload, resumeOptions{}).Return(job, nil).Once()
+				w.On("wakeUp").Once()
+			},
+			expectedCode: http.StatusAccepted,
+			expectedBody: job,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			var (
-				ctx, r   = getTestContext(test.params, test.body)
+				ctx, r   = getTestContext(test.params, test.query, test.body)
 				servMock = &serviceMock{}
-				ctl      = controller{service: servMock}
+				wMock    = &workerMock{}
+				ctl      = controller{service: servMock, worker: wMock}
 			)
 
 			if test.mockApplier != nil {
-				test.mockApplier(servMock)
+				test.mockApplier(servMock, wMock)
 				defer servMock.AssertExpectations(t)
+				defer wMock.AssertExpectations(t)
 			}
 
 			expectedBody := ""
@@ -158,98 +362,850 @@ func TestControllerResumeTransactions(t *testing.T) {
 	}
 }
 
-func TestControllerParseFileToTransactions(t *testing.T) {
+func TestControllerPreviewResume(t *testing.T) {
 	var (
-		date = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
-
+		date             = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		customErr        = errors.New("custom error")
+		body             = [][]string{{"-10", date.Format(time.RFC3339)}}
 		bankTransactions = transactions{
-			items: []transaction{
-				{
-					amount: -10.5,
-					date:   date,
-				},
-				{
-					amount: 15,
-					date:   date.Add(time.Hour),
-				},
-			},
+			items:  []transaction{{amount: money.FromInt(-10), date: date}},
 			userID: 5,
 		}
+		resume = Resume{
+			User:      User{UserID: 5, Name: "name"},
+			Currency:  "USD",
+			Balance:   "-10.00",
+			CreditAvg: "0.00",
+			DebitAvg:  "-10.00",
+			MonthTransactions: []MonthTransaction{
+				singleTransactionMonth(time.October, money.FromInt(-10), money.FromInt(-10)),
+			},
+		}
+		html, _ = resume.ToHTML(resumeHTMLTemplate)
 	)
 
 	tests := []struct {
-		name           string
-		transactions   [][]string
-		expectedResult transactions
-		expectedErr    error
+		name         string
+		params       map[string]string
+		query        map[string]string
+		body         [][]string
+		mockApplier  func(m *serviceMock)
+		expectedCode int
+		expectedBody any
 	}{
 		{
-			name:           "no transactions",
-			transactions:   nil,
-			expectedResult: transactions{userID: 5, items: make([]transaction, 0)},
-			expectedErr:    nil,
+			name:         "user id is not an integer",
+			params:       map[string]string{"user_id": "bad format"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError(fmt.Sprintf("user id '%s' is not an integer", "bad format")),
+		},
+		{
+			name:         "csv file is empty",
+			params:       map[string]string{"user_id": "5"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("csv body is empty"),
 		},
 		{
-			name: "elements by row unexpected",
-			transactions: [][]string{
-				{""},
+			name:   "service internal error",
+			params: map[string]string{"user_id": "5"},
+			body:   body,
+			mockApplier: func(m *serviceMock) {
+				m.On("previewResume", bankTransactions, resumeOptions{}).Return(nil, customErr).Once()
 			},
-			expectedErr: fmt.Errorf(
-				"for row number %d is expected %d elements, however got %d", 1, 2, 1),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
 		},
 		{
-			name: "amount bad format",
-			transactions: [][]string{
-				{"bad format", ""},
+			name:   "resume previewed",
+			params: map[string]string{"user_id": "5"},
+			body:   body,
+			mockApplier: func(m *serviceMock) {
+				m.On("previewResume", bankTransactions, resumeOptions{}).Return(resume, nil).Once()
 			},
-			expectedErr: fmt.Errorf(
-				"error parsing float amount (%s) from row number %d", "bad format", 1),
+			expectedCode: http.StatusOK,
+			expectedBody: ResumePreview{Resume: resume},
 		},
 		{
-			name: "amount zero no valid",
-			transactions: [][]string{
-				{"0", ""},
+			name:   "resume previewed with html",
+			params: map[string]string{"user_id": "5"},
+			query:  map[string]string{"html": "true"},
+			body:   body,
+			mockApplier: func(m *serviceMock) {
+				m.On("previewResume", bankTransactions, resumeOptions{}).Return(resume, nil).Once()
 			},
-			expectedErr: fmt.Errorf(
-				"for row number %d, transaction amount is zero", 1),
+			expectedCode: http.StatusOK,
+			expectedBody: ResumePreview{Resume: resume, HTML: html},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx, r   = getTestContext(test.params, test.query, test.body)
+				servMock = &serviceMock{}
+				ctl      = controller{service: servMock}
+			)
+
+			if test.mockApplier != nil {
+				test.mockApplier(servMock)
+				defer servMock.AssertExpectations(t)
+			}
+
+			b, err := json.Marshal(test.expectedBody)
+			require.Nil(t, err)
+
+			ctl.PreviewResume(ctx)
+
+			assert.Equal(t, test.expectedCode, r.Code)
+			assert.Equal(t, string(b), r.Body.String())
+		})
+	}
+}
+
+func TestControllerGetJob(t *testing.T) {
+	var (
+		date      = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		customErr = errors.New("custom error")
+		job       = Job{
+			ID:           1,
+			UserID:       5,
+			Status:       JobStatusDone,
+			DateCreated:  date,
+			DateUpdated:  date,
+			DateStarted:  &date,
+			DateFinished: &date,
+		}
+	)
+
+	tests := []struct {
+		name         string
+		params       map[string]string
+		mockApplier  func(m *serviceMock)
+		expectedCode int
+		expectedBody any
+	}{
+		{
+			name:         "missing job id param",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("missing job id param"),
 		},
 		{
-			name: "date bad format",
-			transactions: [][]string{
-				{"-10.5", "bad format"},
+			name:         "job id is not an integer",
+			params:       map[string]string{"id": "bad format"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError(fmt.Sprintf("job id '%s' is not an integer", "bad format")),
+		},
+		{
+			name:   "job not found",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m *serviceMock) {
+				m.On("getJob", int64(1)).Return(nil, fmt.Errorf("wrapped: %w", sql.ErrNoRows)).Once()
 			},
-			expectedErr: fmt.Errorf(
-				"error parsing date '%s' because of no compliance with RFC3339 layout for row number %d",
-				"bad format", 1),
+			expectedCode: http.StatusNotFound,
+			expectedBody: notFoundError("job id 1 not found"),
 		},
 		{
-			name: "transactions belong to different year",
-			transactions: [][]string{
-				{"-10.5", date.Format(time.RFC3339)},
-				{"15", date.Add(8760 * time.Hour).Format(time.RFC3339)},
+			name:   "service internal error",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m *serviceMock) {
+				m.On("getJob", int64(1)).Return(nil, customErr).Once()
 			},
-			expectedErr: fmt.Errorf(
-				"transaction set must belong to same year, 1-number-transaction year"+
-					" is %d and %d-number-transaction year is %d", 2021, 2, 2022),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
 		},
 		{
-			name: "successfully parsing",
-			transactions: [][]string{
-				{"-10.5", date.Format(time.RFC3339)},
-				{"15", date.Add(time.Hour).Format(time.RFC3339)},
+			name:   "job found",
+			params: map[string]string{"id": "1"},
+			mockApplier: func(m *serviceMock) {
+				m.On("getJob", int64(1)).Return(job, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: job,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx, r   = getTestContext(test.params, nil, nil)
+				servMock = &serviceMock{}
+				ctl      = controller{service: servMock}
+			)
+
+			if test.mockApplier != nil {
+				test.mockApplier(servMock)
+				defer servMock.AssertExpectations(t)
+			}
+
+			b, err := json.Marshal(test.expectedBody)
+			require.Nil(t, err)
+
+			ctl.GetJob(ctx)
+
+			assert.Equal(t, test.expectedCode, r.Code)
+			assert.Equal(t, string(b), r.Body.String())
+		})
+	}
+}
+
+func TestControllerLoadFXRates(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		rates     = []fxRate{
+			{
+				baseCurrency:  "USD",
+				quoteCurrency: "MXN",
+				rate:          money.MustParseRate("20"),
+				effectiveDate: time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC),
+			},
+		}
+	)
+
+	tests := []struct {
+		name         string
+		body         [][]string
+		mockApplier  func(m *serviceMock)
+		expectedCode int
+		expectedBody any
+	}{
+		{
+			name:         "csv file is empty",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("csv body is empty"),
+		},
+		{
+			name:         "csv row bad format",
+			body:         [][]string{{"USD", "MXN", "20"}},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError(fmt.Errorf(
+				"for row number %d is expected %d elements, however got %d", 1, 4, 3).Error()),
+		},
+		{
+			name: "service internal error",
+			body: [][]string{{"USD", "MXN", "20", "2021-10-01"}},
+			mockApplier: func(m *serviceMock) {
+				m.On("saveFXRates", rates).Return(customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name: "fx rates loaded",
+			body: [][]string{{"USD", "MXN", "20", "2021-10-01"}},
+			mockApplier: func(m *serviceMock) {
+				m.On("saveFXRates", rates).Return(nil).Once()
 			},
-			expectedResult: bankTransactions,
+			expectedCode: http.StatusOK,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			ctl := controller{}
+			var (
+				ctx, r   = getTestContext(nil, nil, test.body)
+				servMock = &serviceMock{}
+				ctl      = controller{service: servMock}
+			)
+
+			if test.mockApplier != nil {
+				test.mockApplier(servMock)
+				defer servMock.AssertExpectations(t)
+			}
 
-			result, err := ctl.parseFileToTransactions(test.transactions, 5)
+			expectedBody := ""
+			if test.expectedBody != nil {
+				b, err := json.Marshal(test.expectedBody)
+				require.Nil(t, err)
+				expectedBody = string(b)
+			}
+
+			ctl.LoadFXRates(ctx)
+
+			assert.Equal(t, test.expectedCode, r.Code)
+			assert.Equal(t, expectedBody, r.Body.String())
+		})
+	}
+}
+
+func TestControllerSaveImportProfile(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		profile   = ImportProfile{Name: "bank", AmountColumn: "Monto", DateColumn: "Fecha"}
+	)
+
+	tests := []struct {
+		name         string
+		params       map[string]string
+		body         string
+		mockApplier  func(m *serviceMock)
+		expectedCode int
+		expectedBody any
+	}{
+		{
+			name:         "missing import profile name param",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("missing import profile name param"),
+		},
+		{
+			name:         "bad body",
+			params:       map[string]string{"name": "bank"},
+			body:         "bad",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError(
+				"error reading import profile body due to: invalid character 'b' looking for beginning of value"),
+		},
+		{
+			name:         "missing required columns",
+			params:       map[string]string{"name": "bank"},
+			body:         `{"AmountColumn": "Monto"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("amount and date columns are required in an import profile"),
+		},
+		{
+			name:   "service internal error",
+			params: map[string]string{"name": "bank"},
+			body:   `{"Name": "other", "AmountColumn": "Monto", "DateColumn": "Fecha"}`,
+			mockApplier: func(m *serviceMock) {
+				m.On("saveImportProfile", profile).Return(customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name:   "import profile saved",
+			params: map[string]string{"name": "bank"},
+			body:   `{"AmountColumn": "Monto", "DateColumn": "Fecha"}`,
+			mockApplier: func(m *serviceMock) {
+				m.On("saveImportProfile", profile).Return(nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: profile,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx, r   = getTestContext(test.params, nil, nil)
+				servMock = &serviceMock{}
+				ctl      = controller{service: servMock}
+			)
+
+			ctx.Request.Body = io.NopCloser(bytes.NewBufferString(test.body))
+
+			if test.mockApplier != nil {
+				test.mockApplier(servMock)
+				defer servMock.AssertExpectations(t)
+			}
+
+			b, err := json.Marshal(test.expectedBody)
+			require.Nil(t, err)
+
+			ctl.SaveImportProfile(ctx)
+
+			assert.Equal(t, test.expectedCode, r.Code)
+			assert.Equal(t, string(b), r.Body.String())
+		})
+	}
+}
+
+func TestControllerGetImportProfile(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		profile   = ImportProfile{Name: "bank", AmountColumn: "Monto", DateColumn: "Fecha"}
+	)
+
+	tests := []struct {
+		name         string
+		params       map[string]string
+		mockApplier  func(m *serviceMock)
+		expectedCode int
+		expectedBody any
+	}{
+		{
+			name:         "missing import profile name param",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("missing import profile name param"),
+		},
+		{
+			name:   "import profile not found",
+			params: map[string]string{"name": "bank"},
+			mockApplier: func(m *serviceMock) {
+				m.On("getImportProfile", "bank").Return(nil, fmt.Errorf("wrapped: %w", sql.ErrNoRows)).Once()
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: notFoundError("import profile 'bank' not found"),
+		},
+		{
+			name:   "service internal error",
+			params: map[string]string{"name": "bank"},
+			mockApplier: func(m *serviceMock) {
+				m.On("getImportProfile", "bank").Return(nil, customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name:   "import profile found",
+			params: map[string]string{"name": "bank"},
+			mockApplier: func(m *serviceMock) {
+				m.On("getImportProfile", "bank").Return(profile, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: profile,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx, r   = getTestContext(test.params, nil, nil)
+				servMock = &serviceMock{}
+				ctl      = controller{service: servMock}
+			)
+
+			if test.mockApplier != nil {
+				test.mockApplier(servMock)
+				defer servMock.AssertExpectations(t)
+			}
+
+			b, err := json.Marshal(test.expectedBody)
+			require.Nil(t, err)
+
+			ctl.GetImportProfile(ctx)
+
+			assert.Equal(t, test.expectedCode, r.Code)
+			assert.Equal(t, string(b), r.Body.String())
+		})
+	}
+}
+
+func TestControllerRollbackImportBatch(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		batch     = ImportBatch{ID: 3, UserID: 1, RowCount: 2, Status: ImportBatchStatusRolledBack}
+	)
+
+	tests := []struct {
+		name         string
+		params       map[string]string
+		mockApplier  func(m *serviceMock)
+		expectedCode int
+		expectedBody any
+	}{
+		{
+			name:         "missing import batch id param",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("missing import batch id param"),
+		},
+		{
+			name:         "import batch id is not an integer",
+			params:       map[string]string{"id": "x"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("import batch id 'x' is not an integer"),
+		},
+		{
+			name:   "import batch not found",
+			params: map[string]string{"id": "3"},
+			mockApplier: func(m *serviceMock) {
+				m.On("rollbackImportBatch", int64(3)).Return(nil, fmt.Errorf("wrapped: %w", sql.ErrNoRows)).Once()
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: notFoundError("import batch id 3 not found"),
+		},
+		{
+			name:   "import batch already rolled back",
+			params: map[string]string{"id": "3"},
+			mockApplier: func(m *serviceMock) {
+				m.On("rollbackImportBatch", int64(3)).Return(nil, batchRolledBackError{batchID: 3}).Once()
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: conflictError("import batch id 3 was already rolled back"),
+		},
+		{
+			name:   "import batch imported before another one",
+			params: map[string]string{"id": "3"},
+			mockApplier: func(m *serviceMock) {
+				m.On("rollbackImportBatch", int64(3)).Return(nil, laterBatchError{batchID: 3, laterBatchID: 5}).Once()
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: conflictError(
+				"import batch id 3 cannot be rolled back before import batch id 5, which was imported after it"),
+		},
+		{
+			name:   "service internal error",
+			params: map[string]string{"id": "3"},
+			mockApplier: func(m *serviceMock) {
+				m.On("rollbackImportBatch", int64(3)).Return(nil, customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name:   "import batch rolled back",
+			params: map[string]string{"id": "3"},
+			mockApplier: func(m *serviceMock) {
+				m.On("rollbackImportBatch", int64(3)).Return(batch, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: batch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx, r   = getTestContext(test.params, nil, nil)
+				servMock = &serviceMock{}
+				ctl      = controller{service: servMock}
+			)
+
+			if test.mockApplier != nil {
+				test.mockApplier(servMock)
+				defer servMock.AssertExpectations(t)
+			}
+
+			b, err := json.Marshal(test.expectedBody)
+			require.Nil(t, err)
+
+			ctl.RollbackImportBatch(ctx)
+
+			assert.Equal(t, test.expectedCode, r.Code)
+			assert.Equal(t, string(b), r.Body.String())
+		})
+	}
+}
+
+func TestControllerCreateCategoryRule(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		rule      = CategoryRule{UserID: 5, Category: "Food", Kind: ruleContains, Pattern: "coffee", Priority: 10}
+		created   = CategoryRule{
+			ID:          1,
+			UserID:      5,
+			Category:    "Food",
+			Kind:        ruleContains,
+			Pattern:     "coffee",
+			Priority:    10,
+			DateCreated: time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC),
+		}
+	)
+
+	tests := []struct {
+		name         string
+		body         string
+		mockApplier  func(m *serviceMock)
+		expectedCode int
+		expectedBody any
+	}{
+		{
+			name:         "bad body",
+			body:         "bad",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError(
+				"error reading category rule body due to: invalid character 'b' looking for beginning of value"),
+		},
+		{
+			name:         "category rule not valid",
+			body:         `{"Category": "Food", "Kind": "contains"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("pattern of a contains rule must have between 1 and 255 characters"),
+		},
+		{
+			name: "service internal error",
+			body: `{"UserID": 5, "Category": "Food", "Kind": "contains", "Pattern": "coffee", "Priority": 10}`,
+			mockApplier: func(m *serviceMock) {
+				m.On("createCategoryRule", rule).Return(nil, customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name: "category rule created",
+			body: `{"UserID": 5, "Category": "Food", "Kind": "contains", "Pattern": "coffee", "Priority": 10}`,
+			mockApplier: func(m *serviceMock) {
+				m.On("createCategoryRule", rule).Return(created, nil).Once()
+			},
+			expectedCode: http.StatusCreated,
+			expectedBody: created,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx, r   = getTestContext(nil, nil, nil)
+				servMock = &serviceMock{}
+				ctl      = controller{service: servMock}
+			)
+
+			ctx.Request.Body = io.NopCloser(bytes.NewBufferString(test.body))
+
+			if test.mockApplier != nil {
+				test.mockApplier(servMock)
+				defer servMock.AssertExpectations(t)
+			}
+
+			b, err := json.Marshal(test.expectedBody)
+			require.Nil(t, err)
+
+			ctl.CreateCategoryRule(ctx)
+
+			assert.Equal(t, test.expectedCode, r.Code)
+			assert.Equal(t, string(b), r.Body.String())
+		})
+	}
+}
+
+func TestControllerDeleteCategoryRule(t *testing.T) {
+	tests := []struct {
+		name         string
+		params       map[string]string
+		mockApplier  func(m *serviceMock)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "category rule id is not an integer",
+			params:       map[string]string{"id": "x"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"Status":400,"Code":"bad request","Message":"category rule id 'x' is not an integer"}`,
+		},
+		{
+			name:   "category rule not found",
+			params: map[string]string{"id": "3"},
+			mockApplier: func(m *serviceMock) {
+				m.On("deleteCategoryRule", int64(3)).Return(fmt.Errorf("wrapped: %w", sql.ErrNoRows)).Once()
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"Status":404,"Code":"not found","Message":"category rule id 3 not found"}`,
+		},
+		{
+			name:   "category rule deleted",
+			params: map[string]string{"id": "3"},
+			mockApplier: func(m *serviceMock) {
+				m.On("deleteCategoryRule", int64(3)).Return(nil).Once()
+			},
+			expectedCode: http.StatusNoContent,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx, r   = getTestContext(test.params, nil, nil)
+				servMock = &serviceMock{}
+				ctl      = controller{service: servMock}
+			)
+
+			if test.mockApplier != nil {
+				test.mockApplier(servMock)
+				defer servMock.AssertExpectations(t)
+			}
+
+			ctl.DeleteCategoryRule(ctx)
+			ctx.Writer.WriteHeaderNow()
+
+			assert.Equal(t, test.expectedCode, r.Code)
+			assert.Equal(t, test.expectedBody, r.Body.String())
+		})
+	}
+}
+
+func TestControllerGetForecast(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		forecast  = Forecast{
+			Currency: "USD",
+			Balance:  "350.00",
+			Date:     "2022-04-10",
+			Months: []ForecastMonth{
+				{Month: "2022-05", Balance: "572.22", Low: "-287.36", High: "1431.81"},
+			},
+		}
+	)
+
+	tests := []struct {
+		name         string
+		params       map[string]string
+		query        map[string]string
+		mockApplier  func(m *serviceMock)
+		expectedCode int
+		expectedBody any
+	}{
+		{
+			name:         "user id is not an integer",
+			params:       map[string]string{"user_id": "x"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("user id 'x' is not an integer"),
+		},
+		{
+			name:         "months not valid",
+			params:       map[string]string{"user_id": "5"},
+			query:        map[string]string{"months": "0"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("months '0' must be an integer between 1 and 12"),
+		},
+		{
+			name:   "user not found",
+			params: map[string]string{"user_id": "5"},
+			mockApplier: func(m *serviceMock) {
+				m.On("getForecast", int64(5), defaultForecastMonths).
+					Return(nil, fmt.Errorf("wrapped: %w", sql.ErrNoRows)).Once()
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: notFoundError("user id 5 not found"),
+		},
+		{
+			name:   "service internal error",
+			params: map[string]string{"user_id": "5"},
+			mockApplier: func(m *serviceMock) {
+				m.On("getForecast", int64(5), defaultForecastMonths).Return(nil, customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name:   "not enough history",
+			params: map[string]string{"user_id": "5"},
+			mockApplier: func(m *serviceMock) {
+				m.On("getForecast", int64(5), defaultForecastMonths).
+					Return(Forecast{Currency: "USD", Balance: "0.00"}, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: Forecast{Currency: "USD", Balance: "0.00", Months: []ForecastMonth{}},
+		},
+		{
+			name:   "balance forecast",
+			params: map[string]string{"user_id": "5"},
+			query:  map[string]string{"months": "1"},
+			mockApplier: func(m *serviceMock) {
+				m.On("getForecast", int64(5), 1).Return(forecast, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: forecast,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx, r   = getTestContext(test.params, test.query, nil)
+				servMock = &serviceMock{}
+				ctl      = controller{service: servMock}
+			)
+
+			if test.mockApplier != nil {
+				test.mockApplier(servMock)
+				defer servMock.AssertExpectations(t)
+			}
+
+			b, err := json.Marshal(test.expectedBody)
+			require.Nil(t, err)
+
+			ctl.GetForecast(ctx)
+
+			assert.Equal(t, test.expectedCode, r.Code)
+			assert.Equal(t, string(b), r.Body.String())
+		})
+	}
+}
+
+func TestControllerGetResumePDF(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		statement = []byte("%PDF-1.4\n")
+	)
+
+	tests := []struct {
+		name         string
+		params       map[string]string
+		mockApplier  func(m *serviceMock)
+		expectedCode int
+		expectedType string
+		expectedBody any
+	}{
+		{
+			name:         "user id is not an integer",
+			params:       map[string]string{"user_id": "x", "year": "2022.pdf"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("user id 'x' is not an integer"),
+		},
+		{
+			name:         "year without extension",
+			params:       map[string]string{"user_id": "5", "year": "2022"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("year '2022' must be a year followed by .pdf"),
+		},
+		{
+			name:         "year not valid",
+			params:       map[string]string{"user_id": "5", "year": "last.pdf"},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: badRequestError("year 'last.pdf' must be a year followed by .pdf"),
+		},
+		{
+			name:   "statement not found",
+			params: map[string]string{"user_id": "5", "year": "2022.pdf"},
+			mockApplier: func(m *serviceMock) {
+				m.On("getStatement", int64(5), 2022).Return(nil, fmt.Errorf("wrapped: %w", sql.ErrNoRows)).Once()
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: notFoundError("statement of user id 5 for 2022 not found"),
+		},
+		{
+			name:   "service internal error",
+			params: map[string]string{"user_id": "5", "year": "2022.pdf"},
+			mockApplier: func(m *serviceMock) {
+				m.On("getStatement", int64(5), 2022).Return(nil, customErr).Once()
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: internalError(customErr.Error()),
+		},
+		{
+			name:   "service error after the statement started",
+			params: map[string]string{"user_id": "5", "year": "2022.pdf"},
+			mockApplier: func(m *serviceMock) {
+				m.On("getStatement", int64(5), 2022).Return(statement, customErr).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedType: "application/pdf",
+		},
+		{
+			name:   "statement downloaded",
+			params: map[string]string{"user_id": "5", "year": "2022.pdf"},
+			mockApplier: func(m *serviceMock) {
+				m.On("getStatement", int64(5), 2022).Return(statement, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedType: "application/pdf",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ctx, r   = getTestContext(test.params, nil, nil)
+				servMock = &serviceMock{}
+				ctl      = controller{service: servMock}
+			)
+
+			if test.mockApplier != nil {
+				test.mockApplier(servMock)
+				defer servMock.AssertExpectations(t)
+			}
+
+			ctl.GetResumePDF(ctx)
+
+			assert.Equal(t, test.expectedCode, r.Code)
+			if test.expectedType != "" {
+				assert.Equal(t, test.expectedType, r.Header().Get("Content-Type"))
+				assert.Equal(t, statement, r.Body.Bytes())
+				return
+			}
 
-			assert.Equal(t, test.expectedResult, result)
-			assert.Equal(t, test.expectedErr, err)
+			b, err := json.Marshal(test.expectedBody)
+			require.Nil(t, err)
+			assert.Equal(t, string(b), r.Body.String())
 		})
 	}
 }
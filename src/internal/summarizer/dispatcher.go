@@ -0,0 +1,139 @@
+package summarizer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+	"transaction-tool-api/src/internal/notifier"
+)
+
+func NewDispatcher(repository Repository, userNotifier notifier.UserNotifier, options DispatcherOptions) Dispatcher {
+	return dispatcher{
+		repository:     repository,
+		notifier:       userNotifier,
+		batchSize:      options.BatchSize,
+		pollInterval:   options.PollInterval,
+		lease:          options.Lease,
+		maxAttempts:    options.MaxAttempts,
+		initialBackoff: options.InitialBackoff,
+		maxBackoff:     options.MaxBackoff,
+	}
+}
+
+type Dispatcher interface {
+	Start(ctx context.Context)
+}
+
+// DispatcherOptions configure the dispatcher. The lease is the time a dispatcher has to send the messages it
+// claimed before another one claims them again, and the backoff the one a message waits to be sent again
+// after each failed attempt, doubling from the initial one up to the max one.
+type DispatcherOptions struct {
+	BatchSize      int
+	PollInterval   time.Duration
+	Lease          time.Duration
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func GetDispatcherOptions() DispatcherOptions {
+	return DispatcherOptions{
+		BatchSize:      20,
+		PollInterval:   5 * time.Second,
+		Lease:          10 * time.Minute,
+		MaxAttempts:    5,
+		InitialBackoff: time.Minute,
+		MaxBackoff:     time.Hour,
+	}
+}
+
+type dispatcher struct {
+	repository     Repository
+	notifier       notifier.UserNotifier
+	batchSize      int
+	pollInterval   time.Duration
+	lease          time.Duration
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+func (d dispatcher) Start(ctx context.Context) {
+	go d.run(ctx)
+}
+
+func (d dispatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.dispatch(ctx); err != nil {
+			log.Printf("error dispatching outbox messages due to: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch sends the messages it claims, recording the outcome of each one as soon as it is sent.
+func (d dispatcher) dispatch(ctx context.Context) (int, error) {
+	var dispatched int
+
+	messages, err := d.repository.claimOutboxMessages(ctx, d.batchSize, d.lease)
+	if err != nil {
+		return 0, fmt.Errorf("error claiming pending outbox messages due to: %w", err)
+	}
+
+	for _, message := range messages {
+		var (
+			status     = outboxStatusSent
+			messageErr string
+			retryIn    time.Duration
+		)
+
+		// the key makes the attempts after the first one skip the channels the message already went through
+		sendErr := d.notifier.NotifyUser(ctx, message.userID, notifier.Message{
+			Key:         outboxKey(message.id),
+			To:          []string{message.email},
+			HTML:        message.message,
+			Text:        message.text,
+			Attachments: message.attachments,
+		})
+		if sendErr != nil {
+			status, messageErr = outboxStatusFailed, sendErr.Error()
+			if attempts := message.attempts + 1; attempts < d.maxAttempts {
+				status, retryIn = outboxStatusPending, d.backoff(attempts)
+			}
+		}
+
+		err = d.repository.updateOutboxMessageStatus(ctx, message.id, status, messageErr, retryIn)
+		if err != nil {
+			// the message is claimed again once its lease expires
+			log.Printf("error marking outbox message id %d as %s due to: %s", message.id, status, err.Error())
+			continue
+		}
+
+		dispatched++
+	}
+
+	return dispatched, nil
+}
+
+// outboxKey identifies the notification of an outbox message across its attempts.
+func outboxKey(messageID int64) string {
+	return fmt.Sprintf("outbox-%d", messageID)
+}
+
+// backoff returns the time to wait after the given failed attempts to send a message.
+func (d dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.initialBackoff << (attempts - 1)
+	if backoff <= 0 || backoff > d.maxBackoff {
+		backoff = d.maxBackoff
+	}
+	return backoff
+}
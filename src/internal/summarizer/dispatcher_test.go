@@ -0,0 +1,128 @@
+package summarizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"transaction-tool-api/src/internal/notifier"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcherDispatch(t *testing.T) {
+	var (
+		customErr   = errors.New("custom error")
+		attachments = []notifier.Attachment{
+			{Name: "statement.pdf", ContentType: "application/pdf", Content: []byte("pdf")},
+		}
+		messages = []outboxMessage{
+			{
+				id:          1,
+				userID:      5,
+				email:       "first",
+				message:     "first message",
+				text:        "first text",
+				attachments: attachments,
+				status:      outboxStatusSending,
+			},
+			{id: 2, userID: 6, email: "second", message: "second message", status: outboxStatusSending, attempts: 1},
+		}
+		first = notifier.Message{
+			Key:         "outbox-1",
+			To:          []string{"first"},
+			HTML:        "first message",
+			Text:        "first text",
+			Attachments: attachments,
+		}
+		second = notifier.Message{Key: "outbox-2", To: []string{"second"}, HTML: "second message"}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(rm *repositoryMock, nm *notifier.Mock)
+		expected    int
+		expectedErr error
+	}{
+		{
+			name: "error claiming messages",
+			mockApplier: func(rm *repositoryMock, nm *notifier.Mock) {
+				rm.On("claimOutboxMessages", 10, time.Minute).Return(nil, customErr).Once()
+			},
+			expectedErr: fmt.Errorf("error claiming pending outbox messages due to: %w", customErr),
+		},
+		{
+			name: "error marking message does not stop the others",
+			mockApplier: func(rm *repositoryMock, nm *notifier.Mock) {
+				rm.On("claimOutboxMessages", 10, time.Minute).Return(messages, nil).Once()
+				nm.On("NotifyUser", int64(5), first).Return(nil).Once()
+				rm.On("updateOutboxMessageStatus", int64(1), outboxStatusSent, "", time.Duration(0)).
+					Return(customErr).Once()
+				nm.On("NotifyUser", int64(6), second).Return(nil).Once()
+				rm.On("updateOutboxMessageStatus", int64(2), outboxStatusSent, "", time.Duration(0)).
+					Return(nil).Once()
+			},
+			expected: 1,
+		},
+		{
+			name: "messages sent and retried",
+			mockApplier: func(rm *repositoryMock, nm *notifier.Mock) {
+				rm.On("claimOutboxMessages", 10, time.Minute).Return(messages, nil).Once()
+				nm.On("NotifyUser", int64(5), first).Return(nil).Once()
+				rm.On("updateOutboxMessageStatus", int64(1), outboxStatusSent, "", time.Duration(0)).
+					Return(nil).Once()
+				nm.On("NotifyUser", int64(6), second).Return(customErr).Once()
+				rm.On("updateOutboxMessageStatus", int64(2), outboxStatusPending, customErr.Error(), 4*time.Second).
+					Return(nil).Once()
+			},
+			expected: 2,
+		},
+		{
+			name: "message failed every attempt",
+			mockApplier: func(rm *repositoryMock, nm *notifier.Mock) {
+				last := messages[1]
+				last.attempts = 3
+				rm.On("claimOutboxMessages", 10, time.Minute).Return([]outboxMessage{last}, nil).Once()
+				nm.On("NotifyUser", int64(6), second).Return(customErr).Once()
+				rm.On("updateOutboxMessageStatus", int64(2), outboxStatusFailed, customErr.Error(), time.Duration(0)).
+					Return(nil).Once()
+			},
+			expected: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repoMock := &repositoryMock{}
+			notifierMock := &notifier.Mock{}
+			test.mockApplier(repoMock, notifierMock)
+			defer repoMock.AssertExpectations(t)
+			defer notifierMock.AssertExpectations(t)
+
+			d := dispatcher{
+				repository:     repoMock,
+				notifier:       notifierMock,
+				batchSize:      10,
+				lease:          time.Minute,
+				maxAttempts:    4,
+				initialBackoff: 2 * time.Second,
+				maxBackoff:     5 * time.Second,
+			}
+
+			dispatched, err := d.dispatch(context.TODO())
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, dispatched)
+		})
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	d := dispatcher{initialBackoff: 2 * time.Second, maxBackoff: 5 * time.Second}
+
+	assert.Equal(t, 2*time.Second, d.backoff(1))
+	assert.Equal(t, 4*time.Second, d.backoff(2))
+	assert.Equal(t, 5*time.Second, d.backoff(3))
+	assert.Equal(t, 5*time.Second, d.backoff(64))
+}
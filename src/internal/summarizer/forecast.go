@@ -0,0 +1,197 @@
+package summarizer
+
+import (
+	"math"
+	"time"
+	"transaction-tool-api/src/internal/money"
+)
+
+const (
+	defaultForecastMonths = 3
+	maxForecastMonths     = 12
+	// minTrendMonths and maxTrendMonths bound the complete months of history the trend is measured in.
+	minTrendMonths = 3
+	maxTrendMonths = 12
+)
+
+// Forecast projects the end-of-month balances from the balance on a date, adding the recurring movements
+// expected and the trend, which is the average net change by month of the other movements of the history.
+// Low and High bound a band of one standard deviation of that net change, widening with the months ahead.
+// There are no months without enough complete months of history.
+type Forecast struct {
+	Currency string
+	Balance  string
+	Date     string `json:",omitempty"`
+	Months   []ForecastMonth
+}
+
+type ForecastMonth struct {
+	Month   string
+	Balance string
+	Low     string
+	High    string
+}
+
+// forecastBalances projects the balance at the end of the given number of months following the one of the
+// latest date, which is the one of the balance or, when later, the one of the latest transaction of the
+// history, given the recurring movements found in it. Amounts are converted with the rates effective on the
+// day of the transactions, or on the latest date for the recurring movements expected.
+func forecastBalances(
+	history historySource,
+	movements []recurringMovement,
+	rates fxRates,
+	currency string,
+	location *time.Location,
+	balance money.Amount,
+	date time.Time,
+	months int,
+) (Forecast, error) {
+	var (
+		latest     = date.In(location)
+		recurring  = make(map[string]bool, len(movements))
+		nets       = make(map[time.Time]money.Amount)
+		first      time.Time
+		convertErr error
+		errMonth   time.Time
+	)
+
+	for _, movement := range movements {
+		recurring[movement.key] = true
+	}
+
+	// the latest month is only known once the whole history is read, so the nets of every month are kept,
+	// and the conversion errors only matter when they are of a month before it
+	err := history(func(txn transaction) error {
+		txnDate := txn.date.In(location)
+		if txnDate.After(latest) {
+			latest = txnDate
+		}
+
+		month := monthStart(txnDate)
+		if first.IsZero() || month.Before(first) {
+			first = month
+		}
+		if recurring[recurringKey(txn)] {
+			return nil
+		}
+
+		amount, err := rates.convert(txn.amount, txn.currency, currency, txnDate)
+		if err != nil {
+			if convertErr == nil || month.Before(errMonth) {
+				convertErr, errMonth = err, month
+			}
+			return nil
+		}
+		nets[month] += amount
+		return nil
+	})
+	if err != nil {
+		return Forecast{}, err
+	}
+
+	forecast := Forecast{Currency: currency, Balance: balance.StringFixed(2)}
+	if latest.IsZero() {
+		return forecast, nil
+	}
+	forecast.Date = latest.Format(balanceDateLayout)
+
+	latestMonth := monthStart(latest)
+	if convertErr != nil && errMonth.Before(latestMonth) {
+		return Forecast{}, convertErr
+	}
+	if !first.Before(latestMonth) {
+		first = time.Time{}
+	}
+
+	if first.IsZero() {
+		return forecast, nil
+	}
+
+	trendStart := latestMonth.AddDate(0, -maxTrendMonths, 0)
+	if first.After(trendStart) {
+		trendStart = first
+	}
+
+	var trend []float64
+	for month := trendStart; month.Before(latestMonth); month = month.AddDate(0, 1, 0) {
+		trend = append(trend, float64(nets[month]))
+	}
+	if len(trend) < minTrendMonths {
+		return forecast, nil
+	}
+
+	mean, deviation := meanAndDeviation(trend)
+
+	// the recurring movements expected after the latest date, by month from the one of the latest date
+	expected := make([]money.Amount, months+1)
+	for _, movement := range movements {
+		amount, err := rates.convert(movement.average, movement.Currency, currency, latest)
+		if err != nil {
+			return Forecast{}, err
+		}
+
+		next := movement.next
+		for !next.After(latest) {
+			next = movement.frequency.next(next)
+		}
+		for ; monthsBetween(latestMonth, monthStart(next)) <= months; next = movement.frequency.next(next) {
+			expected[monthsBetween(latestMonth, monthStart(next))] += amount
+		}
+	}
+
+	var (
+		daysInMonth = latestMonth.AddDate(0, 1, -1).Day()
+		remaining   = float64(daysInMonth-latest.Day()) / float64(daysInMonth)
+		cumulative  = expected[0]
+	)
+
+	for i := 1; i <= months; i++ {
+		cumulative += expected[i]
+		elapsed := remaining + float64(i)
+		projected := float64(balance+cumulative) + mean*elapsed
+		band := deviation * math.Sqrt(elapsed)
+
+		forecast.Months = append(forecast.Months, ForecastMonth{
+			Month:   latestMonth.AddDate(0, i, 0).Format(monthLayout),
+			Balance: money.Amount(math.Round(projected)).StringFixed(2),
+			Low:     money.Amount(math.Round(projected - band)).StringFixed(2),
+			High:    money.Amount(math.Round(projected + band)).StringFixed(2),
+		})
+	}
+
+	return forecast, nil
+}
+
+// getForecast returns the forecast of the summary, nil when there is no month forecast.
+func (s *summarizer) getForecast() *Forecast {
+	if len(s.forecast.Months) == 0 {
+		return nil
+	}
+	forecast := s.forecast
+	return &forecast
+}
+
+// monthStart returns the first day of the month of the date, at midnight in UTC.
+func monthStart(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
+
+// meanAndDeviation returns the mean and the sample standard deviation of the values.
+func meanAndDeviation(values []float64) (float64, float64) {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	mean := sum / float64(len(values))
+
+	var squares float64
+	for _, value := range values {
+		squares += (value - mean) * (value - mean)
+	}
+
+	return mean, math.Sqrt(squares / float64(len(values)-1))
+}
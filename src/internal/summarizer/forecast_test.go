@@ -0,0 +1,105 @@
+package summarizer
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"transaction-tool-api/src/internal/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForecastBalances(t *testing.T) {
+	var (
+		date    = time.Date(2022, time.January, 1, 12, 0, 0, 0, time.UTC)
+		latest  = time.Date(2022, time.May, 15, 12, 0, 0, 0, time.UTC)
+		history = []transaction{
+			{amount: money.FromInt(-300), currency: "USD", description: "market", date: date.AddDate(0, 0, 9)},
+			{amount: money.FromInt(-500), currency: "USD", description: "grocer", date: date.AddDate(0, 1, 9)},
+			{amount: money.FromInt(-400), currency: "USD", description: "bakery", date: date.AddDate(0, 2, 9)},
+			{amount: money.FromInt(-400), currency: "USD", description: "butcher", date: date.AddDate(0, 3, 9)},
+			{amount: money.FromInt(-50), currency: "USD", description: "gift", date: latest},
+		}
+	)
+
+	for i := 0; i < 5; i++ {
+		history = append(history, transaction{
+			amount:      money.FromInt(1000),
+			currency:    "USD",
+			description: "salary",
+			date:        date.AddDate(0, i, 0),
+		})
+	}
+
+	tests := []struct {
+		name        string
+		history     []transaction
+		balance     money.Amount
+		date        time.Time
+		expected    Forecast
+		expectedErr error
+	}{
+		{
+			name:     "nothing saved",
+			expected: Forecast{Currency: "USD", Balance: "0.00"},
+		},
+		{
+			name:     "not enough months of history",
+			history:  history[3:5],
+			balance:  money.FromInt(-450),
+			expected: Forecast{Currency: "USD", Balance: "-450.00", Date: "2022-05-15"},
+		},
+		{
+			name: "missing fx rate",
+			history: append([]transaction{
+				{amount: money.FromInt(-10), currency: "EUR", description: "hotel", date: date},
+			}, history...),
+			expectedErr: errors.New("there is no fx rate from EUR to USD effective on 2022-01-01"),
+		},
+		{
+			name:    "trend and recurring movements",
+			history: history,
+			balance: money.FromInt(3350),
+			expected: Forecast{
+				Currency: "USD",
+				Balance:  "3350.00",
+				Date:     "2022-05-15",
+				Months: []ForecastMonth{
+					{Month: "2022-06", Balance: "3743.55", Low: "3643.01", High: "3844.08"},
+					{Month: "2022-07", Balance: "4343.55", Low: "4214.03", High: "4473.06"},
+					{Month: "2022-08", Balance: "4943.55", Low: "4790.44", High: "5096.65"},
+				},
+			},
+		},
+		{
+			name:    "balance later than the history",
+			history: history,
+			balance: money.FromInt(3350),
+			date:    time.Date(2022, time.June, 30, 12, 0, 0, 0, time.UTC),
+			expected: Forecast{
+				Currency: "USD",
+				Balance:  "3350.00",
+				Date:     "2022-06-30",
+				Months: []ForecastMonth{
+					{Month: "2022-07", Balance: "4020.00", Low: "3848.24", High: "4191.76"},
+					{Month: "2022-08", Balance: "4690.00", Low: "4447.10", High: "4932.90"},
+					{Month: "2022-09", Balance: "5360.00", Low: "5062.51", High: "5657.49"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			movements, err := findRecurring(sliceHistory(test.history), time.UTC)
+			require.Nil(t, err)
+
+			forecast, err := forecastBalances(sliceHistory(test.history), movements,
+				fxRates{}, "USD", time.UTC, test.balance, test.date, defaultForecastMonths)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, forecast)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package summarizer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"transaction-tool-api/src/internal/money"
+)
+
+const fxDateLayout = "2006-01-02"
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+func parseCurrency(value string) (string, error) {
+	currency := strings.ToUpper(strings.TrimSpace(value))
+	if !currencyPattern.MatchString(currency) {
+		return "", fmt.Errorf("currency '%s' is not an ISO 4217 code", value)
+	}
+	return currency, nil
+}
+
+type fxRate struct {
+	baseCurrency  string
+	quoteCurrency string
+	rate          money.Rate
+	effectiveDate time.Time
+}
+
+type fxRates struct {
+	byPair map[string][]fxRate
+}
+
+func fxPair(baseCurrency, quoteCurrency string) string {
+	return baseCurrency + "/" + quoteCurrency
+}
+
+func newFXRates(rates []fxRate) fxRates {
+	byPair := make(map[string][]fxRate)
+	for _, rate := range rates {
+		pair := fxPair(rate.baseCurrency, rate.quoteCurrency)
+		byPair[pair] = append(byPair[pair], rate)
+	}
+
+	for _, pairRates := range byPair {
+		sort.Slice(pairRates, func(i, j int) bool {
+			return pairRates[i].effectiveDate.Before(pairRates[j].effectiveDate)
+		})
+	}
+
+	return fxRates{byPair: byPair}
+}
+
+// loaded tells whether the rates were loaded, even if there were none.
+func (r fxRates) loaded() bool {
+	return r.byPair != nil
+}
+
+// effectiveRate returns the latest rate effective on the day of the date, as read in its own location. Dates of
+// transactions must be in the summary location already, so that an instant gets the same rate whatever the
+// offset it was written with.
+func (r fxRates) effectiveRate(baseCurrency, quoteCurrency string, date time.Time) (fxRate, bool) {
+	var (
+		pairRates = r.byPair[fxPair(baseCurrency, quoteCurrency)]
+		day       = civilDate(date)
+		i         = sort.Search(len(pairRates), func(i int) bool {
+			return pairRates[i].effectiveDate.After(day)
+		})
+	)
+
+	if i == 0 {
+		return fxRate{}, false
+	}
+	return pairRates[i-1], true
+}
+
+func (r fxRates) convert(amount money.Amount, from, to string, date time.Time) (money.Amount, error) {
+	if from == to {
+		return amount, nil
+	}
+
+	if rate, ok := r.effectiveRate(from, to, date); ok {
+		return amount.Mul(rate.rate), nil
+	}
+
+	if rate, ok := r.effectiveRate(to, from, date); ok {
+		return amount.Div(rate.rate)
+	}
+
+	return 0, fmt.Errorf(
+		"there is no fx rate from %s to %s effective on %s", from, to, date.Format(fxDateLayout))
+}
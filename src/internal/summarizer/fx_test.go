@@ -0,0 +1,93 @@
+package summarizer
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"transaction-tool-api/src/internal/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFXRatesConvert(t *testing.T) {
+	rates := newFXRates([]fxRate{
+		{
+			baseCurrency:  "USD",
+			quoteCurrency: "MXN",
+			rate:          money.MustParseRate("20"),
+			effectiveDate: time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			baseCurrency:  "USD",
+			quoteCurrency: "MXN",
+			rate:          money.MustParseRate("17"),
+			effectiveDate: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+	})
+
+	tests := []struct {
+		name        string
+		amount      money.Amount
+		from        string
+		to          string
+		date        time.Time
+		expected    money.Amount
+		expectedErr error
+	}{
+		{
+			name:     "same currency",
+			amount:   money.FromInt(10),
+			from:     "USD",
+			to:       "USD",
+			expected: money.FromInt(10),
+		},
+		{
+			name:     "direct rate effective on the same day",
+			amount:   money.FromInt(10),
+			from:     "USD",
+			to:       "MXN",
+			date:     time.Date(2021, time.March, 1, 23, 59, 0, 0, time.UTC),
+			expected: money.FromInt(200),
+		},
+		{
+			name:     "direct rate effective before",
+			amount:   money.FromInt(10),
+			from:     "USD",
+			to:       "MXN",
+			date:     time.Date(2021, time.February, 28, 0, 0, 0, 0, time.UTC),
+			expected: money.FromInt(170),
+		},
+		{
+			name:     "inverse rate",
+			amount:   money.FromInt(340),
+			from:     "MXN",
+			to:       "USD",
+			date:     time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC),
+			expected: money.FromInt(20),
+		},
+		{
+			name:        "no rate effective yet",
+			amount:      money.FromInt(10),
+			from:        "USD",
+			to:          "MXN",
+			date:        time.Date(2020, time.December, 31, 0, 0, 0, 0, time.UTC),
+			expectedErr: errors.New("there is no fx rate from USD to MXN effective on 2020-12-31"),
+		},
+		{
+			name:        "unknown pair",
+			amount:      money.FromInt(10),
+			from:        "EUR",
+			to:          "MXN",
+			date:        time.Date(2021, time.December, 31, 0, 0, 0, 0, time.UTC),
+			expectedErr: errors.New("there is no fx rate from EUR to MXN effective on 2021-12-31"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			amount, err := rates.convert(test.amount, test.from, test.to, test.date)
+			assert.Equal(t, test.expected, amount)
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package summarizer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+)
+
+type ImportBatchStatus string
+
+const (
+	ImportBatchStatusImported   ImportBatchStatus = "imported"
+	ImportBatchStatusRolledBack ImportBatchStatus = "rolled_back"
+)
+
+// ImportBatch groups the transactions saved from a single upload, so that they can be rolled back together.
+type ImportBatch struct {
+	ID          int64
+	UserID      int64
+	Filename    string
+	Checksum    string
+	RowCount    int
+	Status      ImportBatchStatus
+	DateCreated time.Time
+}
+
+type SavedTransaction struct {
+	ID          int64
+	Amount      string
+	Currency    string
+	Description string
+	Reference   string
+	Category    string `json:",omitempty"`
+	Balance     string `json:",omitempty"`
+	Date        time.Time
+}
+
+type batchRolledBackError struct {
+	batchID int64
+}
+
+func (e batchRolledBackError) Error() string {
+	return fmt.Sprintf("import batch id %d was already rolled back", e.batchID)
+}
+
+// laterBatchError is returned when rolling back a batch imported before another one of the user, whose
+// running balances were computed from the transactions of the former.
+type laterBatchError struct {
+	batchID      int64
+	laterBatchID int64
+}
+
+func (e laterBatchError) Error() string {
+	return fmt.Sprintf(
+		"import batch id %d cannot be rolled back before import batch id %d, which was imported after it",
+		e.batchID, e.laterBatchID)
+}
+
+// checksumReader hashes the bytes of an upload as they are read.
+type checksumReader struct {
+	reader io.Reader
+	hash   hash.Hash
+}
+
+func newChecksumReader(r io.Reader) *checksumReader {
+	checksum := &checksumReader{hash: sha256.New()}
+	checksum.reader = io.TeeReader(r, checksum.hash)
+	return checksum
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	return r.reader.Read(p)
+}
+
+func (r *checksumReader) sum() string {
+	return hex.EncodeToString(r.hash.Sum(nil))
+}
@@ -0,0 +1,137 @@
+package summarizer
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ImportProfile struct {
+	Name              string
+	AmountColumn      string
+	DateColumn        string
+	CurrencyColumn    string
+	DescriptionColumn string
+	ReferenceColumn   string
+}
+
+func (p ImportProfile) mapping() columnMapping {
+	return columnMapping{
+		Amount:      p.AmountColumn,
+		Date:        p.DateColumn,
+		Currency:    p.CurrencyColumn,
+		Description: p.DescriptionColumn,
+		Reference:   p.ReferenceColumn,
+	}
+}
+
+type columnMapping struct {
+	Amount      string
+	Date        string
+	Currency    string
+	Description string
+	Reference   string
+}
+
+var defaultColumnMapping = columnMapping{
+	Amount:      "amount",
+	Date:        "date",
+	Currency:    "currency",
+	Description: "description",
+	Reference:   "reference",
+}
+
+func (m columnMapping) isEmpty() bool {
+	return m == columnMapping{}
+}
+
+func (m columnMapping) override(other columnMapping) columnMapping {
+	if other.Amount != "" {
+		m.Amount = other.Amount
+	}
+	if other.Date != "" {
+		m.Date = other.Date
+	}
+	if other.Currency != "" {
+		m.Currency = other.Currency
+	}
+	if other.Description != "" {
+		m.Description = other.Description
+	}
+	if other.Reference != "" {
+		m.Reference = other.Reference
+	}
+	return m
+}
+
+type columnIndexes struct {
+	amount      int
+	date        int
+	currency    int
+	description int
+	reference   int
+}
+
+func normalizeColumnName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func isDefaultHeader(row []string) bool {
+	var hasAmount, hasDate bool
+	for _, name := range row {
+		switch normalizeColumnName(name) {
+		case defaultColumnMapping.Amount:
+			hasAmount = true
+		case defaultColumnMapping.Date:
+			hasDate = true
+		}
+	}
+	return hasAmount && hasDate
+}
+
+func (m columnMapping) indexes(header []string, strict bool) (columnIndexes, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		if _, ok := positions[normalizeColumnName(name)]; !ok {
+			positions[normalizeColumnName(name)] = i
+		}
+	}
+
+	find := func(name string, mandatory bool) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		if position, ok := positions[normalizeColumnName(name)]; ok {
+			return position, nil
+		}
+		if mandatory {
+			return -1, fmt.Errorf("column '%s' not found in csv header", name)
+		}
+		return -1, nil
+	}
+
+	var (
+		indexes columnIndexes
+		err     error
+	)
+
+	if m.Amount == "" || m.Date == "" {
+		return columnIndexes{}, fmt.Errorf("amount and date columns are required")
+	}
+	if indexes.amount, err = find(m.Amount, true); err != nil {
+		return columnIndexes{}, err
+	}
+	if indexes.date, err = find(m.Date, true); err != nil {
+		return columnIndexes{}, err
+	}
+	if indexes.currency, err = find(m.Currency, strict); err != nil {
+		return columnIndexes{}, err
+	}
+	if indexes.description, err = find(m.Description, strict); err != nil {
+		return columnIndexes{}, err
+	}
+	if indexes.reference, err = find(m.Reference, strict); err != nil {
+		return columnIndexes{}, err
+	}
+
+	return indexes, nil
+}
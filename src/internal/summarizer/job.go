@@ -0,0 +1,68 @@
+package summarizer
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"
+	JobStatusParsing   JobStatus = "parsing"
+	JobStatusSaving    JobStatus = "saving"
+	JobStatusNotifying JobStatus = "notifying"
+	JobStatusDone      JobStatus = "done"
+	JobStatusFailed    JobStatus = "failed"
+)
+
+type Job struct {
+	ID           int64
+	UserID       int64
+	Status       JobStatus
+	Error        string
+	Result       *ImportResult
+	DateCreated  time.Time
+	DateUpdated  time.Time
+	DateStarted  *time.Time
+	DateFinished *time.Time
+	payload      []byte
+	options      resumeOptions
+}
+
+type progressKey struct{}
+
+type progressFunc func(ctx context.Context, status JobStatus)
+
+func withProgress(ctx context.Context, fn progressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func reportProgress(ctx context.Context, status JobStatus) {
+	if fn, ok := ctx.Value(progressKey{}).(progressFunc); ok {
+		fn(ctx, status)
+	}
+}
+
+type jobKey struct{}
+
+// withJob tells the service the transactions are imported by the job, so that it records the result of the
+// import along with it.
+func withJob(ctx context.Context, jobID int64) context.Context {
+	return context.WithValue(ctx, jobKey{}, jobID)
+}
+
+func jobFromContext(ctx context.Context) (int64, bool) {
+	jobID, ok := ctx.Value(jobKey{}).(int64)
+	return jobID, ok
+}
+
+// jobImportedError is returned when the transactions of a job were already imported by another run of it.
+type jobImportedError struct {
+	jobID int64
+}
+
+func (e jobImportedError) Error() string {
+	return fmt.Sprintf("transactions of job id %d were already imported", e.jobID)
+}
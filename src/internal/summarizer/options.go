@@ -0,0 +1,73 @@
+package summarizer
+
+import (
+	"time"
+	"transaction-tool-api/src/internal/money"
+)
+
+type validationMode string
+
+const (
+	validationFailFast validationMode = "fail_fast"
+	validationFull     validationMode = "full"
+
+	defaultMaxRowErrors = 100
+	maxRowErrorsLimit   = 1000
+)
+
+// duplicatePolicy decides what happens to the transactions that were already saved for the user by an earlier
+// upload. Identical transactions within the same file are not duplicates, as a statement can list the same
+// movement twice, such as two equal payments on the same day.
+type duplicatePolicy string
+
+const (
+	duplicatesReject duplicatePolicy = "reject"
+	duplicatesSkip   duplicatePolicy = "skip"
+	duplicatesAllow  duplicatePolicy = "allow"
+)
+
+// previousBalance is the opening balance value that derives it from the transactions already saved for the user.
+const previousBalance = "previous"
+
+// yearGrouping decides how the transactions of a file spanning several fiscal years are summarized:
+// in a single resume with a section by year, or in a resume by year.
+type yearGrouping string
+
+const (
+	yearsCombined yearGrouping = "combined"
+	yearsSeparate yearGrouping = "separate"
+)
+
+type resumeOptions struct {
+	Mapping         columnMapping
+	Validation      validationMode  `json:",omitempty"`
+	MaxErrors       int             `json:",omitempty"`
+	Duplicates      duplicatePolicy `json:",omitempty"`
+	Filename        string          `json:",omitempty"`
+	FiscalYearStart time.Month      `json:",omitempty"`
+	Years           yearGrouping    `json:",omitempty"`
+	OpeningBalance  *money.Amount   `json:",omitempty"`
+	PreviousBalance bool            `json:",omitempty"`
+	ForecastMonths  int             `json:",omitempty"`
+}
+
+func (o resumeOptions) maxErrors() int {
+	if o.MaxErrors <= 0 {
+		return defaultMaxRowErrors
+	}
+	return o.MaxErrors
+}
+
+func (o resumeOptions) forecastMonths() int {
+	if o.ForecastMonths <= 0 {
+		return defaultForecastMonths
+	}
+	return o.ForecastMonths
+}
+
+func (o resumeOptions) fiscalYearStart() time.Month {
+	if o.FiscalYearStart == 0 {
+		return time.January
+	}
+	return o.FiscalYearStart
+}
@@ -0,0 +1,27 @@
+package summarizer
+
+import "transaction-tool-api/src/internal/notifier"
+
+type outboxStatus string
+
+// Outbox messages are pending until a dispatcher claims them as sending for a lease, in which they are sent
+// outside of any database transaction. Messages which could not be sent are pending again after a backoff,
+// until they failed every attempt, and the ones whose lease expired, as their dispatcher stopped, are claimed
+// again.
+const (
+	outboxStatusPending outboxStatus = "pending"
+	outboxStatusSending outboxStatus = "sending"
+	outboxStatusSent    outboxStatus = "sent"
+	outboxStatusFailed  outboxStatus = "failed"
+)
+
+type outboxMessage struct {
+	id          int64
+	userID      int64
+	email       string
+	message     string
+	text        string
+	attachments []notifier.Attachment
+	status      outboxStatus
+	attempts    int
+}
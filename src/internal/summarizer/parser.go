@@ -0,0 +1,332 @@
+package summarizer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"transaction-tool-api/src/internal/money"
+	"unicode/utf8"
+)
+
+type parser struct {
+}
+
+const (
+	maxDescriptionLength = 255
+	maxReferenceLength   = 100
+)
+
+// invalidCSVError wraps the errors caused by the content of an uploaded file, as opposed to
+// the ones found while saving or summarizing its transactions.
+type invalidCSVError struct {
+	err error
+}
+
+func (e invalidCSVError) Error() string {
+	return e.err.Error()
+}
+
+func (e invalidCSVError) Unwrap() error {
+	return e.err
+}
+
+type recordReader interface {
+	Read() ([]string, error)
+}
+
+// newCSVReader returns a reader which lets the parser validate the number of elements by row
+// and reuses the record slice, so that memory does not grow with the file size.
+func newCSVReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	return reader
+}
+
+// transactionReader parses the records of a csv file into transactions as they are read.
+type transactionReader struct {
+	parser    parser
+	records   recordReader
+	userID    int64
+	options   resumeOptions
+	header    []string
+	indexes   columnIndexes
+	rowNumber int
+	read      int
+	collected rowErrors
+	checksum  *checksumReader
+}
+
+func (p parser) newTransactionReader(records recordReader, userID int64, options resumeOptions) *transactionReader {
+	return &transactionReader{
+		parser:    p,
+		records:   records,
+		userID:    userID,
+		options:   options,
+		indexes:   columnIndexes{amount: 0, date: 1, currency: 2, description: -1, reference: -1},
+		collected: rowErrors{max: options.maxErrors()},
+	}
+}
+
+// newUploadReader returns a transaction reader of an uploaded csv body which computes its checksum
+// along the way.
+func (p parser) newUploadReader(body io.Reader, userID int64, options resumeOptions) *transactionReader {
+	checksum := newChecksumReader(body)
+	reader := p.newTransactionReader(newCSVReader(checksum), userID, options)
+	reader.checksum = checksum
+	return reader
+}
+
+func (r *transactionReader) getUserID() int64 {
+	return r.userID
+}
+
+// getChecksum returns the checksum of the bytes read so far, which is the one of the whole upload
+// once next returned false.
+func (r *transactionReader) getChecksum() string {
+	if r.checksum == nil {
+		return ""
+	}
+	return r.checksum.sum()
+}
+
+// next returns the next valid transaction. Once the file is over, it returns false and, when the
+// whole file was validated, the errors collected along the way.
+func (r *transactionReader) next() (transaction, bool, error) {
+	for {
+		row, err := r.records.Read()
+		if errors.Is(err, io.EOF) {
+			return transaction{}, false, r.finish()
+		}
+		if err != nil {
+			return transaction{}, false, invalidCSVError{fmt.Errorf("error reading csv body due to: %w", err)}
+		}
+
+		r.rowNumber++
+
+		if r.rowNumber == 1 {
+			isHeader, err := r.readHeader(row)
+			if err != nil {
+				return transaction{}, false, invalidCSVError{err}
+			}
+			if isHeader {
+				continue
+			}
+		}
+
+		txn, rowErrs := r.parseRecord(row)
+		if len(rowErrs) > 0 {
+			if r.options.Validation != validationFull {
+				return transaction{}, false, invalidCSVError{rowErrs[0].err()}
+			}
+			r.collected.add(rowErrs...)
+			continue
+		}
+
+		r.read++
+		return txn, true, nil
+	}
+}
+
+func (r *transactionReader) finish() error {
+	if r.collected.total > 0 {
+		return invalidCSVError{r.collected}
+	}
+	if r.read == 0 {
+		return invalidCSVError{errors.New("csv body is empty")}
+	}
+	return nil
+}
+
+func (r *transactionReader) readHeader(row []string) (bool, error) {
+	mapping := r.options.Mapping
+	if mapping.isEmpty() && !isDefaultHeader(row) {
+		return false, nil
+	}
+
+	strict := !mapping.isEmpty()
+	if mapping.isEmpty() {
+		mapping = defaultColumnMapping
+	}
+
+	indexes, err := mapping.indexes(row, strict)
+	if err != nil {
+		return false, err
+	}
+
+	r.indexes = indexes
+	r.header = append([]string(nil), row...)
+
+	return true, nil
+}
+
+func (r *transactionReader) parseRecord(row []string) (transaction, []RowError) {
+	var (
+		minElementsByRow = 2
+		maxElementsByRow = 3
+	)
+
+	if r.header != nil {
+		if len(row) != len(r.header) {
+			return transaction{}, []RowError{{
+				Row:  r.rowNumber,
+				Code: rowErrorInvalidLength,
+				Message: fmt.Sprintf(
+					"for row number %d is expected %d elements, however got %d", r.rowNumber, len(r.header), len(row)),
+			}}
+		}
+	} else if len(row) < minElementsByRow || len(row) > maxElementsByRow {
+		return transaction{}, []RowError{{
+			Row:  r.rowNumber,
+			Code: rowErrorInvalidLength,
+			Message: fmt.Sprintf(
+				"for row number %d is expected %d or %d elements, however got %d",
+				r.rowNumber, minElementsByRow, maxElementsByRow, len(row)),
+		}}
+	}
+
+	return r.parser.parseRow(row, r.indexes, r.rowNumber)
+}
+
+func cell(row []string, index int) string {
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	return row[index]
+}
+
+// parseRow returns every error found in the row, so that a whole file validation can
+// report all of them at once.
+func (p parser) parseRow(row []string, indexes columnIndexes, rowNumber int) (transaction, []RowError) {
+	var (
+		txn     transaction
+		rowErrs []RowError
+		err     error
+	)
+
+	amountStr := cell(row, indexes.amount)
+	if txn.amount, err = money.Parse(amountStr); err != nil {
+		rowErrs = append(rowErrs, RowError{
+			Row:     rowNumber,
+			Column:  rowErrorColumnAmount,
+			Value:   amountStr,
+			Code:    rowErrorInvalidAmount,
+			Message: fmt.Sprintf("error parsing decimal amount (%s) from row number %d", amountStr, rowNumber),
+		})
+	} else if txn.amount == 0 {
+		rowErrs = append(rowErrs, RowError{
+			Row:     rowNumber,
+			Column:  rowErrorColumnAmount,
+			Value:   amountStr,
+			Code:    rowErrorZeroAmount,
+			Message: fmt.Sprintf("for row number %d, transaction amount is zero", rowNumber),
+		})
+	}
+
+	dateStr := cell(row, indexes.date)
+	if txn.date, err = time.Parse(time.RFC3339, dateStr); err != nil {
+		rowErrs = append(rowErrs, RowError{
+			Row:    rowNumber,
+			Column: rowErrorColumnDate,
+			Value:  dateStr,
+			Code:   rowErrorInvalidDate,
+			Message: fmt.Sprintf(
+				"error parsing date '%s' because of no compliance with RFC3339 layout for row number %d",
+				dateStr, rowNumber),
+		})
+	}
+
+	if currencyStr := cell(row, indexes.currency); currencyStr != "" {
+		if txn.currency, err = parseCurrency(currencyStr); err != nil {
+			rowErrs = append(rowErrs, RowError{
+				Row:     rowNumber,
+				Column:  rowErrorColumnCurrency,
+				Value:   currencyStr,
+				Code:    rowErrorInvalidCurrency,
+				Message: fmt.Sprintf("for row number %d, %s", rowNumber, err.Error()),
+			})
+		}
+	}
+
+	txn.description = strings.TrimSpace(cell(row, indexes.description))
+	if utf8.RuneCountInString(txn.description) > maxDescriptionLength {
+		rowErrs = append(rowErrs, RowError{
+			Row:    rowNumber,
+			Column: rowErrorColumnDescription,
+			Value:  txn.description,
+			Code:   rowErrorDescriptionTooLong,
+			Message: fmt.Sprintf(
+				"for row number %d, description is longer than %d characters", rowNumber, maxDescriptionLength),
+		})
+	}
+
+	txn.reference = strings.TrimSpace(cell(row, indexes.reference))
+	if utf8.RuneCountInString(txn.reference) > maxReferenceLength {
+		rowErrs = append(rowErrs, RowError{
+			Row:    rowNumber,
+			Column: rowErrorColumnReference,
+			Value:  txn.reference,
+			Code:   rowErrorReferenceTooLong,
+			Message: fmt.Sprintf(
+				"for row number %d, reference is longer than %d characters", rowNumber, maxReferenceLength),
+		})
+	}
+
+	if len(rowErrs) > 0 {
+		return transaction{}, rowErrs
+	}
+
+	return txn, nil
+}
+
+func (p parser) parseFileToFXRates(file [][]string) ([]fxRate, error) {
+	var (
+		totalElementsByRow = 4
+		rates              = make([]fxRate, 0, len(file))
+	)
+
+	for i, row := range file {
+		if len(row) != totalElementsByRow {
+			return nil, fmt.Errorf(
+				"for row number %d is expected %d elements, however got %d", i+1, totalElementsByRow, len(row))
+		}
+
+		baseCurrency, err := parseCurrency(row[0])
+		if err != nil {
+			return nil, fmt.Errorf("for row number %d, base %w", i+1, err)
+		}
+
+		quoteCurrency, err := parseCurrency(row[1])
+		if err != nil {
+			return nil, fmt.Errorf("for row number %d, quote %w", i+1, err)
+		}
+
+		if baseCurrency == quoteCurrency {
+			return nil, fmt.Errorf("for row number %d, base and quote currencies are the same", i+1)
+		}
+
+		rate, err := money.ParseRate(row[2])
+		if err != nil {
+			return nil, fmt.Errorf("for row number %d, %w", i+1, err)
+		}
+
+		effectiveDate, err := time.Parse(fxDateLayout, row[3])
+		if err != nil {
+			return nil, fmt.Errorf(
+				"error parsing effective date '%s' because of no compliance with %s layout for row number %d",
+				row[3], fxDateLayout, i+1)
+		}
+
+		rates = append(rates, fxRate{
+			baseCurrency:  baseCurrency,
+			quoteCurrency: quoteCurrency,
+			rate:          rate,
+			effectiveDate: effectiveDate,
+		})
+	}
+
+	return rates, nil
+}
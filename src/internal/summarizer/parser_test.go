@@ -0,0 +1,350 @@
+package summarizer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+	"transaction-tool-api/src/internal/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fileRecords struct {
+	file     [][]string
+	position int
+}
+
+func (r *fileRecords) Read() ([]string, error) {
+	if r.position >= len(r.file) {
+		return nil, io.EOF
+	}
+	r.position++
+	return r.file[r.position-1], nil
+}
+
+func TestParserTransactionReader(t *testing.T) {
+	var (
+		date = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+
+		bankTransactions = transactions{
+			items: []transaction{
+				{
+					amount: money.MustParse("-10.5"),
+					date:   date,
+				},
+				{
+					amount: money.FromInt(15),
+					date:   date.Add(time.Hour),
+				},
+			},
+			userID: 5,
+		}
+	)
+
+	tests := []struct {
+		name           string
+		transactions   [][]string
+		options        resumeOptions
+		expectedResult transactions
+		expectedErr    error
+	}{
+		{
+			name:         "no transactions",
+			transactions: nil,
+			expectedErr:  errors.New("csv body is empty"),
+		},
+		{
+			name: "only header",
+			transactions: [][]string{
+				{"amount", "date"},
+			},
+			expectedErr: errors.New("csv body is empty"),
+		},
+		{
+			name: "elements by row unexpected",
+			transactions: [][]string{
+				{""},
+			},
+			expectedErr: fmt.Errorf(
+				"for row number %d is expected %d or %d elements, however got %d", 1, 2, 3, 1),
+		},
+		{
+			name: "amount bad format",
+			transactions: [][]string{
+				{"bad format", ""},
+			},
+			expectedErr: fmt.Errorf(
+				"error parsing decimal amount (%s) from row number %d", "bad format", 1),
+		},
+		{
+			name: "amount zero no valid",
+			transactions: [][]string{
+				{"0", ""},
+			},
+			expectedErr: fmt.Errorf(
+				"for row number %d, transaction amount is zero", 1),
+		},
+		{
+			name: "date bad format",
+			transactions: [][]string{
+				{"-10.5", "bad format"},
+			},
+			expectedErr: fmt.Errorf(
+				"error parsing date '%s' because of no compliance with RFC3339 layout for row number %d",
+				"bad format", 1),
+		},
+		{
+			name: "transactions belong to different years",
+			transactions: [][]string{
+				{"-10.5", date.Format(time.RFC3339)},
+				{"15", date.Add(8760 * time.Hour).Format(time.RFC3339)},
+			},
+			expectedResult: transactions{
+				items: []transaction{
+					{amount: money.MustParse("-10.5"), date: date},
+					{amount: money.FromInt(15), date: date.Add(8760 * time.Hour)},
+				},
+				userID: 5,
+			},
+		},
+		{
+			name: "currency bad format",
+			transactions: [][]string{
+				{"-10.5", date.Format(time.RFC3339), "dollars"},
+			},
+			expectedErr: fmt.Errorf(
+				"for row number %d, %s", 1, "currency 'dollars' is not an ISO 4217 code"),
+		},
+		{
+			name: "full validation collects every row error",
+			transactions: [][]string{
+				{"-10.5", date.Format(time.RFC3339)},
+				{"0", "yesterday", "dollars"},
+				{"15"},
+			},
+			options: resumeOptions{Validation: validationFull},
+			expectedErr: rowErrors{
+				items: []RowError{
+					{
+						Row:     2,
+						Column:  "amount",
+						Value:   "0",
+						Code:    "zero_amount",
+						Message: "for row number 2, transaction amount is zero",
+					},
+					{
+						Row:    2,
+						Column: "date",
+						Value:  "yesterday",
+						Code:   "invalid_date",
+						Message: "error parsing date 'yesterday' because of no compliance with RFC3339 layout " +
+							"for row number 2",
+					},
+					{
+						Row:     2,
+						Column:  "currency",
+						Value:   "dollars",
+						Code:    "invalid_currency",
+						Message: "for row number 2, currency 'dollars' is not an ISO 4217 code",
+					},
+					{
+						Row:     3,
+						Code:    "invalid_length",
+						Message: "for row number 3 is expected 2 or 3 elements, however got 1",
+					},
+				},
+				total: 4,
+				max:   defaultMaxRowErrors,
+			},
+		},
+		{
+			name: "full validation reports errors up to the cap",
+			transactions: [][]string{
+				{"bad", date.Format(time.RFC3339)},
+				{"0", date.Format(time.RFC3339)},
+				{"bad", date.Format(time.RFC3339)},
+			},
+			options: resumeOptions{Validation: validationFull, MaxErrors: 1},
+			expectedErr: rowErrors{
+				items: []RowError{
+					{
+						Row:     1,
+						Column:  "amount",
+						Value:   "bad",
+						Code:    "invalid_amount",
+						Message: "error parsing decimal amount (bad) from row number 1",
+					},
+				},
+				total: 3,
+				max:   1,
+			},
+		},
+		{
+			name: "successfully parsing with currency",
+			transactions: [][]string{
+				{"-10.5", date.Format(time.RFC3339), "mxn"},
+				{"15", date.Add(time.Hour).Format(time.RFC3339), ""},
+			},
+			expectedResult: transactions{
+				items: []transaction{
+					{amount: money.MustParse("-10.5"), date: date, currency: "MXN"},
+					{amount: money.FromInt(15), date: date.Add(time.Hour)},
+				},
+				userID: 5,
+			},
+		},
+		{
+			name: "default header detected",
+			transactions: [][]string{
+				{"Date", "Balance", "Amount", "Description"},
+				{date.Format(time.RFC3339), "100", "-10.5", " coffee "},
+				{date.Add(time.Hour).Format(time.RFC3339), "115", "15", ""},
+			},
+			expectedResult: transactions{
+				items: []transaction{
+					{amount: money.MustParse("-10.5"), date: date, description: "coffee"},
+					{amount: money.FromInt(15), date: date.Add(time.Hour)},
+				},
+				userID: 5,
+			},
+		},
+		{
+			name: "mapped columns with unknown columns ignored",
+			transactions: [][]string{
+				{"Fecha", "Monto", "Moneda", "Folio", "Sucursal"},
+				{date.Format(time.RFC3339), "-10.5", "MXN", "A-1", "centro"},
+			},
+			options: resumeOptions{
+				Mapping: columnMapping{Amount: "monto", Date: "FECHA", Currency: "Moneda", Reference: "Folio"},
+			},
+			expectedResult: transactions{
+				items: []transaction{
+					{amount: money.MustParse("-10.5"), date: date, currency: "MXN", reference: "A-1"},
+				},
+				userID: 5,
+			},
+		},
+		{
+			name: "mapped column not found",
+			transactions: [][]string{
+				{"Fecha", "Monto"},
+			},
+			options:     resumeOptions{Mapping: columnMapping{Amount: "Monto", Date: "Fecha", Description: "Concepto"}},
+			expectedErr: errors.New("column 'Concepto' not found in csv header"),
+		},
+		{
+			name: "row with fewer elements than header",
+			transactions: [][]string{
+				{"amount", "date", "reference"},
+				{"-10.5", date.Format(time.RFC3339)},
+			},
+			expectedErr: fmt.Errorf("for row number %d is expected %d elements, however got %d", 2, 3, 2),
+		},
+		{
+			name: "reference too long",
+			transactions: [][]string{
+				{"amount", "date", "reference"},
+				{"-10.5", date.Format(time.RFC3339), strings.Repeat("x", 101)},
+			},
+			expectedErr: fmt.Errorf("for row number %d, reference is longer than %d characters", 2, 100),
+		},
+		{
+			name: "successfully parsing",
+			transactions: [][]string{
+				{"-10.5", date.Format(time.RFC3339)},
+				{"15", date.Add(time.Hour).Format(time.RFC3339)},
+			},
+			expectedResult: bankTransactions,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := parser{}.newTransactionReader(&fileRecords{file: test.transactions}, 5, test.options)
+
+			result, err := readAll(reader)
+
+			assert.Equal(t, test.expectedResult, result)
+			assert.Equal(t, test.expectedErr, errors.Unwrap(err))
+		})
+	}
+}
+
+func TestParserUploadReaderChecksum(t *testing.T) {
+	var (
+		body     = "amount,date\n10,2021-10-01T00:00:00Z\n"
+		checksum = sha256.Sum256([]byte(body))
+		reader   = parser{}.newUploadReader(strings.NewReader(body), 5, resumeOptions{})
+	)
+
+	_, err := readAll(reader)
+
+	require.Nil(t, err)
+	assert.Equal(t, hex.EncodeToString(checksum[:]), reader.getChecksum())
+}
+
+func TestParserParseFileToFXRates(t *testing.T) {
+	date := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		rates          [][]string
+		expectedResult []fxRate
+		expectedErr    error
+	}{
+		{
+			name:           "no rates",
+			expectedResult: []fxRate{},
+		},
+		{
+			name:        "elements by row unexpected",
+			rates:       [][]string{{"USD", "MXN", "20"}},
+			expectedErr: fmt.Errorf("for row number %d is expected %d elements, however got %d", 1, 4, 3),
+		},
+		{
+			name:  "base currency bad format",
+			rates: [][]string{{"US", "MXN", "20", "2021-10-01"}},
+			expectedErr: fmt.Errorf(
+				"for row number %d, base %w", 1, errors.New("currency 'US' is not an ISO 4217 code")),
+		},
+		{
+			name:        "same currencies",
+			rates:       [][]string{{"USD", "usd", "20", "2021-10-01"}},
+			expectedErr: fmt.Errorf("for row number %d, base and quote currencies are the same", 1),
+		},
+		{
+			name:        "rate not positive",
+			rates:       [][]string{{"USD", "MXN", "-20", "2021-10-01"}},
+			expectedErr: fmt.Errorf("for row number %d, %w", 1, errors.New("rate '-20' must be positive")),
+		},
+		{
+			name:  "effective date bad format",
+			rates: [][]string{{"USD", "MXN", "20", "01/10/2021"}},
+			expectedErr: fmt.Errorf(
+				"error parsing effective date '%s' because of no compliance with %s layout for row number %d",
+				"01/10/2021", fxDateLayout, 1),
+		},
+		{
+			name:  "successfully parsing",
+			rates: [][]string{{"usd", "MXN", "20.05", "2021-10-01"}},
+			expectedResult: []fxRate{
+				{baseCurrency: "USD", quoteCurrency: "MXN", rate: money.MustParseRate("20.05"), effectiveDate: date},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := parser{}.parseFileToFXRates(test.rates)
+
+			assert.Equal(t, test.expectedResult, result)
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package summarizer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Pages are A4 in points, with their text inside the margins.
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 40
+)
+
+type pdfFont string
+
+// Fonts are the standard ones every PDF reader has, so they are not embedded. Courier is monospaced, which
+// keeps the columns of the tables aligned.
+const (
+	pdfRegular pdfFont = "F1"
+	pdfBold    pdfFont = "F2"
+	pdfMono    pdfFont = "F3"
+)
+
+var pdfFonts = []struct {
+	name     pdfFont
+	baseFont string
+}{
+	{name: pdfRegular, baseFont: "Helvetica"},
+	{name: pdfBold, baseFont: "Helvetica-Bold"},
+	{name: pdfMono, baseFont: "Courier"},
+}
+
+// pdfDocument writes lines of text from the top of the page down, starting a new page when the current one
+// is full. Each page is written out once full, so that documents of any length are written without holding
+// them in memory, which is why their number of pages has to be known beforehand to number them.
+type pdfDocument struct {
+	w       io.Writer
+	written int
+	offsets []int
+	pages   int
+	page    bytes.Buffer
+	number  int
+	y       float64
+	err     error
+}
+
+// newPDFDocument starts writing a document of the given number of pages to the writer.
+func newPDFDocument(w io.Writer, pages int) *pdfDocument {
+	d := &pdfDocument{w: w, pages: pages}
+
+	d.write("%s", "%PDF-1.4\n")
+
+	kids := make([]string, 0, pages)
+	for i := 0; i < pages; i++ {
+		kids = append(kids, fmt.Sprintf("%d 0 R", d.pageObject(i)))
+	}
+
+	d.object("<< /Type /Catalog /Pages 2 0 R >>")
+	d.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages))
+	for _, font := range pdfFonts {
+		d.object(fmt.Sprintf(
+			"<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", font.baseFont))
+	}
+
+	d.addPage()
+	return d
+}
+
+// pdfPageCount returns the number of pages of the document the function writes.
+func pdfPageCount(write func(*pdfDocument)) int {
+	d := newPDFDocument(io.Discard, 0)
+	write(d)
+	return d.number
+}
+
+// pageObject returns the number of the object of the page, which is followed by the one of its content. The
+// catalog, the page tree and the fonts come before the pages.
+func (d *pdfDocument) pageObject(page int) int {
+	return 3 + len(pdfFonts) + 2*page
+}
+
+func (d *pdfDocument) write(format string, args ...any) {
+	if d.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(d.w, format, args...)
+	d.written += n
+	d.err = err
+}
+
+func (d *pdfDocument) object(content string) {
+	d.offsets = append(d.offsets, d.written)
+	d.write("%d 0 obj\n%s\nendobj\n", len(d.offsets), content)
+}
+
+func (d *pdfDocument) addPage() {
+	if d.number > 0 {
+		d.writePage()
+	}
+	d.number++
+	d.page.Reset()
+	d.y = pdfPageHeight - pdfMargin
+}
+
+// writePage writes the current page, numbered at its bottom.
+func (d *pdfDocument) writePage() {
+	d.text(&d.page, pdfRegular, 8, pdfMargin, pdfMargin/2, fmt.Sprintf("Page %d of %d", d.number, d.pages))
+
+	fonts := make([]string, 0, len(pdfFonts))
+	for i, font := range pdfFonts {
+		fonts = append(fonts, fmt.Sprintf("/%s %d 0 R", font.name, 3+i))
+	}
+
+	d.object(fmt.Sprintf(
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+		pdfPageWidth, pdfPageHeight, strings.Join(fonts, " "), d.pageObject(d.number-1)+1))
+	d.object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", d.page.Len(), d.page.String()))
+}
+
+// line writes the text in a new line of the given font and size, indented from the left margin.
+func (d *pdfDocument) line(font pdfFont, size float64, indent float64, text string) {
+	leading := size * 1.4
+	if d.y-leading < pdfMargin {
+		d.addPage()
+	}
+	d.y -= leading
+	d.text(&d.page, font, size, pdfMargin+indent, d.y, text)
+}
+
+// space leaves an empty line of the given height.
+func (d *pdfDocument) space(height float64) {
+	d.y -= height
+}
+
+func (d *pdfDocument) text(page *bytes.Buffer, font pdfFont, size, x, y float64, text string) {
+	fmt.Fprintf(page, "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, pdfNumber(size), pdfNumber(x), pdfNumber(y),
+		pdfString(text))
+}
+
+// close writes the last page and finishes the document, which fails when it has another number of pages than
+// the one it was started with.
+func (d *pdfDocument) close() error {
+	d.writePage()
+	if d.err != nil {
+		return fmt.Errorf("error writing pdf due to: %w", d.err)
+	}
+	if d.number != d.pages {
+		return fmt.Errorf("pdf has %d pages instead of the %d expected", d.number, d.pages)
+	}
+
+	xref := d.written
+	d.write("xref\n0 %d\n0000000000 65535 f \n", len(d.offsets)+1)
+	for _, offset := range d.offsets {
+		d.write("%010d 00000 n \n", offset)
+	}
+	d.write("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.offsets)+1, xref)
+
+	if d.err != nil {
+		return fmt.Errorf("error writing pdf due to: %w", d.err)
+	}
+	return nil
+}
+
+func pdfNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// pdfString encodes the text as a literal string of the WinAnsi encoding of the fonts, replacing the
+// characters it does not have with a question mark.
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < ' ':
+			b.WriteByte(' ')
+		case r < 0x7f || (r >= 0xa0 && r <= 0xff):
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// pdfColumn is a column of a table written in the monospaced font, as wide as the given number of characters.
+type pdfColumn struct {
+	width int
+	right bool
+}
+
+// pdfRow lays the values out in the columns, cutting the ones too long for them.
+func pdfRow(columns []pdfColumn, values ...string) string {
+	cells := make([]string, 0, len(columns))
+	for i, column := range columns {
+		value := []rune(values[i])
+		if len(value) > column.width {
+			value = value[:column.width]
+		}
+
+		padding := strings.Repeat(" ", column.width-len(value))
+		if column.right {
+			cells = append(cells, padding+string(value))
+		} else {
+			cells = append(cells, string(value)+padding)
+		}
+	}
+	return strings.TrimRight(strings.Join(cells, " "), " ")
+}
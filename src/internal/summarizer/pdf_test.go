@@ -0,0 +1,127 @@
+package summarizer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPDFDocument(t *testing.T) {
+	tests := []struct {
+		name          string
+		lines         int
+		expectedPages int
+	}{
+		{
+			name:          "empty document",
+			expectedPages: 1,
+		},
+		{
+			name:          "single page",
+			lines:         10,
+			expectedPages: 1,
+		},
+		{
+			name:          "lines over several pages",
+			lines:         150,
+			expectedPages: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				b     bytes.Buffer
+				lines = func(doc *pdfDocument) {
+					for i := 0; i < test.lines; i++ {
+						doc.line(pdfMono, 8, 0, "line")
+					}
+				}
+			)
+
+			doc := newPDFDocument(&b, pdfPageCount(lines))
+			lines(doc)
+			require.Nil(t, doc.close())
+
+			pdf := b.String()
+
+			assert.True(t, strings.HasPrefix(pdf, "%PDF-1.4\n"))
+			assert.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+			assert.Equal(t, test.expectedPages, strings.Count(pdf, "/Type /Page "))
+			assert.Equal(t, test.lines, strings.Count(pdf, "(line) Tj"))
+			assert.Contains(t, pdf, fmt.Sprintf("/Count %d >>", test.expectedPages))
+			assert.Contains(t, pdf, fmt.Sprintf("(Page 1 of %d) Tj", test.expectedPages))
+			assert.Contains(t, pdf, fmt.Sprintf("(Page %d of %d) Tj", test.expectedPages, test.expectedPages))
+		})
+	}
+}
+
+func TestPDFDocumentWithOtherPages(t *testing.T) {
+	doc := newPDFDocument(io.Discard, 2)
+	doc.line(pdfMono, 8, 0, "line")
+
+	assert.Equal(t, errors.New("pdf has 1 pages instead of the 2 expected"), doc.close())
+}
+
+func TestPDFString(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{
+			name:     "plain text",
+			text:     "Balance: 10.00",
+			expected: "Balance: 10.00",
+		},
+		{
+			name:     "escaped characters",
+			text:     `fee (bank) \ wire`,
+			expected: `fee \(bank\) \\ wire`,
+		},
+		{
+			name:     "characters out of the encoding",
+			text:     "café\tsushi 🍣",
+			expected: "caf\xe9 sushi ?",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, pdfString(test.text))
+		})
+	}
+}
+
+func TestPDFRow(t *testing.T) {
+	columns := []pdfColumn{{width: 5}, {width: 6, right: true}, {width: 4}}
+
+	tests := []struct {
+		name     string
+		values   []string
+		expected string
+	}{
+		{
+			name:     "padded values",
+			values:   []string{"ab", "1.00", "USD"},
+			expected: "ab      1.00 USD",
+		},
+		{
+			name:     "cut values",
+			values:   []string{"abcdefgh", "1000.00", ""},
+			expected: "abcde 1000.0",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, pdfRow(columns, test.values...))
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package summarizer
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"transaction-tool-api/src/internal/money"
+)
+
+// recurringAmountTolerance is the percentage the amounts of a recurring movement can differ from their average.
+const recurringAmountTolerance = 10
+
+type recurrence struct {
+	name string
+	// minDays and maxDays bound the days between two occurrences, so that months of different lengths,
+	// weekends and leap years do not break the recurrence.
+	minDays        int
+	maxDays        int
+	minOccurrences int
+	perYear        int64
+	next           func(time.Time) time.Time
+}
+
+var recurrences = []recurrence{
+	{
+		name:           "weekly",
+		minDays:        6,
+		maxDays:        8,
+		minOccurrences: 3,
+		perYear:        52,
+		next:           func(date time.Time) time.Time { return date.AddDate(0, 0, 7) },
+	},
+	{
+		name:           "monthly",
+		minDays:        27,
+		maxDays:        34,
+		minOccurrences: 3,
+		perYear:        12,
+		next:           func(date time.Time) time.Time { return date.AddDate(0, 1, 0) },
+	},
+	{
+		name:           "yearly",
+		minDays:        355,
+		maxDays:        375,
+		minOccurrences: 2,
+		perYear:        1,
+		next:           func(date time.Time) time.Time { return date.AddDate(1, 0, 0) },
+	},
+}
+
+var nonLetters = regexp.MustCompile(`[^a-z]+`)
+
+// recurringKey groups the movements that look the same: in the same currency and direction, and with the
+// same description once case, digits and punctuation (e.g. invoice numbers) are ignored.
+func recurringKey(txn transaction) string {
+	description := strings.TrimSpace(nonLetters.ReplaceAllString(strings.ToLower(txn.description), " "))
+	if description == "" {
+		return ""
+	}
+
+	direction := "credit"
+	if txn.amount < 0 {
+		direction = "debit"
+	}
+
+	return txn.currency + "|" + direction + "|" + description
+}
+
+// RecurringMovement is a movement repeated at a regular interval, like a subscription or a salary. Amounts,
+// including the cost of a year of occurrences, are in the currency of the movement, debits being negative.
+type RecurringMovement struct {
+	Description      string
+	Currency         string
+	Frequency        string
+	Occurrences      int
+	AverageAmount    string
+	LastDate         string
+	NextExpectedDate string
+	AnnualizedCost   string
+}
+
+// findRecurring finds the recurring movements in the history, which is expected to be the whole one of the
+// user, sorted from the most expensive one. Movements which missed more than one occurrence before the last
+// transaction are not recurring anymore.
+func findRecurring(history historySource, location *time.Location) ([]recurringMovement, error) {
+	detector := newRecurringDetector(location)
+	if err := history(func(txn transaction) error {
+		detector.add(txn)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return detector.movements(), nil
+}
+
+// summaryRecurring returns the recurring movements as they are shown in the summary.
+func summaryRecurring(movements []recurringMovement) []RecurringMovement {
+	var recurring []RecurringMovement
+	for _, movement := range movements {
+		recurring = append(recurring, movement.RecurringMovement)
+	}
+
+	return recurring
+}
+
+// recurringDetector groups the movements that look the same as they are read from the oldest one, keeping
+// only what is needed to tell whether they are recurring instead of the movements themselves.
+type recurringDetector struct {
+	location *time.Location
+	groups   map[string]*recurringGroup
+	keys     []string
+	latest   time.Time
+}
+
+type recurringGroup struct {
+	count int
+	total money.Amount
+	min   money.Amount
+	max   money.Amount
+	last  transaction
+	// matches tells, by recurrence, whether the days between every two consecutive movements are within its ones
+	matches []bool
+}
+
+func newRecurringDetector(location *time.Location) *recurringDetector {
+	return &recurringDetector{location: location, groups: make(map[string]*recurringGroup)}
+}
+
+// add reads the transaction, which is expected not to be older than the ones read before.
+func (d *recurringDetector) add(txn transaction) {
+	txn.date = txn.date.In(d.location)
+	if txn.date.After(d.latest) {
+		d.latest = txn.date
+	}
+
+	key := recurringKey(txn)
+	if key == "" {
+		return
+	}
+
+	group, ok := d.groups[key]
+	if !ok {
+		group = &recurringGroup{min: txn.amount, max: txn.amount, matches: make([]bool, len(recurrences))}
+		for i := range group.matches {
+			group.matches[i] = true
+		}
+		d.groups[key] = group
+		d.keys = append(d.keys, key)
+	} else {
+		days := int(civilDate(txn.date).Sub(civilDate(group.last.date)).Hours() / 24)
+		for i, frequency := range recurrences {
+			group.matches[i] = group.matches[i] && days >= frequency.minDays && days <= frequency.maxDays
+		}
+	}
+
+	group.count++
+	group.total += txn.amount
+	if txn.amount < group.min {
+		group.min = txn.amount
+	}
+	if txn.amount > group.max {
+		group.max = txn.amount
+	}
+	group.last = txn
+}
+
+// movements returns the recurring movements among the ones read, sorted from the most expensive one.
+func (d *recurringDetector) movements() []recurringMovement {
+	var movements []recurringMovement
+	for _, key := range d.keys {
+		if movement, ok := newRecurringMovement(d.groups[key], d.latest); ok {
+			movement.key = key
+			movements = append(movements, movement)
+		}
+	}
+
+	// the most expensive ones first
+	sort.SliceStable(movements, func(i, j int) bool {
+		if movements[i].annualized.Abs() != movements[j].annualized.Abs() {
+			return movements[i].annualized.Abs() > movements[j].annualized.Abs()
+		}
+		return movements[i].Description < movements[j].Description
+	})
+
+	return movements
+}
+
+type recurringMovement struct {
+	RecurringMovement
+	key        string
+	frequency  recurrence
+	average    money.Amount
+	next       time.Time
+	annualized money.Amount
+}
+
+func newRecurringMovement(group *recurringGroup, latest time.Time) (recurringMovement, bool) {
+	average := group.total.DivRound(int64(group.count), averagePlaces)
+
+	// the smallest and the largest amounts are the farthest ones from the average
+	for _, amount := range []money.Amount{group.min, group.max} {
+		if (amount-average).Abs()*100 > average.Abs()*recurringAmountTolerance {
+			return recurringMovement{}, false
+		}
+	}
+
+	for i, frequency := range recurrences {
+		if group.count < frequency.minOccurrences || !group.matches[i] {
+			continue
+		}
+
+		last := group.last
+		next := frequency.next(last.date)
+		if frequency.next(next).Before(latest) {
+			return recurringMovement{}, false
+		}
+
+		annualized := average * money.Amount(frequency.perYear)
+
+		return recurringMovement{
+			RecurringMovement: RecurringMovement{
+				Description:      last.description,
+				Currency:         last.currency,
+				Frequency:        frequency.name,
+				Occurrences:      group.count,
+				AverageAmount:    average.StringFixed(averagePlaces),
+				LastDate:         last.date.Format(balanceDateLayout),
+				NextExpectedDate: next.Format(balanceDateLayout),
+				AnnualizedCost:   annualized.StringFixed(averagePlaces),
+			},
+			frequency:  frequency,
+			average:    average,
+			next:       next,
+			annualized: annualized,
+		}, true
+	}
+
+	return recurringMovement{}, false
+}
+
+// civilDate returns the midnight of the date in UTC, so that days can be counted regardless of daylight
+// saving time changes.
+func civilDate(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,153 @@
+package summarizer
+
+import (
+	"testing"
+	"time"
+	"transaction-tool-api/src/internal/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindRecurring(t *testing.T) {
+	var (
+		date    = time.Date(2022, time.January, 31, 12, 0, 0, 0, time.UTC)
+		monthly = func(description string, amounts ...string) []transaction {
+			txns := make([]transaction, 0, len(amounts))
+			for i, amount := range amounts {
+				txns = append(txns, transaction{
+					amount:      money.MustParse(amount),
+					currency:    "USD",
+					description: description,
+					date:        date.AddDate(0, i, 0),
+				})
+			}
+			return txns
+		}
+		latest = transaction{amount: money.FromInt(-1), currency: "USD", date: date.AddDate(0, 5, 0)}
+	)
+
+	tests := []struct {
+		name     string
+		txns     []transaction
+		expected []RecurringMovement
+	}{
+		{
+			name: "monthly subscription with invoice numbers",
+			txns: []transaction{
+				{amount: money.MustParse("-15.49"), currency: "USD", description: "NETFLIX.COM 1001", date: date},
+				{
+					amount:      money.MustParse("-15.49"),
+					currency:    "USD",
+					description: "Netflix.com 1002",
+					date:        date.AddDate(0, 0, 28),
+				},
+				{
+					amount:      money.MustParse("-15.49"),
+					currency:    "USD",
+					description: "NETFLIX.COM 1003",
+					date:        date.AddDate(0, 0, 59),
+				},
+			},
+			expected: []RecurringMovement{
+				{
+					Description:      "NETFLIX.COM 1003",
+					Currency:         "USD",
+					Frequency:        "monthly",
+					Occurrences:      3,
+					AverageAmount:    "-15.49",
+					LastDate:         "2022-03-31",
+					NextExpectedDate: "2022-05-01",
+					AnnualizedCost:   "-185.88",
+				},
+			},
+		},
+		{
+			name: "weekly and yearly movements sorted by annualized cost",
+			txns: []transaction{
+				{amount: money.FromInt(-10), currency: "USD", description: "gym", date: date},
+				{amount: money.FromInt(-11), currency: "USD", description: "gym", date: date.AddDate(0, 0, 7)},
+				{amount: money.FromInt(-10), currency: "USD", description: "gym", date: date.AddDate(0, 0, 15)},
+				{amount: money.FromInt(-99), currency: "USD", description: "domain", date: date.AddDate(-1, 0, 5)},
+				{amount: money.FromInt(-99), currency: "USD", description: "domain", date: date.AddDate(0, 0, 6)},
+			},
+			expected: []RecurringMovement{
+				{
+					Description:      "gym",
+					Currency:         "USD",
+					Frequency:        "weekly",
+					Occurrences:      3,
+					AverageAmount:    "-10.33",
+					LastDate:         "2022-02-15",
+					NextExpectedDate: "2022-02-22",
+					AnnualizedCost:   "-537.16",
+				},
+				{
+					Description:      "domain",
+					Currency:         "USD",
+					Frequency:        "yearly",
+					Occurrences:      2,
+					AverageAmount:    "-99.00",
+					LastDate:         "2022-02-06",
+					NextExpectedDate: "2023-02-06",
+					AnnualizedCost:   "-99.00",
+				},
+			},
+		},
+		{
+			name: "amounts too different",
+			txns: monthly("electricity", "-40", "-80", "-60"),
+		},
+		{
+			name: "irregular intervals",
+			txns: []transaction{
+				{amount: money.FromInt(-5), currency: "USD", description: "coffee", date: date},
+				{amount: money.FromInt(-5), currency: "USD", description: "coffee", date: date.AddDate(0, 0, 2)},
+				{amount: money.FromInt(-5), currency: "USD", description: "coffee", date: date.AddDate(0, 0, 30)},
+			},
+		},
+		{
+			name: "too few occurrences",
+			txns: monthly("streaming", "-9.99", "-9.99"),
+		},
+		{
+			name: "salary and rent kept apart",
+			txns: append(monthly("transfer", "2000", "2000", "2000"), monthly("transfer", "-900", "-900", "-900")...),
+			expected: []RecurringMovement{
+				{
+					Description:      "transfer",
+					Currency:         "USD",
+					Frequency:        "monthly",
+					Occurrences:      3,
+					AverageAmount:    "2000.00",
+					LastDate:         "2022-03-31",
+					NextExpectedDate: "2022-05-01",
+					AnnualizedCost:   "24000.00",
+				},
+				{
+					Description:      "transfer",
+					Currency:         "USD",
+					Frequency:        "monthly",
+					Occurrences:      3,
+					AverageAmount:    "-900.00",
+					LastDate:         "2022-03-31",
+					NextExpectedDate: "2022-05-01",
+					AnnualizedCost:   "-10800.00",
+				},
+			},
+		},
+		{
+			name: "subscription cancelled",
+			txns: append(monthly("magazine", "-5", "-5", "-5"), latest),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			movements, err := findRecurring(sliceHistory(test.txns), time.UTC)
+
+			require.Nil(t, err)
+			assert.Equal(t, test.expected, summaryRecurring(movements))
+		})
+	}
+}
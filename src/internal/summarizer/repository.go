@@ -3,9 +3,12 @@ package summarizer
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
+	"transaction-tool-api/src/internal/money"
 )
 
 func NewRepository(client *sql.DB) Repository {
@@ -16,7 +19,43 @@ type Repository interface {
 	initTransactionalOperations(context.Context) (tx, error)
 	finishTransactionalOperations(context.Context, tx, error) error
 	saveBankTransactions(context.Context, tx, transactions) error
+	getSavedFingerprints(context.Context, tx, int64, int64, []string) (map[string]bool, error)
+	getDailyBalances(context.Context, tx, int64) ([]dailyBalance, error)
+	getDailyTotals(context.Context, tx, int64, time.Time, time.Time) ([]dailyTotal, error)
+	getDailyDebits(context.Context, tx, int64, *time.Location) ([]dailyBalance, error)
+	getAmountStats(context.Context, tx, int64) ([]amountStats, error)
 	getUserByID(context.Context, tx, int64) (User, error)
+	createJob(context.Context, Job) (int64, error)
+	getJobByID(context.Context, int64) (Job, error)
+	claimNextJob(context.Context) (Job, bool, error)
+	updateJobStatus(context.Context, int64, JobStatus) error
+	finishJob(context.Context, int64, JobStatus, string, *ImportResult) error
+	renewJobLease(context.Context, int64) error
+	saveJobResult(context.Context, tx, int64, ImportResult) (bool, error)
+	requeueInterruptedJobs(context.Context, time.Duration) (int64, error)
+	saveOutboxMessage(context.Context, tx, outboxMessage) error
+	claimOutboxMessages(context.Context, int, time.Duration) ([]outboxMessage, error)
+	updateOutboxMessageStatus(context.Context, int64, outboxStatus, string, time.Duration) error
+	saveFXRates(context.Context, tx, []fxRate) error
+	getFXRates(context.Context, tx, string) ([]fxRate, error)
+	saveImportProfile(context.Context, ImportProfile) error
+	getImportProfileByName(context.Context, string) (ImportProfile, error)
+	createImportBatch(context.Context, tx, ImportBatch) (int64, error)
+	finishImportBatch(context.Context, tx, int64, string, int) error
+	getImportBatchByID(context.Context, int64) (ImportBatch, error)
+	getImportBatchesByUserID(context.Context, int64) ([]ImportBatch, error)
+	getImportBatchTransactions(context.Context, int64) ([]SavedTransaction, error)
+	rollbackImportBatch(context.Context, tx, int64) (bool, error)
+	getLaterImportBatchID(context.Context, tx, ImportBatch) (int64, error)
+	deleteImportBatchTransactions(context.Context, tx, int64) (int64, error)
+	createCategoryRule(context.Context, CategoryRule) (int64, error)
+	getCategoryRuleByID(context.Context, int64) (CategoryRule, error)
+	getCategoryRules(context.Context, int64) ([]CategoryRule, error)
+	deleteCategoryRule(context.Context, int64) (bool, error)
+	getUserTransactions(context.Context, tx, int64) ([]storedTransaction, error)
+	forEachUserTransaction(context.Context, tx, int64, func(transaction) error) error
+	hasForeignTransactions(context.Context, tx, int64, string) (bool, error)
+	updateTransactionsCategory(context.Context, tx, string, []int64) error
 }
 
 type tx struct {
@@ -30,6 +69,13 @@ func (t tx) Exec(ctx context.Context, query string, params ...any) (sql.Result,
 	return t.client.ExecContext(ctx, query, params...)
 }
 
+func (t tx) Query(ctx context.Context, query string, params ...any) (*sql.Rows, error) {
+	if t.client == nil {
+		return nil, errors.New("transaction client is nil")
+	}
+	return t.client.QueryContext(ctx, query, params...)
+}
+
 func (t tx) QueryRow(ctx context.Context, query string, params ...any) (*sql.Row, error) {
 	if t.client == nil {
 		return nil, errors.New("transaction client is nil")
@@ -41,6 +87,14 @@ type repository struct {
 	client *sql.DB
 }
 
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func nullInt64(value int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: value, Valid: value != 0}
+}
+
 func (r repository) initTransactionalOperations(ctx context.Context) (tx, error) {
 	tnx, err := r.client.BeginTx(ctx, nil)
 	if err != nil {
@@ -57,20 +111,51 @@ func (r repository) finishTransactionalOperations(_ context.Context, tnx tx, err
 	return tnx.client.Commit()
 }
 
+// maxTransactionsByInsert bounds the rows of each insert statement, keeping its placeholders far
+// below the MySQL limit of 65,535.
+const maxTransactionsByInsert = 1000
+
 func (r repository) saveBankTransactions(ctx context.Context, tnx tx, bankTxns transactions) error {
-	if bankTxns.items == nil {
-		return nil
+	for start := 0; start < len(bankTxns.items); start += maxTransactionsByInsert {
+		end := start + maxTransactionsByInsert
+		if end > len(bankTxns.items) {
+			end = len(bankTxns.items)
+		}
+
+		batch := transactions{userID: bankTxns.userID, batchID: bankTxns.batchID, items: bankTxns.items[start:end]}
+		if err := r.insertBankTransactions(ctx, tnx, batch); err != nil {
+			return err
+		}
 	}
+
+	return nil
+}
+
+func (r repository) insertBankTransactions(ctx context.Context, tnx tx, bankTxns transactions) error {
 	var (
-		query              = `INSERT INTO transaction (user_id, amount, date_created) VALUES %s`
-		transactionFormat  = `(?,?,?)`
-		params             = make([]any, 0, 3*len(bankTxns.items))
+		query = `INSERT INTO transaction ` +
+			`(user_id, batch_id, amount, currency, description, reference, category, fingerprint, balance, ` +
+			`date_created) VALUES %s`
+		transactionFormat  = `(?,?,?,?,?,?,?,?,?,?)`
+		params             = make([]any, 0, 10*len(bankTxns.items))
 		transactionFormats = make([]string, 0, len(bankTxns.items))
 	)
 
 	for _, bankTxn := range bankTxns.items {
 		transactionFormats = append(transactionFormats, transactionFormat)
-		params = append(params, bankTxns.userID, bankTxn.amount, bankTxn.date)
+		params = append(
+			params,
+			bankTxns.userID,
+			nullInt64(bankTxns.batchID),
+			bankTxn.amount,
+			bankTxn.currency,
+			nullString(bankTxn.description),
+			nullString(bankTxn.reference),
+			nullString(bankTxn.category),
+			bankTxn.fingerprint(bankTxns.userID),
+			bankTxn.balance,
+			bankTxn.date,
+		)
 	}
 
 	query = fmt.Sprintf(query, strings.Join(transactionFormats, ","))
@@ -93,16 +178,221 @@ func (r repository) saveBankTransactions(ctx context.Context, tnx tx, bankTxns t
 	return nil
 }
 
+// getSavedFingerprints returns which of the fingerprints belong to transactions of the user saved by other
+// import batches than the given one, which saves the transactions of the file being imported.
+func (r repository) getSavedFingerprints(
+	ctx context.Context, tnx tx, userID int64, batchID int64, fingerprints []string) (map[string]bool, error) {
+	var (
+		saved = make(map[string]bool, len(fingerprints))
+		query = `SELECT fingerprint FROM transaction WHERE user_id = ? AND (batch_id IS NULL OR batch_id <> ?) ` +
+			`AND fingerprint IN (%s)`
+		params = make([]any, 0, len(fingerprints)+2)
+	)
+
+	if len(fingerprints) == 0 {
+		return saved, nil
+	}
+
+	params = append(params, userID, batchID)
+	for _, fingerprint := range fingerprints {
+		params = append(params, fingerprint)
+	}
+
+	query = fmt.Sprintf(query, strings.TrimSuffix(strings.Repeat("?,", len(fingerprints)), ","))
+
+	rows, err := tnx.Query(ctx, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying transaction fingerprints due to: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fingerprint string
+		if err = rows.Scan(&fingerprint); err != nil {
+			return nil, fmt.Errorf("error scanning transaction fingerprint due to: %w", err)
+		}
+		saved[fingerprint] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transaction fingerprints due to: %w", err)
+	}
+
+	return saved, nil
+}
+
+// getDailyBalances returns the sum of the transactions saved for the user by currency and day.
+func (r repository) getDailyBalances(ctx context.Context, tnx tx, userID int64) ([]dailyBalance, error) {
+	var (
+		balances []dailyBalance
+		query    = `SELECT currency, DATE(date_created), SUM(amount) FROM transaction WHERE user_id = ? ` +
+			`GROUP BY currency, DATE(date_created)`
+	)
+
+	rows, err := tnx.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying daily balances of user id %d due to: %w", userID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var balance dailyBalance
+		if err = rows.Scan(&balance.currency, &balance.date, &balance.amount); err != nil {
+			return nil, fmt.Errorf("error scanning daily balance due to: %w", err)
+		}
+		balances = append(balances, balance)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating daily balances due to: %w", err)
+	}
+
+	return balances, nil
+}
+
+// getDailyTotals returns the totals of the transactions saved for the user from the given time until the
+// other one, by currency and day. Days are the ones of the location of the from time, which is given by its
+// name so that they follow its daylight saving time changes.
+func (r repository) getDailyTotals(
+	ctx context.Context, tnx tx, userID int64, from time.Time, to time.Time) ([]dailyTotal, error) {
+	var (
+		totals []dailyTotal
+		query  = `SELECT currency, DATE(CONVERT_TZ(date_created, '+00:00', ?)) AS day, COUNT(*), ` +
+			`SUM(CASE WHEN amount > 0 THEN amount ELSE 0 END), SUM(amount > 0), ` +
+			`SUM(CASE WHEN amount < 0 THEN amount ELSE 0 END), SUM(amount < 0) ` +
+			`FROM transaction WHERE user_id = ? AND date_created >= ? AND date_created < ? ` +
+			`GROUP BY currency, day`
+	)
+
+	rows, err := tnx.Query(ctx, query, from.Location().String(), userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error querying daily totals of user id %d due to: %w", userID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var total dailyTotal
+		err = rows.Scan(
+			&total.currency, &total.date, &total.count,
+			&total.credits, &total.creditCount, &total.debits, &total.debitCount)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning daily total due to: %w", err)
+		}
+		totals = append(totals, total)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating daily totals due to: %w", err)
+	}
+
+	return totals, nil
+}
+
+// getDailyDebits returns the sum of the debits saved for the user by currency and day in the location, which
+// is given by its name so that the days follow its daylight saving time changes.
+func (r repository) getDailyDebits(
+	ctx context.Context, tnx tx, userID int64, location *time.Location) ([]dailyBalance, error) {
+	var (
+		debits []dailyBalance
+		query  = `SELECT currency, DATE(CONVERT_TZ(date_created, '+00:00', ?)) AS day, SUM(amount) ` +
+			`FROM transaction WHERE user_id = ? AND amount < 0 GROUP BY currency, day`
+	)
+
+	rows, err := tnx.Query(ctx, query, location.String(), userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying daily debits of user id %d due to: %w", userID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var debit dailyBalance
+		if err = rows.Scan(&debit.currency, &debit.date, &debit.amount); err != nil {
+			return nil, fmt.Errorf("error scanning daily debit due to: %w", err)
+		}
+		debits = append(debits, debit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating daily debits due to: %w", err)
+	}
+
+	return debits, nil
+}
+
+// getAmountStats returns the stats of the absolute amounts saved for the user by currency and direction,
+// computed by the database so that the transactions are not read one by one. The medians of an even number
+// of amounts are the average of the two middle ones, rounded to the scale of the amounts.
+func (r repository) getAmountStats(ctx context.Context, tnx tx, userID int64) ([]amountStats, error) {
+	var (
+		stats []amountStats
+		query = `WITH amounts AS (` +
+			`SELECT currency, amount < 0 AS debit, ABS(amount) AS amount, ` +
+			`ROW_NUMBER() OVER (PARTITION BY currency, amount < 0 ORDER BY ABS(amount)) AS row_position, ` +
+			`COUNT(*) OVER (PARTITION BY currency, amount < 0) AS total ` +
+			`FROM transaction WHERE user_id = ?), ` +
+			`medians AS (` +
+			`SELECT currency, debit, total, ROUND(AVG(amount), 4) AS median FROM amounts ` +
+			`WHERE row_position IN (FLOOR((total + 1) / 2), FLOOR(total / 2) + 1) GROUP BY currency, debit, total), ` +
+			`deviations AS (` +
+			`SELECT m.currency, m.debit, m.total, m.median, ABS(a.amount - m.median) AS deviation, ` +
+			`ROW_NUMBER() OVER (PARTITION BY m.currency, m.debit ORDER BY ABS(a.amount - m.median)) AS row_position ` +
+			`FROM amounts a JOIN medians m ON m.currency = a.currency AND m.debit = a.debit) ` +
+			`SELECT currency, debit, total, median, ` +
+			`ROUND(AVG(CASE WHEN row_position IN (FLOOR((total + 1) / 2), FLOOR(total / 2) + 1) ` +
+			`THEN deviation END), 4), SUM(deviation) ` +
+			`FROM deviations GROUP BY currency, debit, total, median`
+	)
+
+	rows, err := tnx.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying amount stats of user id %d due to: %w", userID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var keyStats amountStats
+		err = rows.Scan(
+			&keyStats.currency, &keyStats.debit, &keyStats.count,
+			&keyStats.median, &keyStats.medianDeviation, &keyStats.totalDeviation)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning amount stats due to: %w", err)
+		}
+		stats = append(stats, keyStats)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating amount stats due to: %w", err)
+	}
+
+	return stats, nil
+}
+
 type User struct {
-	UserID int64
-	Name   string
-	Email  string
+	UserID   int64
+	Name     string
+	Email    string
+	Currency string
+	Timezone string
+}
+
+// location returns the timezone the transactions of the user are summarized in, UTC when it is not set.
+func (u User) location() (*time.Location, error) {
+	if u.Timezone == "" {
+		return time.UTC, nil
+	}
+
+	location, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("error loading timezone '%s' of user id %d due to: %w", u.Timezone, u.UserID, err)
+	}
+
+	return location, nil
 }
 
 func (r repository) getUserByID(ctx context.Context, tnx tx, userID int64) (User, error) {
 	var (
 		user  User
-		query = `SELECT id, user_name, email FROM user WHERE id = ?`
+		query = `SELECT id, user_name, email, currency, timezone FROM user WHERE id = ?`
 	)
 
 	row, err := tnx.QueryRow(ctx, query, userID)
@@ -110,9 +400,875 @@ func (r repository) getUserByID(ctx context.Context, tnx tx, userID int64) (User
 		return User{}, err
 	}
 
-	if err = row.Scan(&user.UserID, &user.Name, &user.Email); err != nil {
+	if err = row.Scan(&user.UserID, &user.Name, &user.Email, &user.Currency, &user.Timezone); err != nil {
 		return User{}, fmt.Errorf("error scanning user by id %d due to: %w", userID, err)
 	}
 
 	return user, nil
 }
+
+func (r repository) createJob(ctx context.Context, job Job) (int64, error) {
+	query := `INSERT INTO job (user_id, status, payload, options) VALUES (?,?,?,?)`
+
+	options, err := json.Marshal(job.options)
+	if err != nil {
+		return 0, fmt.Errorf("error encoding job options due to: %w", err)
+	}
+
+	result, err := r.client.ExecContext(ctx, query, job.UserID, job.Status, job.payload, options)
+	if err != nil {
+		return 0, fmt.Errorf("error inserting job due to: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting inserted job id due to: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r repository) getJobByID(ctx context.Context, jobID int64) (Job, error) {
+	var (
+		job   Job
+		query = `SELECT id, user_id, status, error, batch_id, inserted_transactions, skipped_transactions, ` +
+			`date_created, date_updated, date_started, date_finished FROM job WHERE id = ?`
+		jobErr       sql.NullString
+		batchID      sql.NullInt64
+		inserted     sql.NullInt64
+		skipped      sql.NullInt64
+		dateStarted  sql.NullTime
+		dateFinished sql.NullTime
+	)
+
+	err := r.client.QueryRowContext(ctx, query, jobID).Scan(
+		&job.ID,
+		&job.UserID,
+		&job.Status,
+		&jobErr,
+		&batchID,
+		&inserted,
+		&skipped,
+		&job.DateCreated,
+		&job.DateUpdated,
+		&dateStarted,
+		&dateFinished,
+	)
+	if err != nil {
+		return Job{}, fmt.Errorf("error scanning job by id %d due to: %w", jobID, err)
+	}
+
+	job.Error = jobErr.String
+	if inserted.Valid {
+		job.Result = &ImportResult{
+			BatchID:              batchID.Int64,
+			InsertedTransactions: int(inserted.Int64),
+			SkippedTransactions:  int(skipped.Int64),
+		}
+	}
+	if dateStarted.Valid {
+		job.DateStarted = &dateStarted.Time
+	}
+	if dateFinished.Valid {
+		job.DateFinished = &dateFinished.Time
+	}
+
+	return job, nil
+}
+
+func (r repository) claimNextJob(ctx context.Context) (job Job, found bool, err error) {
+	var (
+		tnx         tx
+		selectQuery = `SELECT id, user_id, payload, options, batch_id, inserted_transactions, skipped_transactions ` +
+			`FROM job WHERE status = ? ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED`
+		updateQuery = `UPDATE job SET status = ?, date_started = current_timestamp(), ` +
+			`date_updated = current_timestamp() WHERE id = ?`
+		row      *sql.Row
+		options  []byte
+		batchID  sql.NullInt64
+		inserted sql.NullInt64
+		skipped  sql.NullInt64
+	)
+
+	if tnx, err = r.initTransactionalOperations(ctx); err != nil {
+		return Job{}, false, fmt.Errorf("error creating job claim transaction due to: %w", err)
+	}
+	defer func() {
+		err = r.finishTransactionalOperations(ctx, tnx, err)
+	}()
+
+	if row, err = tnx.QueryRow(ctx, selectQuery, JobStatusQueued); err != nil {
+		return Job{}, false, err
+	}
+
+	if err = row.Scan(&job.ID, &job.UserID, &job.payload, &options, &batchID, &inserted, &skipped); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Job{}, false, nil
+		}
+		return Job{}, false, fmt.Errorf("error scanning queued job due to: %w", err)
+	}
+
+	// the transactions of the job were imported by a run interrupted before finishing it
+	if batchID.Valid {
+		job.Result = &ImportResult{
+			BatchID:              batchID.Int64,
+			InsertedTransactions: int(inserted.Int64),
+			SkippedTransactions:  int(skipped.Int64),
+		}
+	}
+
+	if err = json.Unmarshal(options, &job.options); err != nil {
+		return Job{}, false, fmt.Errorf("error decoding options of job id %d due to: %w", job.ID, err)
+	}
+
+	if _, err = tnx.Exec(ctx, updateQuery, JobStatusParsing, job.ID); err != nil {
+		return Job{}, false, fmt.Errorf("error claiming job id %d due to: %w", job.ID, err)
+	}
+
+	job.Status = JobStatusParsing
+
+	return job, true, nil
+}
+
+func (r repository) updateJobStatus(ctx context.Context, jobID int64, status JobStatus) error {
+	query := `UPDATE job SET status = ?, date_updated = current_timestamp() WHERE id = ?`
+
+	if _, err := r.client.ExecContext(ctx, query, status, jobID); err != nil {
+		return fmt.Errorf("error updating status of job id %d due to: %w", jobID, err)
+	}
+	return nil
+}
+
+func (r repository) finishJob(
+	ctx context.Context, jobID int64, status JobStatus, jobErr string, result *ImportResult) error {
+	var (
+		query = `UPDATE job SET status = ?, error = ?, batch_id = ?, inserted_transactions = ?, ` +
+			`skipped_transactions = ?, date_updated = current_timestamp(), date_finished = current_timestamp() ` +
+			`WHERE id = ?`
+		batchID  sql.NullInt64
+		inserted sql.NullInt64
+		skipped  sql.NullInt64
+	)
+
+	if result != nil {
+		batchID = nullInt64(result.BatchID)
+		inserted = sql.NullInt64{Int64: int64(result.InsertedTransactions), Valid: true}
+		skipped = sql.NullInt64{Int64: int64(result.SkippedTransactions), Valid: true}
+	}
+
+	_, err := r.client.ExecContext(ctx, query, status, nullString(jobErr), batchID, inserted, skipped, jobID)
+	if err != nil {
+		return fmt.Errorf("error finishing job id %d due to: %w", jobID, err)
+	}
+	return nil
+}
+
+// renewJobLease tells the job is still being processed, so that it is not queued again.
+func (r repository) renewJobLease(ctx context.Context, jobID int64) error {
+	query := `UPDATE job SET date_updated = current_timestamp() WHERE id = ?`
+
+	if _, err := r.client.ExecContext(ctx, query, jobID); err != nil {
+		return fmt.Errorf("error renewing lease of job id %d due to: %w", jobID, err)
+	}
+	return nil
+}
+
+// saveJobResult records the result of the import of the job along with its transactions, returning false when
+// another run of the job already recorded one.
+func (r repository) saveJobResult(ctx context.Context, tnx tx, jobID int64, result ImportResult) (bool, error) {
+	query := `UPDATE job SET batch_id = ?, inserted_transactions = ?, skipped_transactions = ?, ` +
+		`date_updated = current_timestamp() WHERE id = ? AND batch_id IS NULL`
+
+	res, err := tnx.Exec(
+		ctx, query, result.BatchID, result.InsertedTransactions, result.SkippedTransactions, jobID)
+	if err != nil {
+		return false, fmt.Errorf("error saving result of job id %d due to: %w", jobID, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting total jobs with saved result due to: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// requeueInterruptedJobs queues again the jobs being processed whose lease was not renewed for the given
+// time, as the instance processing them stopped. Queued jobs are left as they are, so that sweeping does not
+// reorder them.
+func (r repository) requeueInterruptedJobs(ctx context.Context, lease time.Duration) (int64, error) {
+	query := `UPDATE job SET status = ?, date_updated = current_timestamp() ` +
+		`WHERE status IN (?,?,?) AND date_updated < current_timestamp() - INTERVAL ? SECOND`
+
+	result, err := r.client.ExecContext(
+		ctx, query, JobStatusQueued,
+		// only the statuses of the jobs being processed are matched
+		JobStatusParsing, JobStatusSaving, JobStatusNotifying, int64(lease.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("error requeueing interrupted jobs due to: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error getting total requeued jobs due to: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (r repository) saveOutboxMessage(ctx context.Context, tnx tx, message outboxMessage) error {
+	var (
+		attachments []byte
+		err         error
+		query       = `INSERT INTO outbox (user_id, email, message, text, attachments, status) VALUES (?,?,?,?,?,?)`
+	)
+
+	if len(message.attachments) > 0 {
+		if attachments, err = json.Marshal(message.attachments); err != nil {
+			return fmt.Errorf("error encoding outbox message attachments due to: %w", err)
+		}
+	}
+
+	_, err = tnx.Exec(
+		ctx, query, message.userID, message.email, message.message, message.text, attachments, message.status)
+	if err != nil {
+		return fmt.Errorf("error inserting outbox message due to: %w", err)
+	}
+	return nil
+}
+
+// claimOutboxMessages claims as sending, for the given lease, the pending messages due and the ones whose
+// lease expired, up to the given limit.
+func (r repository) claimOutboxMessages(
+	ctx context.Context, limit int, lease time.Duration) (messages []outboxMessage, err error) {
+	var (
+		tnx         tx
+		selectQuery = `SELECT id, user_id, email, message, text, attachments, attempts FROM outbox ` +
+			`WHERE status IN (?,?) AND date_next_attempt <= current_timestamp() ORDER BY id LIMIT ? ` +
+			`FOR UPDATE SKIP LOCKED`
+		updateQuery = `UPDATE outbox SET status = ?, date_next_attempt = current_timestamp() + INTERVAL ? SECOND ` +
+			`WHERE id IN (%s)`
+		params []any
+	)
+
+	if tnx, err = r.initTransactionalOperations(ctx); err != nil {
+		return nil, fmt.Errorf("error creating outbox claim transaction due to: %w", err)
+	}
+	defer func() {
+		if err = r.finishTransactionalOperations(ctx, tnx, err); err != nil {
+			messages = nil
+		}
+	}()
+
+	rows, err := tnx.Query(ctx, selectQuery, outboxStatusPending, outboxStatusSending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying pending outbox messages due to: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			message     = outboxMessage{status: outboxStatusSending}
+			text        sql.NullString
+			attachments []byte
+		)
+		err = rows.Scan(
+			&message.id, &message.userID, &message.email, &message.message, &text, &attachments, &message.attempts)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning pending outbox message due to: %w", err)
+		}
+		message.text = text.String
+		if attachments != nil {
+			if err = json.Unmarshal(attachments, &message.attachments); err != nil {
+				return nil, fmt.Errorf("error decoding pending outbox message attachments due to: %w", err)
+			}
+		}
+		messages = append(messages, message)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pending outbox messages due to: %w", err)
+	}
+
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	params = append(params, outboxStatusSending, int64(lease.Seconds()))
+	for _, message := range messages {
+		params = append(params, message.id)
+	}
+
+	updateQuery = fmt.Sprintf(updateQuery, strings.TrimSuffix(strings.Repeat("?,", len(messages)), ","))
+
+	if _, err = tnx.Exec(ctx, updateQuery, params...); err != nil {
+		return nil, fmt.Errorf("error claiming outbox messages due to: %w", err)
+	}
+
+	return messages, nil
+}
+
+// updateOutboxMessageStatus records an attempt to send the message, which is attempted again after the given
+// time when it is pending.
+func (r repository) updateOutboxMessageStatus(
+	ctx context.Context, messageID int64, status outboxStatus, messageErr string, retryIn time.Duration) error {
+	query := `UPDATE outbox SET status = ?, attempts = attempts + 1, error = ?, ` +
+		`date_sent = IF(? = 'sent', current_timestamp(), NULL), ` +
+		`date_next_attempt = current_timestamp() + INTERVAL ? SECOND WHERE id = ?`
+
+	_, err := r.client.ExecContext(
+		ctx, query, status, nullString(messageErr), status, int64(retryIn.Seconds()), messageID)
+	if err != nil {
+		return fmt.Errorf("error updating status of outbox message id %d due to: %w", messageID, err)
+	}
+	return nil
+}
+
+func (r repository) saveFXRates(ctx context.Context, tnx tx, rates []fxRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+	var (
+		query = `INSERT INTO fx_rate (base_currency, quote_currency, rate, effective_date) VALUES %s ` +
+			`ON DUPLICATE KEY UPDATE rate = VALUES(rate)`
+		rateFormat  = `(?,?,?,?)`
+		params      = make([]any, 0, 4*len(rates))
+		rateFormats = make([]string, 0, len(rates))
+	)
+
+	for _, rate := range rates {
+		rateFormats = append(rateFormats, rateFormat)
+		params = append(
+			params, rate.baseCurrency, rate.quoteCurrency, rate.rate, rate.effectiveDate.Format(fxDateLayout))
+	}
+
+	query = fmt.Sprintf(query, strings.Join(rateFormats, ","))
+
+	if _, err := tnx.Exec(ctx, query, params...); err != nil {
+		return fmt.Errorf("error inserting fx rates due to: %w", err)
+	}
+
+	return nil
+}
+
+func (r repository) getFXRates(ctx context.Context, tnx tx, currency string) ([]fxRate, error) {
+	var (
+		rates []fxRate
+		query = `SELECT base_currency, quote_currency, rate, effective_date FROM fx_rate ` +
+			`WHERE base_currency = ? OR quote_currency = ?`
+	)
+
+	rows, err := tnx.Query(ctx, query, currency, currency)
+	if err != nil {
+		return nil, fmt.Errorf("error querying fx rates due to: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rate fxRate
+		if err = rows.Scan(&rate.baseCurrency, &rate.quoteCurrency, &rate.rate, &rate.effectiveDate); err != nil {
+			return nil, fmt.Errorf("error scanning fx rate due to: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating fx rates due to: %w", err)
+	}
+
+	return rates, nil
+}
+
+func (r repository) saveImportProfile(ctx context.Context, profile ImportProfile) error {
+	query := `INSERT INTO import_profile ` +
+		`(name, amount_column, date_column, currency_column, description_column, reference_column) ` +
+		`VALUES (?,?,?,?,?,?) ON DUPLICATE KEY UPDATE amount_column = VALUES(amount_column), ` +
+		`date_column = VALUES(date_column), currency_column = VALUES(currency_column), ` +
+		`description_column = VALUES(description_column), reference_column = VALUES(reference_column)`
+
+	_, err := r.client.ExecContext(
+		ctx,
+		query,
+		profile.Name,
+		profile.AmountColumn,
+		profile.DateColumn,
+		nullString(profile.CurrencyColumn),
+		nullString(profile.DescriptionColumn),
+		nullString(profile.ReferenceColumn),
+	)
+	if err != nil {
+		return fmt.Errorf("error saving import profile '%s' due to: %w", profile.Name, err)
+	}
+	return nil
+}
+
+func (r repository) getImportProfileByName(ctx context.Context, name string) (ImportProfile, error) {
+	var (
+		profile           ImportProfile
+		currencyColumn    sql.NullString
+		descriptionColumn sql.NullString
+		referenceColumn   sql.NullString
+		query             = `SELECT name, amount_column, date_column, currency_column, description_column, ` +
+			`reference_column FROM import_profile WHERE name = ?`
+	)
+
+	err := r.client.QueryRowContext(ctx, query, name).Scan(
+		&profile.Name,
+		&profile.AmountColumn,
+		&profile.DateColumn,
+		&currencyColumn,
+		&descriptionColumn,
+		&referenceColumn,
+	)
+	if err != nil {
+		return ImportProfile{}, fmt.Errorf("error scanning import profile '%s' due to: %w", name, err)
+	}
+
+	profile.CurrencyColumn = currencyColumn.String
+	profile.DescriptionColumn = descriptionColumn.String
+	profile.ReferenceColumn = referenceColumn.String
+
+	return profile, nil
+}
+
+func (r repository) createImportBatch(ctx context.Context, tnx tx, batch ImportBatch) (int64, error) {
+	query := `INSERT INTO import_batch (user_id, filename, status) VALUES (?,?,?)`
+
+	result, err := tnx.Exec(ctx, query, batch.UserID, nullString(batch.Filename), batch.Status)
+	if err != nil {
+		return 0, fmt.Errorf("error inserting import batch due to: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting inserted import batch id due to: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r repository) finishImportBatch(ctx context.Context, tnx tx, batchID int64, checksum string, rowCount int) error {
+	query := `UPDATE import_batch SET checksum = ?, row_count = ? WHERE id = ?`
+
+	if _, err := tnx.Exec(ctx, query, nullString(checksum), rowCount, batchID); err != nil {
+		return fmt.Errorf("error finishing import batch id %d due to: %w", batchID, err)
+	}
+	return nil
+}
+
+const importBatchColumns = `id, user_id, filename, checksum, row_count, status, date_created`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanImportBatch(row rowScanner) (ImportBatch, error) {
+	var (
+		batch    ImportBatch
+		filename sql.NullString
+		checksum sql.NullString
+	)
+
+	err := row.Scan(
+		&batch.ID,
+		&batch.UserID,
+		&filename,
+		&checksum,
+		&batch.RowCount,
+		&batch.Status,
+		&batch.DateCreated,
+	)
+	if err != nil {
+		return ImportBatch{}, err
+	}
+
+	batch.Filename = filename.String
+	batch.Checksum = checksum.String
+
+	return batch, nil
+}
+
+func (r repository) getImportBatchByID(ctx context.Context, batchID int64) (ImportBatch, error) {
+	query := `SELECT ` + importBatchColumns + ` FROM import_batch WHERE id = ?`
+
+	batch, err := scanImportBatch(r.client.QueryRowContext(ctx, query, batchID))
+	if err != nil {
+		return ImportBatch{}, fmt.Errorf("error scanning import batch by id %d due to: %w", batchID, err)
+	}
+
+	return batch, nil
+}
+
+func (r repository) getImportBatchesByUserID(ctx context.Context, userID int64) ([]ImportBatch, error) {
+	var (
+		batches []ImportBatch
+		query   = `SELECT ` + importBatchColumns + ` FROM import_batch WHERE user_id = ? ORDER BY id DESC`
+	)
+
+	rows, err := r.client.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying import batches of user id %d due to: %w", userID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		batch, err := scanImportBatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning import batch due to: %w", err)
+		}
+		batches = append(batches, batch)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating import batches due to: %w", err)
+	}
+
+	return batches, nil
+}
+
+func (r repository) getImportBatchTransactions(ctx context.Context, batchID int64) ([]SavedTransaction, error) {
+	var (
+		txns  []SavedTransaction
+		query = `SELECT id, amount, currency, description, reference, category, balance, date_created ` +
+			`FROM transaction WHERE batch_id = ? ORDER BY id`
+	)
+
+	rows, err := r.client.QueryContext(ctx, query, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying transactions of import batch id %d due to: %w", batchID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			txn         SavedTransaction
+			amount      money.Amount
+			description sql.NullString
+			reference   sql.NullString
+			category    sql.NullString
+			balance     sql.NullString
+		)
+		err = rows.Scan(&txn.ID, &amount, &txn.Currency, &description, &reference, &category, &balance, &txn.Date)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning import batch transaction due to: %w", err)
+		}
+		txn.Amount = amount.String()
+		txn.Description = description.String
+		txn.Reference = reference.String
+		txn.Category = category.String
+		txn.Balance = balance.String
+		txns = append(txns, txn)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating import batch transactions due to: %w", err)
+	}
+
+	return txns, nil
+}
+
+// rollbackImportBatch marks the batch as rolled back, returning false when it was not imported.
+func (r repository) rollbackImportBatch(ctx context.Context, tnx tx, batchID int64) (bool, error) {
+	query := `UPDATE import_batch SET status = ? WHERE id = ? AND status = ?`
+
+	result, err := tnx.Exec(ctx, query, ImportBatchStatusRolledBack, batchID, ImportBatchStatusImported)
+	if err != nil {
+		return false, fmt.Errorf("error rolling back import batch id %d due to: %w", batchID, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting total rolled back import batches due to: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// getLaterImportBatchID returns the id of the latest batch of the user imported after the given one, locking
+// it until the transaction ends, or 0 when there is none.
+func (r repository) getLaterImportBatchID(ctx context.Context, tnx tx, batch ImportBatch) (int64, error) {
+	var (
+		laterBatchID int64
+		query        = `SELECT id FROM import_batch WHERE user_id = ? AND id > ? AND status = ? ` +
+			`ORDER BY id DESC LIMIT 1 FOR UPDATE`
+	)
+
+	row, err := tnx.QueryRow(ctx, query, batch.UserID, batch.ID, ImportBatchStatusImported)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = row.Scan(&laterBatchID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error scanning import batch after id %d due to: %w", batch.ID, err)
+	}
+
+	return laterBatchID, nil
+}
+
+func (r repository) deleteImportBatchTransactions(ctx context.Context, tnx tx, batchID int64) (int64, error) {
+	query := `DELETE FROM transaction WHERE batch_id = ?`
+
+	result, err := tnx.Exec(ctx, query, batchID)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting transactions of import batch id %d due to: %w", batchID, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error getting total deleted transactions due to: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (r repository) createCategoryRule(ctx context.Context, rule CategoryRule) (int64, error) {
+	query := `INSERT INTO category_rule (user_id, category, kind, pattern, min_amount, max_amount, priority) ` +
+		`VALUES (?,?,?,?,?,?,?)`
+
+	result, err := r.client.ExecContext(
+		ctx,
+		query,
+		nullInt64(rule.UserID),
+		rule.Category,
+		rule.Kind,
+		nullString(rule.Pattern),
+		nullString(rule.MinAmount),
+		nullString(rule.MaxAmount),
+		rule.Priority,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error inserting category rule due to: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting inserted category rule id due to: %w", err)
+	}
+
+	return id, nil
+}
+
+func scanCategoryRule(row rowScanner) (CategoryRule, error) {
+	var (
+		rule      CategoryRule
+		userID    sql.NullInt64
+		pattern   sql.NullString
+		minAmount sql.NullString
+		maxAmount sql.NullString
+	)
+
+	err := row.Scan(
+		&rule.ID,
+		&userID,
+		&rule.Category,
+		&rule.Kind,
+		&pattern,
+		&minAmount,
+		&maxAmount,
+		&rule.Priority,
+		&rule.DateCreated,
+	)
+	if err != nil {
+		return CategoryRule{}, err
+	}
+
+	rule.UserID = userID.Int64
+	rule.Pattern = pattern.String
+	rule.MinAmount = minAmount.String
+	rule.MaxAmount = maxAmount.String
+
+	return rule, nil
+}
+
+func (r repository) getCategoryRuleByID(ctx context.Context, ruleID int64) (CategoryRule, error) {
+	query := `SELECT id, user_id, category, kind, pattern, min_amount, max_amount, priority, date_created ` +
+		`FROM category_rule WHERE id = ?`
+
+	rule, err := scanCategoryRule(r.client.QueryRowContext(ctx, query, ruleID))
+	if err != nil {
+		return CategoryRule{}, fmt.Errorf("error scanning category rule by id %d due to: %w", ruleID, err)
+	}
+
+	return rule, nil
+}
+
+// getCategoryRules returns the rules of the user and the global ones in order of precedence: by priority,
+// the ones of the user first and then the oldest ones. Only the global ones are returned for user id 0.
+func (r repository) getCategoryRules(ctx context.Context, userID int64) ([]CategoryRule, error) {
+	var (
+		rules []CategoryRule
+		query = `SELECT id, user_id, category, kind, pattern, min_amount, max_amount, priority, date_created ` +
+			`FROM category_rule WHERE user_id = ? OR user_id IS NULL ORDER BY priority DESC, user_id IS NULL, id`
+	)
+
+	rows, err := r.client.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying category rules of user id %d due to: %w", userID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rule, err := scanCategoryRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning category rule due to: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating category rules due to: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (r repository) deleteCategoryRule(ctx context.Context, ruleID int64) (bool, error) {
+	query := `DELETE FROM category_rule WHERE id = ?`
+
+	result, err := r.client.ExecContext(ctx, query, ruleID)
+	if err != nil {
+		return false, fmt.Errorf("error deleting category rule id %d due to: %w", ruleID, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting total deleted category rules due to: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// forEachUserTransaction calls the function with every transaction saved for the user, from the oldest one,
+// as they are read from the database, and returns its first error. As the rows are being read, the function
+// must not use the transaction.
+func (r repository) forEachUserTransaction(
+	ctx context.Context, tnx tx, userID int64, fn func(transaction) error) error {
+	query := `SELECT amount, currency, description, reference, category, date_created FROM transaction ` +
+		`WHERE user_id = ? ORDER BY date_created, id`
+
+	rows, err := tnx.Query(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("error querying transactions of user id %d due to: %w", userID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			txn         transaction
+			description sql.NullString
+			reference   sql.NullString
+			category    sql.NullString
+		)
+		if err = rows.Scan(&txn.amount, &txn.currency, &description, &reference, &category, &txn.date); err != nil {
+			return fmt.Errorf("error scanning user transaction due to: %w", err)
+		}
+		txn.description = description.String
+		txn.reference = reference.String
+		txn.category = category.String
+
+		if err = fn(txn); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating user transactions due to: %w", err)
+	}
+
+	return nil
+}
+
+// hasForeignTransactions tells whether one of the transactions saved for the user is not in the currency.
+func (r repository) hasForeignTransactions(ctx context.Context, tnx tx, userID int64, currency string) (bool, error) {
+	var (
+		foreign bool
+		query   = `SELECT EXISTS(SELECT 1 FROM transaction WHERE user_id = ? AND currency <> ?)`
+	)
+
+	row, err := tnx.QueryRow(ctx, query, userID, currency)
+	if err != nil {
+		return false, fmt.Errorf("error querying currencies of user id %d due to: %w", userID, err)
+	}
+
+	if err = row.Scan(&foreign); err != nil {
+		return false, fmt.Errorf("error scanning currencies of user id %d due to: %w", userID, err)
+	}
+
+	return foreign, nil
+}
+
+func (r repository) getUserTransactions(ctx context.Context, tnx tx, userID int64) ([]storedTransaction, error) {
+	var (
+		txns  []storedTransaction
+		query = `SELECT id, amount, currency, description, reference, category, date_created FROM transaction ` +
+			`WHERE user_id = ? ORDER BY id`
+	)
+
+	rows, err := tnx.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying transactions of user id %d due to: %w", userID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			stored      storedTransaction
+			description sql.NullString
+			reference   sql.NullString
+			category    sql.NullString
+		)
+		err = rows.Scan(
+			&stored.id,
+			&stored.txn.amount,
+			&stored.txn.currency,
+			&description,
+			&reference,
+			&category,
+			&stored.txn.date,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning user transaction due to: %w", err)
+		}
+		stored.txn.description = description.String
+		stored.txn.reference = reference.String
+		stored.txn.category = category.String
+		txns = append(txns, stored)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user transactions due to: %w", err)
+	}
+
+	return txns, nil
+}
+
+// updateTransactionsCategory sets the category of the transactions, clearing it when it is empty.
+func (r repository) updateTransactionsCategory(ctx context.Context, tnx tx, category string, txnIDs []int64) error {
+	for start := 0; start < len(txnIDs); start += maxTransactionsByInsert {
+		end := start + maxTransactionsByInsert
+		if end > len(txnIDs) {
+			end = len(txnIDs)
+		}
+
+		var (
+			query  = `UPDATE transaction SET category = ? WHERE id IN (%s)`
+			params = make([]any, 0, end-start+1)
+		)
+
+		params = append(params, nullString(category))
+		for _, txnID := range txnIDs[start:end] {
+			params = append(params, txnID)
+		}
+
+		query = fmt.Sprintf(query, strings.TrimSuffix(strings.Repeat("?,", end-start), ","))
+
+		if _, err := tnx.Exec(ctx, query, params...); err != nil {
+			return fmt.Errorf("error updating category of transactions due to: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package summarizer
 
 import (
 	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -32,6 +33,71 @@ func (m *repositoryMock) saveBankTransactions(_ context.Context, txn tx, bankTxn
 	return args.Error(0)
 }
 
+func (m *repositoryMock) getSavedFingerprints(
+	_ context.Context, txn tx, userID int64, batchID int64, fingerprints []string) (map[string]bool, error) {
+	var (
+		saved map[string]bool
+		args  = m.Called(txn, userID, batchID, fingerprints)
+	)
+
+	if value, ok := args.Get(0).(map[string]bool); ok {
+		saved = value
+	}
+	return saved, args.Error(1)
+}
+
+func (m *repositoryMock) getDailyBalances(_ context.Context, txn tx, userID int64) ([]dailyBalance, error) {
+	var (
+		balances []dailyBalance
+		args     = m.Called(txn, userID)
+	)
+
+	if value, ok := args.Get(0).([]dailyBalance); ok {
+		balances = value
+	}
+	return balances, args.Error(1)
+}
+
+func (m *repositoryMock) getDailyTotals(
+	_ context.Context, txn tx, userID int64, from time.Time, to time.Time) ([]dailyTotal, error) {
+	var (
+		totals []dailyTotal
+		args   = m.Called(txn, userID, from, to)
+	)
+
+	if value, ok := args.Get(0).([]dailyTotal); ok {
+		totals = value
+	}
+	return totals, args.Error(1)
+}
+
+func (m *repositoryMock) getDailyDebits(
+	_ context.Context, txn tx, userID int64, location *time.Location) ([]dailyBalance, error) {
+	var (
+		debits []dailyBalance
+		args   = m.Called(txn, userID, location)
+	)
+
+	if value, ok := args.Get(0).([]dailyBalance); ok {
+		debits = value
+	}
+
+	return debits, args.Error(1)
+}
+
+func (m *repositoryMock) getAmountStats(_ context.Context, txn tx, userID int64) ([]amountStats, error) {
+	var (
+		stats []amountStats
+		args  = m.Called(txn, userID)
+	)
+
+	if value, ok := args.Get(0).([]amountStats); ok {
+		stats = value
+	}
+
+	return stats, args.Error(1)
+}
+
 func (m *repositoryMock) getUserByID(_ context.Context, txn tx, userID int64) (User, error) {
 	var (
 		user User
@@ -44,3 +110,250 @@ func (m *repositoryMock) getUserByID(_ context.Context, txn tx, userID int64) (U
 
 	return user, args.Error(1)
 }
+
+func (m *repositoryMock) createJob(_ context.Context, job Job) (int64, error) {
+	args := m.Called(job)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *repositoryMock) getJobByID(_ context.Context, jobID int64) (Job, error) {
+	var (
+		job  Job
+		args = m.Called(jobID)
+	)
+
+	if value, ok := args.Get(0).(Job); ok {
+		job = value
+	}
+	return job, args.Error(1)
+}
+
+func (m *repositoryMock) claimNextJob(_ context.Context) (Job, bool, error) {
+	var (
+		job  Job
+		args = m.Called()
+	)
+
+	if value, ok := args.Get(0).(Job); ok {
+		job = value
+	}
+	return job, args.Bool(1), args.Error(2)
+}
+
+func (m *repositoryMock) updateJobStatus(_ context.Context, jobID int64, status JobStatus) error {
+	args := m.Called(jobID, status)
+	return args.Error(0)
+}
+
+func (m *repositoryMock) finishJob(
+	_ context.Context, jobID int64, status JobStatus, jobErr string, result *ImportResult) error {
+	args := m.Called(jobID, status, jobErr, result)
+	return args.Error(0)
+}
+
+func (m *repositoryMock) renewJobLease(_ context.Context, jobID int64) error {
+	args := m.Called(jobID)
+	return args.Error(0)
+}
+
+func (m *repositoryMock) saveJobResult(_ context.Context, txn tx, jobID int64, result ImportResult) (bool, error) {
+	args := m.Called(txn, jobID, result)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *repositoryMock) requeueInterruptedJobs(_ context.Context, lease time.Duration) (int64, error) {
+	args := m.Called(lease)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *repositoryMock) saveOutboxMessage(_ context.Context, txn tx, message outboxMessage) error {
+	args := m.Called(txn, message)
+	return args.Error(0)
+}
+
+func (m *repositoryMock) claimOutboxMessages(
+	_ context.Context, limit int, lease time.Duration) ([]outboxMessage, error) {
+	var (
+		messages []outboxMessage
+		args     = m.Called(limit, lease)
+	)
+
+	if value, ok := args.Get(0).([]outboxMessage); ok {
+		messages = value
+	}
+	return messages, args.Error(1)
+}
+
+func (m *repositoryMock) updateOutboxMessageStatus(
+	_ context.Context, messageID int64, status outboxStatus, messageErr string, retryIn time.Duration) error {
+	args := m.Called(messageID, status, messageErr, retryIn)
+	return args.Error(0)
+}
+
+func (m *repositoryMock) saveFXRates(_ context.Context, txn tx, rates []fxRate) error {
+	args := m.Called(txn, rates)
+	return args.Error(0)
+}
+
+func (m *repositoryMock) getFXRates(_ context.Context, txn tx, currency string) ([]fxRate, error) {
+	var (
+		rates []fxRate
+		args  = m.Called(txn, currency)
+	)
+
+	if value, ok := args.Get(0).([]fxRate); ok {
+		rates = value
+	}
+	return rates, args.Error(1)
+}
+
+func (m *repositoryMock) saveImportProfile(_ context.Context, profile ImportProfile) error {
+	args := m.Called(profile)
+	return args.Error(0)
+}
+
+func (m *repositoryMock) getImportProfileByName(_ context.Context, name string) (ImportProfile, error) {
+	var (
+		profile ImportProfile
+		args    = m.Called(name)
+	)
+
+	if value, ok := args.Get(0).(ImportProfile); ok {
+		profile = value
+	}
+	return profile, args.Error(1)
+}
+
+func (m *repositoryMock) createImportBatch(_ context.Context, txn tx, batch ImportBatch) (int64, error) {
+	args := m.Called(txn, batch)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *repositoryMock) finishImportBatch(
+	_ context.Context, txn tx, batchID int64, checksum string, rowCount int) error {
+	args := m.Called(txn, batchID, checksum, rowCount)
+	return args.Error(0)
+}
+
+func (m *repositoryMock) getImportBatchByID(_ context.Context, batchID int64) (ImportBatch, error) {
+	var (
+		batch ImportBatch
+		args  = m.Called(batchID)
+	)
+
+	if value, ok := args.Get(0).(ImportBatch); ok {
+		batch = value
+	}
+	return batch, args.Error(1)
+}
+
+func (m *repositoryMock) getImportBatchesByUserID(_ context.Context, userID int64) ([]ImportBatch, error) {
+	var (
+		batches []ImportBatch
+		args    = m.Called(userID)
+	)
+
+	if value, ok := args.Get(0).([]ImportBatch); ok {
+		batches = value
+	}
+	return batches, args.Error(1)
+}
+
+func (m *repositoryMock) getImportBatchTransactions(_ context.Context, batchID int64) ([]SavedTransaction, error) {
+	var (
+		txns []SavedTransaction
+		args = m.Called(batchID)
+	)
+
+	if value, ok := args.Get(0).([]SavedTransaction); ok {
+		txns = value
+	}
+	return txns, args.Error(1)
+}
+
+func (m *repositoryMock) rollbackImportBatch(_ context.Context, txn tx, batchID int64) (bool, error) {
+	args := m.Called(txn, batchID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *repositoryMock) getLaterImportBatchID(_ context.Context, txn tx, batch ImportBatch) (int64, error) {
+	args := m.Called(txn, batch)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *repositoryMock) deleteImportBatchTransactions(_ context.Context, txn tx, batchID int64) (int64, error) {
+	args := m.Called(txn, batchID)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *repositoryMock) createCategoryRule(_ context.Context, rule CategoryRule) (int64, error) {
+	args := m.Called(rule)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *repositoryMock) getCategoryRuleByID(_ context.Context, ruleID int64) (CategoryRule, error) {
+	var (
+		rule CategoryRule
+		args = m.Called(ruleID)
+	)
+
+	if value, ok := args.Get(0).(CategoryRule); ok {
+		rule = value
+	}
+	return rule, args.Error(1)
+}
+
+func (m *repositoryMock) getCategoryRules(_ context.Context, userID int64) ([]CategoryRule, error) {
+	var (
+		rules []CategoryRule
+		args  = m.Called(userID)
+	)
+
+	if value, ok := args.Get(0).([]CategoryRule); ok {
+		rules = value
+	}
+	return rules, args.Error(1)
+}
+
+func (m *repositoryMock) deleteCategoryRule(_ context.Context, ruleID int64) (bool, error) {
+	args := m.Called(ruleID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *repositoryMock) forEachUserTransaction(
+	_ context.Context, txn tx, userID int64, fn func(transaction) error) error {
+	args := m.Called(txn, userID)
+
+	if value, ok := args.Get(0).([]transaction); ok {
+		for _, userTxn := range value {
+			if err := fn(userTxn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return args.Error(1)
+}
+
+func (m *repositoryMock) hasForeignTransactions(
+	_ context.Context, txn tx, userID int64, currency string) (bool, error) {
+	args := m.Called(txn, userID, currency)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *repositoryMock) getUserTransactions(_ context.Context, txn tx, userID int64) ([]storedTransaction, error) {
+	var (
+		txns []storedTransaction
+		args = m.Called(txn, userID)
+	)
+
+	if value, ok := args.Get(0).([]storedTransaction); ok {
+		txns = value
+	}
+	return txns, args.Error(1)
+}
+
+func (m *repositoryMock) updateTransactionsCategory(_ context.Context, txn tx, category string, txnIDs []int64) error {
+	args := m.Called(txn, category, txnIDs)
+	return args.Error(0)
+}
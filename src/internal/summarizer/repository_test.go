@@ -4,11 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
+	"transaction-tool-api/src/internal/money"
+	"transaction-tool-api/src/internal/notifier"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -30,20 +34,40 @@ func TestSQLRepositorySaveBankTransactions(t *testing.T) {
 		bankTxns  = transactions{
 			items: []transaction{
 				{
-					amount: 10,
-					date:   date,
+					amount:      money.FromInt(10),
+					date:        date,
+					currency:    "USD",
+					description: "coffee",
+					category:    "Food",
+					balance:     money.FromInt(10),
 				},
 				{
-					amount: 10,
-					date:   date.Add(time.Hour),
+					amount:    money.FromInt(10),
+					date:      date.Add(time.Hour),
+					currency:  "MXN",
+					reference: "A-1",
+					balance:   money.FromInt(15),
 				},
 			},
-			userID: 5,
+			userID:  5,
+			batchID: 3,
 		}
-		query  = regexp.QuoteMeta(`INSERT INTO transaction (user_id, amount, date_created) VALUES (?,?,?),(?,?,?)`)
+		query = regexp.QuoteMeta(
+			`INSERT INTO transaction ` +
+				`(user_id, batch_id, amount, currency, description, reference, category, fingerprint, balance, ` +
+				`date_created) VALUES (?,?,?,?,?,?,?,?,?,?),(?,?,?,?,?,?,?,?,?,?)`)
 		params = []driver.Value{
-			bankTxns.userID, bankTxns.items[0].amount, bankTxns.items[0].date,
-			bankTxns.userID, bankTxns.items[1].amount, bankTxns.items[1].date,
+			bankTxns.userID, int64(3), "10.0000", "USD", "coffee", nil, "Food",
+			bankTxns.items[0].fingerprint(5), "10.0000", bankTxns.items[0].date,
+			bankTxns.userID, int64(3), "10.0000", "MXN", nil, "A-1", nil,
+			bankTxns.items[1].fingerprint(5), "15.0000", bankTxns.items[1].date,
+		}
+		manyTxns   = transactions{items: make([]transaction, maxTransactionsByInsert+1), userID: 5}
+		batchQuery = func(size int) string {
+			return regexp.QuoteMeta(
+				`INSERT INTO transaction (user_id, batch_id, amount, currency, description, reference, category, `+
+					`fingerprint, balance, date_created) VALUES (?,?,?,?,?,?,?,?,?,?)`+
+					strings.Repeat(`,(?,?,?,?,?,?,?,?,?,?)`, size-1)) + `$`
 		}
 	)
 	tests := []struct {
@@ -83,6 +107,16 @@ func TestSQLRepositorySaveBankTransactions(t *testing.T) {
 			expected: fmt.Errorf(
 				"total affected rows (%d) mismatch with total transactions (%d)", 1, 2),
 		},
+		{
+			name:     "transactions inserted in batches",
+			bankTxns: manyTxns,
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(batchQuery(maxTransactionsByInsert)).WillReturnResult(
+					sqlmock.NewResult(0, maxTransactionsByInsert))
+				m.ExpectExec(batchQuery(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expected: nil,
+		},
 		{
 			name:     "transactions inserted successfully",
 			bankTxns: bankTxns,
@@ -115,7 +149,7 @@ func TestSQLRepositorySaveBankTransactions(t *testing.T) {
 
 func TestSQLRepositoryGetUserByID(t *testing.T) {
 	var (
-		query = regexp.QuoteMeta(`SELECT id, user_name, email FROM user WHERE id = ?`)
+		query = regexp.QuoteMeta(`SELECT id, user_name, email, currency, timezone FROM user WHERE id = ?`)
 	)
 	tests := []struct {
 		name        string
@@ -134,14 +168,16 @@ func TestSQLRepositoryGetUserByID(t *testing.T) {
 		{
 			name: "error scanning",
 			mockApplier: func(m sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "name", "mail"})
-				rows.AddRow(1, "name", "email")
+				rows := sqlmock.NewRows([]string{"id", "name", "mail", "currency", "timezone"})
+				rows.AddRow(1, "name", "email", "USD", "America/Mexico_City")
 				m.ExpectQuery(query).WithArgs(int64(1)).WillReturnRows(rows)
 			},
 			result: User{
-				UserID: 1,
-				Name:   "name",
-				Email:  "email",
+				UserID:   1,
+				Name:     "name",
+				Email:    "email",
+				Currency: "USD",
+				Timezone: "America/Mexico_City",
 			},
 		},
 	}
@@ -169,3 +205,1442 @@ func TestSQLRepositoryGetUserByID(t *testing.T) {
 		})
 	}
 }
+
+func TestSQLRepositoryCreateJob(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(`INSERT INTO job (user_id, status, payload, options) VALUES (?,?,?,?)`)
+		job       = Job{
+			UserID:  5,
+			Status:  JobStatusQueued,
+			payload: []byte("payload"),
+			options: resumeOptions{Mapping: columnMapping{Amount: "monto", Date: "fecha"}},
+		}
+		options = []byte(`{"Mapping":{"Amount":"monto","Date":"fecha","Currency":"","Description":"","Reference":""}}`)
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    int64
+		expectedErr error
+	}{
+		{
+			name: "error executing query",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs(int64(5), "queued", []byte("payload"), options).WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error inserting job due to: %w", customErr),
+		},
+		{
+			name: "error getting inserted id",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs(int64(5), "queued", []byte("payload"), options).
+					WillReturnResult(sqlmock.NewErrorResult(customErr))
+			},
+			expectedErr: fmt.Errorf("error getting inserted job id due to: %w", customErr),
+		},
+		{
+			name: "job inserted successfully",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs(int64(5), "queued", []byte("payload"), options).
+					WillReturnResult(sqlmock.NewResult(7, 1))
+			},
+			expected: 7,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			jobID, err := repository{client: db}.createJob(context.TODO(), job)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, jobID)
+		})
+	}
+}
+
+func TestSQLRepositoryGetJobByID(t *testing.T) {
+	var (
+		date  = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		query = regexp.QuoteMeta(`SELECT id, user_id, status, error, batch_id, inserted_transactions, ` +
+			`skipped_transactions, date_created, date_updated, date_started, date_finished FROM job WHERE id = ?`)
+		columns = []string{
+			"id", "user_id", "status", "error", "batch_id", "inserted_transactions", "skipped_transactions",
+			"date_created", "date_updated", "date_started", "date_finished"}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    Job
+		expectedErr error
+	}{
+		{
+			name: "job not found",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(columns))
+			},
+			expectedErr: fmt.Errorf("error scanning job by id %d due to: %w", 1, sql.ErrNoRows),
+		},
+		{
+			name: "queued job",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(1)).WillReturnRows(
+					sqlmock.NewRows(columns).AddRow(1, 5, "queued", nil, nil, nil, nil, date, date, nil, nil))
+			},
+			expected: Job{ID: 1, UserID: 5, Status: JobStatusQueued, DateCreated: date, DateUpdated: date},
+		},
+		{
+			name: "failed job",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(1)).WillReturnRows(
+					sqlmock.NewRows(columns).AddRow(1, 5, "failed", "custom error", nil, nil, nil, date, date, date, date))
+			},
+			expected: Job{
+				ID:           1,
+				UserID:       5,
+				Status:       JobStatusFailed,
+				Error:        "custom error",
+				DateCreated:  date,
+				DateUpdated:  date,
+				DateStarted:  &date,
+				DateFinished: &date,
+			},
+		},
+		{
+			name: "done job",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(1)).WillReturnRows(
+					sqlmock.NewRows(columns).AddRow(1, 5, "done", nil, 3, 10, 2, date, date, date, date))
+			},
+			expected: Job{
+				ID:           1,
+				UserID:       5,
+				Status:       JobStatusDone,
+				Result:       &ImportResult{BatchID: 3, InsertedTransactions: 10, SkippedTransactions: 2},
+				DateCreated:  date,
+				DateUpdated:  date,
+				DateStarted:  &date,
+				DateFinished: &date,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			job, err := repository{client: db}.getJobByID(context.TODO(), 1)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, job)
+		})
+	}
+}
+
+func TestSQLRepositoryClaimNextJob(t *testing.T) {
+	var (
+		customErr   = errors.New("custom error")
+		selectQuery = regexp.QuoteMeta(
+			`SELECT id, user_id, payload, options, batch_id, inserted_transactions, skipped_transactions ` +
+				`FROM job WHERE status = ? ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED`)
+		updateQuery = regexp.QuoteMeta(`UPDATE job SET status = ?, date_started = current_timestamp(), ` +
+			`date_updated = current_timestamp() WHERE id = ?`)
+		columns = []string{
+			"id", "user_id", "payload", "options", "batch_id", "inserted_transactions", "skipped_transactions"}
+		options = []byte(`{"Mapping":{"Amount":"monto","Date":"fecha"}}`)
+	)
+
+	tests := []struct {
+		name          string
+		mockApplier   func(sqlmock.Sqlmock)
+		expected      Job
+		expectedFound bool
+		expectedErr   error
+	}{
+		{
+			name: "no queued jobs",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(selectQuery).WithArgs("queued").WillReturnRows(sqlmock.NewRows(columns))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name: "error updating job",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(selectQuery).WithArgs("queued").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, []byte("payload"), options, nil, nil, nil))
+				m.ExpectExec(updateQuery).WithArgs("parsing", int64(1)).WillReturnError(customErr)
+				m.ExpectRollback()
+			},
+			expectedErr: fmt.Errorf("error claiming job id %d due to: %w", 1, customErr),
+		},
+		{
+			name: "job claimed",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(selectQuery).WithArgs("queued").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, []byte("payload"), options, nil, nil, nil))
+				m.ExpectExec(updateQuery).WithArgs("parsing", int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+			expected: Job{
+				ID:      1,
+				UserID:  5,
+				Status:  JobStatusParsing,
+				payload: []byte("payload"),
+				options: resumeOptions{Mapping: columnMapping{Amount: "monto", Date: "fecha"}},
+			},
+			expectedFound: true,
+		},
+		{
+			name: "job imported before an interruption claimed with its result",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(selectQuery).WithArgs("queued").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 5, []byte("payload"), options, 7, 10, 2))
+				m.ExpectExec(updateQuery).WithArgs("parsing", int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+			expected: Job{
+				ID:      1,
+				UserID:  5,
+				Status:  JobStatusParsing,
+				Result:  &ImportResult{BatchID: 7, InsertedTransactions: 10, SkippedTransactions: 2},
+				payload: []byte("payload"),
+				options: resumeOptions{Mapping: columnMapping{Amount: "monto", Date: "fecha"}},
+			},
+			expectedFound: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			job, found, err := repository{client: db}.claimNextJob(context.TODO())
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expectedFound, found)
+			assert.Equal(t, test.expected, job)
+		})
+	}
+}
+
+func TestSQLRepositorySaveJobResult(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(
+			`UPDATE job SET batch_id = ?, inserted_transactions = ?, skipped_transactions = ?, ` +
+				`date_updated = current_timestamp() WHERE id = ? AND batch_id IS NULL`)
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    bool
+		expectedErr error
+	}{
+		{
+			name: "error executing query",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs(int64(7), 10, 2, int64(1)).WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error saving result of job id %d due to: %w", 1, customErr),
+		},
+		{
+			name: "result saved by another run",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs(int64(7), 10, 2, int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			name: "result saved",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs(int64(7), 10, 2, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			mock.ExpectBegin()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			tnx, err := db.Begin()
+			require.Nil(t, err)
+
+			result := ImportResult{BatchID: 7, InsertedTransactions: 10, SkippedTransactions: 2}
+			saved, err := repository{client: db}.saveJobResult(context.TODO(), tx{client: tnx}, 1, result)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, saved)
+		})
+	}
+}
+
+func TestSQLRepositoryRequeueInterruptedJobs(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(
+			`UPDATE job SET status = ?, date_updated = current_timestamp() WHERE status IN (?,?,?) ` +
+				`AND date_updated < current_timestamp() - INTERVAL ? SECOND`)
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    int64
+		expectedErr error
+	}{
+		{
+			name: "error executing query",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("queued", "parsing", "saving", "notifying", int64(120)).
+					WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error requeueing interrupted jobs due to: %w", customErr),
+		},
+		{
+			name: "processing jobs with an expired lease requeued, queued ones left alone",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("queued", "parsing", "saving", "notifying", int64(120)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+			expected: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			requeued, err := repository{client: db}.requeueInterruptedJobs(context.TODO(), 2*time.Minute)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, requeued)
+		})
+	}
+}
+
+func TestSQLRepositoryFinishJob(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(
+			`UPDATE job SET status = ?, error = ?, batch_id = ?, inserted_transactions = ?, ` +
+				`skipped_transactions = ?, date_updated = current_timestamp(), date_finished = current_timestamp() ` +
+				`WHERE id = ?`)
+	)
+
+	tests := []struct {
+		name        string
+		status      JobStatus
+		jobErr      string
+		result      *ImportResult
+		mockApplier func(sqlmock.Sqlmock)
+		expected    error
+	}{
+		{
+			name:   "error executing query",
+			status: JobStatusDone,
+			result: &ImportResult{BatchID: 3, InsertedTransactions: 10, SkippedTransactions: 2},
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("done", nil, int64(3), int64(10), int64(2), int64(1)).
+					WillReturnError(customErr)
+			},
+			expected: fmt.Errorf("error finishing job id %d due to: %w", 1, customErr),
+		},
+		{
+			name:   "job failed",
+			status: JobStatusFailed,
+			jobErr: "custom error",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("failed", "custom error", nil, nil, nil, int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			assert.Equal(t, test.expected, repository{client: db}.finishJob(
+				context.TODO(), 1, test.status, test.jobErr, test.result))
+		})
+	}
+}
+
+func TestSQLRepositoryClaimOutboxMessages(t *testing.T) {
+	var (
+		customErr   = errors.New("custom error")
+		selectQuery = regexp.QuoteMeta(`SELECT id, user_id, email, message, text, attachments, attempts FROM outbox ` +
+			`WHERE status IN (?,?) AND date_next_attempt <= current_timestamp() ORDER BY id LIMIT ? ` +
+			`FOR UPDATE SKIP LOCKED`)
+		updateQuery = regexp.QuoteMeta(
+			`UPDATE outbox SET status = ?, date_next_attempt = current_timestamp() + INTERVAL ? SECOND ` +
+				`WHERE id IN (?,?)`)
+		columns = []string{"id", "user_id", "email", "message", "text", "attachments", "attempts"}
+		rows    = func() *sqlmock.Rows {
+			return sqlmock.NewRows(columns).
+				AddRow(1, 5, "email", "message", nil, nil, 0).
+				AddRow(2, 6, "email", "message", "text", []byte(`[{"Name":"statement.pdf","Content":"cGRm"}]`), 2)
+		}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    []outboxMessage
+		expectedErr error
+	}{
+		{
+			name: "error beginning transaction",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin().WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error creating outbox claim transaction due to: %w", customErr),
+		},
+		{
+			name: "error executing query",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(selectQuery).WithArgs("pending", "sending", 10).WillReturnError(customErr)
+				m.ExpectRollback()
+			},
+			expectedErr: fmt.Errorf("error querying pending outbox messages due to: %w", customErr),
+		},
+		{
+			name: "no pending messages",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(selectQuery).WithArgs("pending", "sending", 10).WillReturnRows(sqlmock.NewRows(columns))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name: "invalid attachments",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(selectQuery).WithArgs("pending", "sending", 10).WillReturnRows(
+					sqlmock.NewRows(columns).AddRow(1, 5, "email", "message", "text", []byte(`{`), 0))
+				m.ExpectRollback()
+			},
+			expectedErr: fmt.Errorf(
+				"error decoding pending outbox message attachments due to: %w",
+				json.Unmarshal([]byte(`{`), &[]notifier.Attachment{})),
+		},
+		{
+			name: "error claiming messages",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(selectQuery).WithArgs("pending", "sending", 10).WillReturnRows(rows())
+				m.ExpectExec(updateQuery).WithArgs("sending", int64(300), int64(1), int64(2)).
+					WillReturnError(customErr)
+				m.ExpectRollback()
+			},
+			expectedErr: fmt.Errorf("error claiming outbox messages due to: %w", customErr),
+		},
+		{
+			name: "error committing claim",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(selectQuery).WithArgs("pending", "sending", 10).WillReturnRows(rows())
+				m.ExpectExec(updateQuery).WithArgs("sending", int64(300), int64(1), int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				m.ExpectCommit().WillReturnError(customErr)
+			},
+			expectedErr: customErr,
+		},
+		{
+			name: "messages claimed",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(selectQuery).WithArgs("pending", "sending", 10).WillReturnRows(rows())
+				m.ExpectExec(updateQuery).WithArgs("sending", int64(300), int64(1), int64(2)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				m.ExpectCommit()
+			},
+			expected: []outboxMessage{
+				{id: 1, userID: 5, email: "email", message: "message", status: outboxStatusSending},
+				{
+					id:          2,
+					userID:      6,
+					email:       "email",
+					message:     "message",
+					text:        "text",
+					attachments: []notifier.Attachment{{Name: "statement.pdf", Content: []byte("pdf")}},
+					status:      outboxStatusSending,
+					attempts:    2,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			messages, err := repository{client: db}.claimOutboxMessages(context.TODO(), 10, 5*time.Minute)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, messages)
+		})
+	}
+}
+
+func TestSQLRepositoryUpdateOutboxMessageStatus(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(`UPDATE outbox SET status = ?, attempts = attempts + 1, error = ?, ` +
+			`date_sent = IF(? = 'sent', current_timestamp(), NULL), ` +
+			`date_next_attempt = current_timestamp() + INTERVAL ? SECOND WHERE id = ?`)
+	)
+
+	tests := []struct {
+		name        string
+		status      outboxStatus
+		messageErr  string
+		retryIn     time.Duration
+		mockApplier func(sqlmock.Sqlmock)
+		expected    error
+	}{
+		{
+			name:   "error executing query",
+			status: outboxStatusSent,
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("sent", nil, "sent", int64(0), int64(1)).WillReturnError(customErr)
+			},
+			expected: fmt.Errorf("error updating status of outbox message id %d due to: %w", 1, customErr),
+		},
+		{
+			name:       "message retried",
+			status:     outboxStatusPending,
+			messageErr: "custom error",
+			retryIn:    2 * time.Minute,
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("pending", "custom error", "pending", int64(120), int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			assert.Equal(t, test.expected, repository{client: db}.updateOutboxMessageStatus(
+				context.TODO(), 1, test.status, test.messageErr, test.retryIn))
+		})
+	}
+}
+
+func TestSQLRepositorySaveFXRates(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		date      = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		rates     = []fxRate{
+			{baseCurrency: "USD", quoteCurrency: "MXN", rate: money.MustParseRate("20.1"), effectiveDate: date},
+			{baseCurrency: "EUR", quoteCurrency: "USD", rate: money.MustParseRate("1.05"), effectiveDate: date},
+		}
+		query = regexp.QuoteMeta(`INSERT INTO fx_rate (base_currency, quote_currency, rate, effective_date) ` +
+			`VALUES (?,?,?,?),(?,?,?,?) ON DUPLICATE KEY UPDATE rate = VALUES(rate)`)
+		params = []driver.Value{
+			"USD", "MXN", "20.10000000", "2021-10-01",
+			"EUR", "USD", "1.05000000", "2021-10-01",
+		}
+	)
+
+	tests := []struct {
+		name        string
+		rates       []fxRate
+		mockApplier func(sqlmock.Sqlmock)
+		expected    error
+	}{
+		{
+			name: "no rates",
+		},
+		{
+			name:  "error executing query",
+			rates: rates,
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs(params...).WillReturnError(customErr)
+			},
+			expected: fmt.Errorf("error inserting fx rates due to: %w", customErr),
+		},
+		{
+			name:  "rates inserted successfully",
+			rates: rates,
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs(params...).WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			mock.ExpectBegin()
+			if test.mockApplier != nil {
+				test.mockApplier(mock)
+			}
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+			tnx, err := db.Begin()
+			require.Nil(t, err)
+
+			assert.Equal(t, test.expected, repository{client: db}.saveFXRates(context.TODO(), tx{tnx}, test.rates))
+		})
+	}
+}
+
+func TestSQLRepositoryGetFXRates(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		date      = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		query     = regexp.QuoteMeta(`SELECT base_currency, quote_currency, rate, effective_date FROM fx_rate ` +
+			`WHERE base_currency = ? OR quote_currency = ?`)
+		columns = []string{"base_currency", "quote_currency", "rate", "effective_date"}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    []fxRate
+		expectedErr error
+	}{
+		{
+			name: "error executing query",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs("USD", "USD").WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error querying fx rates due to: %w", customErr),
+		},
+		{
+			name: "rates",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs("USD", "USD").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow("USD", "MXN", "20.10000000", date))
+			},
+			expected: []fxRate{
+				{baseCurrency: "USD", quoteCurrency: "MXN", rate: money.MustParseRate("20.1"), effectiveDate: date},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			mock.ExpectBegin()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+			tnx, err := db.Begin()
+			require.Nil(t, err)
+
+			rates, err := repository{client: db}.getFXRates(context.TODO(), tx{tnx}, "USD")
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, rates)
+		})
+	}
+}
+
+func TestSQLRepositoryGetImportProfileByName(t *testing.T) {
+	var (
+		query = regexp.QuoteMeta(`SELECT name, amount_column, date_column, currency_column, description_column, ` +
+			`reference_column FROM import_profile WHERE name = ?`)
+		columns = []string{
+			"name", "amount_column", "date_column", "currency_column", "description_column", "reference_column",
+		}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    ImportProfile
+		expectedErr error
+	}{
+		{
+			name: "error scanning",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs("bank").WillReturnError(sql.ErrNoRows)
+			},
+			expectedErr: fmt.Errorf("error scanning import profile '%s' due to: %w", "bank", sql.ErrNoRows),
+		},
+		{
+			name: "profile with optional columns unset",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs("bank").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow("bank", "Monto", "Fecha", nil, "Concepto", nil))
+			},
+			expected: ImportProfile{
+				Name:              "bank",
+				AmountColumn:      "Monto",
+				DateColumn:        "Fecha",
+				DescriptionColumn: "Concepto",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			profile, err := repository{client: db}.getImportProfileByName(context.TODO(), "bank")
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, profile)
+		})
+	}
+}
+
+func TestSQLRepositoryGetSavedFingerprints(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(
+			`SELECT fingerprint FROM transaction WHERE user_id = ? AND (batch_id IS NULL OR batch_id <> ?) ` +
+				`AND fingerprint IN (?,?)`)
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    map[string]bool
+		expectedErr error
+	}{
+		{
+			name: "error executing query",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5), int64(7), "a", "b").WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error querying transaction fingerprints due to: %w", customErr),
+		},
+		{
+			name: "saved fingerprints",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5), int64(7), "a", "b").
+					WillReturnRows(sqlmock.NewRows([]string{"fingerprint"}).AddRow("b"))
+			},
+			expected: map[string]bool{"b": true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			mock.ExpectBegin()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+			tnx, err := db.Begin()
+			require.Nil(t, err)
+
+			saved, err := repository{client: db}.getSavedFingerprints(
+				context.TODO(), tx{tnx}, 5, 7, []string{"a", "b"})
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, saved)
+		})
+	}
+}
+
+func TestSQLRepositoryGetDailyBalances(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		date      = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		query     = regexp.QuoteMeta(`SELECT currency, DATE(date_created), SUM(amount) FROM transaction ` +
+			`WHERE user_id = ? GROUP BY currency, DATE(date_created)`)
+		columns = []string{"currency", "DATE(date_created)", "SUM(amount)"}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    []dailyBalance
+		expectedErr error
+	}{
+		{
+			name: "error querying",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5)).WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error querying daily balances of user id %d due to: %w", 5, customErr),
+		},
+		{
+			name: "daily balances found",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5)).WillReturnRows(sqlmock.NewRows(columns).
+					AddRow("USD", date, "-12.5000").
+					AddRow("MXN", date.AddDate(0, 0, 1), "300.0000"))
+			},
+			expected: []dailyBalance{
+				{currency: "USD", date: date, amount: money.MustParse("-12.5")},
+				{currency: "MXN", date: date.AddDate(0, 0, 1), amount: money.FromInt(300)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			mock.ExpectBegin()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+			tnx, err := db.Begin()
+			require.Nil(t, err)
+
+			balances, err := repository{client: db}.getDailyBalances(context.TODO(), tx{tnx}, 5)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, balances)
+		})
+	}
+}
+
+func TestSQLRepositoryGetDailyTotals(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		location  = time.FixedZone("America/Sao_Paulo", -3*60*60)
+		from      = time.Date(2021, 10, 1, 0, 0, 0, 0, location)
+		to        = from.AddDate(0, 1, 0)
+		date      = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		query     = regexp.QuoteMeta(`SELECT currency, DATE(CONVERT_TZ(date_created, '+00:00', ?)) AS day, ` +
+			`COUNT(*), SUM(CASE WHEN amount > 0 THEN amount ELSE 0 END), SUM(amount > 0), ` +
+			`SUM(CASE WHEN amount < 0 THEN amount ELSE 0 END), SUM(amount < 0) FROM transaction ` +
+			`WHERE user_id = ? AND date_created >= ? AND date_created < ? GROUP BY currency, day`)
+		columns = []string{"currency", "day", "COUNT(*)", "credits", "credit_count", "debits", "debit_count"}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    []dailyTotal
+		expectedErr error
+	}{
+		{
+			name: "error querying",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs("America/Sao_Paulo", int64(5), from, to).WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error querying daily totals of user id %d due to: %w", 5, customErr),
+		},
+		{
+			name: "daily totals found",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs("America/Sao_Paulo", int64(5), from, to).WillReturnRows(sqlmock.NewRows(columns).
+					AddRow("USD", date, 3, "20.0000", "1", "-12.5000", "2").
+					AddRow("MXN", date.AddDate(0, 0, 1), 1, "300.0000", "1", "0.0000", "0"))
+			},
+			expected: []dailyTotal{
+				{
+					currency:    "USD",
+					date:        date,
+					count:       3,
+					credits:     money.FromInt(20),
+					creditCount: 1,
+					debits:      money.MustParse("-12.5"),
+					debitCount:  2,
+				},
+				{currency: "MXN", date: date.AddDate(0, 0, 1), count: 1, credits: money.FromInt(300), creditCount: 1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			mock.ExpectBegin()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+			tnx, err := db.Begin()
+			require.Nil(t, err)
+
+			totals, err := repository{client: db}.getDailyTotals(context.TODO(), tx{tnx}, 5, from, to)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, totals)
+		})
+	}
+}
+
+func TestSQLRepositoryGetDailyDebits(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		location  = time.FixedZone("America/Santiago", -3*60*60)
+		date      = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		query     = regexp.QuoteMeta(`SELECT currency, DATE(CONVERT_TZ(date_created, '+00:00', ?)) AS day, ` +
+			`SUM(amount) FROM transaction WHERE user_id = ? AND amount < 0 GROUP BY currency, day`)
+		columns = []string{"currency", "day", "SUM(amount)"}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    []dailyBalance
+		expectedErr error
+	}{
+		{
+			name: "error querying",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs("America/Santiago", int64(5)).WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error querying daily debits of user id %d due to: %w", 5, customErr),
+		},
+		{
+			name: "daily debits found",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs("America/Santiago", int64(5)).WillReturnRows(sqlmock.NewRows(columns).
+					AddRow("USD", date, "-12.5000").
+					AddRow("MXN", date.AddDate(0, 0, 1), "-300.0000"))
+			},
+			expected: []dailyBalance{
+				{currency: "USD", date: date, amount: money.MustParse("-12.5")},
+				{currency: "MXN", date: date.AddDate(0, 0, 1), amount: money.FromInt(-300)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			mock.ExpectBegin()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+			tnx, err := db.Begin()
+			require.Nil(t, err)
+
+			debits, err := repository{client: db}.getDailyDebits(context.TODO(), tx{tnx}, 5, location)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, debits)
+		})
+	}
+}
+
+func TestSQLRepositoryGetAmountStats(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = `WITH amounts AS \(.+\) SELECT currency, debit, total, median, .+ ` +
+			`FROM deviations GROUP BY currency, debit, total, median`
+		columns = []string{"currency", "debit", "total", "median", "median_deviation", "total_deviation"}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    []amountStats
+		expectedErr error
+	}{
+		{
+			name: "error querying",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5)).WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error querying amount stats of user id %d due to: %w", 5, customErr),
+		},
+		{
+			name: "amount stats found",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5)).WillReturnRows(sqlmock.NewRows(columns).
+					AddRow("USD", 1, 12, "50.5000", "2.2500", "30.0000").
+					AddRow("USD", 0, 3, "1000.0000", "0.0000", "0.0000"))
+			},
+			expected: []amountStats{
+				{
+					currency:        "USD",
+					debit:           true,
+					count:           12,
+					median:          money.MustParse("50.5"),
+					medianDeviation: money.MustParse("2.25"),
+					totalDeviation:  money.FromInt(30),
+				},
+				{currency: "USD", count: 3, median: money.FromInt(1000)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			mock.ExpectBegin()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+			tnx, err := db.Begin()
+			require.Nil(t, err)
+
+			stats, err := repository{client: db}.getAmountStats(context.TODO(), tx{tnx}, 5)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, stats)
+		})
+	}
+}
+
+func TestSQLRepositoryForEachUserTransaction(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		date      = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		query     = regexp.QuoteMeta(`SELECT amount, currency, description, reference, category, date_created ` +
+			`FROM transaction WHERE user_id = ? ORDER BY date_created, id`)
+		columns = []string{"amount", "currency", "description", "reference", "category", "date_created"}
+		rent    = transaction{
+			amount: money.MustParse("-12.5"), currency: "USD", description: "rent", category: "housing", date: date,
+		}
+		rows = func() *sqlmock.Rows {
+			return sqlmock.NewRows(columns).
+				AddRow("-12.5000", "USD", "rent", nil, "housing", date).
+				AddRow("300.0000", "MXN", nil, "ref", nil, date.AddDate(0, 0, 1))
+		}
+	)
+
+	tests := []struct {
+		name        string
+		fnErr       error
+		mockApplier func(sqlmock.Sqlmock)
+		expected    []transaction
+		expectedErr error
+	}{
+		{
+			name: "error querying",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5)).WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error querying transactions of user id %d due to: %w", 5, customErr),
+		},
+		{
+			name:  "function fails",
+			fnErr: customErr,
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5)).WillReturnRows(rows())
+			},
+			expected:    []transaction{rent},
+			expectedErr: customErr,
+		},
+		{
+			name: "transactions read",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5)).WillReturnRows(rows())
+			},
+			expected: []transaction{
+				rent,
+				{amount: money.FromInt(300), currency: "MXN", reference: "ref", date: date.AddDate(0, 0, 1)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			mock.ExpectBegin()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+			tnx, err := db.Begin()
+			require.Nil(t, err)
+
+			var txns []transaction
+			err = repository{client: db}.forEachUserTransaction(context.TODO(), tx{tnx}, 5, func(txn transaction) error {
+				txns = append(txns, txn)
+				return test.fnErr
+			})
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, txns)
+		})
+	}
+}
+
+func TestSQLRepositoryHasForeignTransactions(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM transaction WHERE user_id = ? AND currency <> ?)`)
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    bool
+		expectedErr error
+	}{
+		{
+			name: "error querying",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5), "USD").WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error scanning currencies of user id %d due to: %w", 5, customErr),
+		},
+		{
+			name: "foreign transactions found",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5), "USD").
+					WillReturnRows(sqlmock.NewRows([]string{"foreign"}).AddRow(true))
+			},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			mock.ExpectBegin()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+			tnx, err := db.Begin()
+			require.Nil(t, err)
+
+			foreign, err := repository{client: db}.hasForeignTransactions(context.TODO(), tx{tnx}, 5, "USD")
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, foreign)
+		})
+	}
+}
+
+func TestSQLRepositoryGetImportBatchesByUserID(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		date      = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		query     = regexp.QuoteMeta(`SELECT id, user_id, filename, checksum, row_count, status, date_created ` +
+			`FROM import_batch WHERE user_id = ? ORDER BY id DESC`)
+		columns = []string{"id", "user_id", "filename", "checksum", "row_count", "status", "date_created"}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    []ImportBatch
+		expectedErr error
+	}{
+		{
+			name: "error querying",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5)).WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error querying import batches of user id %d due to: %w", 5, customErr),
+		},
+		{
+			name: "import batches found",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5)).WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(2, 5, nil, "abc", 0, "rolled_back", date).
+					AddRow(1, 5, "october.csv", "def", 10, "imported", date))
+			},
+			expected: []ImportBatch{
+				{ID: 2, UserID: 5, Checksum: "abc", Status: ImportBatchStatusRolledBack, DateCreated: date},
+				{
+					ID:          1,
+					UserID:      5,
+					Filename:    "october.csv",
+					Checksum:    "def",
+					RowCount:    10,
+					Status:      ImportBatchStatusImported,
+					DateCreated: date,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			batches, err := repository{client: db}.getImportBatchesByUserID(context.TODO(), 5)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, batches)
+		})
+	}
+}
+
+func TestSQLRepositoryRollbackImportBatch(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(`UPDATE import_batch SET status = ? WHERE id = ? AND status = ?`)
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    bool
+		expectedErr error
+	}{
+		{
+			name: "error executing query",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("rolled_back", int64(3), "imported").WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error rolling back import batch id %d due to: %w", 3, customErr),
+		},
+		{
+			name: "import batch not imported",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("rolled_back", int64(3), "imported").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expected: false,
+		},
+		{
+			name: "import batch rolled back",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("rolled_back", int64(3), "imported").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			mock.ExpectBegin()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			tnx, err := db.Begin()
+			require.Nil(t, err)
+
+			rolledBack, err := repository{client: db}.rollbackImportBatch(context.TODO(), tx{tnx}, 3)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, rolledBack)
+		})
+	}
+}
+
+func TestSQLRepositoryGetLaterImportBatchID(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(`SELECT id FROM import_batch WHERE user_id = ? AND id > ? AND status = ? ` +
+			`ORDER BY id DESC LIMIT 1 FOR UPDATE`)
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    int64
+		expectedErr error
+	}{
+		{
+			name: "error executing query",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(1), int64(3), "imported").WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error scanning import batch after id %d due to: %w", 3, customErr),
+		},
+		{
+			name: "latest import batch",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(1), int64(3), "imported").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+		},
+		{
+			name: "import batch imported later",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(1), int64(3), "imported").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+			},
+			expected: 5,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			mock.ExpectBegin()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			tnx, err := db.Begin()
+			require.Nil(t, err)
+
+			laterBatchID, err := repository{client: db}.getLaterImportBatchID(
+				context.TODO(), tx{tnx}, ImportBatch{ID: 3, UserID: 1})
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, laterBatchID)
+		})
+	}
+}
+
+func TestSQLRepositoryGetCategoryRules(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		date      = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		query     = regexp.QuoteMeta(`SELECT id, user_id, category, kind, pattern, min_amount, max_amount, priority, ` +
+			`date_created FROM category_rule WHERE user_id = ? OR user_id IS NULL ` +
+			`ORDER BY priority DESC, user_id IS NULL, id`)
+		columns = []string{
+			"id", "user_id", "category", "kind", "pattern", "min_amount", "max_amount", "priority", "date_created",
+		}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    []CategoryRule
+		expectedErr error
+	}{
+		{
+			name: "error querying",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5)).WillReturnError(customErr)
+			},
+			expectedErr: fmt.Errorf("error querying category rules of user id %d due to: %w", 5, customErr),
+		},
+		{
+			name: "category rules found",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(query).WithArgs(int64(5)).WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(2, 5, "Food", "contains", "coffee", nil, nil, 10, date).
+					AddRow(1, nil, "Rent", "amount_range", nil, "-2000.0000", "-1000.0000", 0, date))
+			},
+			expected: []CategoryRule{
+				{
+					ID:          2,
+					UserID:      5,
+					Category:    "Food",
+					Kind:        ruleContains,
+					Pattern:     "coffee",
+					Priority:    10,
+					DateCreated: date,
+				},
+				{
+					ID:          1,
+					Category:    "Rent",
+					Kind:        ruleAmountRange,
+					MinAmount:   "-2000.0000",
+					MaxAmount:   "-1000.0000",
+					DateCreated: date,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+
+			rules, err := repository{client: db}.getCategoryRules(context.TODO(), 5)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, rules)
+		})
+	}
+}
+
+func TestSQLRepositoryUpdateTransactionsCategory(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		query     = regexp.QuoteMeta(`UPDATE transaction SET category = ? WHERE id IN (?,?)`)
+	)
+
+	tests := []struct {
+		name        string
+		category    string
+		mockApplier func(sqlmock.Sqlmock)
+		expected    error
+	}{
+		{
+			name:     "error updating",
+			category: "Food",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs("Food", int64(1), int64(2)).WillReturnError(customErr)
+			},
+			expected: fmt.Errorf("error updating category of transactions due to: %w", customErr),
+		},
+		{
+			name: "category cleared",
+			mockApplier: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(query).WithArgs(nil, int64(1), int64(2)).WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := newDBMock()
+			mock.ExpectBegin()
+			test.mockApplier(mock)
+			defer func() {
+				require.Nil(t, mock.ExpectationsWereMet())
+			}()
+			tnx, err := db.Begin()
+			require.Nil(t, err)
+
+			err = repository{client: db}.
+				updateTransactionsCategory(context.TODO(), tx{tnx}, test.category, []int64{1, 2})
+
+			assert.Equal(t, test.expected, err)
+		})
+	}
+}
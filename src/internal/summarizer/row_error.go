@@ -0,0 +1,57 @@
+package summarizer
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	rowErrorInvalidLength      = "invalid_length"
+	rowErrorInvalidAmount      = "invalid_amount"
+	rowErrorZeroAmount         = "zero_amount"
+	rowErrorInvalidDate        = "invalid_date"
+	rowErrorInvalidCurrency    = "invalid_currency"
+	rowErrorDescriptionTooLong = "description_too_long"
+	rowErrorReferenceTooLong   = "reference_too_long"
+	rowErrorColumnAmount       = "amount"
+	rowErrorColumnDate         = "date"
+	rowErrorColumnCurrency     = "currency"
+	rowErrorColumnDescription  = "description"
+	rowErrorColumnReference    = "reference"
+)
+
+type RowError struct {
+	Row     int
+	Column  string `json:",omitempty"`
+	Value   string `json:",omitempty"`
+	Code    string
+	Message string
+}
+
+func (e RowError) err() error {
+	return errors.New(e.Message)
+}
+
+// rowErrors gathers the row errors found while validating a whole file. Only the first
+// max ones are kept, but all of them are counted.
+type rowErrors struct {
+	items []RowError
+	total int
+	max   int
+}
+
+func (e *rowErrors) add(rowErrs ...RowError) {
+	for _, rowErr := range rowErrs {
+		e.total++
+		if len(e.items) < e.max {
+			e.items = append(e.items, rowErr)
+		}
+	}
+}
+
+func (e rowErrors) Error() string {
+	if e.total > len(e.items) {
+		return fmt.Sprintf("csv body has %d errors, only the first %d are reported", e.total, len(e.items))
+	}
+	return fmt.Sprintf("csv body has %d errors", e.total)
+}
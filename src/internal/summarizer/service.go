@@ -2,36 +2,814 @@ package summarizer
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+	"transaction-tool-api/src/internal/money"
 	"transaction-tool-api/src/internal/notifier"
 )
 
-func NewService(repository Repository, notifierClient notifier.Client) Service {
+func NewService(repository Repository) Service {
 	return service{
 		repository: repository,
-		notifier:   notifierClient,
 	}
 }
 
 type Service interface {
-	notifyResume(ctx context.Context, txns transactions) (err error)
+	notifyResume(ctx context.Context, txns transactionIterator, options resumeOptions) (ImportResult, error)
+	previewResume(ctx context.Context, txns transactionIterator, options resumeOptions) (Resume, error)
+	createJob(ctx context.Context, userID int64, payload []byte, options resumeOptions) (Job, error)
+	getJob(ctx context.Context, jobID int64) (Job, error)
+	saveFXRates(ctx context.Context, rates []fxRate) (err error)
+	saveImportProfile(ctx context.Context, profile ImportProfile) error
+	getImportProfile(ctx context.Context, name string) (ImportProfile, error)
+	getImportBatches(ctx context.Context, userID int64) ([]ImportBatch, error)
+	getImportBatchTransactions(ctx context.Context, batchID int64) ([]SavedTransaction, error)
+	rollbackImportBatch(ctx context.Context, batchID int64) (ImportBatch, error)
+	createCategoryRule(ctx context.Context, rule CategoryRule) (CategoryRule, error)
+	getCategoryRules(ctx context.Context, userID int64) ([]CategoryRule, error)
+	deleteCategoryRule(ctx context.Context, ruleID int64) error
+	recategorizeTransactions(ctx context.Context, userID int64) (RecategorizeResult, error)
+	getForecast(ctx context.Context, userID int64, months int) (Forecast, error)
+	getStatement(ctx context.Context, userID int64, year int, w io.Writer) error
 }
 
 type service struct {
 	repository Repository
-	notifier   notifier.Client
 }
 
-func (s service) notifyResume(ctx context.Context, txns transactions) (err error) {
-	if len(txns.items) == 0 {
-		return nil
-	}
-
+func (s service) notifyResume(
+	ctx context.Context, txns transactionIterator, options resumeOptions) (result ImportResult, err error) {
 	var (
-		summ    summarizer
+		summ    *summarizer
 		user    User
 		repoTx  tx
+		resumes []Resume
+		periods []*summarizer
 		message string
+		batchID int64
+		userID  = txns.getUserID()
+	)
+
+	if repoTx, err = s.repository.initTransactionalOperations(ctx); err != nil {
+		err = fmt.Errorf("error creating repository transaction due to: %w", err)
+		return
+	}
+	defer func() {
+		if err = s.repository.finishTransactionalOperations(ctx, repoTx, err); err != nil {
+			result = ImportResult{}
+		}
+	}()
+
+	if user, err = s.repository.getUserByID(ctx, repoTx, userID); err != nil {
+		err = fmt.Errorf("error getting user due to: %w", err)
+		return
+	}
+
+	reportProgress(ctx, JobStatusSaving)
+
+	batchID, err = s.repository.createImportBatch(ctx, repoTx, ImportBatch{
+		UserID:   userID,
+		Filename: options.Filename,
+		Status:   ImportBatchStatusImported,
+	})
+	if err != nil {
+		err = fmt.Errorf("error creating import batch due to: %w", err)
+		return
+	}
+
+	if summ, result, err = s.summarizeTransactions(ctx, repoTx, user, txns, options, batchID); err != nil {
+		return
+	}
+
+	err = s.repository.finishImportBatch(ctx, repoTx, batchID, txns.getChecksum(), result.InsertedTransactions)
+	if err != nil {
+		err = fmt.Errorf("error finishing import batch due to: %w", err)
+		return
+	}
+
+	result.BatchID = batchID
+
+	// the history includes the transactions just saved
+	if err = s.analyzeHistory(ctx, repoTx, summ, userID, true, options.forecastMonths()); err != nil {
+		return
+	}
+
+	if options.Years == yearsSeparate {
+		for _, year := range summ.sortedYears() {
+			if err = s.compareWithPreviousYear(ctx, repoTx, summ, summ.years[year], userID); err != nil {
+				return
+			}
+			periods = append(periods, summ.years[year])
+		}
+		resumes = summ.yearResumes(user)
+	} else {
+		if err = s.compareWithPreviousYear(ctx, repoTx, summ, summ, userID); err != nil {
+			return
+		}
+		resumes = []Resume{summ.resume(user)}
+		periods = []*summarizer{summ}
+	}
+
+	reportProgress(ctx, JobStatusNotifying)
+
+	for i, resume := range resumes {
+		var (
+			statement   *notifier.Attachment
+			attachments []notifier.Attachment
+		)
+
+		resume.SkippedTransactions = result.SkippedTransactions
+
+		if message, attachments, err = resume.ToEmail(resumeHTMLTemplate); err != nil {
+			err = fmt.Errorf("error generating message for user id %d due to: %w", userID, err)
+			return
+		}
+
+		history := s.userHistory(ctx, repoTx, userID)
+		if statement, err = statementAttachment(user, history, summ.rates, periods[i], resume.Year); err != nil {
+			err = fmt.Errorf("error generating statement for user id %d due to: %w", userID, err)
+			return
+		}
+		if statement != nil {
+			attachments = append(attachments, *statement)
+		}
+
+		err = s.repository.saveOutboxMessage(ctx, repoTx, outboxMessage{
+			userID:      userID,
+			email:       user.Email,
+			message:     message,
+			text:        resume.ToText(),
+			attachments: attachments,
+			status:      outboxStatusPending,
+		})
+		if err != nil {
+			err = fmt.Errorf("error queueing notification to user id %d due to: %w", userID, err)
+			return
+		}
+	}
+
+	// the result is saved last, as it locks the job until the transaction ends
+	if jobID, ok := jobFromContext(ctx); ok {
+		var saved bool
+		if saved, err = s.repository.saveJobResult(ctx, repoTx, jobID, result); err != nil {
+			err = fmt.Errorf("error saving import result due to: %w", err)
+			return
+		}
+		if !saved {
+			err = jobImportedError{jobID: jobID}
+			return
+		}
+	}
+
+	return result, nil
+}
+
+func (s service) previewResume(
+	ctx context.Context, txns transactionIterator, options resumeOptions) (resume Resume, err error) {
+	var (
+		summ   *summarizer
+		user   User
+		repoTx tx
+	)
+
+	if repoTx, err = s.repository.initTransactionalOperations(ctx); err != nil {
+		err = fmt.Errorf("error creating repository transaction due to: %w", err)
+		return
+	}
+	defer func() {
+		err = s.repository.finishTransactionalOperations(ctx, repoTx, err)
+	}()
+
+	if user, err = s.repository.getUserByID(ctx, repoTx, txns.getUserID()); err != nil {
+		err = fmt.Errorf("error getting user due to: %w", err)
+		return
+	}
+
+	if summ, _, err = s.summarizeTransactions(ctx, repoTx, user, txns, options, 0); err != nil {
+		return
+	}
+
+	if err = s.analyzeHistory(ctx, repoTx, summ, txns.getUserID(), false, options.forecastMonths()); err != nil {
+		return
+	}
+
+	if err = s.compareWithPreviousYear(ctx, repoTx, summ, summ, txns.getUserID()); err != nil {
+		return
+	}
+
+	return summ.resume(user), nil
+}
+
+// summarizeTransactions categorizes every transaction of the iterator and reads it into a summarizer,
+// starting from the opening balance of the options, and, when an import batch id is given, saves them
+// under it with their running balance in batches along the way according to the duplicate policy of
+// the options. The transactions skipped as duplicates are left out of the summary.
+func (s service) summarizeTransactions(
+	ctx context.Context,
+	repoTx tx,
+	user User,
+	txns transactionIterator,
+	options resumeOptions,
+	importBatchID int64,
+) (*summarizer, ImportResult, error) {
+	var (
+		summ        = newSummarizer(user.Currency, fxRates{})
+		result      ImportResult
+		ratesLoaded bool
+		save        = importBatchID != 0
+		userID      = txns.getUserID()
+		batch       = transactions{userID: userID, batchID: importBatchID}
+	)
+
+	// addBatch reads the transactions of the batch which are saved, if any, into the summarizer and saves them
+	addBatch := func() error {
+		if save {
+			unique, err := s.uniqueTransactions(ctx, repoTx, batch, options.Duplicates, &result)
+			if err != nil {
+				return err
+			}
+			batch = unique
+		}
+
+		for i := range batch.items {
+			if err := summ.add(batch.items[i]); err != nil {
+				return fmt.Errorf("error generating resume for user id %d due to: %w", userID, err)
+			}
+			batch.items[i].balance = summ.getBalance()
+		}
+
+		if save && len(batch.items) > 0 {
+			if err := s.repository.saveBankTransactions(ctx, repoTx, batch); err != nil {
+				return fmt.Errorf("error saving transactions due to: %w", err)
+			}
+			result.InsertedTransactions += len(batch.items)
+		}
+
+		batch.items = nil
+		return nil
+	}
+
+	location, err := user.location()
+	if err != nil {
+		return nil, ImportResult{}, err
+	}
+
+	summ.fiscalYearStart = options.fiscalYearStart()
+	summ.location = location
+
+	loadRates := func() error {
+		if ratesLoaded {
+			return nil
+		}
+		rates, err := s.getFXRates(ctx, repoTx, user.Currency)
+		if err != nil {
+			return err
+		}
+		summ.rates = rates
+		ratesLoaded = true
+		return nil
+	}
+
+	switch {
+	case options.OpeningBalance != nil:
+		summ.opening = *options.OpeningBalance
+	case options.PreviousBalance:
+		summ.opening, summ.rates, err = s.getSavedBalance(ctx, repoTx, userID, user.Currency, summ.rates)
+		if err != nil {
+			return nil, ImportResult{}, err
+		}
+		ratesLoaded = summ.rates.loaded()
+	}
+
+	categories, err := s.getCategorizer(ctx, userID)
+	if err != nil {
+		return nil, ImportResult{}, err
+	}
+
+	// the unusual activity is the one which does not look like the transactions saved before, which are
+	// aggregated by the database
+	stats, err := s.repository.getAmountStats(ctx, repoTx, userID)
+	if err != nil {
+		return nil, ImportResult{}, fmt.Errorf("error getting transaction history due to: %w", err)
+	}
+	dailyDebits, err := s.repository.getDailyDebits(ctx, repoTx, userID, location)
+	if err != nil {
+		return nil, ImportResult{}, fmt.Errorf("error getting transaction history due to: %w", err)
+	}
+	for _, daily := range dailyDebits {
+		if daily.currency != user.Currency {
+			if err = loadRates(); err != nil {
+				return nil, ImportResult{}, err
+			}
+			break
+		}
+	}
+	if err = summ.learnHistory(stats, dailyDebits); err != nil {
+		return nil, ImportResult{}, fmt.Errorf(
+			"error converting transaction history of user id %d due to: %w", userID, err)
+	}
+
+	for {
+		txn, ok, err := txns.next()
+		if err != nil {
+			return nil, ImportResult{}, err
+		}
+		if !ok {
+			break
+		}
+
+		if txn.currency == "" {
+			txn.currency = user.Currency
+		}
+
+		if txn.currency != user.Currency {
+			if err = loadRates(); err != nil {
+				return nil, ImportResult{}, err
+			}
+		}
+
+		txn.category = categories.categorize(txn)
+
+		batch.items = append(batch.items, txn)
+		if len(batch.items) == maxTransactionsByInsert {
+			if err = addBatch(); err != nil {
+				return nil, ImportResult{}, err
+			}
+		}
+	}
+
+	if len(batch.items) > 0 {
+		if err = addBatch(); err != nil {
+			return nil, ImportResult{}, err
+		}
+	}
+
+	if summ.total == 0 {
+		if result.SkippedTransactions > 0 {
+			return nil, ImportResult{}, transactionsSavedError{userID: userID, skipped: result.SkippedTransactions}
+		}
+		return nil, ImportResult{}, fmt.Errorf("there are no transactions to resume for user id %d", userID)
+	}
+
+	return summ, result, nil
+}
+
+// analyzeHistory finds the recurring movements among the transactions saved for the user, and forecasts the
+// balance for the given months from the one of the saved transactions, plus the net change of the summary when
+// its transactions are not saved. The history is read from the database one transaction at a time, once for each.
+func (s service) analyzeHistory(
+	ctx context.Context, repoTx tx, summ *summarizer, userID int64, saved bool, months int) error {
+	history := s.userHistory(ctx, repoTx, userID)
+
+	movements, err := findRecurring(history, summ.location)
+	if err != nil {
+		return fmt.Errorf("error getting transaction history due to: %w", err)
+	}
+	summ.recurring = summaryRecurring(movements)
+
+	if summ.rates, err = s.getHistoryRates(ctx, repoTx, userID, summ.currency, summ.rates); err != nil {
+		return err
+	}
+
+	balance, rates, err := s.getSavedBalance(ctx, repoTx, userID, summ.currency, summ.rates)
+	if err != nil {
+		return err
+	}
+	summ.rates = rates
+	if !saved {
+		balance += summ.balance
+	}
+
+	forecast, err := forecastBalances(
+		history, movements, summ.rates, summ.currency, summ.location, balance, summ.lastDate, months)
+	if err != nil {
+		return fmt.Errorf("error forecasting balance of user id %d due to: %w", userID, err)
+	}
+	summ.forecast = forecast
+
+	return nil
+}
+
+// compareWithPreviousYear sets on the summary, which is the whole one or one of its years, the totals of
+// its period in the previous year, converted with the rates of the whole one.
+func (s service) compareWithPreviousYear(
+	ctx context.Context, repoTx tx, whole, summ *summarizer, userID int64) error {
+	from, to := summ.previousPeriod()
+
+	totals, err := s.repository.getDailyTotals(ctx, repoTx, userID, from, to)
+	if err != nil {
+		return fmt.Errorf("error getting totals of the previous year due to: %w", err)
+	}
+
+	for _, daily := range totals {
+		if daily.currency != whole.currency && !whole.rates.loaded() {
+			if whole.rates, err = s.getFXRates(ctx, repoTx, whole.currency); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = summ.setPreviousPeriod(from, to, totals, whole.rates); err != nil {
+		return fmt.Errorf("error converting totals of the previous year of user id %d due to: %w", userID, err)
+	}
+
+	return nil
+}
+
+// getSavedBalance returns the balance of the transactions saved for the user in their currency, which is the
+// one the forecasts start from and the opening one of a summary following them. The daily balances of every
+// currency are converted with the rates effective on their day, which are loaded when needed and returned.
+func (s service) getSavedBalance(
+	ctx context.Context, repoTx tx, userID int64, currency string, rates fxRates) (money.Amount, fxRates, error) {
+	var balance money.Amount
+
+	dailyBalances, err := s.repository.getDailyBalances(ctx, repoTx, userID)
+	if err != nil {
+		return 0, fxRates{}, fmt.Errorf("error getting saved balance due to: %w", err)
+	}
+
+	for _, daily := range dailyBalances {
+		if daily.currency != currency && !rates.loaded() {
+			if rates, err = s.getFXRates(ctx, repoTx, currency); err != nil {
+				return 0, fxRates{}, err
+			}
+		}
+
+		amount, err := rates.convert(daily.amount, daily.currency, currency, daily.date)
+		if err != nil {
+			return 0, fxRates{}, fmt.Errorf("error converting saved balance of user id %d due to: %w", userID, err)
+		}
+		balance += amount
+	}
+
+	return balance, rates, nil
+}
+
+// userHistory returns the transactions saved for the user as a history source, which reads them from the
+// database one at a time.
+func (s service) userHistory(ctx context.Context, repoTx tx, userID int64) historySource {
+	return func(fn func(transaction) error) error {
+		return s.repository.forEachUserTransaction(ctx, repoTx, userID, fn)
+	}
+}
+
+// getHistoryRates returns the rates to convert the transactions saved for the user to the currency, which are
+// only loaded when they were not and one of the transactions is in another currency.
+func (s service) getHistoryRates(
+	ctx context.Context, repoTx tx, userID int64, currency string, rates fxRates) (fxRates, error) {
+	if rates.loaded() {
+		return rates, nil
+	}
+
+	foreign, err := s.repository.hasForeignTransactions(ctx, repoTx, userID, currency)
+	if err != nil {
+		return fxRates{}, fmt.Errorf("error getting transaction history due to: %w", err)
+	}
+	if !foreign {
+		return rates, nil
+	}
+
+	return s.getFXRates(ctx, repoTx, currency)
+}
+
+// uniqueTransactions returns the transactions of the batch which were not saved by an earlier upload, unless
+// duplicates are allowed, adding the skipped ones to the result. Identical rows of the file are all kept.
+func (s service) uniqueTransactions(
+	ctx context.Context, repoTx tx, batch transactions, policy duplicatePolicy, result *ImportResult,
+) (transactions, error) {
+	if policy != duplicatesAllow {
+		fingerprints := make([]string, 0, len(batch.items))
+		for _, txn := range batch.items {
+			fingerprints = append(fingerprints, txn.fingerprint(batch.userID))
+		}
+
+		saved, err := s.repository.getSavedFingerprints(ctx, repoTx, batch.userID, batch.batchID, fingerprints)
+		if err != nil {
+			return transactions{}, fmt.Errorf("error getting saved transactions due to: %w", err)
+		}
+		if saved == nil {
+			saved = make(map[string]bool, len(fingerprints))
+		}
+
+		unique := transactions{userID: batch.userID, batchID: batch.batchID, items: make([]transaction, 0, len(batch.items))}
+		for i, txn := range batch.items {
+			if saved[fingerprints[i]] {
+				if policy == duplicatesSkip {
+					result.SkippedTransactions++
+					continue
+				}
+				return transactions{}, duplicateTransactionError{txn: txn}
+			}
+			unique.items = append(unique.items, txn)
+		}
+		batch = unique
+	}
+
+	return batch, nil
+}
+
+func (s service) createJob(ctx context.Context, userID int64, payload []byte, options resumeOptions) (Job, error) {
+	job := Job{
+		UserID:  userID,
+		Status:  JobStatusQueued,
+		payload: payload,
+		options: options,
+	}
+
+	jobID, err := s.repository.createJob(ctx, job)
+	if err != nil {
+		return Job{}, fmt.Errorf("error creating job for user id %d due to: %w", userID, err)
+	}
+
+	return s.getJob(ctx, jobID)
+}
+
+func (s service) getJob(ctx context.Context, jobID int64) (Job, error) {
+	job, err := s.repository.getJobByID(ctx, jobID)
+	if err != nil {
+		return Job{}, fmt.Errorf("error getting job due to: %w", err)
+	}
+	return job, nil
+}
+
+func (s service) getFXRates(ctx context.Context, repoTx tx, currency string) (fxRates, error) {
+	rates, err := s.repository.getFXRates(ctx, repoTx, currency)
+	if err != nil {
+		return fxRates{}, fmt.Errorf("error getting fx rates due to: %w", err)
+	}
+	return newFXRates(rates), nil
+}
+
+func (s service) saveFXRates(ctx context.Context, rates []fxRate) (err error) {
+	var repoTx tx
+
+	if repoTx, err = s.repository.initTransactionalOperations(ctx); err != nil {
+		err = fmt.Errorf("error creating repository transaction due to: %w", err)
+		return
+	}
+	defer func() {
+		err = s.repository.finishTransactionalOperations(ctx, repoTx, err)
+	}()
+
+	if err = s.repository.saveFXRates(ctx, repoTx, rates); err != nil {
+		err = fmt.Errorf("error saving fx rates due to: %w", err)
+		return
+	}
+
+	return nil
+}
+
+func (s service) saveImportProfile(ctx context.Context, profile ImportProfile) error {
+	if err := s.repository.saveImportProfile(ctx, profile); err != nil {
+		return fmt.Errorf("error saving import profile due to: %w", err)
+	}
+	return nil
+}
+
+func (s service) getImportProfile(ctx context.Context, name string) (ImportProfile, error) {
+	profile, err := s.repository.getImportProfileByName(ctx, name)
+	if err != nil {
+		return ImportProfile{}, fmt.Errorf("error getting import profile due to: %w", err)
+	}
+	return profile, nil
+}
+
+func (s service) getImportBatches(ctx context.Context, userID int64) ([]ImportBatch, error) {
+	batches, err := s.repository.getImportBatchesByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting import batches due to: %w", err)
+	}
+	return batches, nil
+}
+
+func (s service) getImportBatchTransactions(ctx context.Context, batchID int64) ([]SavedTransaction, error) {
+	if _, err := s.repository.getImportBatchByID(ctx, batchID); err != nil {
+		return nil, fmt.Errorf("error getting import batch due to: %w", err)
+	}
+
+	txns, err := s.repository.getImportBatchTransactions(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting import batch transactions due to: %w", err)
+	}
+	return txns, nil
+}
+
+// rollbackImportBatch deletes every transaction saved by the batch, keeping the batch as rolled back. Only the
+// latest batch of the user can be rolled back, as the running balances saved by the later ones include the
+// transactions of the batch.
+func (s service) rollbackImportBatch(ctx context.Context, batchID int64) (batch ImportBatch, err error) {
+	var (
+		repoTx       tx
+		rolledBack   bool
+		laterBatchID int64
+	)
+
+	if batch, err = s.repository.getImportBatchByID(ctx, batchID); err != nil {
+		err = fmt.Errorf("error getting import batch due to: %w", err)
+		return
+	}
+
+	if batch.Status == ImportBatchStatusRolledBack {
+		return ImportBatch{}, batchRolledBackError{batchID: batchID}
+	}
+
+	if repoTx, err = s.repository.initTransactionalOperations(ctx); err != nil {
+		err = fmt.Errorf("error creating repository transaction due to: %w", err)
+		return
+	}
+	defer func() {
+		if err = s.repository.finishTransactionalOperations(ctx, repoTx, err); err != nil {
+			batch = ImportBatch{}
+		}
+	}()
+
+	if rolledBack, err = s.repository.rollbackImportBatch(ctx, repoTx, batchID); err != nil {
+		err = fmt.Errorf("error rolling back import batch due to: %w", err)
+		return
+	}
+
+	// another request rolled the batch back since it was read
+	if !rolledBack {
+		err = batchRolledBackError{batchID: batchID}
+		return
+	}
+
+	if laterBatchID, err = s.repository.getLaterImportBatchID(ctx, repoTx, batch); err != nil {
+		err = fmt.Errorf("error getting later import batches due to: %w", err)
+		return
+	}
+	if laterBatchID != 0 {
+		err = laterBatchError{batchID: batchID, laterBatchID: laterBatchID}
+		return
+	}
+
+	if _, err = s.repository.deleteImportBatchTransactions(ctx, repoTx, batchID); err != nil {
+		err = fmt.Errorf("error deleting import batch transactions due to: %w", err)
+		return
+	}
+
+	batch.Status = ImportBatchStatusRolledBack
+
+	return batch, nil
+}
+
+// getCategorizer returns a categorizer with the rules of the user and the global ones.
+func (s service) getCategorizer(ctx context.Context, userID int64) (categorizer, error) {
+	rules, err := s.repository.getCategoryRules(ctx, userID)
+	if err != nil {
+		return categorizer{}, fmt.Errorf("error getting category rules due to: %w", err)
+	}
+	return newCategorizer(rules)
+}
+
+func (s service) createCategoryRule(ctx context.Context, rule CategoryRule) (CategoryRule, error) {
+	ruleID, err := s.repository.createCategoryRule(ctx, rule)
+	if err != nil {
+		return CategoryRule{}, fmt.Errorf("error creating category rule due to: %w", err)
+	}
+
+	if rule, err = s.repository.getCategoryRuleByID(ctx, ruleID); err != nil {
+		return CategoryRule{}, fmt.Errorf("error getting category rule due to: %w", err)
+	}
+	return rule, nil
+}
+
+func (s service) getCategoryRules(ctx context.Context, userID int64) ([]CategoryRule, error) {
+	rules, err := s.repository.getCategoryRules(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting category rules due to: %w", err)
+	}
+	return rules, nil
+}
+
+func (s service) deleteCategoryRule(ctx context.Context, ruleID int64) error {
+	deleted, err := s.repository.deleteCategoryRule(ctx, ruleID)
+	if err != nil {
+		return fmt.Errorf("error deleting category rule due to: %w", err)
+	}
+	if !deleted {
+		return fmt.Errorf("error deleting category rule id %d due to: %w", ruleID, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// recategorizeTransactions applies the current rules to every transaction saved for the user, updating the
+// ones whose category changed.
+func (s service) recategorizeTransactions(ctx context.Context, userID int64) (result RecategorizeResult, err error) {
+	var (
+		repoTx     tx
+		txns       []storedTransaction
+		categories categorizer
+		changed    = make(map[string][]int64)
+	)
+
+	if categories, err = s.getCategorizer(ctx, userID); err != nil {
+		return
+	}
+
+	if repoTx, err = s.repository.initTransactionalOperations(ctx); err != nil {
+		err = fmt.Errorf("error creating repository transaction due to: %w", err)
+		return
+	}
+	defer func() {
+		if err = s.repository.finishTransactionalOperations(ctx, repoTx, err); err != nil {
+			result = RecategorizeResult{}
+		}
+	}()
+
+	if txns, err = s.repository.getUserTransactions(ctx, repoTx, userID); err != nil {
+		err = fmt.Errorf("error getting transactions due to: %w", err)
+		return
+	}
+
+	for _, stored := range txns {
+		if category := categories.categorize(stored.txn); category != stored.txn.category {
+			changed[category] = append(changed[category], stored.id)
+		}
+	}
+
+	names := make([]string, 0, len(changed))
+	for category := range changed {
+		names = append(names, category)
+	}
+	sort.Strings(names)
+
+	for _, category := range names {
+		if err = s.repository.updateTransactionsCategory(ctx, repoTx, category, changed[category]); err != nil {
+			err = fmt.Errorf("error updating category of transactions due to: %w", err)
+			return
+		}
+		result.UpdatedTransactions += len(changed[category])
+	}
+
+	return result, nil
+}
+
+// getForecast forecasts the balance of the user for the given months, from the one of every transaction saved.
+func (s service) getForecast(ctx context.Context, userID int64, months int) (forecast Forecast, err error) {
+	var (
+		repoTx    tx
+		user      User
+		movements []recurringMovement
+		rates     fxRates
+		balance   money.Amount
+	)
+
+	if repoTx, err = s.repository.initTransactionalOperations(ctx); err != nil {
+		err = fmt.Errorf("error creating repository transaction due to: %w", err)
+		return
+	}
+	defer func() {
+		if err = s.repository.finishTransactionalOperations(ctx, repoTx, err); err != nil {
+			forecast = Forecast{}
+		}
+	}()
+
+	if user, err = s.repository.getUserByID(ctx, repoTx, userID); err != nil {
+		err = fmt.Errorf("error getting user due to: %w", err)
+		return
+	}
+
+	location, err := user.location()
+	if err != nil {
+		return
+	}
+
+	history := s.userHistory(ctx, repoTx, userID)
+
+	if movements, err = findRecurring(history, location); err != nil {
+		err = fmt.Errorf("error getting transaction history due to: %w", err)
+		return
+	}
+
+	if rates, err = s.getHistoryRates(ctx, repoTx, userID, user.Currency, rates); err != nil {
+		return
+	}
+
+	if balance, rates, err = s.getSavedBalance(ctx, repoTx, userID, user.Currency, rates); err != nil {
+		return
+	}
+
+	forecast, err = forecastBalances(history, movements, rates, user.Currency, location, balance, time.Time{}, months)
+	if err != nil {
+		err = fmt.Errorf("error forecasting balance of user id %d due to: %w", userID, err)
+		return
+	}
+
+	return forecast, nil
+}
+
+// getStatement writes the statement of the transactions saved for the user in the calendar year, in the
+// time zone of the user, as it is rendered. Nothing is written when the statement fails before its first page.
+func (s service) getStatement(ctx context.Context, userID int64, year int, w io.Writer) (err error) {
+	var (
+		repoTx tx
+		user   User
+		rates  fxRates
 	)
 
 	if repoTx, err = s.repository.initTransactionalOperations(ctx); err != nil {
@@ -42,23 +820,31 @@ func (s service) notifyResume(ctx context.Context, txns transactions) (err error
 		err = s.repository.finishTransactionalOperations(ctx, repoTx, err)
 	}()
 
-	if user, err = s.repository.getUserByID(ctx, repoTx, txns.userID); err != nil {
+	if user, err = s.repository.getUserByID(ctx, repoTx, userID); err != nil {
 		err = fmt.Errorf("error getting user due to: %w", err)
 		return
 	}
 
-	if err = s.repository.saveBankTransactions(ctx, repoTx, txns); err != nil {
-		err = fmt.Errorf("error saving transactions due to: %w", err)
+	location, err := user.location()
+	if err != nil {
 		return
 	}
 
-	if message, err = summ.resume(user, txns).ToHTML(resumeHTMLTemplate); err != nil {
-		err = fmt.Errorf("error generating message for user id %d due to: %w", txns.userID, err)
+	if rates, err = s.getHistoryRates(ctx, repoTx, userID, user.Currency, rates); err != nil {
 		return
 	}
 
-	if err = s.notifier.NotifyToUser(ctx, message, user.Email); err != nil {
-		err = fmt.Errorf("error notifying transactions to user id %d due to: %w", txns.userID, err)
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, location)
+	to := from.AddDate(1, 0, 0)
+
+	history := s.userHistory(ctx, repoTx, userID)
+	written, err := writeStatement(w, user, history, rates, location, from, to, strconv.Itoa(year), 0)
+	if err != nil {
+		err = fmt.Errorf("error generating statement of user id %d due to: %w", userID, err)
+		return
+	}
+	if !written {
+		err = fmt.Errorf("there are no transactions of user id %d in %d: %w", userID, year, sql.ErrNoRows)
 		return
 	}
 
@@ -2,6 +2,7 @@ package summarizer
 
 import (
 	"context"
+	"io"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -10,7 +11,196 @@ type serviceMock struct {
 	mock.Mock
 }
 
-func (m *serviceMock) notifyResume(_ context.Context, txns transactions) (err error) {
-	args := m.Called(txns)
+// readAll reads the whole iterator, as the service does, so that mocks register their calls with
+// the transactions read. Errors found while reading are returned without registering the call.
+func readAll(txns transactionIterator) (transactions, error) {
+	read := transactions{userID: txns.getUserID()}
+	for {
+		txn, ok, err := txns.next()
+		if err != nil {
+			return transactions{}, err
+		}
+		if !ok {
+			return read, nil
+		}
+		read.items = append(read.items, txn)
+	}
+}
+
+func (m *serviceMock) notifyResume(
+	_ context.Context, txns transactionIterator, options resumeOptions) (ImportResult, error) {
+	var result ImportResult
+
+	read, err := readAll(txns)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	args := m.Called(read, options)
+	if value, ok := args.Get(0).(ImportResult); ok {
+		result = value
+	}
+	return result, args.Error(1)
+}
+
+func (m *serviceMock) previewResume(
+	_ context.Context, txns transactionIterator, options resumeOptions) (Resume, error) {
+	var resume Resume
+
+	read, err := readAll(txns)
+	if err != nil {
+		return Resume{}, err
+	}
+
+	args := m.Called(read, options)
+	if value, ok := args.Get(0).(Resume); ok {
+		resume = value
+	}
+	return resume, args.Error(1)
+}
+
+func (m *serviceMock) createJob(_ context.Context, userID int64, payload []byte, options resumeOptions) (Job, error) {
+	var (
+		job  Job
+		args = m.Called(userID, payload, options)
+	)
+
+	if value, ok := args.Get(0).(Job); ok {
+		job = value
+	}
+	return job, args.Error(1)
+}
+
+func (m *serviceMock) getJob(_ context.Context, jobID int64) (Job, error) {
+	var (
+		job  Job
+		args = m.Called(jobID)
+	)
+
+	if value, ok := args.Get(0).(Job); ok {
+		job = value
+	}
+	return job, args.Error(1)
+}
+
+func (m *serviceMock) saveFXRates(_ context.Context, rates []fxRate) (err error) {
+	args := m.Called(rates)
+	return args.Error(0)
+}
+
+func (m *serviceMock) saveImportProfile(_ context.Context, profile ImportProfile) error {
+	args := m.Called(profile)
 	return args.Error(0)
 }
+
+func (m *serviceMock) getImportProfile(_ context.Context, name string) (ImportProfile, error) {
+	var (
+		profile ImportProfile
+		args    = m.Called(name)
+	)
+
+	if value, ok := args.Get(0).(ImportProfile); ok {
+		profile = value
+	}
+	return profile, args.Error(1)
+}
+
+func (m *serviceMock) getImportBatches(_ context.Context, userID int64) ([]ImportBatch, error) {
+	var (
+		batches []ImportBatch
+		args    = m.Called(userID)
+	)
+
+	if value, ok := args.Get(0).([]ImportBatch); ok {
+		batches = value
+	}
+	return batches, args.Error(1)
+}
+
+func (m *serviceMock) getImportBatchTransactions(_ context.Context, batchID int64) ([]SavedTransaction, error) {
+	var (
+		txns []SavedTransaction
+		args = m.Called(batchID)
+	)
+
+	if value, ok := args.Get(0).([]SavedTransaction); ok {
+		txns = value
+	}
+	return txns, args.Error(1)
+}
+
+func (m *serviceMock) rollbackImportBatch(_ context.Context, batchID int64) (ImportBatch, error) {
+	var (
+		batch ImportBatch
+		args  = m.Called(batchID)
+	)
+
+	if value, ok := args.Get(0).(ImportBatch); ok {
+		batch = value
+	}
+	return batch, args.Error(1)
+}
+
+func (m *serviceMock) createCategoryRule(_ context.Context, rule CategoryRule) (CategoryRule, error) {
+	var (
+		created CategoryRule
+		args    = m.Called(rule)
+	)
+
+	if value, ok := args.Get(0).(CategoryRule); ok {
+		created = value
+	}
+	return created, args.Error(1)
+}
+
+func (m *serviceMock) getCategoryRules(_ context.Context, userID int64) ([]CategoryRule, error) {
+	var (
+		rules []CategoryRule
+		args  = m.Called(userID)
+	)
+
+	if value, ok := args.Get(0).([]CategoryRule); ok {
+		rules = value
+	}
+	return rules, args.Error(1)
+}
+
+func (m *serviceMock) deleteCategoryRule(_ context.Context, ruleID int64) error {
+	args := m.Called(ruleID)
+	return args.Error(0)
+}
+
+func (m *serviceMock) recategorizeTransactions(_ context.Context, userID int64) (RecategorizeResult, error) {
+	var (
+		result RecategorizeResult
+		args   = m.Called(userID)
+	)
+
+	if value, ok := args.Get(0).(RecategorizeResult); ok {
+		result = value
+	}
+	return result, args.Error(1)
+}
+
+func (m *serviceMock) getForecast(_ context.Context, userID int64, months int) (Forecast, error) {
+	var (
+		forecast Forecast
+		args     = m.Called(userID, months)
+	)
+
+	if value, ok := args.Get(0).(Forecast); ok {
+		forecast = value
+	}
+	return forecast, args.Error(1)
+}
+
+func (m *serviceMock) getStatement(_ context.Context, userID int64, year int, w io.Writer) error {
+	args := m.Called(userID, year)
+
+	if statement, ok := args.Get(0).([]byte); ok {
+		if _, err := w.Write(statement); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
@@ -1,14 +1,17 @@
 package summarizer
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"testing"
 	"time"
-	"transaction-tool-api/src/internal/notifier"
+	"transaction-tool-api/src/internal/money"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestServiceResumeTransactions(t *testing.T) {
@@ -17,28 +20,90 @@ func TestServiceResumeTransactions(t *testing.T) {
 		customErr = errors.New("custom error")
 		bankTnxs  = transactions{
 			items: []transaction{
-				{amount: 10, date: date},
+				{amount: money.FromInt(10), date: date},
 			},
 			userID: 1,
 		}
-		msg, _ = Resume{
-			Balance:           "10.00",
-			CreditAvg:         "10.00",
-			DebitAvg:          "0.00",
-			MonthTransactions: []MonthTransaction{{time.December.String(), 1}},
-		}.ToHTML(resumeHTMLTemplate)
+		resume = Resume{
+			Balance:        "10.00",
+			MinBalance:     "10.00",
+			MinBalanceDate: "2021-12-01",
+			MaxBalance:     "10.00",
+			MaxBalanceDate: "2021-12-01",
+			CreditAvg:      "10.00",
+			DebitAvg:       "0.00",
+			MonthTransactions: []MonthTransaction{
+				singleTransactionMonth(time.December, money.FromInt(10), money.FromInt(10)),
+			},
+		}
+		msg, charts, _ = resume.ToEmail(resumeHTMLTemplate)
+		foreignTnxs    = transactions{
+			items: []transaction{
+				{amount: money.FromInt(10), date: date, currency: "MXN"},
+			},
+			userID: 1,
+		}
+		fingerprints = []string{bankTnxs.items[0].fingerprint(1)}
+		dupTnxs      = transactions{
+			items: []transaction{
+				{amount: money.FromInt(10), date: date},
+				{amount: money.FromInt(20), date: date},
+				{amount: money.FromInt(20), date: date},
+			},
+			userID: 1,
+		}
+		dupFingerprints = []string{
+			dupTnxs.items[0].fingerprint(1), dupTnxs.items[1].fingerprint(1), dupTnxs.items[2].fingerprint(1),
+		}
+		yearsTnxs = transactions{
+			items: []transaction{
+				{amount: money.FromInt(10), date: date},
+				{amount: money.FromInt(20), date: date.AddDate(1, 0, 0)},
+			},
+			userID: 1,
+		}
+		manyTnxs       = transactions{userID: 1}
+		openingBalance = money.FromInt(-5)
+		savedTnxs      = transactions{items: runningBalances(bankTnxs.items, 0), userID: 1, batchID: 7}
+		importBatch    = ImportBatch{UserID: 1, Status: ImportBatchStatusImported}
+		outboxMsg      = outboxMessage{
+			userID:      1,
+			email:       "email",
+			message:     msg,
+			text:        resume.ToText(),
+			attachments: charts,
+			status:      outboxStatusPending,
+		}
 	)
 
+	for i := 0; i <= maxTransactionsByInsert; i++ {
+		manyTnxs.items = append(manyTnxs.items, transaction{amount: money.FromInt(int64(i + 1)), date: date})
+	}
+
 	tests := []struct {
-		name         string
-		transactions transactions
-		mockApplier  func(rm *repositoryMock, nm *notifier.Mock)
-		expected     error
+		name           string
+		transactions   transactions
+		options        resumeOptions
+		mockApplier    func(rm *repositoryMock)
+		expectedResult ImportResult
+		expected       error
 	}{
 		{
 			name:         "no transactions",
-			transactions: transactions{},
-			expected:     nil,
+			transactions: transactions{userID: 1},
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf("there are no transactions to resume for user id %d", 1)).
+					Return(customErr).Once()
+			},
+			expected: customErr,
 		},
 		{
 			name: "init transactional operations fails",
@@ -47,7 +112,7 @@ func TestServiceResumeTransactions(t *testing.T) {
 					{},
 				},
 			},
-			mockApplier: func(rm *repositoryMock, nm *notifier.Mock) {
+			mockApplier: func(rm *repositoryMock) {
 				rm.On("initTransactionalOperations").Return(nil, customErr).Once()
 			},
 			expected: fmt.Errorf("error creating repository transaction due to: %w", customErr),
@@ -55,7 +120,7 @@ func TestServiceResumeTransactions(t *testing.T) {
 		{
 			name:         "error getting user",
 			transactions: bankTnxs,
-			mockApplier: func(rm *repositoryMock, nm *notifier.Mock) {
+			mockApplier: func(rm *repositoryMock) {
 				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
 				rm.On("getUserByID", tx{}, int64(1)).Return(nil, customErr).Once()
 				rm.On(
@@ -65,13 +130,149 @@ func TestServiceResumeTransactions(t *testing.T) {
 			},
 			expected: customErr,
 		},
+		{
+			name:         "user timezone not valid",
+			transactions: bankTnxs,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{UserID: 1, Timezone: "Mars/Olympus"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, mock.Anything).Return(customErr).Once()
+			},
+			expected: customErr,
+		},
+		{
+			name:         "error getting fx rates",
+			transactions: foreignTnxs,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email", Currency: "USD"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("getFXRates", tx{}, "USD").Return(nil, customErr).Once()
+				rm.On("finishTransactionalOperations",
+					tx{}, fmt.Errorf("error getting fx rates due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expected: customErr,
+		},
+		{
+			name:         "missing fx rate",
+			transactions: foreignTnxs,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email", Currency: "USD"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("getFXRates", tx{}, "USD").Return(nil, nil).Once()
+				rm.On("getSavedFingerprints", tx{}, int64(1), int64(7), mock.Anything).Return(nil, nil).Once()
+				rm.On("finishTransactionalOperations",
+					tx{}, fmt.Errorf("error generating resume for user id %d due to: %w", 1,
+						errors.New("there is no fx rate from MXN to USD effective on 2021-12-01"))).
+					Return(customErr).Once()
+			},
+			expected: customErr,
+		},
+		{
+			name:         "missing fx rate of transaction history",
+			transactions: bankTnxs,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email", Currency: "USD"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return([]amountStats{
+					{currency: "MXN", debit: true, count: 1, median: money.FromInt(10)},
+				}, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return([]dailyBalance{
+					{currency: "MXN", date: date, amount: money.FromInt(-10)},
+				}, nil).Once()
+				rm.On("getFXRates", tx{}, "USD").Return(nil, nil).Once()
+				rm.On("finishTransactionalOperations",
+					tx{}, fmt.Errorf("error converting transaction history of user id %d due to: %w", 1,
+						errors.New("there is no fx rate from MXN to USD effective on 2021-12-01"))).
+					Return(customErr).Once()
+			},
+			expected: customErr,
+		},
 		{
 			name:         "save bank transactions fails",
 			transactions: bankTnxs,
-			mockApplier: func(rm *repositoryMock, nm *notifier.Mock) {
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("getSavedFingerprints", tx{}, int64(1), int64(7), fingerprints).Return(nil, nil).Once()
+				rm.On("saveBankTransactions", tx{}, savedTnxs).Return(customErr).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf("error saving transactions due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expected: customErr,
+		},
+		{
+			name:         "create import batch fails",
+			transactions: bankTnxs,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(0, customErr).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf("error creating import batch due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expected: customErr,
+		},
+		{
+			name:         "finish import batch fails",
+			transactions: bankTnxs,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("getSavedFingerprints", tx{}, int64(1), int64(7), fingerprints).Return(nil, nil).Once()
+				rm.On("saveBankTransactions", tx{}, savedTnxs).Return(nil).Once()
+				rm.On("finishImportBatch", tx{}, int64(7), "", 1).Return(customErr).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf("error finishing import batch due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expected: customErr,
+		},
+		{
+			name:         "transactions saved in batches",
+			transactions: manyTnxs,
+			options:      resumeOptions{Duplicates: duplicatesAllow},
+			mockApplier: func(rm *repositoryMock) {
 				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
 				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
-				rm.On("saveBankTransactions", tx{}, bankTnxs).Return(customErr).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("saveBankTransactions", tx{}, transactions{
+					items:   runningBalances(manyTnxs.items, 0)[:maxTransactionsByInsert],
+					userID:  1,
+					batchID: 7,
+				}).Return(nil).Once()
+				rm.On("saveBankTransactions", tx{}, transactions{
+					items:   runningBalances(manyTnxs.items, 0)[maxTransactionsByInsert:],
+					userID:  1,
+					batchID: 7,
+				}).Return(customErr).Once()
 				rm.On(
 					"finishTransactionalOperations",
 					tx{}, fmt.Errorf("error saving transactions due to: %w", customErr)).
@@ -80,16 +281,63 @@ func TestServiceResumeTransactions(t *testing.T) {
 			expected: customErr,
 		},
 		{
-			name:         "notifier user fails",
+			name:         "error getting transaction history",
+			transactions: bankTnxs,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, customErr).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf("error getting transaction history due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expected: customErr,
+		},
+		{
+			name:         "error getting transaction history after saving",
+			transactions: bankTnxs,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("getSavedFingerprints", tx{}, int64(1), int64(7), fingerprints).Return(nil, nil).Once()
+				rm.On("saveBankTransactions", tx{}, savedTnxs).Return(nil).Once()
+				rm.On("finishImportBatch", tx{}, int64(7), "", 1).Return(nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, customErr).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf("error getting transaction history due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expected: customErr,
+		},
+		{
+			name:         "save outbox message fails",
 			transactions: bankTnxs,
-			mockApplier: func(rm *repositoryMock, nm *notifier.Mock) {
+			mockApplier: func(rm *repositoryMock) {
 				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
 				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
-				rm.On("saveBankTransactions", tx{}, bankTnxs).Return(nil).Once()
-				nm.On("NotifyToUser", msg, "email").Return(customErr).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getSavedFingerprints", tx{}, int64(1), int64(7), fingerprints).Return(nil, nil).Once()
+				rm.On("saveBankTransactions", tx{}, savedTnxs).Return(nil).Once()
+				rm.On("finishImportBatch", tx{}, int64(7), "", 1).Return(nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), mock.Anything).Return(false, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), mock.Anything, mock.Anything).Return(nil, nil).Once()
+				rm.On("saveOutboxMessage", tx{}, outboxMsg).Return(customErr).Once()
 				rm.On(
 					"finishTransactionalOperations", tx{},
-					fmt.Errorf("error notifying transactions to user id %d due to: %w", bankTnxs.userID, customErr)).
+					fmt.Errorf("error queueing notification to user id %d due to: %w", bankTnxs.userID, customErr)).
 					Return(customErr).Once()
 			},
 			expected: customErr,
@@ -97,11 +345,21 @@ func TestServiceResumeTransactions(t *testing.T) {
 		{
 			name:         "finish transaction fails",
 			transactions: bankTnxs,
-			mockApplier: func(rm *repositoryMock, nm *notifier.Mock) {
+			mockApplier: func(rm *repositoryMock) {
 				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
-				rm.On("saveBankTransactions", tx{}, bankTnxs).Return(nil).Once()
+				rm.On("getSavedFingerprints", tx{}, int64(1), int64(7), fingerprints).Return(nil, nil).Once()
+				rm.On("saveBankTransactions", tx{}, savedTnxs).Return(nil).Once()
 				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
-				nm.On("NotifyToUser", msg, "email").Return(nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("finishImportBatch", tx{}, int64(7), "", 1).Return(nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), mock.Anything).Return(false, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), mock.Anything, mock.Anything).Return(nil, nil).Once()
+				rm.On("saveOutboxMessage", tx{}, outboxMsg).Return(nil).Once()
 				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(customErr).Once()
 			},
 			expected: customErr,
@@ -109,31 +367,1238 @@ func TestServiceResumeTransactions(t *testing.T) {
 		{
 			name:         "user notified successfully",
 			transactions: bankTnxs,
-			mockApplier: func(rm *repositoryMock, nm *notifier.Mock) {
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getSavedFingerprints", tx{}, int64(1), int64(7), fingerprints).Return(nil, nil).Once()
+				rm.On("saveBankTransactions", tx{}, savedTnxs).Return(nil).Once()
+				rm.On("finishImportBatch", tx{}, int64(7), "", 1).Return(nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), mock.Anything).Return(false, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), mock.Anything, mock.Anything).Return(nil, nil).Once()
+				rm.On("saveOutboxMessage", tx{}, outboxMsg).Return(nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expectedResult: ImportResult{BatchID: 7, InsertedTransactions: 1},
+			expected:       nil,
+		},
+		{
+			name:         "error getting saved transactions",
+			transactions: bankTnxs,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("getSavedFingerprints", tx{}, int64(1), int64(7), fingerprints).Return(nil, customErr).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf("error getting saved transactions due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expected: customErr,
+		},
+		{
+			name:         "duplicate transaction rejected",
+			transactions: bankTnxs,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("getSavedFingerprints", tx{}, int64(1), int64(7), fingerprints).
+					Return(map[string]bool{fingerprints[0]: true}, nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, duplicateTransactionError{txn: bankTnxs.items[0]}).
+					Return(duplicateTransactionError{txn: bankTnxs.items[0]}).Once()
+			},
+			expected: duplicateTransactionError{txn: bankTnxs.items[0]},
+		},
+		{
+			name:         "identical rows of the file saved",
+			transactions: dupTnxs,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getSavedFingerprints", tx{}, int64(1), int64(7), dupFingerprints).
+					Return(nil, nil).Once()
+				rm.On("saveBankTransactions", tx{}, transactions{
+					items: runningBalances(dupTnxs.items, 0), userID: 1, batchID: 7,
+				}).
+					Return(nil).Once()
+				rm.On("finishImportBatch", tx{}, int64(7), "", 3).Return(nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), mock.Anything).Return(false, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), mock.Anything, mock.Anything).Return(nil, nil).Once()
+				rm.On("saveOutboxMessage", tx{}, mock.Anything).Return(nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expectedResult: ImportResult{BatchID: 7, InsertedTransactions: 3},
+		},
+		{
+			name:         "duplicate transactions skipped",
+			transactions: dupTnxs,
+			options:      resumeOptions{Duplicates: duplicatesSkip},
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getSavedFingerprints", tx{}, int64(1), int64(7), dupFingerprints).
+					Return(map[string]bool{dupFingerprints[0]: true}, nil).Once()
+				rm.On("saveBankTransactions", tx{}, transactions{
+					items: runningBalances(dupTnxs.items[1:], 0), userID: 1, batchID: 7,
+				}).
+					Return(nil).Once()
+				rm.On("finishImportBatch", tx{}, int64(7), "", 2).Return(nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), mock.Anything).Return(false, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), mock.Anything, mock.Anything).Return(nil, nil).Once()
+				rm.On("saveOutboxMessage", tx{}, mock.Anything).Return(nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expectedResult: ImportResult{BatchID: 7, InsertedTransactions: 2, SkippedTransactions: 1},
+		},
+		{
+			name:         "every transaction already saved",
+			transactions: dupTnxs,
+			options:      resumeOptions{Duplicates: duplicatesSkip},
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("getSavedFingerprints", tx{}, int64(1), int64(7), dupFingerprints).
+					Return(map[string]bool{dupFingerprints[0]: true, dupFingerprints[1]: true}, nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, transactionsSavedError{userID: 1, skipped: 3}).
+					Return(transactionsSavedError{userID: 1, skipped: 3}).Once()
+			},
+			expected: transactionsSavedError{userID: 1, skipped: 3},
+		},
+		{
+			name:         "user notified by year",
+			transactions: yearsTnxs,
+			options:      resumeOptions{Duplicates: duplicatesAllow, Years: yearsSeparate},
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("saveBankTransactions", tx{}, transactions{
+					items: runningBalances(yearsTnxs.items, 0), userID: 1, batchID: 7,
+				}).
+					Return(nil).Once()
+				rm.On("finishImportBatch", tx{}, int64(7), "", 2).Return(nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), mock.Anything).Return(false, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), date.AddDate(-1, 0, 0), date.AddDate(-1, 0, 1)).
+					Return(nil, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), date, date.AddDate(0, 0, 1)).Return(nil, nil).Once()
+				rm.On("saveOutboxMessage", tx{}, mock.Anything).Return(nil).Twice()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expectedResult: ImportResult{BatchID: 7, InsertedTransactions: 2},
+		},
+		{
+			name:         "user notified by year with statements",
+			transactions: yearsTnxs,
+			options:      resumeOptions{Duplicates: duplicatesAllow, Years: yearsSeparate},
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("saveBankTransactions", tx{}, mock.Anything).Return(nil).Once()
+				rm.On("finishImportBatch", tx{}, int64(7), "", 2).Return(nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(yearsTnxs.items, nil).Times(6)
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), "").Return(false, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), mock.Anything, mock.Anything).Return(nil, nil).Twice()
+				for _, name := range []string{"statement-2021.pdf", "statement-2022.pdf"} {
+					name := name
+					rm.On("saveOutboxMessage", tx{}, mock.MatchedBy(func(msg outboxMessage) bool {
+						// the charts go first, inline, and the statement last
+						if len(msg.attachments) != 3 {
+							return false
+						}
+						statement := msg.attachments[2]
+						return msg.attachments[0].Inline && msg.attachments[1].Inline && !statement.Inline &&
+							statement.Name == name &&
+							statement.ContentType == "application/pdf" &&
+							bytes.HasPrefix(statement.Content, []byte("%PDF-1.4"))
+					})).Return(nil).Once()
+				}
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expectedResult: ImportResult{BatchID: 7, InsertedTransactions: 2},
+		},
+		{
+			name:         "duplicate transactions allowed",
+			transactions: dupTnxs,
+			options:      resumeOptions{Duplicates: duplicatesAllow},
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("saveBankTransactions", tx{}, transactions{
+					items: runningBalances(dupTnxs.items, 0), userID: 1, batchID: 7,
+				}).Return(nil).Once()
+				rm.On("finishImportBatch", tx{}, int64(7), "", 3).Return(nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), mock.Anything).Return(false, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), mock.Anything, mock.Anything).Return(nil, nil).Once()
+				rm.On("saveOutboxMessage", tx{}, mock.Anything).Return(nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expectedResult: ImportResult{BatchID: 7, InsertedTransactions: 3},
+		},
+		{
+			name:         "error getting category rules",
+			transactions: bankTnxs,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, customErr).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf("error getting category rules due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expected: customErr,
+		},
+		{
+			name: "transactions categorized",
+			transactions: transactions{
+				items: []transaction{
+					{amount: money.FromInt(-10), date: date, description: "Corner coffee"},
+					{amount: money.FromInt(20), date: date, description: "refund"},
+				},
+				userID: 1,
+			},
+			options: resumeOptions{Duplicates: duplicatesAllow},
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).
+					Return([]CategoryRule{{Category: "Food", Kind: ruleContains, Pattern: "coffee"}}, nil).Once()
+				rm.On("saveBankTransactions", tx{}, transactions{
+					items: []transaction{
+						{
+							amount:      money.FromInt(-10),
+							date:        date,
+							description: "Corner coffee",
+							category:    "Food",
+							balance:     money.FromInt(-10),
+						},
+						{amount: money.FromInt(20), date: date, description: "refund", balance: money.FromInt(10)},
+					},
+					userID:  1,
+					batchID: 7,
+				}).Return(nil).Once()
+				rm.On("finishImportBatch", tx{}, int64(7), "", 2).Return(nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), mock.Anything).Return(false, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), mock.Anything, mock.Anything).Return(nil, nil).Once()
+				rm.On("saveOutboxMessage", tx{}, mock.Anything).Return(nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expectedResult: ImportResult{BatchID: 7, InsertedTransactions: 2},
+		},
+		{
+			name:         "opening balance given",
+			transactions: bankTnxs,
+			options:      resumeOptions{Duplicates: duplicatesAllow, OpeningBalance: &openingBalance},
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("saveBankTransactions", tx{}, transactions{
+					items: runningBalances(bankTnxs.items, openingBalance), userID: 1, batchID: 7,
+				}).Return(nil).Once()
+				rm.On("finishImportBatch", tx{}, int64(7), "", 1).Return(nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), mock.Anything).Return(false, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), mock.Anything, mock.Anything).Return(nil, nil).Once()
+				rm.On("saveOutboxMessage", tx{}, mock.Anything).Return(nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expectedResult: ImportResult{BatchID: 7, InsertedTransactions: 1},
+		},
+		{
+			name:         "error getting previous balance",
+			transactions: bankTnxs,
+			options:      resumeOptions{PreviousBalance: true},
+			mockApplier: func(rm *repositoryMock) {
 				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
 				rm.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
-				rm.On("saveBankTransactions", tx{}, bankTnxs).Return(nil).Once()
-				nm.On("NotifyToUser", msg, "email").Return(nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, customErr).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf("error getting saved balance due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expected: customErr,
+		},
+		{
+			name:         "opening balance derived from saved transactions",
+			transactions: bankTnxs,
+			options:      resumeOptions{Duplicates: duplicatesAllow, PreviousBalance: true},
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).
+					Return(User{UserID: 1, Email: "email", Currency: "USD"}, nil).Once()
+				rm.On("createImportBatch", tx{}, importBatch).Return(7, nil).Once()
+				rm.On("getDailyBalances", tx{}, int64(1)).Return([]dailyBalance{
+					{currency: "USD", date: date.AddDate(0, -1, 0), amount: money.FromInt(100)},
+					{currency: "MXN", date: date.AddDate(0, -1, 0), amount: money.FromInt(40)},
+				}, nil).Twice()
+				rm.On("getFXRates", tx{}, "USD").Return([]fxRate{{
+					baseCurrency:  "MXN",
+					quoteCurrency: "USD",
+					rate:          money.MustParseRate("0.5"),
+					effectiveDate: date.AddDate(0, -2, 0),
+				}}, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("saveBankTransactions", tx{}, transactions{
+					items: []transaction{
+						{amount: money.FromInt(10), date: date, currency: "USD", balance: money.FromInt(130)},
+					},
+					userID:  1,
+					batchID: 7,
+				}).Return(nil).Once()
+				rm.On("finishImportBatch", tx{}, int64(7), "", 1).Return(nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+				rm.On("getDailyTotals", tx{}, int64(1), mock.Anything, mock.Anything).Return(nil, nil).Once()
+				rm.On("saveOutboxMessage", tx{}, mock.Anything).Return(nil).Once()
 				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
 			},
-			expected: nil,
+			expectedResult: ImportResult{BatchID: 7, InsertedTransactions: 1},
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			notifierMock := &notifier.Mock{}
 			repoMock := &repositoryMock{}
 			if test.mockApplier != nil {
-				test.mockApplier(repoMock, notifierMock)
+				test.mockApplier(repoMock)
 				defer repoMock.AssertExpectations(t)
-				defer notifierMock.AssertExpectations(t)
 			}
 			serv := service{
 				repository: repoMock,
-				notifier:   notifierMock,
 			}
-			assert.Equal(t, test.expected, serv.notifyResume(context.TODO(), test.transactions))
+
+			result, err := serv.notifyResume(context.TODO(), test.transactions.iterator(), test.options)
+
+			assert.Equal(t, test.expected, err)
+			assert.Equal(t, test.expectedResult, result)
+		})
+	}
+}
+
+// runningBalances returns a copy of the transactions with the running balance they are saved with.
+func runningBalances(items []transaction, opening money.Amount) []transaction {
+	var (
+		withBalances = make([]transaction, 0, len(items))
+		balance      = opening
+	)
+
+	for _, txn := range items {
+		balance += txn.amount
+		txn.balance = balance
+		withBalances = append(withBalances, txn)
+	}
+
+	return withBalances
+}
+
+func TestServiceNotifyResumeReportsProgress(t *testing.T) {
+	var (
+		bankTnxs = transactions{
+			items:  []transaction{{amount: money.FromInt(10), date: time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC)}},
+			userID: 1,
+		}
+		repoMock = &repositoryMock{}
+		statuses []JobStatus
+	)
+
+	repoMock.On("initTransactionalOperations").Return(tx{}, nil).Once()
+	repoMock.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+	repoMock.On("createImportBatch", tx{}, mock.Anything).Return(7, nil).Once()
+	repoMock.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+	repoMock.On("getSavedFingerprints", tx{}, int64(1), int64(7), mock.Anything).Return(nil, nil).Once()
+	repoMock.On("saveBankTransactions", tx{}, mock.Anything).Return(nil).Once()
+	repoMock.On("finishImportBatch", tx{}, int64(7), "", 1).Return(nil).Once()
+	repoMock.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+	repoMock.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+	repoMock.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+	repoMock.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+	repoMock.On("hasForeignTransactions", tx{}, int64(1), mock.Anything).Return(false, nil).Once()
+	repoMock.On("getDailyTotals", tx{}, int64(1), mock.Anything, mock.Anything).Return(nil, nil).Once()
+	repoMock.On("saveOutboxMessage", tx{}, mock.Anything).Return(nil).Once()
+	repoMock.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+	defer repoMock.AssertExpectations(t)
+
+	ctx := withProgress(context.TODO(), func(_ context.Context, status JobStatus) {
+		statuses = append(statuses, status)
+	})
+
+	serv := service{repository: repoMock}
+
+	_, err := serv.notifyResume(ctx, bankTnxs.iterator(), resumeOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []JobStatus{JobStatusSaving, JobStatusNotifying}, statuses)
+}
+
+func TestServiceNotifyResumeSavesJobResult(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		date      = time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC)
+		bankTnxs  = transactions{items: []transaction{{amount: money.FromInt(10), date: date}}, userID: 1}
+		result    = ImportResult{BatchID: 7, InsertedTransactions: 1}
+	)
+
+	tests := []struct {
+		name           string
+		mockApplier    func(rm *repositoryMock)
+		expected       error
+		expectedResult ImportResult
+	}{
+		{
+			name: "error saving result",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("saveJobResult", tx{}, int64(3), result).Return(false, customErr).Once()
+				rm.On("finishTransactionalOperations", tx{}, mock.Anything).Return(customErr).Once()
+			},
+			expected: customErr,
+		},
+		{
+			name: "job imported by another run",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("saveJobResult", tx{}, int64(3), result).Return(false, nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, jobImportedError{jobID: 3}).
+					Return(jobImportedError{jobID: 3}).Once()
+			},
+			expected: jobImportedError{jobID: 3},
+		},
+		{
+			name: "result saved with the transactions",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("saveJobResult", tx{}, int64(3), result).Return(true, nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expectedResult: result,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repoMock := &repositoryMock{}
+			repoMock.On("initTransactionalOperations").Return(tx{}, nil).Once()
+			repoMock.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+			repoMock.On("createImportBatch", tx{}, mock.Anything).Return(7, nil).Once()
+			repoMock.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+			repoMock.On("getSavedFingerprints", tx{}, int64(1), int64(7), mock.Anything).Return(nil, nil).Once()
+			repoMock.On("saveBankTransactions", tx{}, mock.Anything).Return(nil).Once()
+			repoMock.On("finishImportBatch", tx{}, int64(7), "", 1).Return(nil).Once()
+			repoMock.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+			repoMock.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+			repoMock.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+			repoMock.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+			repoMock.On("hasForeignTransactions", tx{}, int64(1), mock.Anything).Return(false, nil).Once()
+			repoMock.On("getDailyTotals", tx{}, int64(1), mock.Anything, mock.Anything).Return(nil, nil).Once()
+			repoMock.On("saveOutboxMessage", tx{}, mock.Anything).Return(nil).Once()
+			test.mockApplier(repoMock)
+			defer repoMock.AssertExpectations(t)
+
+			serv := service{repository: repoMock}
+
+			got, err := serv.notifyResume(withJob(context.TODO(), 3), bankTnxs.iterator(), resumeOptions{})
+
+			assert.Equal(t, test.expected, err)
+			assert.Equal(t, test.expectedResult, got)
+		})
+	}
+}
+
+func TestServicePreviewResume(t *testing.T) {
+	var (
+		date      = time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC)
+		customErr = errors.New("custom error")
+		user      = User{UserID: 1, Email: "email", Currency: "USD"}
+		bankTnxs  = transactions{
+			items:  []transaction{{amount: money.FromInt(10), date: date}},
+			userID: 1,
+		}
+		history = []transaction{
+			{amount: money.FromInt(-100), currency: "USD", description: "hotel", date: date.AddDate(0, -3, 0)},
+			{amount: money.FromInt(-200), currency: "USD", description: "bus", date: date.AddDate(0, -2, 0)},
+			{amount: money.FromInt(-300), currency: "USD", description: "car", date: date.AddDate(0, -1, 0)},
+		}
+	)
+
+	tests := []struct {
+		name           string
+		mockApplier    func(rm *repositoryMock)
+		expectedResume Resume
+		expectedErr    error
+	}{
+		{
+			name: "error getting user",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(nil, customErr).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf("error getting user due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expectedErr: customErr,
+		},
+		{
+			name: "error getting totals of the previous year",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(user, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), mock.Anything).Return(false, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), date.AddDate(-1, 0, 0), date.AddDate(-1, 0, 1)).
+					Return(nil, customErr).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf("error getting totals of the previous year due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expectedErr: customErr,
+		},
+		{
+			name: "resume compared with the previous year",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(user, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), mock.Anything).Return(false, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), date.AddDate(-1, 0, 0), date.AddDate(-1, 0, 1)).
+					Return([]dailyTotal{
+						{
+							currency:    "MXN",
+							date:        date.AddDate(-1, 0, 0),
+							count:       1,
+							credits:     money.FromInt(100),
+							creditCount: 1,
+						},
+					}, nil).Once()
+				rm.On("getFXRates", tx{}, "USD").Return([]fxRate{
+					{
+						baseCurrency:  "USD",
+						quoteCurrency: "MXN",
+						rate:          money.MustParseRate("20"),
+						effectiveDate: date.AddDate(-2, 0, 0),
+					},
+				}, nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expectedResume: Resume{
+				User:           user,
+				Currency:       "USD",
+				Balance:        "10.00",
+				MinBalance:     "10.00",
+				MinBalanceDate: "2021-12-01",
+				MaxBalance:     "10.00",
+				MaxBalanceDate: "2021-12-01",
+				CreditAvg:      "10.00",
+				DebitAvg:       "0.00",
+				MonthTransactions: []MonthTransaction{
+					singleTransactionMonth(time.December, money.FromInt(10), money.FromInt(10)),
+				},
+				Comparison: &Comparison{
+					PreviousFrom: "2020-12-01",
+					PreviousTo:   "2020-12-01",
+					NetChange: ComparedAmount{
+						Current:         "10.00",
+						Previous:        "5.00",
+						Delta:           "5.00",
+						DeltaPercentage: "100.00",
+					},
+					CreditAvg: ComparedAmount{
+						Current:         "10.00",
+						Previous:        "5.00",
+						Delta:           "5.00",
+						DeltaPercentage: "100.00",
+					},
+					DebitAvg: ComparedAmount{Current: "0.00", Previous: "0.00", Delta: "0.00"},
+					MonthTransactions: []ComparedMonth{
+						{Month: "December", Current: 1, Previous: 1, Delta: 0, DeltaPercentage: "0.00"},
+					},
+				},
+			},
+		},
+		{
+			name: "resume with balance forecast",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(user, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return([]amountStats{
+					{
+						currency:        "USD",
+						debit:           true,
+						count:           3,
+						median:          money.FromInt(200),
+						medianDeviation: money.FromInt(100),
+						totalDeviation:  money.FromInt(200),
+					},
+				}, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return([]dailyBalance{
+					{currency: "USD", date: history[0].date, amount: history[0].amount},
+					{currency: "USD", date: history[1].date, amount: history[1].amount},
+					{currency: "USD", date: history[2].date, amount: history[2].amount},
+				}, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(history, nil).Twice()
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), "USD").Return(false, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), mock.Anything, mock.Anything).Return(nil, nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expectedResume: Resume{
+				User:           user,
+				Currency:       "USD",
+				Balance:        "10.00",
+				MinBalance:     "10.00",
+				MinBalanceDate: "2021-12-01",
+				MaxBalance:     "10.00",
+				MaxBalanceDate: "2021-12-01",
+				CreditAvg:      "10.00",
+				DebitAvg:       "0.00",
+				MonthTransactions: []MonthTransaction{
+					singleTransactionMonth(time.December, money.FromInt(10), money.FromInt(10)),
+				},
+				Forecast: &Forecast{
+					Currency: "USD",
+					Balance:  "10.00",
+					Date:     "2021-12-01",
+					Months: []ForecastMonth{
+						{Month: "2022-01", Balance: "-383.55", Low: "-523.82", High: "-243.27"},
+						{Month: "2022-02", Balance: "-583.55", Low: "-755.82", High: "-411.28"},
+						{Month: "2022-03", Balance: "-783.55", Low: "-982.74", High: "-584.36"},
+					},
+				},
+			},
+		},
+		{
+			name: "resume previewed without saving transactions",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(user, nil).Once()
+				rm.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+				rm.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(nil, nil)
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(nil, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), mock.Anything).Return(false, nil).Once()
+				rm.On("getDailyTotals", tx{}, int64(1), mock.Anything, mock.Anything).Return(nil, nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expectedResume: Resume{
+				User:           user,
+				Currency:       "USD",
+				Balance:        "10.00",
+				MinBalance:     "10.00",
+				MinBalanceDate: "2021-12-01",
+				MaxBalance:     "10.00",
+				MaxBalanceDate: "2021-12-01",
+				CreditAvg:      "10.00",
+				DebitAvg:       "0.00",
+				MonthTransactions: []MonthTransaction{
+					singleTransactionMonth(time.December, money.FromInt(10), money.FromInt(10)),
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repoMock := &repositoryMock{}
+			test.mockApplier(repoMock)
+			defer repoMock.AssertExpectations(t)
+
+			resume, err := service{repository: repoMock}.previewResume(context.TODO(), bankTnxs.iterator(), resumeOptions{})
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expectedResume, resume)
+		})
+	}
+}
+
+func TestServiceCreateJob(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		payload   = []byte("10,2021-12-01T00:00:00Z")
+		options   = resumeOptions{Mapping: columnMapping{Amount: "monto", Date: "fecha"}}
+		job       = Job{UserID: 1, Status: JobStatusQueued, payload: payload, options: options}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(rm *repositoryMock)
+		expected    Job
+		expectedErr error
+	}{
+		{
+			name: "error creating job",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("createJob", job).Return(0, customErr).Once()
+			},
+			expectedErr: fmt.Errorf("error creating job for user id %d due to: %w", 1, customErr),
+		},
+		{
+			name: "error getting created job",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("createJob", job).Return(7, nil).Once()
+				rm.On("getJobByID", int64(7)).Return(nil, customErr).Once()
+			},
+			expectedErr: fmt.Errorf("error getting job due to: %w", customErr),
+		},
+		{
+			name: "job created",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("createJob", job).Return(7, nil).Once()
+				rm.On("getJobByID", int64(7)).Return(Job{ID: 7, UserID: 1, Status: JobStatusQueued}, nil).Once()
+			},
+			expected: Job{ID: 7, UserID: 1, Status: JobStatusQueued},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repoMock := &repositoryMock{}
+			test.mockApplier(repoMock)
+			defer repoMock.AssertExpectations(t)
+
+			result, err := service{repository: repoMock}.createJob(context.TODO(), 1, payload, options)
+
+			assert.Equal(t, test.expected, result)
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
+
+func TestServiceSaveFXRates(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		rates     = []fxRate{{baseCurrency: "USD", quoteCurrency: "MXN", rate: money.MustParseRate("20")}}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(rm *repositoryMock)
+		expected    error
+	}{
+		{
+			name: "init transactional operations fails",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(nil, customErr).Once()
+			},
+			expected: fmt.Errorf("error creating repository transaction due to: %w", customErr),
+		},
+		{
+			name: "save fx rates fails",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("saveFXRates", tx{}, rates).Return(customErr).Once()
+				rm.On("finishTransactionalOperations",
+					tx{}, fmt.Errorf("error saving fx rates due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expected: customErr,
+		},
+		{
+			name: "fx rates saved",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("saveFXRates", tx{}, rates).Return(nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repoMock := &repositoryMock{}
+			test.mockApplier(repoMock)
+			defer repoMock.AssertExpectations(t)
+
+			assert.Equal(t, test.expected, service{repository: repoMock}.saveFXRates(context.TODO(), rates))
+		})
+	}
+}
+
+func TestServiceNotifyResumeWithInvalidCSV(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		repoMock  = &repositoryMock{}
+		reader    = parser{}.newTransactionReader(&fileRecords{file: [][]string{{"bad"}}}, 1, resumeOptions{})
+	)
+
+	repoMock.On("initTransactionalOperations").Return(tx{}, nil).Once()
+	repoMock.On("getUserByID", tx{}, int64(1)).Return(User{Email: "email"}, nil).Once()
+	repoMock.On("createImportBatch", tx{}, mock.Anything).Return(7, nil).Once()
+	repoMock.On("getCategoryRules", int64(1)).Return(nil, nil).Once()
+	repoMock.On("getAmountStats", tx{}, int64(1)).Return(nil, nil).Once()
+	repoMock.On("getDailyDebits", tx{}, int64(1), time.UTC).Return(nil, nil).Once()
+	repoMock.On("finishTransactionalOperations", tx{}, invalidCSVError{
+		errors.New("for row number 1 is expected 2 or 3 elements, however got 1"),
+	}).Return(customErr).Once()
+	defer repoMock.AssertExpectations(t)
+
+	serv := service{repository: repoMock}
+
+	_, err := serv.notifyResume(context.TODO(), reader, resumeOptions{})
+
+	assert.Equal(t, customErr, err)
+}
+
+func TestServiceRollbackImportBatch(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		batch     = ImportBatch{ID: 3, UserID: 1, RowCount: 2, Status: ImportBatchStatusImported}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(rm *repositoryMock)
+		expected    ImportBatch
+		expectedErr error
+	}{
+		{
+			name: "error getting import batch",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("getImportBatchByID", int64(3)).Return(nil, customErr).Once()
+			},
+			expectedErr: fmt.Errorf("error getting import batch due to: %w", customErr),
+		},
+		{
+			name: "import batch already rolled back",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("getImportBatchByID", int64(3)).
+					Return(ImportBatch{ID: 3, Status: ImportBatchStatusRolledBack}, nil).Once()
+			},
+			expectedErr: batchRolledBackError{batchID: 3},
+		},
+		{
+			name: "import batch rolled back meanwhile",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("getImportBatchByID", int64(3)).Return(batch, nil).Once()
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("rollbackImportBatch", tx{}, int64(3)).Return(false, nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, batchRolledBackError{batchID: 3}).
+					Return(batchRolledBackError{batchID: 3}).Once()
+			},
+			expectedErr: batchRolledBackError{batchID: 3},
+		},
+		{
+			name: "error getting later import batches",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("getImportBatchByID", int64(3)).Return(batch, nil).Once()
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("rollbackImportBatch", tx{}, int64(3)).Return(true, nil).Once()
+				rm.On("getLaterImportBatchID", tx{}, batch).Return(0, customErr).Once()
+				rm.On("finishTransactionalOperations",
+					tx{}, fmt.Errorf("error getting later import batches due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expectedErr: customErr,
+		},
+		{
+			name: "import batch imported before another one",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("getImportBatchByID", int64(3)).Return(batch, nil).Once()
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("rollbackImportBatch", tx{}, int64(3)).Return(true, nil).Once()
+				rm.On("getLaterImportBatchID", tx{}, batch).Return(5, nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, laterBatchError{batchID: 3, laterBatchID: 5}).
+					Return(laterBatchError{batchID: 3, laterBatchID: 5}).Once()
+			},
+			expectedErr: laterBatchError{batchID: 3, laterBatchID: 5},
+		},
+		{
+			name: "delete transactions fails",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("getImportBatchByID", int64(3)).Return(batch, nil).Once()
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("rollbackImportBatch", tx{}, int64(3)).Return(true, nil).Once()
+				rm.On("getLaterImportBatchID", tx{}, batch).Return(0, nil).Once()
+				rm.On("deleteImportBatchTransactions", tx{}, int64(3)).Return(0, customErr).Once()
+				rm.On("finishTransactionalOperations",
+					tx{}, fmt.Errorf("error deleting import batch transactions due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expectedErr: customErr,
+		},
+		{
+			name: "import batch rolled back",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("getImportBatchByID", int64(3)).Return(batch, nil).Once()
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("rollbackImportBatch", tx{}, int64(3)).Return(true, nil).Once()
+				rm.On("getLaterImportBatchID", tx{}, batch).Return(0, nil).Once()
+				rm.On("deleteImportBatchTransactions", tx{}, int64(3)).Return(2, nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expected: ImportBatch{ID: 3, UserID: 1, RowCount: 2, Status: ImportBatchStatusRolledBack},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repoMock := &repositoryMock{}
+			test.mockApplier(repoMock)
+			defer repoMock.AssertExpectations(t)
+
+			batch, err := service{repository: repoMock}.rollbackImportBatch(context.TODO(), 3)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, batch)
+		})
+	}
+}
+
+func TestServiceRecategorizeTransactions(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		rules     = []CategoryRule{
+			{Category: "Food", Kind: ruleContains, Pattern: "coffee"},
+			{Category: "Rent", Kind: ruleAmountRange, MaxAmount: "-1000"},
+		}
+		stored = []storedTransaction{
+			{id: 1, txn: transaction{amount: money.FromInt(-5), description: "coffee", category: "Food"}},
+			{id: 2, txn: transaction{amount: money.FromInt(-5), description: "iced coffee"}},
+			{id: 3, txn: transaction{amount: money.FromInt(-1200), category: "Food"}},
+			{id: 4, txn: transaction{amount: money.FromInt(-20), description: "gym", category: "Sports"}},
+		}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(rm *repositoryMock)
+		expected    RecategorizeResult
+		expectedErr error
+	}{
+		{
+			name: "error getting category rules",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("getCategoryRules", int64(1)).Return(nil, customErr).Once()
+			},
+			expectedErr: fmt.Errorf("error getting category rules due to: %w", customErr),
+		},
+		{
+			name: "error getting transactions",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("getCategoryRules", int64(1)).Return(rules, nil).Once()
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserTransactions", tx{}, int64(1)).Return(nil, customErr).Once()
+				rm.On("finishTransactionalOperations",
+					tx{}, fmt.Errorf("error getting transactions due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expectedErr: customErr,
+		},
+		{
+			name: "error updating transactions",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("getCategoryRules", int64(1)).Return(rules, nil).Once()
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserTransactions", tx{}, int64(1)).Return(stored, nil).Once()
+				rm.On("updateTransactionsCategory", tx{}, "", []int64{4}).Return(customErr).Once()
+				rm.On("finishTransactionalOperations",
+					tx{}, fmt.Errorf("error updating category of transactions due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expectedErr: customErr,
+		},
+		{
+			name: "transactions recategorized",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("getCategoryRules", int64(1)).Return(rules, nil).Once()
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserTransactions", tx{}, int64(1)).Return(stored, nil).Once()
+				rm.On("updateTransactionsCategory", tx{}, "", []int64{4}).Return(nil).Once()
+				rm.On("updateTransactionsCategory", tx{}, "Food", []int64{2}).Return(nil).Once()
+				rm.On("updateTransactionsCategory", tx{}, "Rent", []int64{3}).Return(nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expected: RecategorizeResult{UpdatedTransactions: 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repoMock := &repositoryMock{}
+			test.mockApplier(repoMock)
+			defer repoMock.AssertExpectations(t)
+
+			result, err := service{repository: repoMock}.recategorizeTransactions(context.TODO(), 1)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestServiceGetForecast(t *testing.T) {
+	var (
+		date      = time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+		customErr = errors.New("custom error")
+		user      = User{UserID: 1, Email: "email", Currency: "USD"}
+		history   = []transaction{
+			{amount: money.FromInt(1000), currency: "USD", description: "deposit", date: date},
+			{amount: money.FromInt(-100), currency: "USD", description: "hotel", date: date},
+			{amount: money.FromInt(-4000), currency: "MXN", description: "flight", date: date.AddDate(0, 1, 0)},
+			{amount: money.FromInt(-300), currency: "USD", description: "car", date: date.AddDate(0, 2, 0)},
+			{amount: money.FromInt(-50), currency: "USD", description: "tip", date: date.AddDate(0, 3, 0)},
+		}
+		dailyBalances = []dailyBalance{
+			{currency: "USD", date: date, amount: money.FromInt(900)},
+			{currency: "MXN", date: date.AddDate(0, 1, 0), amount: money.FromInt(-4000)},
+			{currency: "USD", date: date.AddDate(0, 2, 0), amount: money.FromInt(-350)},
+		}
+		rates = []fxRate{
+			{
+				baseCurrency:  "USD",
+				quoteCurrency: "MXN",
+				rate:          money.MustParseRate("20"),
+				effectiveDate: date,
+			},
+		}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(rm *repositoryMock)
+		expected    Forecast
+		expectedErr error
+	}{
+		{
+			name: "error getting user",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(nil, customErr).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf("error getting user due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expectedErr: customErr,
+		},
+		{
+			name: "missing fx rate of transaction history",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(user, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), "USD").Return(true, nil).Once()
+				rm.On("getFXRates", tx{}, "USD").Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(history, nil).Once()
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(dailyBalances, nil).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf(
+						"error converting saved balance of user id 1 due to: %w",
+						errors.New("there is no fx rate from MXN to USD effective on 2022-02-10"))).
+					Return(customErr).Once()
+			},
+			expectedErr: customErr,
+		},
+		{
+			name: "balance forecast",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(user, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), "USD").Return(true, nil).Once()
+				rm.On("getFXRates", tx{}, "USD").Return(rates, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(history, nil).Twice()
+				rm.On("getDailyBalances", tx{}, int64(1)).Return(dailyBalances, nil).Once()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expected: Forecast{
+				Currency: "USD",
+				Balance:  "350.00",
+				Date:     "2022-04-10",
+				Months: []ForecastMonth{
+					{Month: "2022-05", Balance: "572.22", Low: "-287.36", High: "1431.81"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repoMock := &repositoryMock{}
+			test.mockApplier(repoMock)
+			defer repoMock.AssertExpectations(t)
+
+			forecast, err := service{repository: repoMock}.getForecast(context.TODO(), 1, 1)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expected, forecast)
+		})
+	}
+}
+
+func TestServiceGetStatement(t *testing.T) {
+	var (
+		date      = time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+		customErr = errors.New("custom error")
+		user      = User{UserID: 1, Email: "email", Currency: "USD"}
+		history   = []transaction{
+			{amount: money.FromInt(1000), currency: "USD", description: "deposit", date: date},
+			{amount: money.FromInt(-4000), currency: "MXN", description: "flight", date: date.AddDate(0, 1, 0)},
+		}
+		rates = []fxRate{
+			{
+				baseCurrency:  "USD",
+				quoteCurrency: "MXN",
+				rate:          money.MustParseRate("20"),
+				effectiveDate: date,
+			},
+		}
+	)
+
+	tests := []struct {
+		name          string
+		year          int
+		mockApplier   func(rm *repositoryMock)
+		expectedLines []string
+		expectedErr   error
+	}{
+		{
+			name: "error getting user",
+			year: 2022,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(nil, customErr).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf("error getting user due to: %w", customErr)).
+					Return(customErr).Once()
+			},
+			expectedErr: customErr,
+		},
+		{
+			name: "missing fx rate of transaction history",
+			year: 2022,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(user, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), "USD").Return(true, nil).Once()
+				rm.On("getFXRates", tx{}, "USD").Return(nil, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(history, nil).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf(
+						"error generating statement of user id 1 due to: %w",
+						errors.New("there is no fx rate from MXN to USD effective on 2022-02-10"))).
+					Return(customErr).Once()
+			},
+			expectedErr: customErr,
+		},
+		{
+			name: "no transactions in the year",
+			year: 2021,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(user, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), "USD").Return(true, nil).Once()
+				rm.On("getFXRates", tx{}, "USD").Return(rates, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(history, nil).Once()
+				rm.On(
+					"finishTransactionalOperations",
+					tx{}, fmt.Errorf("there are no transactions of user id 1 in 2021: %w", sql.ErrNoRows)).
+					Return(sql.ErrNoRows).Once()
+			},
+			expectedErr: sql.ErrNoRows,
+		},
+		{
+			name: "statement of the year",
+			year: 2022,
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("initTransactionalOperations").Return(tx{}, nil).Once()
+				rm.On("getUserByID", tx{}, int64(1)).Return(user, nil).Once()
+				rm.On("hasForeignTransactions", tx{}, int64(1), "USD").Return(true, nil).Once()
+				rm.On("getFXRates", tx{}, "USD").Return(rates, nil).Once()
+				rm.On("forEachUserTransaction", tx{}, int64(1)).Return(history, nil).Twice()
+				rm.On("finishTransactionalOperations", tx{}, error(nil)).Return(nil).Once()
+			},
+			expectedLines: []string{
+				"(Statement 2022) Tj",
+				"(From 2022-01-01 to 2022-12-31, in USD) Tj",
+				"(Closing balance: 800.00) Tj",
+				"(Transactions: 2) Tj",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repoMock := &repositoryMock{}
+			test.mockApplier(repoMock)
+			defer repoMock.AssertExpectations(t)
+
+			var statement bytes.Buffer
+
+			err := service{repository: repoMock}.getStatement(context.TODO(), 1, test.year, &statement)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expectedLines == nil, statement.Len() == 0)
+			for _, line := range test.expectedLines {
+				assert.Contains(t, statement.String(), line)
+			}
 		})
 	}
 }
@@ -0,0 +1,250 @@
+package summarizer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"transaction-tool-api/src/internal/money"
+	"transaction-tool-api/src/internal/notifier"
+)
+
+const statementContentType = "application/pdf"
+
+var (
+	statementMonthColumns = []pdfColumn{
+		{width: 10}, {width: 12, right: true}, {width: 16, right: true}, {width: 16, right: true},
+		{width: 16, right: true}, {width: 16, right: true},
+	}
+	statementTransactionColumns = []pdfColumn{
+		{width: 10}, {width: 40}, {width: 14, right: true}, {width: 3}, {width: 16, right: true},
+	}
+)
+
+// period returns the period of the summary, from the midnight of its first day until the midnight after its
+// last one in the summary location.
+func (s *summarizer) period() (time.Time, time.Time) {
+	return time.Date(s.firstDate.Year(), s.firstDate.Month(), s.firstDate.Day(), 0, 0, 0, 0, s.location),
+		time.Date(s.lastDate.Year(), s.lastDate.Month(), s.lastDate.Day()+1, 0, 0, 0, 0, s.location)
+}
+
+// maxEmailedStatementTransactions bounds the transactions listed in the statement attached to the emails, which
+// keeps it within the size limits of the outbox and of the mail servers. The statement of each year downloaded
+// from the API lists all of them.
+const maxEmailedStatementTransactions = 1000
+
+// writeStatement writes the statement of the transactions saved for the user in the period, starting from the
+// balance of the ones saved before it, listing up to limit of them when limit is not zero. Amounts are
+// converted to the currency of the user with the rates effective on the day of the transactions. The history
+// is read twice, first to summarize the period and then to list its transactions, so the statement is written
+// as it is rendered. Nothing is written, and false is returned, when no transaction was saved in the period.
+func writeStatement(
+	w io.Writer,
+	user User,
+	history historySource,
+	rates fxRates,
+	location *time.Location,
+	from time.Time,
+	to time.Time,
+	title string,
+	limit int,
+) (bool, error) {
+	summ := newSummarizer(user.Currency, rates)
+	summ.location = location
+
+	convert := func(txn transaction) (money.Amount, error) {
+		txnCurrency := txn.currency
+		if txnCurrency == "" {
+			txnCurrency = user.Currency
+		}
+		return rates.convert(txn.amount, txnCurrency, user.Currency, txn.date.In(location))
+	}
+
+	// the history is read from the oldest transaction, so every one before the period adds to the opening
+	// balance before the ones of the period are added
+	err := history(func(txn transaction) error {
+		if !txn.date.Before(to) {
+			return nil
+		}
+
+		if txn.date.Before(from) {
+			amount, err := convert(txn)
+			if err != nil {
+				return err
+			}
+			summ.opening += amount
+			return nil
+		}
+
+		return summ.add(txn)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if summ.total == 0 {
+		return false, nil
+	}
+
+	statement := statementLayout{
+		resume: summ.resume(user),
+		title:  title,
+		from:   from,
+		to:     to.AddDate(0, 0, -1),
+		total:  summ.total,
+		listed: summ.total,
+	}
+	if limit > 0 && statement.listed > limit {
+		statement.listed = limit
+	}
+
+	doc := newPDFDocument(w, pdfPageCount(func(doc *pdfDocument) {
+		statement.header(doc)
+		for i := 0; i < statement.listed; i++ {
+			statement.row(doc, transaction{})
+		}
+		statement.footer(doc)
+	}))
+
+	statement.header(doc)
+
+	var (
+		balance = summ.opening
+		listed  = 0
+	)
+	err = history(func(txn transaction) error {
+		if txn.date.Before(from) || !txn.date.Before(to) || listed == statement.listed {
+			return nil
+		}
+
+		amount, err := convert(txn)
+		if err != nil {
+			return err
+		}
+		balance += amount
+
+		txn.date = txn.date.In(location)
+		txn.balance = balance
+		statement.row(doc, txn)
+		listed++
+		return nil
+	})
+	if err != nil {
+		return true, err
+	}
+
+	statement.footer(doc)
+
+	return true, doc.close()
+}
+
+// statementAttachment attaches the statement of the period of the summary, which is the whole one or one
+// of its years, to the notification of its resume. It is nil when no transaction was saved in the period.
+func statementAttachment(
+	user User, history historySource, rates fxRates, summ *summarizer, year string) (*notifier.Attachment, error) {
+	var (
+		content  bytes.Buffer
+		from, to = summ.period()
+	)
+
+	written, err := writeStatement(
+		&content, user, history, rates, summ.location, from, to, year, maxEmailedStatementTransactions)
+	if err != nil || !written {
+		return nil, err
+	}
+
+	name := "statement.pdf"
+	if year != "" {
+		name = fmt.Sprintf("statement-%s.pdf", strings.ReplaceAll(year, "/", "-"))
+	}
+
+	return &notifier.Attachment{Name: name, ContentType: statementContentType, Content: content.Bytes()}, nil
+}
+
+// statementLayout lays out the statement of a resume: its summary, followed by the listed transactions of its
+// period with the balance after each one, and a note of the ones left out.
+type statementLayout struct {
+	resume Resume
+	title  string
+	from   time.Time
+	to     time.Time
+	total  int
+	listed int
+}
+
+func (l statementLayout) header(doc *pdfDocument) {
+	heading := "Statement"
+	if l.title != "" {
+		heading = "Statement " + l.title
+	}
+	doc.line(pdfBold, 18, 0, heading)
+	doc.line(pdfRegular, 11, 0, fmt.Sprintf("%s <%s>", l.resume.User.Name, l.resume.User.Email))
+	doc.line(pdfRegular, 11, 0, fmt.Sprintf(
+		"From %s to %s, in %s", l.from.Format(balanceDateLayout), l.to.Format(balanceDateLayout),
+		l.resume.Currency))
+
+	doc.space(10)
+	doc.line(pdfBold, 13, 0, "Summary")
+	if l.resume.OpeningBalance != "" {
+		doc.line(pdfRegular, 10, 0, "Opening balance: "+l.resume.OpeningBalance)
+	}
+	doc.line(pdfRegular, 10, 0, "Closing balance: "+l.resume.Balance)
+	doc.line(pdfRegular, 10, 0, fmt.Sprintf(
+		"Lowest balance: %s on %s", l.resume.MinBalance, l.resume.MinBalanceDate))
+	doc.line(pdfRegular, 10, 0, fmt.Sprintf(
+		"Highest balance: %s on %s", l.resume.MaxBalance, l.resume.MaxBalanceDate))
+	doc.line(pdfRegular, 10, 0, "Credit average: "+l.resume.CreditAvg)
+	doc.line(pdfRegular, 10, 0, "Debit average: "+l.resume.DebitAvg)
+	doc.line(pdfRegular, 10, 0, fmt.Sprintf("Transactions: %d", l.total))
+
+	doc.space(10)
+	doc.line(pdfBold, 13, 0, "Transactions by month")
+	if len(l.resume.Years) > 0 {
+		for _, year := range l.resume.Years {
+			doc.line(pdfBold, 10, 0, "Year "+year.Year)
+			statementMonths(doc, year.MonthTransactions)
+		}
+	} else {
+		statementMonths(doc, l.resume.MonthTransactions)
+	}
+
+	doc.space(10)
+	doc.line(pdfBold, 13, 0, "Transactions")
+	doc.line(pdfMono, 8, 0, pdfRow(
+		statementTransactionColumns, "Date", "Description", "Amount", "", "Balance "+l.resume.Currency))
+}
+
+func (l statementLayout) row(doc *pdfDocument, txn transaction) {
+	doc.line(pdfMono, 8, 0, pdfRow(
+		statementTransactionColumns,
+		txn.date.Format(balanceDateLayout),
+		txn.description,
+		txn.amount.StringFixed(2),
+		txn.currency,
+		txn.balance.StringFixed(2)))
+}
+
+func (l statementLayout) footer(doc *pdfDocument) {
+	if l.listed < l.total {
+		doc.space(10)
+		doc.line(pdfRegular, 10, 0, fmt.Sprintf(
+			"%d more transactions are listed in the statement of each year, which can be downloaded.",
+			l.total-l.listed))
+	}
+}
+
+func statementMonths(doc *pdfDocument, months []MonthTransaction) {
+	doc.line(pdfMono, 8, 0, pdfRow(
+		statementMonthColumns, "Month", "Transactions", "Credits", "Debits", "Net change", "End balance"))
+	for _, month := range months {
+		doc.line(pdfMono, 8, 0, pdfRow(
+			statementMonthColumns,
+			month.Month,
+			fmt.Sprint(month.TotalTransactions),
+			month.Credits,
+			month.Debits,
+			month.NetChange,
+			month.EndBalance))
+	}
+}
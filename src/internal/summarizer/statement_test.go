@@ -0,0 +1,152 @@
+package summarizer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"transaction-tool-api/src/internal/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteStatement(t *testing.T) {
+	var (
+		date    = time.Date(2022, time.January, 10, 12, 0, 0, 0, time.UTC)
+		user    = User{UserID: 1, Name: "Jane", Email: "jane@mail.com", Currency: "USD"}
+		from    = time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+		to      = from.AddDate(1, 0, 0)
+		history = []transaction{
+			{amount: money.FromInt(500), currency: "USD", description: "previous salary", date: date.AddDate(-1, 0, 0)},
+			{amount: money.FromInt(1000), currency: "USD", description: "salary", date: date},
+			{amount: money.FromInt(-200), currency: "MXN", description: "taxi", date: date.AddDate(0, 1, 0)},
+			{amount: money.FromInt(-50), currency: "USD", description: "next dinner", date: to},
+		}
+		rates = newFXRates([]fxRate{
+			{baseCurrency: "USD", quoteCurrency: "MXN", rate: money.MustParseRate("20"), effectiveDate: from},
+		})
+	)
+
+	tests := []struct {
+		name          string
+		history       []transaction
+		rates         fxRates
+		limit         int
+		expectedLines []string
+		missingLines  []string
+		expectedErr   error
+	}{
+		{
+			name:    "no transactions in the period",
+			history: history[:1],
+		},
+		{
+			name:        "missing fx rate",
+			history:     history,
+			expectedErr: errors.New("there is no fx rate from MXN to USD effective on 2022-02-10"),
+		},
+		{
+			name:    "statement of the period",
+			history: history,
+			rates:   rates,
+			expectedLines: []string{
+				"(Statement 2022) Tj",
+				"(Jane <jane@mail.com>) Tj",
+				"(From 2022-01-01 to 2022-12-31, in USD) Tj",
+				"(Opening balance: 500.00) Tj",
+				"(Closing balance: 1490.00) Tj",
+				"(Lowest balance: 1490.00 on 2022-02-10) Tj",
+				"(Highest balance: 1500.00 on 2022-01-10) Tj",
+				"(Transactions: 2) Tj",
+				"(January               1          1000.00             0.00          1000.00          1500.00) Tj",
+				"(February              1             0.00           -10.00           -10.00          1490.00) Tj",
+				"(2022-01-10 salary                                          1000.00 USD          1500.00) Tj",
+				"(2022-02-10 taxi                                            -200.00 MXN          1490.00) Tj",
+				"(Page 1 of 1) Tj",
+			},
+		},
+		{
+			name:    "statement listing some of the transactions",
+			history: history,
+			rates:   rates,
+			limit:   1,
+			expectedLines: []string{
+				"(Closing balance: 1490.00) Tj",
+				"(Transactions: 2) Tj",
+				"(February              1             0.00           -10.00           -10.00          1490.00) Tj",
+				"(2022-01-10 salary                                          1000.00 USD          1500.00) Tj",
+				"(1 more transactions are listed in the statement of each year, which can be downloaded.) Tj",
+			},
+			missingLines: []string{"(2022-02-10 taxi"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var statement bytes.Buffer
+
+			written, err := writeStatement(
+				&statement, user, sliceHistory(test.history), test.rates, time.UTC, from, to, "2022", test.limit)
+
+			assert.Equal(t, test.expectedErr, err)
+			assert.Equal(t, test.expectedLines != nil, written)
+			assert.Equal(t, test.expectedLines == nil, statement.Len() == 0)
+			for _, line := range test.expectedLines {
+				assert.Contains(t, statement.String(), line)
+			}
+			for _, line := range test.missingLines {
+				assert.NotContains(t, statement.String(), line)
+			}
+			assert.NotContains(t, statement.String(), "next dinner")
+		})
+	}
+}
+
+func TestStatementAttachment(t *testing.T) {
+	var (
+		date = time.Date(2021, time.December, 10, 12, 0, 0, 0, time.UTC)
+		user = User{UserID: 1, Email: "email", Currency: "USD"}
+		summ = newSummarizer("USD", fxRates{})
+		txns = []transaction{
+			{amount: money.FromInt(100), currency: "USD", description: "salary", date: date},
+			{amount: money.FromInt(-20), currency: "USD", description: "dinner", date: date.AddDate(0, 1, 0)},
+		}
+	)
+
+	summ.fiscalYearStart = time.July
+	for _, txn := range txns {
+		assert.NoError(t, summ.add(txn))
+	}
+
+	tests := []struct {
+		name         string
+		summ         *summarizer
+		year         string
+		expectedName string
+	}{
+		{
+			name:         "whole summary",
+			summ:         summ,
+			expectedName: "statement.pdf",
+		},
+		{
+			name:         "fiscal year",
+			summ:         summ.years[2021],
+			year:         "2021/2022",
+			expectedName: "statement-2021-2022.pdf",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attachment, err := statementAttachment(user, sliceHistory(txns), fxRates{}, test.summ, test.year)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedName, attachment.Name)
+			assert.Equal(t, "application/pdf", attachment.ContentType)
+			assert.True(t, strings.HasPrefix(string(attachment.Content), "%PDF-1.4\n"))
+			assert.Contains(t, string(attachment.Content), "(From 2021-12-10 to 2022-01-10, in USD) Tj")
+		})
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"html/template"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"transaction-tool-api/src/internal/money"
+	"transaction-tool-api/src/internal/notifier"
 )
 
 const (
@@ -14,121 +17,721 @@ const (
 <body>
     <img src="https://blog.storicard.com/wp-content/uploads/2019/07/Stori-horizontal-11.jpg">
     <p>Hello {{.User.Name}}</p>
-    <h1>Balance: {{.Balance}}</h1>
+    {{- if .Year}}
+    <h1>Year: {{.Year}}</h1>
+    {{- end}}
+    {{- if .OpeningBalance}}
+    <h1>Opening Balance: {{.OpeningBalance}} {{.Currency}}</h1>
+    {{- end}}
+    <h1>Balance: {{.Balance}} {{.Currency}}</h1>
+    <p>Lowest balance: {{.MinBalance}} {{.Currency}} on {{.MinBalanceDate}}</p>
+    <p>Highest balance: {{.MaxBalance}} {{.Currency}} on {{.MaxBalanceDate}}</p>
     <h1>Credit Average: {{.CreditAvg}}</h1>
     <h1>Debit Average: {{.DebitAvg}}</h1>
+    {{- if .Years}}
+    {{- range .Years}}
+    <h1>Year {{.Year}}</h1>
+    <p>Balance: {{.Balance}} {{$.Currency}}</p>
+    <p>Credit Average: {{.CreditAvg}}</p>
+    <p>Debit Average: {{.DebitAvg}}</p>
+    {{- template "months" .MonthTransactions}}
+    {{- end}}
+    {{- else}}
     <h1>Transactions by month</h1>
+    {{- template "months" .MonthTransactions}}
+    {{- end}}
+    {{- with .Comparison}}
+    <h1>Compared with {{.PreviousFrom}} to {{.PreviousTo}}</h1>
+    <table>
+        <tr>
+            <th></th><th>This period</th><th>Previous period</th><th>Change</th>
+        </tr>
+        <tr>
+            <td>Net change</td>{{template "compared" .NetChange}}
+        </tr>
+        <tr>
+            <td>Credit average</td>{{template "compared" .CreditAvg}}
+        </tr>
+        <tr>
+            <td>Debit average</td>{{template "compared" .DebitAvg}}
+        </tr>
+        {{- range .MonthTransactions}}
+        <tr>
+            <td>{{.Month}} transactions</td><td>{{.Current}}</td><td>{{.Previous}}</td>
+            <td>{{.Delta}}{{if .DeltaPercentage}} ({{.DeltaPercentage}}%){{end}}</td>
+        </tr>
+        {{- end}}
+    </table>
+    {{- end}}
+    {{- if .CurrencySubtotals}}
+    <h1>Balance by currency</h1>
     <p>
-        {{range .MonthTransactions}} <p>{{.Month}}: {{.TotalTransactions}}</p>{{end}}
+        {{range .CurrencySubtotals}} <p>{{.Currency}}: {{.Balance}} ({{.ConvertedBalance}} {{$.Currency}})</p>{{end}}
     </p>
-</body>`
+    {{- end}}
+    {{- if .CategoryTotals}}
+    <h1>Totals by category</h1>
+    <table>
+        <tr>
+            <th>Category</th><th>Transactions</th><th>Credits</th><th>Debits</th><th>Total</th>
+        </tr>
+        {{- range .CategoryTotals}}
+        <tr>
+            <td>{{.Category}}</td><td>{{.TotalTransactions}}</td>
+            <td>{{.Credits}}</td><td>{{.Debits}}</td><td>{{.Total}}</td>
+        </tr>
+        {{- end}}
+    </table>
+    {{- end}}
+    {{- if .RecurringMovements}}
+    <h1>Recurring movements</h1>
+    <table>
+        <tr>
+            <th>Description</th><th>Frequency</th><th>Average amount</th>
+            <th>Next expected</th><th>Annualized cost</th>
+        </tr>
+        {{- range .RecurringMovements}}
+        <tr>
+            <td>{{.Description}}</td><td>{{.Frequency}}</td><td>{{.AverageAmount}} {{.Currency}}</td>
+            <td>{{.NextExpectedDate}}</td><td>{{.AnnualizedCost}} {{.Currency}}</td>
+        </tr>
+        {{- end}}
+    </table>
+    {{- end}}
+    {{- if .Forecast}}
+    <h1>Balance forecast</h1>
+    <p>From {{.Forecast.Balance}} {{.Forecast.Currency}} on {{.Forecast.Date}}</p>
+    <table>
+        <tr>
+            <th>Month</th><th>End balance</th><th>Low</th><th>High</th>
+        </tr>
+        {{- range .Forecast.Months}}
+        <tr>
+            <td>{{.Month}}</td><td>{{.Balance}}</td><td>{{.Low}}</td><td>{{.High}}</td>
+        </tr>
+        {{- end}}
+    </table>
+    {{- end}}
+    {{- if .UnusualActivity}}
+    <h1>Unusual activity</h1>
+    <table>
+        <tr>
+            <th>Date</th><th>Description</th><th>Amount</th><th>Detail</th>
+        </tr>
+        {{- range .UnusualActivity}}
+        <tr>
+            <td>{{.Date}}</td><td>{{.Description}}</td><td>{{.Amount}} {{.Currency}}</td><td>{{.Detail}}</td>
+        </tr>
+        {{- end}}
+    </table>
+    {{- end}}
+    {{- if .SkippedTransactions}}
+    <p>{{.SkippedTransactions}} transactions were already saved, so they were not saved again.</p>
+    {{- end}}
+</body>
+{{- define "months"}}
+    <table>
+        <tr>
+            <th>Month</th><th>Transactions</th><th>Credits</th><th>Debits</th>
+            <th>Net change</th><th>End balance</th><th>Largest movement</th>
+        </tr>
+        {{- range .}}
+        <tr>
+            <td>{{.Month}}</td><td>{{.TotalTransactions}}</td>
+            <td>{{.Credits}} ({{.CreditCount}}, avg {{.CreditAvg}})</td>
+            <td>{{.Debits}} ({{.DebitCount}}, avg {{.DebitAvg}})</td>
+            <td>{{.NetChange}}</td><td>{{.EndBalance}}</td>
+            <td>{{.LargestMovement}}{{if .LargestMovementDescription}} ({{.LargestMovementDescription}}){{end}}</td>
+        </tr>
+        {{- end}}
+    </table>
+    {{- with monthsChart .}}
+    <p>{{.}}</p>
+    {{- end}}
+    {{- with balanceChart .}}
+    <p>{{.}}</p>
+    {{- end}}
+{{- end}}
+{{- define "compared"}}
+            <td>{{.Current}}</td><td>{{.Previous}}</td>
+            <td>{{.Delta}}{{if .DeltaPercentage}} ({{.DeltaPercentage}}%){{end}}</td>
+{{- end}}`
 )
 
+// averagePlaces is the precision averages are rounded to (half away from zero) before being formatted.
+const averagePlaces = 2
+
+const balanceDateLayout = "2006-01-02"
+
+// summarizer aggregates transactions one at a time, so that a resume can be generated from
+// files of any size without holding their transactions in memory. The balance starts from the
+// opening one and runs in the order the transactions are added, which is expected to be the
+// order of the bank statement. The opening balance of each fiscal year does not depend on that
+// order, as it is the balance before the earliest transaction of the year.
 type summarizer struct {
+	currency        string
+	rates           fxRates
+	total           int
+	opening         money.Amount
+	balance         money.Amount
+	minBalance      money.Amount
+	minBalanceDate  time.Time
+	maxBalance      money.Amount
+	maxBalanceDate  time.Time
+	credits         money.Amount
+	creditCount     int64
+	debits          money.Amount
+	debitCount      int64
+	months          map[time.Month]*monthSummary
+	subtotals       map[string]*currencySubtotal
+	foreign         bool
+	categories      map[string]*categorySummary
+	categorized     bool
+	recurring       []RecurringMovement
+	forecast        Forecast
+	anomalies       *anomalyDetector
+	firstDate       time.Time
+	lastDate        time.Time
+	previous        *periodTotals
+	fiscalYearStart time.Month
+	location        *time.Location
+	years           map[int]*summarizer
 }
 
-func (s summarizer) getBalance(txns transactions) float64 {
-	var balance float64
-	for _, txn := range txns.items {
-		balance += txn.amount
+// monthSummary aggregates the transactions of a month, in the reporting currency.
+type monthSummary struct {
+	count              int
+	credits            money.Amount
+	creditCount        int64
+	debits             money.Amount
+	debitCount         int64
+	largest            money.Amount
+	largestDescription string
+}
+
+func (m *monthSummary) add(amount money.Amount, description string) {
+	m.count++
+
+	switch {
+	case amount > 0:
+		m.credits += amount
+		m.creditCount++
+	case amount < 0:
+		m.debits += amount
+		m.debitCount++
+	}
+
+	if amount.Abs() > m.largest.Abs() {
+		m.largest = amount
+		m.largestDescription = description
 	}
-	return balance
 }
 
-func (s summarizer) getDebitAvg(txns transactions) float64 {
-	var (
-		avg   float64
-		count float64
-	)
+func (m *monthSummary) creditAvg() money.Amount {
+	return m.credits.DivRound(m.creditCount, averagePlaces)
+}
+
+func (m *monthSummary) debitAvg() money.Amount {
+	return m.debits.DivRound(m.debitCount, averagePlaces)
+}
+
+// categorySummary aggregates the transactions of a category, in the reporting currency.
+type categorySummary struct {
+	count   int
+	credits money.Amount
+	debits  money.Amount
+}
+
+type currencySubtotal struct {
+	balance   money.Amount
+	converted money.Amount
+	count     int
+}
+
+func newSummarizer(currency string, rates fxRates) *summarizer {
+	return &summarizer{
+		currency:        currency,
+		rates:           rates,
+		months:          make(map[time.Month]*monthSummary),
+		subtotals:       make(map[string]*currencySubtotal),
+		categories:      make(map[string]*categorySummary),
+		anomalies:       newAnomalyDetector(currency),
+		fiscalYearStart: time.January,
+		location:        time.UTC,
+		years:           make(map[int]*summarizer),
+	}
+}
+
+// fiscalYear returns the year in which the fiscal year of the date starts.
+func fiscalYear(date time.Time, start time.Month) int {
+	if date.Month() < start {
+		return date.Year() - 1
+	}
+	return date.Year()
+}
+
+// fiscalYearLabel names a fiscal year after its calendar year, or after both of the calendar years it
+// spans when it does not start in January (e.g. 2021/2022).
+func fiscalYearLabel(year int, start time.Month) string {
+	if start == time.January {
+		return strconv.Itoa(year)
+	}
+	return fmt.Sprintf("%d/%d", year, year+1)
+}
+
+// add converts the transaction to the reporting currency and accumulates it, both in the whole
+// summary and in the one of its fiscal year. Years, months and the day of its fx rate are the ones of the
+// summary location, whatever the offset the transaction date came with.
+func (s *summarizer) add(txn transaction) error {
+	txnCurrency := txn.currency
+	if txnCurrency == "" {
+		txnCurrency = s.currency
+	}
+
+	txn.date = txn.date.In(s.location)
+
+	amount, err := s.rates.convert(txn.amount, txnCurrency, s.currency, txn.date)
+	if err != nil {
+		return err
+	}
+
+	year := fiscalYear(txn.date, s.fiscalYearStart)
+	yearSumm, ok := s.years[year]
+	if !ok {
+		yearSumm = newSummarizer(s.currency, fxRates{})
+		yearSumm.opening = s.balanceBefore(year)
+		yearSumm.fiscalYearStart = s.fiscalYearStart
+		yearSumm.location = s.location
+		s.years[year] = yearSumm
+	}
+
+	s.accumulate(txn, txnCurrency, amount)
+	yearSumm.accumulate(txn, txnCurrency, amount)
 
-	for _, txn := range txns.items {
-		if txn.amount < 0 {
-			avg += txn.amount
-			count++
+	// the transaction is before every later year, whatever its place in the file
+	for later, laterSumm := range s.years {
+		if later > year {
+			laterSumm.shiftOpening(amount)
 		}
 	}
+	s.anomalies.add(txn, txnCurrency, amount, s.getBalance())
 
-	if count > 0 {
-		return avg / count
+	return nil
+}
+
+// learnHistory tells the summary what is usual for the user from the transactions saved before, given as the
+// stats of their absolute amounts by currency and direction and their debits by day in the summary location,
+// so that the unusual activity can be flagged. Debits by month are converted to the reporting currency.
+func (s *summarizer) learnHistory(stats []amountStats, dailyDebits []dailyBalance) error {
+	monthDebits := make(map[time.Time]money.Amount)
+
+	for i := range stats {
+		if stats[i].currency == "" {
+			stats[i].currency = s.currency
+		}
+	}
+
+	for _, daily := range dailyDebits {
+		currency := daily.currency
+		if currency == "" {
+			currency = s.currency
+		}
+
+		amount, err := s.rates.convert(daily.amount, currency, s.currency, daily.date)
+		if err != nil {
+			return err
+		}
+		monthDebits[time.Date(daily.date.Year(), daily.date.Month(), 1, 0, 0, 0, 0, time.UTC)] -= amount
 	}
-	return 0
+
+	s.anomalies.learn(stats, monthDebits)
+
+	return nil
 }
 
-func (s summarizer) getCreditAvg(txns transactions) float64 {
-	var (
-		avg   float64
-		count float64
-	)
+// accumulate adds the transaction, whose amount was already converted to the reporting currency.
+func (s *summarizer) accumulate(txn transaction, txnCurrency string, amount money.Amount) {
+	subtotal, ok := s.subtotals[txnCurrency]
+	if !ok {
+		subtotal = &currencySubtotal{}
+		s.subtotals[txnCurrency] = subtotal
+	}
+	subtotal.balance += txn.amount
+	subtotal.converted += amount
+	subtotal.count++
+
+	s.foreign = s.foreign || txnCurrency != s.currency
+	s.total++
+
+	if s.total == 1 || txn.date.Before(s.firstDate) {
+		s.firstDate = txn.date
+	}
+	if s.total == 1 || txn.date.After(s.lastDate) {
+		s.lastDate = txn.date
+	}
+	s.balance += amount
+
+	balance := s.getBalance()
+	if s.total == 1 || balance < s.minBalance {
+		s.minBalance = balance
+		s.minBalanceDate = txn.date
+	}
+	if s.total == 1 || balance > s.maxBalance {
+		s.maxBalance = balance
+		s.maxBalanceDate = txn.date
+	}
+
+	month, ok := s.months[txn.date.Month()]
+	if !ok {
+		month = &monthSummary{}
+		s.months[txn.date.Month()] = month
+	}
+	month.add(amount, txn.description)
+
+	category, ok := s.categories[txn.category]
+	if !ok {
+		category = &categorySummary{}
+		s.categories[txn.category] = category
+	}
+	category.count++
+	if amount > 0 {
+		category.credits += amount
+	} else {
+		category.debits += amount
+	}
+	s.categorized = s.categorized || txn.category != ""
 
-	for _, txn := range txns.items {
-		if txn.amount > 0 {
-			avg += txn.amount
-			count++
+	switch {
+	case amount > 0:
+		s.credits += amount
+		s.creditCount++
+	case amount < 0:
+		s.debits += amount
+		s.debitCount++
+	}
+}
+
+// balanceBefore returns the balance before the earliest transaction of the fiscal year, which is the opening
+// one plus the net change of the transactions of the previous years added so far.
+func (s *summarizer) balanceBefore(year int) money.Amount {
+	balance := s.opening
+	for previous, previousSumm := range s.years {
+		if previous < year {
+			balance += previousSumm.balance
 		}
 	}
+	return balance
+}
+
+// shiftOpening adds the amount to the opening balance, and so to every running balance after it.
+func (s *summarizer) shiftOpening(amount money.Amount) {
+	s.opening += amount
+	s.minBalance += amount
+	s.maxBalance += amount
+}
+
+// getBalance returns the running balance, which is the closing one once every transaction was added.
+func (s *summarizer) getBalance() money.Amount {
+	return s.opening + s.balance
+}
+
+func (s *summarizer) getDebitAvg() money.Amount {
+	return s.debits.DivRound(s.debitCount, averagePlaces)
+}
+
+func (s *summarizer) getCreditAvg() money.Amount {
+	return s.credits.DivRound(s.creditCount, averagePlaces)
+}
+
+func (s *summarizer) getTotalTransactionsByMonth() map[time.Month]int {
+	transactionsByMonth := make(map[time.Month]int, len(s.months))
+	for month, summary := range s.months {
+		transactionsByMonth[month] = summary.count
+	}
+	return transactionsByMonth
+}
+
+func (s *summarizer) getCurrencySubtotals() []CurrencySubtotal {
+	if !s.foreign {
+		return nil
+	}
 
-	if count > 0 {
-		return avg / count
+	currencies := make([]string, 0, len(s.subtotals))
+	for txnCurrency := range s.subtotals {
+		currencies = append(currencies, txnCurrency)
 	}
-	return 0
+	sort.Strings(currencies)
+
+	subtotals := make([]CurrencySubtotal, 0, len(currencies))
+	for _, txnCurrency := range currencies {
+		subtotal := s.subtotals[txnCurrency]
+		subtotals = append(subtotals, CurrencySubtotal{
+			Currency:          txnCurrency,
+			Balance:           subtotal.balance.StringFixed(2),
+			ConvertedBalance:  subtotal.converted.StringFixed(2),
+			TotalTransactions: subtotal.count,
+		})
+	}
+
+	return subtotals
 }
 
-func (s summarizer) getTotalTransactionsByMonth(txns transactions) map[time.Month]int {
-	totalTransactionsByMonth := make(map[time.Month]int)
-	for _, txn := range txns.items {
-		totalTransactionsByMonth[txn.date.Month()]++
+// getCategoryTotals returns the totals by category sorted by name, followed by the one of the uncategorized
+// transactions. There are no totals when no transaction was categorized.
+func (s *summarizer) getCategoryTotals() []CategoryTotal {
+	if !s.categorized {
+		return nil
 	}
-	return totalTransactionsByMonth
+
+	categories := make([]string, 0, len(s.categories))
+	for category := range s.categories {
+		if category != "" {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+
+	if _, ok := s.categories[""]; ok {
+		categories = append(categories, "")
+	}
+
+	totals := make([]CategoryTotal, 0, len(categories))
+	for _, category := range categories {
+		summary := s.categories[category]
+		if category == "" {
+			category = uncategorized
+		}
+		totals = append(totals, CategoryTotal{
+			Category:          category,
+			TotalTransactions: summary.count,
+			Credits:           summary.credits.StringFixed(2),
+			Debits:            summary.debits.StringFixed(2),
+			Total:             (summary.credits + summary.debits).StringFixed(2),
+		})
+	}
+
+	return totals
 }
 
-func (s summarizer) resume(user User, txns transactions) Resume {
+func (s *summarizer) resume(user User) Resume {
 	var (
-		months              []time.Month
-		monthTransactions   []MonthTransaction
-		transactionsByMonth = s.getTotalTransactionsByMonth(txns)
+		months            []time.Month
+		monthTransactions []MonthTransaction
+		balance           = s.opening
 	)
 
-	for month, _ := range transactionsByMonth {
+	for month := range s.months {
 		months = append(months, month)
 	}
 
+	// months are listed from the start of the fiscal year
 	sort.Slice(months, func(i, j int) bool {
-		return months[i] < months[j]
+		return (months[i]+12-s.fiscalYearStart)%12 < (months[j]+12-s.fiscalYearStart)%12
 	})
 
 	for _, month := range months {
+		summary := s.months[month]
+		balance += summary.credits + summary.debits
+
 		monthTransactions = append(monthTransactions, MonthTransaction{
-			Month:             month.String(),
-			TotalTransactions: transactionsByMonth[month],
+			Month:                      month.String(),
+			TotalTransactions:          summary.count,
+			Credits:                    summary.credits.StringFixed(2),
+			Debits:                     summary.debits.StringFixed(2),
+			NetChange:                  (summary.credits + summary.debits).StringFixed(2),
+			EndBalance:                 balance.StringFixed(2),
+			CreditCount:                int(summary.creditCount),
+			DebitCount:                 int(summary.debitCount),
+			CreditAvg:                  summary.creditAvg().StringFixed(averagePlaces),
+			DebitAvg:                   summary.debitAvg().StringFixed(averagePlaces),
+			LargestMovement:            summary.largest.StringFixed(2),
+			LargestMovementDescription: summary.largestDescription,
 		})
 	}
 
-	return Resume{
-		User:              user,
-		Balance:           fmt.Sprintf("%.2f", s.getBalance(txns)),
-		CreditAvg:         fmt.Sprintf("%.2f", s.getCreditAvg(txns)),
-		DebitAvg:          fmt.Sprintf("%.2f", s.getDebitAvg(txns)),
-		MonthTransactions: monthTransactions,
+	resume := Resume{
+		User:               user,
+		Currency:           user.Currency,
+		Balance:            s.getBalance().StringFixed(2),
+		MinBalance:         s.minBalance.StringFixed(2),
+		MinBalanceDate:     s.minBalanceDate.Format(balanceDateLayout),
+		MaxBalance:         s.maxBalance.StringFixed(2),
+		MaxBalanceDate:     s.maxBalanceDate.Format(balanceDateLayout),
+		CreditAvg:          s.getCreditAvg().StringFixed(averagePlaces),
+		DebitAvg:           s.getDebitAvg().StringFixed(averagePlaces),
+		MonthTransactions:  monthTransactions,
+		CurrencySubtotals:  s.getCurrencySubtotals(),
+		CategoryTotals:     s.getCategoryTotals(),
+		RecurringMovements: s.recurring,
+		Forecast:           s.getForecast(),
+		Comparison:         s.getComparison(),
+	}
+
+	for _, unusual := range s.anomalies.getAnomalies() {
+		resume.UnusualActivity = append(resume.UnusualActivity, unusual.UnusualActivity)
 	}
+
+	if s.opening != 0 {
+		resume.OpeningBalance = s.opening.StringFixed(2)
+	}
+
+	// the months of different years are not merged, so they are only listed by year
+	if len(s.years) > 1 {
+		for _, yearResume := range s.yearResumes(user) {
+			resume.Years = append(resume.Years, YearResume{
+				Year:              yearResume.Year,
+				Balance:           yearResume.Balance,
+				CreditAvg:         yearResume.CreditAvg,
+				DebitAvg:          yearResume.DebitAvg,
+				MonthTransactions: yearResume.MonthTransactions,
+			})
+		}
+		resume.MonthTransactions = nil
+	}
+
+	return resume
 }
 
+// sortedYears returns the fiscal years of the summary, from the oldest one.
+func (s *summarizer) sortedYears() []int {
+	years := make([]int, 0, len(s.years))
+	for year := range s.years {
+		years = append(years, year)
+	}
+	sort.Ints(years)
+	return years
+}
+
+// yearResumes returns a resume by fiscal year, sorted from the oldest one.
+func (s *summarizer) yearResumes(user User) []Resume {
+	years := s.sortedYears()
+
+	resumes := make([]Resume, 0, len(years))
+	for _, year := range years {
+		resume := s.years[year].resume(user)
+		resume.Year = fiscalYearLabel(year, s.fiscalYearStart)
+		resumes = append(resumes, resume)
+	}
+
+	// recurring movements and the forecast are about the whole history, so they are only listed with the
+	// latest year
+	if len(resumes) > 0 {
+		resumes[len(resumes)-1].RecurringMovements = s.recurring
+		resumes[len(resumes)-1].Forecast = s.getForecast()
+	}
+
+	// unusual activity is listed with the year it happened in
+	for _, unusual := range s.anomalies.getAnomalies() {
+		i := sort.SearchInts(years, fiscalYear(unusual.date, s.fiscalYearStart))
+		resumes[i].UnusualActivity = append(resumes[i].UnusualActivity, unusual.UnusualActivity)
+	}
+
+	return resumes
+}
+
+// MonthTransaction summarizes the transactions of a month. EndBalance accumulates the net changes of the
+// previous months of the resume to its opening balance.
 type MonthTransaction struct {
-	Month             string
+	Month                      string
+	TotalTransactions          int
+	Credits                    string
+	Debits                     string
+	NetChange                  string
+	EndBalance                 string
+	CreditCount                int
+	DebitCount                 int
+	CreditAvg                  string
+	DebitAvg                   string
+	LargestMovement            string
+	LargestMovementDescription string `json:",omitempty"`
+}
+
+type CurrencySubtotal struct {
+	Currency          string
+	Balance           string
+	ConvertedBalance  string
 	TotalTransactions int
 }
 
-type Resume struct {
-	User              User
+type CategoryTotal struct {
+	Category          string
+	TotalTransactions int
+	Credits           string
+	Debits            string
+	Total             string
+}
+
+type YearResume struct {
+	Year              string
 	Balance           string
 	CreditAvg         string
 	DebitAvg          string
 	MonthTransactions []MonthTransaction
 }
 
+type Resume struct {
+	User                User
+	Year                string `json:",omitempty"`
+	Currency            string
+	OpeningBalance      string `json:",omitempty"`
+	Balance             string
+	MinBalance          string
+	MinBalanceDate      string
+	MaxBalance          string
+	MaxBalanceDate      string
+	CreditAvg           string
+	DebitAvg            string
+	MonthTransactions   []MonthTransaction
+	CurrencySubtotals   []CurrencySubtotal
+	CategoryTotals      []CategoryTotal     `json:",omitempty"`
+	RecurringMovements  []RecurringMovement `json:",omitempty"`
+	Forecast            *Forecast           `json:",omitempty"`
+	UnusualActivity     []UnusualActivity   `json:",omitempty"`
+	Comparison          *Comparison         `json:",omitempty"`
+	Years               []YearResume        `json:",omitempty"`
+	SkippedTransactions int
+}
+
+// ToText writes the main figures of the resume in a single line, for the channels which cannot show its html.
+func (r Resume) ToText() string {
+	var (
+		title   = "Transaction resume"
+		balance = r.Balance
+	)
+
+	if r.Year != "" {
+		title += " " + r.Year
+	}
+	if r.Currency != "" {
+		balance += " " + r.Currency
+	}
+
+	return fmt.Sprintf(
+		"%s: balance %s, lowest %s on %s, highest %s on %s, credit average %s, debit average %s.",
+		title, balance, r.MinBalance, r.MinBalanceDate, r.MaxBalance, r.MaxBalanceDate, r.CreditAvg, r.DebitAvg)
+}
+
+// ToHTML writes the resume with the template, carrying its charts as data urls, for the previews opened in a
+// browser.
 func (r Resume) ToHTML(tmpl string) (string, error) {
+	return r.render(tmpl, &chartImages{})
+}
+
+// ToEmail writes the resume with the template for an email, returning its charts as the inline attachments
+// the html references.
+func (r Resume) ToEmail(tmpl string) (string, []notifier.Attachment, error) {
+	images := &chartImages{attach: true}
+	html, err := r.render(tmpl, images)
+	if err != nil {
+		return "", nil, err
+	}
+	return html, images.attachments, nil
+}
+
+func (r Resume) render(tmpl string, images *chartImages) (string, error) {
 	tmpl = strings.ReplaceAll(tmpl, "\n", "")
 
-	t, err := template.New("resume").Parse(tmpl)
+	t, err := template.New("resume").Funcs(images.funcs()).Parse(tmpl)
 	if err != nil {
 		return "", fmt.Errorf("error creating resume template due to %w", err)
 	}
@@ -1,60 +1,107 @@
 package summarizer
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 	"time"
+	"transaction-tool-api/src/internal/money"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+func summarize(t *testing.T, txns transactions) *summarizer {
+	summ := newSummarizer("USD", fxRates{})
+	for _, txn := range txns.items {
+		require.Nil(t, summ.add(txn))
+	}
+	return summ
+}
+
+// singleTransactionMonth returns the month of a resume with a single transaction of the amount, in the
+// reporting currency.
+func singleTransactionMonth(month time.Month, amount money.Amount, endBalance money.Amount) MonthTransaction {
+	monthTxn := MonthTransaction{
+		Month:             month.String(),
+		TotalTransactions: 1,
+		Credits:           "0.00",
+		Debits:            "0.00",
+		NetChange:         amount.StringFixed(2),
+		EndBalance:        endBalance.StringFixed(2),
+		CreditAvg:         "0.00",
+		DebitAvg:          "0.00",
+		LargestMovement:   amount.StringFixed(2),
+	}
+
+	if amount > 0 {
+		monthTxn.Credits, monthTxn.CreditAvg, monthTxn.CreditCount = amount.StringFixed(2), amount.StringFixed(2), 1
+	} else {
+		monthTxn.Debits, monthTxn.DebitAvg, monthTxn.DebitCount = amount.StringFixed(2), amount.StringFixed(2), 1
+	}
+
+	return monthTxn
+}
+
 func TestSummarizerGetBalance(t *testing.T) {
 	tests := []struct {
 		name         string
 		transactions transactions
-		expected     float64
+		expected     money.Amount
 	}{
 		{
 			name:         "without transactions return 0",
 			transactions: transactions{},
-			expected:     0,
+			expected:     money.FromInt(0),
 		},
 		{
 			name: "only credit transactions",
 			transactions: transactions{
 				items: []transaction{
-					{amount: 10},
-					{amount: 20},
-					{amount: 30.5},
+					{amount: money.FromInt(10)},
+					{amount: money.FromInt(20)},
+					{amount: money.MustParse("30.5")},
 				},
 			},
-			expected: 60.5,
+			expected: money.MustParse("60.5"),
 		},
 		{
 			name: "only debit transactions",
 			transactions: transactions{
 				items: []transaction{
-					{amount: -10},
-					{amount: -20},
-					{amount: -30.5},
+					{amount: money.FromInt(-10)},
+					{amount: money.FromInt(-20)},
+					{amount: money.MustParse("-30.5")},
 				},
 			},
-			expected: -60.5,
+			expected: money.MustParse("-60.5"),
 		},
 		{
 			name: "debit and credit transactions",
 			transactions: transactions{
 				items: []transaction{
-					{amount: -10},
-					{amount: 20},
-					{amount: -30.5},
+					{amount: money.FromInt(-10)},
+					{amount: money.FromInt(20)},
+					{amount: money.MustParse("-30.5")},
 				},
 			},
-			expected: -20.5,
+			expected: money.MustParse("-20.5"),
+		},
+		{
+			name: "cents do not drift",
+			transactions: transactions{
+				items: []transaction{
+					{amount: money.MustParse("0.1")},
+					{amount: money.MustParse("0.2")},
+					{amount: money.MustParse("-0.3")},
+				},
+			},
+			expected: money.FromInt(0),
 		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			assert.Equal(t, test.expected, summarizer{}.getBalance(test.transactions))
+			assert.Equal(t, test.expected, summarize(t, test.transactions).getBalance())
 
 		})
 	}
@@ -64,46 +111,46 @@ func TestSummarizerGetDebitAvg(t *testing.T) {
 	tests := []struct {
 		name         string
 		transactions transactions
-		expected     float64
+		expected     money.Amount
 	}{
 		{
 			name: "without debit transactions return 0",
 			transactions: transactions{
 				items: []transaction{
-					{amount: 10},
-					{amount: 20},
-					{amount: 30.5},
+					{amount: money.FromInt(10)},
+					{amount: money.FromInt(20)},
+					{amount: money.MustParse("30.5")},
 				},
 			},
-			expected: 0,
+			expected: money.FromInt(0),
 		},
 		{
 			name: "only debit transactions",
 			transactions: transactions{
 				items: []transaction{
-					{amount: -10},
-					{amount: -20},
-					{amount: -60},
+					{amount: money.FromInt(-10)},
+					{amount: money.FromInt(-20)},
+					{amount: money.FromInt(-60)},
 				},
 			},
-			expected: -30,
+			expected: money.FromInt(-30),
 		},
 		{
 			name: "debit and credit transactions",
 			transactions: transactions{
 				items: []transaction{
-					{amount: -10},
-					{amount: 20},
-					{amount: -60},
+					{amount: money.FromInt(-10)},
+					{amount: money.FromInt(20)},
+					{amount: money.FromInt(-60)},
 				},
 			},
-			expected: -35,
+			expected: money.FromInt(-35),
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			assert.Equal(t, test.expected, summarizer{}.getDebitAvg(test.transactions))
+			assert.Equal(t, test.expected, summarize(t, test.transactions).getDebitAvg())
 
 		})
 	}
@@ -113,46 +160,56 @@ func TestSummarizerGetCreditAvg(t *testing.T) {
 	tests := []struct {
 		name         string
 		transactions transactions
-		expected     float64
+		expected     money.Amount
 	}{
 		{
 			name: "without credit transactions return 0",
 			transactions: transactions{
 				items: []transaction{
-					{amount: -10},
-					{amount: -20},
-					{amount: -30.5},
+					{amount: money.FromInt(-10)},
+					{amount: money.FromInt(-20)},
+					{amount: money.MustParse("-30.5")},
 				},
 			},
-			expected: 0,
+			expected: money.FromInt(0),
 		},
 		{
 			name: "only credit transactions",
 			transactions: transactions{
 				items: []transaction{
-					{amount: 10},
-					{amount: 20},
-					{amount: 60},
+					{amount: money.FromInt(10)},
+					{amount: money.FromInt(20)},
+					{amount: money.FromInt(60)},
 				},
 			},
-			expected: 30,
+			expected: money.FromInt(30),
 		},
 		{
 			name: "debit and credit transactions",
 			transactions: transactions{
 				items: []transaction{
-					{amount: 10},
-					{amount: -20},
-					{amount: 60},
+					{amount: money.FromInt(10)},
+					{amount: money.FromInt(-20)},
+					{amount: money.FromInt(60)},
+				},
+			},
+			expected: money.FromInt(35),
+		},
+		{
+			name: "average rounded half away from zero",
+			transactions: transactions{
+				items: []transaction{
+					{amount: money.MustParse("0.01")},
+					{amount: money.MustParse("0.02")},
 				},
 			},
-			expected: 35,
+			expected: money.MustParse("0.02"),
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			assert.Equal(t, test.expected, summarizer{}.getCreditAvg(test.transactions))
+			assert.Equal(t, test.expected, summarize(t, test.transactions).getCreditAvg())
 
 		})
 	}
@@ -173,9 +230,9 @@ func TestSummarizerGetTotalTransactionsByMonth(t *testing.T) {
 			name: "only transactions in january",
 			transactions: transactions{
 				items: []transaction{
-					{amount: 10, date: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
-					{amount: 20, date: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
-					{amount: 30, date: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
+					{amount: money.FromInt(10), date: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
+					{amount: money.FromInt(20), date: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
+					{amount: money.FromInt(30), date: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
 				},
 			},
 			expected: map[time.Month]int{
@@ -186,9 +243,9 @@ func TestSummarizerGetTotalTransactionsByMonth(t *testing.T) {
 			name: "different months",
 			transactions: transactions{
 				items: []transaction{
-					{amount: -10, date: time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC)},
-					{amount: 20, date: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
-					{amount: -30, date: time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC)},
+					{amount: money.FromInt(-10), date: time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC)},
+					{amount: money.FromInt(20), date: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
+					{amount: money.FromInt(-30), date: time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC)},
 				},
 			},
 			expected: map[time.Month]int{
@@ -201,72 +258,552 @@ func TestSummarizerGetTotalTransactionsByMonth(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			assert.Equal(t, test.expected, summarizer{}.getTotalTransactionsByMonth(test.transactions))
+			assert.Equal(t, test.expected, summarize(t, test.transactions).getTotalTransactionsByMonth())
 		})
 	}
 }
 
 func TestSummarizerResume(t *testing.T) {
 	user := User{
-		UserID: 1,
-		Name:   "name",
-		Email:  "email",
+		UserID:   1,
+		Name:     "name",
+		Email:    "email",
+		Currency: "USD",
 	}
 
+	rates := newFXRates([]fxRate{
+		{
+			baseCurrency:  "USD",
+			quoteCurrency: "MXN",
+			rate:          money.MustParseRate("20"),
+			effectiveDate: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+	})
+
 	tests := []struct {
 		name         string
+		rates        fxRates
 		transactions transactions
 		expected     Resume
+		expectedErr  error
 	}{
 		{
 			name: "resume",
 			transactions: transactions{
 				items: []transaction{
-					{amount: -10, date: time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC)},
-					{amount: 15, date: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
-					{amount: -60, date: time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC)},
+					{amount: money.FromInt(-10), date: time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC)},
+					{amount: money.FromInt(15), date: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
+					{amount: money.FromInt(-60), date: time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC)},
 				},
 			},
 			expected: Resume{
-				User:      user,
-				Balance:   "-55.00",
-				CreditAvg: "15.00",
-				DebitAvg:  "-35.00",
+				User:           user,
+				Currency:       "USD",
+				Balance:        "-55.00",
+				MinBalance:     "-55.00",
+				MinBalanceDate: "2021-04-01",
+				MaxBalance:     "5.00",
+				MaxBalanceDate: "2021-01-01",
+				CreditAvg:      "15.00",
+				DebitAvg:       "-35.00",
 
 				MonthTransactions: []MonthTransaction{
-					{time.January.String(), 1},
-					{time.April.String(), 1},
-					{time.December.String(), 1},
+					singleTransactionMonth(time.January, money.FromInt(15), money.FromInt(15)),
+					singleTransactionMonth(time.April, money.FromInt(-60), money.FromInt(-45)),
+					singleTransactionMonth(time.December, money.FromInt(-10), money.FromInt(-55)),
+				},
+				UnusualActivity: []UnusualActivity{
+					{
+						Kind:     UnusualBalanceDrop,
+						Date:     "2021-04-01",
+						Amount:   "-60.00",
+						Currency: "USD",
+						Detail:   "balance fell from 5.00 to -55.00 in a day",
+					},
+				},
+			},
+		},
+		{
+			name:  "resume converted to reporting currency",
+			rates: rates,
+			transactions: transactions{
+				items: []transaction{
+					{
+						amount:   money.FromInt(-10),
+						date:     time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC),
+						currency: "USD",
+					},
+					{
+						amount:   money.FromInt(300),
+						date:     time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+						currency: "MXN",
+					},
+				},
+			},
+			expected: Resume{
+				User:           user,
+				Currency:       "USD",
+				Balance:        "5.00",
+				MinBalance:     "-10.00",
+				MinBalanceDate: "2021-12-01",
+				MaxBalance:     "5.00",
+				MaxBalanceDate: "2021-01-01",
+				CreditAvg:      "15.00",
+				DebitAvg:       "-10.00",
+				MonthTransactions: []MonthTransaction{
+					singleTransactionMonth(time.January, money.FromInt(15), money.FromInt(15)),
+					singleTransactionMonth(time.December, money.FromInt(-10), money.FromInt(5)),
+				},
+				CurrencySubtotals: []CurrencySubtotal{
+					{Currency: "MXN", Balance: "300.00", ConvertedBalance: "15.00", TotalTransactions: 1},
+					{Currency: "USD", Balance: "-10.00", ConvertedBalance: "-10.00", TotalTransactions: 1},
 				},
 			},
 		},
+		{
+			name:  "missing fx rate",
+			rates: rates,
+			transactions: transactions{
+				items: []transaction{
+					{
+						amount:   money.FromInt(300),
+						date:     time.Date(2020, time.December, 31, 0, 0, 0, 0, time.UTC),
+						currency: "MXN",
+					},
+				},
+			},
+			expectedErr: errors.New("there is no fx rate from MXN to USD effective on 2020-12-31"),
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			assert.Equal(t, test.expected, summarizer{}.resume(user, test.transactions))
+			var (
+				resume Resume
+				err    error
+				summ   = newSummarizer(user.Currency, test.rates)
+			)
+
+			for _, txn := range test.transactions.items {
+				if err = summ.add(txn); err != nil {
+					break
+				}
+			}
+
+			if err == nil {
+				resume = summ.resume(user)
+			}
+
+			assert.Equal(t, test.expected, resume)
+			assert.Equal(t, test.expectedErr, err)
 		})
 	}
 }
 
+func TestSummarizerMonthBreakdown(t *testing.T) {
+	var (
+		march = time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+		summ  = summarize(t, transactions{
+			items: []transaction{
+				{amount: money.FromInt(100), date: march.AddDate(0, -1, 0)},
+				{amount: money.FromInt(50), date: march, description: "salary"},
+				{amount: money.MustParse("-20.5"), date: march, description: "groceries"},
+				{amount: money.FromInt(-70), date: march.AddDate(0, 0, 1), description: "rent"},
+				{amount: money.FromInt(25), date: march.AddDate(0, 0, 2)},
+			},
+		})
+	)
+
+	assert.Equal(t, []MonthTransaction{
+		singleTransactionMonth(time.February, money.FromInt(100), money.FromInt(100)),
+		{
+			Month:                      time.March.String(),
+			TotalTransactions:          4,
+			Credits:                    "75.00",
+			Debits:                     "-90.50",
+			NetChange:                  "-15.50",
+			EndBalance:                 "84.50",
+			CreditCount:                2,
+			DebitCount:                 2,
+			CreditAvg:                  "37.50",
+			DebitAvg:                   "-45.25",
+			LargestMovement:            "-70.00",
+			LargestMovementDescription: "rent",
+		},
+	}, summ.resume(User{}).MonthTransactions)
+}
+
+func TestSummarizerOpeningBalance(t *testing.T) {
+	var (
+		date = time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+		summ = newSummarizer("USD", fxRates{})
+	)
+
+	summ.opening = money.FromInt(100)
+	require.Nil(t, summ.add(transaction{amount: money.FromInt(-30), date: date}))
+	require.Nil(t, summ.add(transaction{amount: money.FromInt(50), date: date.AddDate(0, 0, 1)}))
+	require.Nil(t, summ.add(transaction{amount: money.FromInt(-150), date: date.AddDate(0, 1, 0)}))
+
+	assert.Equal(t, Resume{
+		User:           User{Currency: "USD"},
+		Currency:       "USD",
+		OpeningBalance: "100.00",
+		Balance:        "-30.00",
+		MinBalance:     "-30.00",
+		MinBalanceDate: "2021-04-01",
+		MaxBalance:     "120.00",
+		MaxBalanceDate: "2021-03-02",
+		CreditAvg:      "50.00",
+		DebitAvg:       "-90.00",
+		MonthTransactions: []MonthTransaction{
+			{
+				Month:             time.March.String(),
+				TotalTransactions: 2,
+				Credits:           "50.00",
+				Debits:            "-30.00",
+				NetChange:         "20.00",
+				EndBalance:        "120.00",
+				CreditCount:       1,
+				DebitCount:        1,
+				CreditAvg:         "50.00",
+				DebitAvg:          "-30.00",
+				LargestMovement:   "50.00",
+			},
+			singleTransactionMonth(time.April, money.FromInt(-150), money.FromInt(-30)),
+		},
+		UnusualActivity: []UnusualActivity{
+			{
+				Kind:     UnusualBalanceDrop,
+				Date:     "2021-04-01",
+				Amount:   "-150.00",
+				Currency: "USD",
+				Detail:   "balance fell from 120.00 to -30.00 in a day",
+			},
+		},
+	}, summ.resume(User{Currency: "USD"}))
+}
+
+func TestSummarizerCategoryTotals(t *testing.T) {
+	var (
+		date = time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+		summ = summarize(t, transactions{
+			items: []transaction{
+				{amount: money.FromInt(-30), date: date, category: "Groceries"},
+				{amount: money.FromInt(5), date: date, category: "Groceries"},
+				{amount: money.FromInt(-10), date: date},
+				{amount: money.FromInt(-800), date: date, category: "Rent"},
+			},
+		})
+	)
+
+	assert.Equal(t, []CategoryTotal{
+		{Category: "Groceries", TotalTransactions: 2, Credits: "5.00", Debits: "-30.00", Total: "-25.00"},
+		{Category: "Rent", TotalTransactions: 1, Credits: "0.00", Debits: "-800.00", Total: "-800.00"},
+		{Category: uncategorized, TotalTransactions: 1, Credits: "0.00", Debits: "-10.00", Total: "-10.00"},
+	}, summ.resume(User{}).CategoryTotals)
+
+	assert.Nil(t, summarize(t, transactions{
+		items: []transaction{{amount: money.FromInt(-10), date: date}},
+	}).resume(User{}).CategoryTotals)
+}
+
+func TestSummarizerBucketsInLocation(t *testing.T) {
+	var (
+		summ = newSummarizer("USD", fxRates{})
+		date = time.Date(2022, time.January, 31, 23, 30, 0, 0, time.FixedZone("", -6*60*60))
+	)
+
+	location, err := time.LoadLocation("Asia/Tokyo")
+	require.Nil(t, err)
+	summ.location = location
+
+	require.Nil(t, summ.add(transaction{amount: money.FromInt(10), date: date}))
+	require.Nil(t, summ.add(transaction{amount: money.FromInt(10), date: date.Add(-24 * time.Hour)}))
+
+	assert.Equal(t, map[time.Month]int{time.January: 1, time.February: 1}, summ.getTotalTransactionsByMonth())
+}
+
+func TestSummarizerConvertsOnTheDayOfItsLocation(t *testing.T) {
+	var (
+		rates = newFXRates([]fxRate{
+			{
+				baseCurrency:  "USD",
+				quoteCurrency: "MXN",
+				rate:          money.MustParseRate("17"),
+				effectiveDate: time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC),
+			},
+			{
+				baseCurrency:  "USD",
+				quoteCurrency: "MXN",
+				rate:          money.MustParseRate("20"),
+				effectiveDate: time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC),
+			},
+		})
+		// the same instant, on the last day of February in Mexico City
+		instant = time.Date(2021, time.March, 1, 1, 0, 0, 0, time.UTC)
+	)
+
+	location, err := time.LoadLocation("America/Mexico_City")
+	require.Nil(t, err)
+
+	for _, date := range []time.Time{instant, instant.In(time.FixedZone("", -6*60*60))} {
+		summ := newSummarizer("USD", rates)
+		summ.location = location
+
+		require.Nil(t, summ.add(transaction{amount: money.FromInt(340), currency: "MXN", date: date}))
+		assert.Equal(t, money.FromInt(20), summ.getBalance())
+	}
+}
+
+func TestSummarizerResumeByFiscalYear(t *testing.T) {
+	var (
+		user = User{UserID: 1, Name: "name", Currency: "USD"}
+		txns = transactions{
+			items: []transaction{
+				{amount: money.FromInt(10), date: time.Date(2021, time.March, 31, 0, 0, 0, 0, time.UTC)},
+				{amount: money.FromInt(-4), date: time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC)},
+				{amount: money.FromInt(6), date: time.Date(2022, time.January, 15, 0, 0, 0, 0, time.UTC)},
+			},
+		}
+		firstYear = Resume{
+			User:           user,
+			Year:           "2020/2021",
+			Currency:       "USD",
+			Balance:        "10.00",
+			MinBalance:     "10.00",
+			MinBalanceDate: "2021-03-31",
+			MaxBalance:     "10.00",
+			MaxBalanceDate: "2021-03-31",
+			CreditAvg:      "10.00",
+			DebitAvg:       "0.00",
+			MonthTransactions: []MonthTransaction{
+				singleTransactionMonth(time.March, money.FromInt(10), money.FromInt(10)),
+			},
+		}
+		secondYear = Resume{
+			User:           user,
+			Year:           "2021/2022",
+			Currency:       "USD",
+			OpeningBalance: "10.00",
+			Balance:        "12.00",
+			MinBalance:     "6.00",
+			MinBalanceDate: "2021-04-01",
+			MaxBalance:     "12.00",
+			MaxBalanceDate: "2022-01-15",
+			CreditAvg:      "6.00",
+			DebitAvg:       "-4.00",
+			MonthTransactions: []MonthTransaction{
+				singleTransactionMonth(time.April, money.FromInt(-4), money.FromInt(6)),
+				singleTransactionMonth(time.January, money.FromInt(6), money.FromInt(12)),
+			},
+		}
+		summ = newSummarizer(user.Currency, fxRates{})
+	)
+
+	summ.fiscalYearStart = time.April
+	for _, txn := range txns.items {
+		require.Nil(t, summ.add(txn))
+	}
+
+	assert.Equal(t, []Resume{firstYear, secondYear}, summ.yearResumes(user))
+	assert.Equal(t, Resume{
+		User:           user,
+		Currency:       "USD",
+		Balance:        "12.00",
+		MinBalance:     "6.00",
+		MinBalanceDate: "2021-04-01",
+		MaxBalance:     "12.00",
+		MaxBalanceDate: "2022-01-15",
+		CreditAvg:      "8.00",
+		DebitAvg:       "-4.00",
+		Years: []YearResume{
+			{
+				Year:              firstYear.Year,
+				Balance:           firstYear.Balance,
+				CreditAvg:         firstYear.CreditAvg,
+				DebitAvg:          firstYear.DebitAvg,
+				MonthTransactions: firstYear.MonthTransactions,
+			},
+			{
+				Year:              secondYear.Year,
+				Balance:           secondYear.Balance,
+				CreditAvg:         secondYear.CreditAvg,
+				DebitAvg:          secondYear.DebitAvg,
+				MonthTransactions: secondYear.MonthTransactions,
+			},
+		},
+	}, summ.resume(user))
+}
+
+func TestSummarizerYearOpeningWhateverTheFileOrder(t *testing.T) {
+	txns := []transaction{
+		{amount: money.FromInt(6), date: time.Date(2022, time.January, 15, 0, 0, 0, 0, time.UTC)},
+		{amount: money.FromInt(-4), date: time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		{amount: money.FromInt(10), date: time.Date(2020, time.March, 31, 0, 0, 0, 0, time.UTC)},
+		{amount: money.FromInt(1), date: time.Date(2022, time.February, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	summ := newSummarizer("USD", fxRates{})
+	summ.opening = money.FromInt(100)
+	for _, txn := range txns {
+		require.Nil(t, summ.add(txn))
+	}
+
+	resumes := summ.yearResumes(User{Currency: "USD"})
+	require.Len(t, resumes, 3)
+
+	assert.Equal(t, "100.00", resumes[0].OpeningBalance)
+	assert.Equal(t, "110.00", resumes[0].Balance)
+	assert.Equal(t, "110.00", resumes[1].OpeningBalance)
+	assert.Equal(t, "106.00", resumes[1].Balance)
+	assert.Equal(t, "106.00", resumes[2].OpeningBalance)
+	assert.Equal(t, "113.00", resumes[2].Balance)
+	assert.Equal(t, "112.00", resumes[2].MinBalance)
+	assert.Equal(t, "113.00", resumes[2].MaxBalance)
+}
+
 func TestResumeToHTML(t *testing.T) {
 	resume := Resume{
-		User:      User{Name: "name"},
-		Balance:   "1.12",
-		CreditAvg: "1.4",
-		DebitAvg:  "1.55",
+		User:           User{Name: "name"},
+		Currency:       "USD",
+		Balance:        "1.12",
+		MinBalance:     "1.12",
+		MinBalanceDate: "2021-12-31",
+		MaxBalance:     "2.67",
+		MaxBalanceDate: "2021-12-01",
+		CreditAvg:      "1.4",
+		DebitAvg:       "1.55",
 		MonthTransactions: []MonthTransaction{
+			singleTransactionMonth(time.January, money.MustParse("1.4"), money.MustParse("1.4")),
 			{
-				Month:             time.January.String(),
-				TotalTransactions: 5,
+				Month:                      time.December.String(),
+				TotalTransactions:          2,
+				Credits:                    "1.27",
+				Debits:                     "-1.55",
+				NetChange:                  "-0.28",
+				EndBalance:                 "1.12",
+				CreditCount:                1,
+				DebitCount:                 1,
+				CreditAvg:                  "1.27",
+				DebitAvg:                   "-1.55",
+				LargestMovement:            "-1.55",
+				LargestMovementDescription: "rent",
 			},
-			{
-				Month:             time.December.String(),
-				TotalTransactions: 5,
+		},
+	}
+
+	multiCurrencyResume := resume
+	multiCurrencyResume.CurrencySubtotals = []CurrencySubtotal{
+		{Currency: "MXN", Balance: "20.00", ConvertedBalance: "1.00", TotalTransactions: 1},
+	}
+
+	multiYearResume := resume
+	multiYearResume.MonthTransactions = nil
+	multiYearResume.Years = []YearResume{
+		{
+			Year:      "2021",
+			Balance:   "1.00",
+			CreditAvg: "1.00",
+			DebitAvg:  "0.00",
+			MonthTransactions: []MonthTransaction{
+				singleTransactionMonth(time.December, money.FromInt(1), money.FromInt(1)),
 			},
 		},
+		{
+			Year:      "2022",
+			Balance:   "0.12",
+			CreditAvg: "0.40",
+			DebitAvg:  "-0.28",
+			MonthTransactions: []MonthTransaction{
+				singleTransactionMonth(time.January, money.MustParse("0.12"), money.MustParse("0.12")),
+			},
+		},
+	}
+
+	yearResume := resume
+	yearResume.Year = "2021/2022"
+
+	openingResume := resume
+	openingResume.OpeningBalance = "-0.28"
+
+	categorizedResume := resume
+	categorizedResume.CategoryTotals = []CategoryTotal{
+		{Category: "Rent", TotalTransactions: 1, Credits: "0.00", Debits: "-1.55", Total: "-1.55"},
+	}
+
+	comparedResume := resume
+	comparedResume.Comparison = &Comparison{
+		PreviousFrom: "2020-01-01",
+		PreviousTo:   "2020-12-31",
+		NetChange:    ComparedAmount{Current: "1.12", Previous: "0.00", Delta: "1.12"},
+		CreditAvg:    ComparedAmount{Current: "1.34", Previous: "1.00", Delta: "0.34", DeltaPercentage: "34.00"},
+		DebitAvg:     ComparedAmount{Current: "-1.55", Previous: "-1.00", Delta: "-0.55", DeltaPercentage: "-55.00"},
+		MonthTransactions: []ComparedMonth{
+			{Month: "January", Current: 1, Previous: 2, Delta: -1, DeltaPercentage: "-50.00"},
+		},
 	}
 
+	unusualResume := resume
+	unusualResume.UnusualActivity = []UnusualActivity{
+		{
+			Kind:        UnusualTransaction,
+			Date:        "2021-12-01",
+			Description: "rent",
+			Amount:      "-1.55",
+			Currency:    "USD",
+			Detail:      "unusually large transaction, usually -0.50 (robust z-score 4.2)",
+		},
+	}
+
+	recurringResume := resume
+	recurringResume.RecurringMovements = []RecurringMovement{
+		{
+			Description:      "rent",
+			Currency:         "USD",
+			Frequency:        "monthly",
+			Occurrences:      3,
+			AverageAmount:    "-1.55",
+			LastDate:         "2021-12-01",
+			NextExpectedDate: "2022-01-01",
+			AnnualizedCost:   "-18.60",
+		},
+	}
+
+	forecastResume := resume
+	forecastResume.Forecast = &Forecast{
+		Currency: "USD",
+		Balance:  "1.12",
+		Date:     "2021-12-31",
+		Months: []ForecastMonth{
+			{Month: "2022-01", Balance: "0.80", Low: "0.50", High: "1.10"},
+		},
+	}
+
+	var (
+		head = "<body>    <img src=\"https://blog.storicard.com/wp-content/uploads/2019/07/Stori-horizontal-11.jpg\">" +
+			"    <p>Hello name</p>"
+		averages = "    <h1>Balance: 1.12 USD</h1>" +
+			"    <p>Lowest balance: 1.12 USD on 2021-12-31</p>    <p>Highest balance: 2.67 USD on 2021-12-01</p>" +
+			"    <h1>Credit Average: 1.4</h1>    <h1>Debit Average: 1.55</h1>"
+		charts = func(months []MonthTransaction) string {
+			images := chartImages{}
+			monthsImage, err := images.image(monthsChart)(months)
+			require.Nil(t, err)
+			balanceImage, err := images.image(balanceChart)(months)
+			require.Nil(t, err)
+			return "    <p>" + string(monthsImage) + "</p>    <p>" + string(balanceImage) + "</p>"
+		}
+		tableStart = "    <table>        <tr>            <th>Month</th><th>Transactions</th><th>Credits</th>" +
+			"<th>Debits</th>            <th>Net change</th><th>End balance</th><th>Largest movement</th>        </tr>"
+		months = tableStart +
+			"        <tr>            <td>January</td><td>1</td>            <td>1.40 (1, avg 1.40)</td>" +
+			"            <td>0.00 (0, avg 0.00)</td>            <td>1.40</td><td>1.40</td>            <td>1.40</td>" +
+			"        </tr>" +
+			"        <tr>            <td>December</td><td>2</td>            <td>1.27 (1, avg 1.27)</td>" +
+			"            <td>-1.55 (1, avg -1.55)</td>            <td>-0.28</td><td>1.12</td>" +
+			"            <td>-1.55 (rent)</td>        </tr>    </table>" + charts(resume.MonthTransactions)
+	)
+
 	tests := []struct {
 		name        string
 		tmpl        string
@@ -281,13 +818,112 @@ func TestResumeToHTML(t *testing.T) {
 			expectedErr: true,
 		},
 		{
-			name:   "error parsing",
-			resume: resume,
+			name:        "error parsing",
+			resume:      resume,
+			tmpl:        resumeHTMLTemplate,
+			result:      head + averages + "    <h1>Transactions by month</h1>" + months + "</body>",
+			expectedErr: false,
+		},
+		{
+			name:   "parsing with currency subtotals",
+			resume: multiCurrencyResume,
+			tmpl:   resumeHTMLTemplate,
+			result: head + averages + "    <h1>Transactions by month</h1>" + months +
+				"    <h1>Balance by currency</h1>    <p>         <p>MXN: 20.00 (1.00 USD)</p>    </p></body>",
+			expectedErr: false,
+		},
+		{
+			name:   "parsing with year sections",
+			resume: multiYearResume,
+			tmpl:   resumeHTMLTemplate,
+			result: head + averages +
+				"    <h1>Year 2021</h1>    <p>Balance: 1.00 USD</p>    <p>Credit Average: 1.00</p>" +
+				"    <p>Debit Average: 0.00</p>" + tableStart +
+				"        <tr>            <td>December</td><td>1</td>            <td>1.00 (1, avg 1.00)</td>" +
+				"            <td>0.00 (0, avg 0.00)</td>            <td>1.00</td><td>1.00</td>            <td>1.00</td>" +
+				"        </tr>    </table>" + charts(multiYearResume.Years[0].MonthTransactions) +
+				"    <h1>Year 2022</h1>    <p>Balance: 0.12 USD</p>    <p>Credit Average: 0.40</p>" +
+				"    <p>Debit Average: -0.28</p>" + tableStart +
+				"        <tr>            <td>January</td><td>1</td>            <td>0.12 (1, avg 0.12)</td>" +
+				"            <td>0.00 (0, avg 0.00)</td>            <td>0.12</td><td>0.12</td>            <td>0.12</td>" +
+				"        </tr>    </table>" + charts(multiYearResume.Years[1].MonthTransactions) + "</body>",
+			expectedErr: false,
+		},
+		{
+			name:   "parsing resume of a year",
+			resume: yearResume,
+			tmpl:   resumeHTMLTemplate,
+			result: head + "    <h1>Year: 2021/2022</h1>" + averages + "    <h1>Transactions by month</h1>" + months +
+				"</body>",
+			expectedErr: false,
+		},
+		{
+			name:   "parsing with opening balance",
+			resume: openingResume,
+			tmpl:   resumeHTMLTemplate,
+			result: head + "    <h1>Opening Balance: -0.28 USD</h1>" + averages + "    <h1>Transactions by month</h1>" +
+				months + "</body>",
+			expectedErr: false,
+		},
+		{
+			name:   "parsing with category totals",
+			resume: categorizedResume,
 			tmpl:   resumeHTMLTemplate,
-			result: "<body>    <img src=\"https://blog.storicard.com/wp-content/uploads/2019/07/Stori-horizontal-11.jpg\"> " +
-				"   <p>Hello name</p>    <h1>Balance: 1.12</h1>    <h1>Credit Average: 1.4</h1>   " +
-				" <h1>Debit Average: 1.55</h1>    <h1>Transactions by month</h1>    " +
-				"<p>         <p>January: 5</p> <p>December: 5</p>    </p></body>",
+			result: head + averages + "    <h1>Transactions by month</h1>" + months +
+				"    <h1>Totals by category</h1>    <table>        <tr>            <th>Category</th>" +
+				"<th>Transactions</th><th>Credits</th><th>Debits</th><th>Total</th>        </tr>" +
+				"        <tr>            <td>Rent</td><td>1</td>            <td>0.00</td><td>-1.55</td>" +
+				"<td>-1.55</td>        </tr>    </table></body>",
+			expectedErr: false,
+		},
+		{
+			name:   "parsing with recurring movements",
+			resume: recurringResume,
+			tmpl:   resumeHTMLTemplate,
+			result: head + averages + "    <h1>Transactions by month</h1>" + months +
+				"    <h1>Recurring movements</h1>    <table>        <tr>            <th>Description</th>" +
+				"<th>Frequency</th><th>Average amount</th>            <th>Next expected</th>" +
+				"<th>Annualized cost</th>        </tr>        <tr>            <td>rent</td><td>monthly</td>" +
+				"<td>-1.55 USD</td>            <td>2022-01-01</td><td>-18.60 USD</td>        </tr>    </table></body>",
+			expectedErr: false,
+		},
+		{
+			name:   "parsing with comparison",
+			resume: comparedResume,
+			tmpl:   resumeHTMLTemplate,
+			result: head + averages + "    <h1>Transactions by month</h1>" + months +
+				"    <h1>Compared with 2020-01-01 to 2020-12-31</h1>    <table>        <tr>            <th></th>" +
+				"<th>This period</th><th>Previous period</th><th>Change</th>        </tr>" +
+				"        <tr>            <td>Net change</td>            <td>1.12</td><td>0.00</td>" +
+				"            <td>1.12</td>        </tr>" +
+				"        <tr>            <td>Credit average</td>            <td>1.34</td><td>1.00</td>" +
+				"            <td>0.34 (34.00%)</td>        </tr>" +
+				"        <tr>            <td>Debit average</td>            <td>-1.55</td><td>-1.00</td>" +
+				"            <td>-0.55 (-55.00%)</td>        </tr>" +
+				"        <tr>            <td>January transactions</td><td>1</td><td>2</td>" +
+				"            <td>-1 (-50.00%)</td>        </tr>    </table></body>",
+			expectedErr: false,
+		},
+		{
+			name:   "parsing with balance forecast",
+			resume: forecastResume,
+			tmpl:   resumeHTMLTemplate,
+			result: head + averages + "    <h1>Transactions by month</h1>" + months +
+				"    <h1>Balance forecast</h1>    <p>From 1.12 USD on 2021-12-31</p>    <table>        <tr>" +
+				"            <th>Month</th><th>End balance</th><th>Low</th><th>High</th>        </tr>" +
+				"        <tr>            <td>2022-01</td><td>0.80</td><td>0.50</td><td>1.10</td>        </tr>" +
+				"    </table></body>",
+			expectedErr: false,
+		},
+		{
+			name:   "parsing with unusual activity",
+			resume: unusualResume,
+			tmpl:   resumeHTMLTemplate,
+			result: head + averages + "    <h1>Transactions by month</h1>" + months +
+				"    <h1>Unusual activity</h1>    <table>        <tr>            <th>Date</th><th>Description</th>" +
+				"<th>Amount</th><th>Detail</th>        </tr>        <tr>            <td>2021-12-01</td><td>rent</td>" +
+				"<td>-1.55 USD</td><td>unusually large transaction, usually -0.50 (robust z-score 4.2)</td>" +
+				"        </tr>    </table></body>",
 			expectedErr: false,
 		},
 	}
@@ -300,3 +936,65 @@ func TestResumeToHTML(t *testing.T) {
 		})
 	}
 }
+
+func TestResumeToEmail(t *testing.T) {
+	resume := Resume{
+		MonthTransactions: []MonthTransaction{
+			singleTransactionMonth(time.January, money.MustParse("1.4"), money.MustParse("1.4")),
+		},
+	}
+
+	html, attachments, err := resume.ToEmail(resumeHTMLTemplate)
+	require.Nil(t, err)
+	require.Len(t, attachments, 2)
+	for i, attachment := range attachments {
+		contentID := fmt.Sprintf("chart-%d@transaction-tool", i+1)
+		assert.Equal(t, contentID, attachment.ContentID)
+		assert.True(t, attachment.Inline)
+		assert.Equal(t, "image/png", attachment.ContentType)
+		assert.Contains(t, html, `<img src="cid:`+contentID+`"`)
+	}
+	assert.NotContains(t, html, "data:image/png")
+
+	_, attachments, err = resume.ToEmail("<h1>Balance: {{.Bad}}</h1>")
+	assert.NotNil(t, err)
+	assert.Nil(t, attachments)
+}
+
+func TestResumeToText(t *testing.T) {
+	resume := Resume{
+		Currency:       "USD",
+		Balance:        "90.00",
+		MinBalance:     "-10.00",
+		MinBalanceDate: "2022-01-02",
+		MaxBalance:     "100.00",
+		MaxBalanceDate: "2022-01-01",
+		CreditAvg:      "100.00",
+		DebitAvg:       "-110.00",
+	}
+
+	tests := []struct {
+		name     string
+		year     string
+		expected string
+	}{
+		{
+			name: "whole resume",
+			expected: "Transaction resume: balance 90.00 USD, lowest -10.00 on 2022-01-02, " +
+				"highest 100.00 on 2022-01-01, credit average 100.00, debit average -110.00.",
+		},
+		{
+			name: "resume of a year",
+			year: "2021/2022",
+			expected: "Transaction resume 2021/2022: balance 90.00 USD, lowest -10.00 on 2022-01-02, " +
+				"highest 100.00 on 2022-01-01, credit average 100.00, debit average -110.00.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resume.Year = test.year
+			assert.Equal(t, test.expected, resume.ToText())
+		})
+	}
+}
@@ -1,13 +1,139 @@
 package summarizer
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+	"transaction-tool-api/src/internal/money"
+)
 
 type transactions struct {
-	items  []transaction
-	userID int64
+	items   []transaction
+	userID  int64
+	batchID int64
 }
 
 type transaction struct {
-	amount float64
-	date   time.Time
+	amount      money.Amount
+	date        time.Time
+	currency    string
+	description string
+	reference   string
+	category    string
+	// balance is the running balance of the user after the transaction, in the reporting currency.
+	balance money.Amount
+}
+
+// dailyBalance is the sum of the transactions saved for a user in a currency on a day.
+type dailyBalance struct {
+	currency string
+	date     time.Time
+	amount   money.Amount
+}
+
+// dailyTotal aggregates the transactions saved for a user in a currency on a day.
+type dailyTotal struct {
+	currency    string
+	date        time.Time
+	count       int
+	credits     money.Amount
+	creditCount int64
+	debits      money.Amount
+	debitCount  int64
+}
+
+// fingerprint identifies a bank movement of the user, so that it is not saved twice when files overlap.
+func (txn transaction) fingerprint(userID int64) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf(
+		"%d|%s|%s|%s|%s|%s",
+		userID,
+		txn.amount,
+		txn.currency,
+		txn.date.UTC().Format(time.RFC3339Nano),
+		txn.reference,
+		txn.description,
+	)))
+	return hex.EncodeToString(hash[:])
+}
+
+type duplicateTransactionError struct {
+	txn transaction
+}
+
+func (e duplicateTransactionError) Error() string {
+	return fmt.Sprintf(
+		"transaction of %s %s dated %s was already saved by an earlier upload",
+		e.txn.amount, e.txn.currency, e.txn.date.Format(time.RFC3339))
+}
+
+// transactionsSavedError is returned when every transaction of the file was skipped as already saved.
+type transactionsSavedError struct {
+	userID  int64
+	skipped int
+}
+
+func (e transactionsSavedError) Error() string {
+	return fmt.Sprintf("every transaction of the file (%d) was already saved for user id %d", e.skipped, e.userID)
+}
+
+type ImportResult struct {
+	BatchID              int64
+	InsertedTransactions int
+	SkippedTransactions  int
+}
+
+// transactionIterator yields the transactions of a user one at a time, so that they can be
+// saved and summarized without holding all of them in memory.
+type transactionIterator interface {
+	getUserID() int64
+	getChecksum() string
+	next() (transaction, bool, error)
+}
+
+// historySource calls the function with every transaction saved for a user, from the oldest one, stopping
+// at its first error, so that the history can be analyzed without holding all of it in memory.
+type historySource func(func(transaction) error) error
+
+// sliceHistory returns the transactions as a history source, sorted from the oldest one.
+func sliceHistory(txns []transaction) historySource {
+	sorted := append([]transaction(nil), txns...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].date.Before(sorted[j].date)
+	})
+
+	return func(fn func(transaction) error) error {
+		for _, txn := range sorted {
+			if err := fn(txn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+type transactionsIterator struct {
+	txns     transactions
+	position int
+}
+
+func (txns transactions) iterator() *transactionsIterator {
+	return &transactionsIterator{txns: txns}
+}
+
+func (it *transactionsIterator) getUserID() int64 {
+	return it.txns.userID
+}
+
+func (it *transactionsIterator) getChecksum() string {
+	return ""
+}
+
+func (it *transactionsIterator) next() (transaction, bool, error) {
+	if it.position >= len(it.txns.items) {
+		return transaction{}, false, nil
+	}
+	it.position++
+	return it.txns.items[it.position-1], true, nil
 }
@@ -0,0 +1,194 @@
+package summarizer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+func NewWorker(repository Repository, service Service, options WorkerOptions) Worker {
+	return worker{
+		repository:   repository,
+		service:      service,
+		size:         options.Size,
+		pollInterval: options.PollInterval,
+		lease:        options.Lease,
+		wakeUps:      make(chan struct{}, options.Size),
+	}
+}
+
+type Worker interface {
+	Start(ctx context.Context)
+	wakeUp()
+}
+
+// WorkerOptions configure the worker pool. Jobs being processed renew their lease, and the ones whose lease
+// expired, as the instance processing them stopped, are queued again.
+type WorkerOptions struct {
+	Size         int
+	PollInterval time.Duration
+	Lease        time.Duration
+}
+
+func GetWorkerOptions() WorkerOptions {
+	return WorkerOptions{
+		Size:         2,
+		PollInterval: 5 * time.Second,
+		Lease:        2 * time.Minute,
+	}
+}
+
+type worker struct {
+	parser       parser
+	repository   Repository
+	service      Service
+	size         int
+	pollInterval time.Duration
+	lease        time.Duration
+	wakeUps      chan struct{}
+}
+
+func (w worker) Start(ctx context.Context) {
+	w.requeueInterruptedJobs(ctx)
+
+	go w.watchLeases(ctx)
+
+	for i := 0; i < w.size; i++ {
+		go w.run(ctx)
+	}
+}
+
+// watchLeases queues again, every lease, the jobs whose lease expired.
+func (w worker) watchLeases(ctx context.Context) {
+	ticker := time.NewTicker(w.lease)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.requeueInterruptedJobs(ctx)
+		}
+	}
+}
+
+func (w worker) requeueInterruptedJobs(ctx context.Context) {
+	requeued, err := w.repository.requeueInterruptedJobs(ctx, w.lease)
+	if err != nil {
+		log.Printf("error requeueing interrupted jobs due to: %s", err.Error())
+		return
+	}
+	if requeued > 0 {
+		w.wakeUp()
+	}
+}
+
+func (w worker) wakeUp() {
+	select {
+	case w.wakeUps <- struct{}{}:
+	default:
+	}
+}
+
+func (w worker) run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for w.processNextJob(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wakeUps:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w worker) processNextJob(ctx context.Context) bool {
+	job, found, err := w.repository.claimNextJob(ctx)
+	if err != nil {
+		log.Printf("error claiming next job due to: %s", err.Error())
+		return false
+	}
+
+	if !found {
+		return false
+	}
+
+	var (
+		status    = JobStatusDone
+		jobErr    string
+		jobResult = job.Result
+	)
+
+	// a job whose transactions were imported is only finished, as importing them again would duplicate them
+	if jobResult == nil {
+		result, err := w.processJob(ctx, job)
+		switch {
+		case errors.As(err, &jobImportedError{}):
+			// another run of the job imported them and finishes it
+			log.Printf("error processing job id %d due to: %s", job.ID, err.Error())
+			return true
+		case err != nil:
+			status, jobErr = JobStatusFailed, err.Error()
+		default:
+			jobResult = &result
+		}
+	}
+
+	if err = w.repository.finishJob(ctx, job.ID, status, jobErr, jobResult); err != nil {
+		log.Printf("error finishing job id %d due to: %s", job.ID, err.Error())
+	}
+
+	return true
+}
+
+func (w worker) processJob(ctx context.Context, job Job) (ImportResult, error) {
+	reader := w.parser.newUploadReader(bytes.NewReader(job.payload), job.UserID, job.options)
+
+	if w.lease > 0 {
+		stop := w.renewLease(ctx, job.ID)
+		defer stop()
+	}
+
+	ctx = withJob(ctx, job.ID)
+	ctx = withProgress(ctx, func(ctx context.Context, status JobStatus) {
+		if err := w.repository.updateJobStatus(ctx, job.ID, status); err != nil {
+			log.Printf("error reporting progress of job id %d due to: %s", job.ID, err.Error())
+		}
+	})
+
+	return w.service.notifyResume(ctx, reader, job.options)
+}
+
+// renewLease renews the lease of the job three times by lease until the returned function is called.
+func (w worker) renewLease(ctx context.Context, jobID int64) func() {
+	var (
+		done   = make(chan struct{})
+		ticker = time.NewTicker(w.lease / 3)
+	)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := w.repository.renewJobLease(ctx, jobID); err != nil {
+					log.Printf("error renewing lease of job id %d due to: %s", jobID, err.Error())
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
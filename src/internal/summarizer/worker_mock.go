@@ -0,0 +1,19 @@
+package summarizer
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type workerMock struct {
+	mock.Mock
+}
+
+func (m *workerMock) Start(_ context.Context) {
+	m.Called()
+}
+
+func (m *workerMock) wakeUp() {
+	m.Called()
+}
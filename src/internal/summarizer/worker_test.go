@@ -0,0 +1,158 @@
+package summarizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"transaction-tool-api/src/internal/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerProcessNextJob(t *testing.T) {
+	var (
+		customErr = errors.New("custom error")
+		date      = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		job       = Job{
+			ID:      3,
+			UserID:  5,
+			Status:  JobStatusParsing,
+			payload: []byte("-10," + date.Format(time.RFC3339) + "\n"),
+			options: resumeOptions{Duplicates: duplicatesSkip},
+		}
+		bankTransactions = transactions{
+			items:  []transaction{{amount: money.FromInt(-10), date: date}},
+			userID: 5,
+		}
+	)
+
+	tests := []struct {
+		name        string
+		mockApplier func(rm *repositoryMock, sm *serviceMock)
+		expected    bool
+	}{
+		{
+			name: "error claiming job",
+			mockApplier: func(rm *repositoryMock, sm *serviceMock) {
+				rm.On("claimNextJob").Return(nil, false, customErr).Once()
+			},
+			expected: false,
+		},
+		{
+			name: "no queued jobs",
+			mockApplier: func(rm *repositoryMock, sm *serviceMock) {
+				rm.On("claimNextJob").Return(nil, false, nil).Once()
+			},
+			expected: false,
+		},
+		{
+			name: "job with bad csv fails",
+			mockApplier: func(rm *repositoryMock, sm *serviceMock) {
+				rm.On("claimNextJob").Return(Job{ID: 3, UserID: 5, payload: []byte("bad")}, true, nil).Once()
+				rm.On("finishJob", int64(3), JobStatusFailed, fmt.Errorf(
+					"for row number %d is expected %d or %d elements, however got %d", 1, 2, 3, 1).Error(),
+					(*ImportResult)(nil)).
+					Return(nil).Once()
+			},
+			expected: true,
+		},
+		{
+			name: "job with service error fails",
+			mockApplier: func(rm *repositoryMock, sm *serviceMock) {
+				rm.On("claimNextJob").Return(job, true, nil).Once()
+				sm.On("notifyResume", bankTransactions, job.options).Return(nil, customErr).Once()
+				rm.On("finishJob", int64(3), JobStatusFailed, customErr.Error(), (*ImportResult)(nil)).
+					Return(nil).Once()
+			},
+			expected: true,
+		},
+		{
+			name: "job imported before an interruption is finished",
+			mockApplier: func(rm *repositoryMock, sm *serviceMock) {
+				imported := job
+				imported.Result = &ImportResult{BatchID: 7, InsertedTransactions: 1}
+				rm.On("claimNextJob").Return(imported, true, nil).Once()
+				rm.On("finishJob", int64(3), JobStatusDone, "", &ImportResult{BatchID: 7, InsertedTransactions: 1}).
+					Return(nil).Once()
+			},
+			expected: true,
+		},
+		{
+			name: "job imported by another run is not finished",
+			mockApplier: func(rm *repositoryMock, sm *serviceMock) {
+				rm.On("claimNextJob").Return(job, true, nil).Once()
+				sm.On("notifyResume", bankTransactions, job.options).Return(nil, jobImportedError{jobID: 3}).Once()
+			},
+			expected: true,
+		},
+		{
+			name: "job done",
+			mockApplier: func(rm *repositoryMock, sm *serviceMock) {
+				rm.On("claimNextJob").Return(job, true, nil).Once()
+				sm.On("notifyResume", bankTransactions, job.options).
+					Return(ImportResult{InsertedTransactions: 1}, nil).Once()
+				rm.On("finishJob", int64(3), JobStatusDone, "", &ImportResult{InsertedTransactions: 1}).
+					Return(customErr).Once()
+			},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repoMock := &repositoryMock{}
+			servMock := &serviceMock{}
+			test.mockApplier(repoMock, servMock)
+			defer repoMock.AssertExpectations(t)
+			defer servMock.AssertExpectations(t)
+
+			w := worker{repository: repoMock, service: servMock}
+
+			assert.Equal(t, test.expected, w.processNextJob(context.TODO()))
+		})
+	}
+}
+
+func TestWorkerRequeueInterruptedJobs(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockApplier   func(rm *repositoryMock)
+		expectedWakes int
+	}{
+		{
+			name: "error requeueing jobs",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("requeueInterruptedJobs", time.Minute).Return(0, errors.New("custom error")).Once()
+			},
+		},
+		{
+			name: "no interrupted jobs",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("requeueInterruptedJobs", time.Minute).Return(0, nil).Once()
+			},
+		},
+		{
+			name: "interrupted jobs requeued",
+			mockApplier: func(rm *repositoryMock) {
+				rm.On("requeueInterruptedJobs", time.Minute).Return(2, nil).Once()
+			},
+			expectedWakes: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repoMock := &repositoryMock{}
+			test.mockApplier(repoMock)
+			defer repoMock.AssertExpectations(t)
+
+			w := worker{repository: repoMock, lease: time.Minute, wakeUps: make(chan struct{}, 1)}
+
+			w.requeueInterruptedJobs(context.TODO())
+
+			assert.Len(t, w.wakeUps, test.expectedWakes)
+		})
+	}
+}